@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/config"
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+// runCommand menjalankan subcommand CLI dan mengembalikan exit code
+func runCommand(args []string, dbMaster, dbKepegawaian *pgxpool.Pool, cfg *config.Config) int {
+	switch args[0] {
+	case "audit-verify":
+		return runAuditVerify(args[1:], dbMaster, cfg)
+	case "audit-anchor":
+		return runAuditAnchor(args[1:], dbMaster, cfg)
+	case "audit-export":
+		return runAuditExport(args[1:], dbMaster)
+	case "audit-archive":
+		return runAuditArchive(dbMaster, cfg)
+	case "audit-restore":
+		return runAuditRestore(args[1:], dbMaster)
+	case "pendidikan-sync":
+		return runPendidikanSync(dbMaster, dbKepegawaian)
+	case "tanggungan-sync":
+		return runTanggunganSync(dbKepegawaian)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\navailable commands: audit-verify, audit-anchor, audit-export, audit-archive, audit-restore, pendidikan-sync, tanggungan-sync\n", args[0])
+		return 2
+	}
+}
+
+// runAuditVerify memverifikasi hash chain audit log terhadap anchor dari file
+// (bila ada) dan dari database. Exit code 1 bila chain rusak.
+func runAuditVerify(args []string, dbMaster *pgxpool.Pool, cfg *config.Config) int {
+	fs := flag.NewFlagSet("audit-verify", flag.ContinueOnError)
+	anchorFile := fs.String("anchors", cfg.Audit.AnchorFile, "file JSONL anchor hasil ekspor")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	repo := repositories.NewAuditRepository(dbMaster)
+
+	start, err := repo.ChainStart(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	anchors, err := repo.ListAnchors(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *anchorFile != "" {
+		exported, err := audit.ReadAnchorFile(*anchorFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		anchors = append(anchors, exported...)
+	}
+
+	result, err := audit.VerifyChain(ctx, repo, start, anchors)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(out))
+	if !result.Valid {
+		return 1
+	}
+	return 0
+}
+
+// runAuditAnchor mengekspor anchor ujung chain saat ini ke file
+func runAuditAnchor(args []string, dbMaster *pgxpool.Pool, cfg *config.Config) int {
+	fs := flag.NewFlagSet("audit-anchor", flag.ContinueOnError)
+	anchorFile := fs.String("out", cfg.Audit.AnchorFile, "file JSONL tujuan anchor")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	anchor, err := audit.ExportAnchor(context.Background(), repositories.NewAuditRepository(dbMaster), *anchorFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if anchor == nil {
+		fmt.Println("no new audit rows since last anchor")
+		return 0
+	}
+
+	fmt.Printf("anchored seq %d (%s) to %s\n", anchor.Seq, anchor.RowHash, *anchorFile)
+	return 0
+}
+
+// runAuditExport menulis audit log yang cocok dengan filter ke file atau
+// stdout sebagai CSV/JSONL. Ekspor dicatat di audit_logs atas nama user OS.
+func runAuditExport(args []string, dbMaster *pgxpool.Pool) int {
+	fs := flag.NewFlagSet("audit-export", flag.ContinueOnError)
+	format := fs.String("format", audit.FormatCSV, "csv atau jsonl")
+	out := fs.String("out", "-", "file tujuan, - untuk stdout")
+	unmask := fs.Bool("unmask", false, "tulis data tanpa masking PII")
+	params := map[string]*string{}
+	for _, name := range []string{"action", "resource", "resource_id", "user_id", "username", "status", "ip_address", "from", "to", "changes", "changed_field"} {
+		params[name] = fs.String(name, "", "filter "+name)
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	filter, badParam := repositories.ParseAuditLogFilter(func(key string) string {
+		return *params[key]
+	})
+	if badParam != "" {
+		fmt.Fprintf(os.Stderr, "invalid -%s\n", badParam)
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	buffered := bufio.NewWriter(w)
+
+	exporter, err := audit.NewExportWriter(buffered, *format, !*unmask)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+	repo := repositories.NewAuditRepository(dbMaster)
+	err = repo.Stream(ctx, filter, exporter.Write)
+	if err == nil {
+		if err = exporter.Flush(); err == nil {
+			err = buffered.Flush()
+		}
+	}
+
+	query := []string{}
+	fs.Visit(func(f *flag.Flag) {
+		query = append(query, f.Name+"="+f.Value.String())
+	})
+	entry := repositories.AuditLogInput{
+		Username: "cli:" + osUsername(),
+		Action:   "export",
+		Resource: "audit_logs",
+		Status:   "success",
+		Changes: map[string]interface{}{
+			"source":   "cli",
+			"format":   *format,
+			"unmasked": *unmask,
+			"query":    strings.Join(query, "&"),
+			"rows":     exporter.Rows(),
+		},
+	}
+	if err != nil {
+		entry.Status = "failed"
+		msg := err.Error()
+		entry.ErrorMessage = &msg
+	}
+	if logErr := repo.Log(ctx, entry); logErr != nil {
+		fmt.Fprintln(os.Stderr, "failed to record export in audit log:", logErr)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "exported %d audit log rows\n", exporter.Rows())
+	return 0
+}
+
+// auditRetentionPolicy membentuk policy partisi audit log dari konfigurasi
+func auditRetentionPolicy(cfg *config.Config) audit.RetentionPolicy {
+	return audit.RetentionPolicy{
+		Months:     cfg.Audit.RetentionMonths,
+		Ahead:      cfg.Audit.PartitionsAhead,
+		ArchiveDir: cfg.Audit.ArchiveDir,
+	}
+}
+
+// auditDetector membentuk detector aktivitas mencurigakan dari file rule
+// dan webhook di konfigurasi
+func auditDetector(dbMaster *pgxpool.Pool, cfg *config.Config) (*audit.Detector, error) {
+	repo := repositories.NewAuditRepository(dbMaster)
+
+	ruleConfigs, err := audit.LoadRules(cfg.Audit.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := audit.BuildRules(ruleConfigs, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	notifiers := audit.Notifiers{audit.LogNotifier{}}
+	if cfg.Audit.AlertWebhookURL != "" {
+		notifiers = append(notifiers, audit.NewWebhookNotifier(cfg.Audit.AlertWebhookURL))
+	}
+	return audit.NewDetector(rules, repo, notifiers), nil
+}
+
+// runAuditArchive langsung menjalankan maintenance partisi: membuat partisi
+// ke depan lalu mengarsipkan partisi yang melewati masa retensi
+func runAuditArchive(dbMaster *pgxpool.Pool, cfg *config.Config) int {
+	repo := repositories.NewAuditRepository(dbMaster)
+	archives, err := auditRetentionPolicy(cfg).Apply(context.Background(), repo, time.Now())
+	for _, a := range archives {
+		fmt.Printf("archived %s (%d rows) to %s sha256 %s\n", a.Partition, a.Rows, a.File, a.SHA256)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(archives) == 0 {
+		fmt.Println("no audit partitions past retention")
+	}
+	return 0
+}
+
+// runAuditRestore memeriksa checksum dan hash chain file arsip lalu memuat
+// isinya ke audit_logs_restored untuk investigasi
+func runAuditRestore(args []string, dbMaster *pgxpool.Pool) int {
+	fs := flag.NewFlagSet("audit-restore", flag.ContinueOnError)
+	file := fs.String("file", "", "file arsip .jsonl.gz")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		return 2
+	}
+
+	entries := []audit.ChainEntry{}
+	err := audit.ReadArchive(*file, func(e audit.ChainEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx := context.Background()
+	repo := repositories.NewAuditRepository(dbMaster)
+
+	// File .sha256 bisa dibuat ulang bersama arsip yang diubah, sehingga
+	// checksum juga dicocokkan dengan catatan audit_archives
+	archives, err := repo.ListArchives(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	sum, err := audit.ArchiveChecksum(*file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	recorded := false
+	for _, a := range archives {
+		recorded = recorded || a.SHA256 == sum
+	}
+	if !recorded {
+		fmt.Fprintf(os.Stderr, "checksum %s is not recorded in audit_archives\n", sum)
+		return 1
+	}
+
+	restored, err := repo.RestoreEntries(ctx, *file, entries)
+	entry := repositories.AuditLogInput{
+		Username: "cli:" + osUsername(),
+		Action:   "restore",
+		Resource: "audit_logs",
+		Status:   "success",
+		Changes: map[string]interface{}{
+			"source": "cli",
+			"file":   *file,
+			"sha256": sum,
+			"rows":   restored,
+		},
+	}
+	if err != nil {
+		entry.Status = "failed"
+		msg := err.Error()
+		entry.ErrorMessage = &msg
+	}
+	if logErr := repo.Log(ctx, entry); logErr != nil {
+		fmt.Fprintln(os.Stderr, "failed to record restore in audit log:", logErr)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("restored %d audit log rows into audit_logs_restored\n", restored)
+	return 0
+}
+
+// osUsername mengembalikan user OS yang menjalankan CLI
+func osUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// runPendidikanSync menghitung ulang pendidikan tertinggi seluruh pegawai
+// dari riwayat pendidikan, dijalankan sekali setelah migration 21
+func runPendidikanSync(dbMaster, dbKepegawaian *pgxpool.Pool) int {
+	repo := repositories.NewRiwayatRepository(dbKepegawaian, dbMaster)
+	changed, err := repo.SyncPendidikanTerakhirAll(context.Background())
+	fmt.Printf("pendidikan terakhir updated for %d pegawai\n", changed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runTanggunganSync menghitung ulang status tanggungan keluarga seluruh
+// pegawai; dijadwalkan harian karena status anak berubah seiring usianya
+func runTanggunganSync(dbKepegawaian *pgxpool.Pool) int {
+	repo := repositories.NewKeluargaRepository(dbKepegawaian)
+	changed, err := repo.SyncTanggunganAll(context.Background())
+	fmt.Printf("tanggungan updated for %d keluarga\n", changed)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
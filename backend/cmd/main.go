@@ -19,10 +19,12 @@ import (
 	"github.com/gofiber/fiber/v3/middleware/recover"
 	"github.com/joho/godotenv"
 
+	"github.com/sikerma/backend/internal/audit"
 	"github.com/sikerma/backend/internal/config"
 	"github.com/sikerma/backend/internal/database"
 	"github.com/sikerma/backend/internal/handlers"
 	customMiddleware "github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/repositories"
 	"github.com/sikerma/backend/internal/routes"
 )
 
@@ -42,6 +44,13 @@ func main() {
 	}
 	defer database.Close(dbMaster, dbKepegawaian)
 
+	// Subcommand CLI, misalnya "audit-verify"
+	if len(os.Args) > 1 {
+		code := runCommand(os.Args[1:], dbMaster, dbKepegawaian, cfg)
+		database.Close(dbMaster, dbKepegawaian)
+		os.Exit(code)
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "SIKERMA Backend API",
@@ -69,7 +78,7 @@ func main() {
 		AllowOrigins:     strings.Split(cfg.CORS.Origins, ","),
 		AllowCredentials: cfg.CORS.Credentials,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "X-CSRF-Token"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "X-CSRF-Token", "X-API-Key", "X-Impersonate-Session"},
 		ExposeHeaders:    []string{"X-Request-ID", "X-CSRF-Token"},
 	}))
 
@@ -81,6 +90,16 @@ func main() {
 		CookieSameSite: "Strict",
 		CookieSessionOnly: false,
 		Extractor:      extractors.FromHeader("X-CSRF-Token"),
+		// Endpoint token tidak memakai cookie sesi sehingga tidak rentan CSRF,
+		// dan dipanggil oleh tools non-browser yang tidak punya cookie csrf_
+		Next: func(c fiber.Ctx) bool {
+			switch c.Path() {
+			case "/api/v1/auth/login", "/api/v1/auth/refresh", "/api/v1/auth/logout":
+				return true
+			}
+			// Client mesin dengan API key juga tidak memakai cookie
+			return c.Get("X-API-Key") != "" && c.Get("Authorization") == ""
+		},
 	}))
 
 	// Global Rate Limiting (100 req/min per IP)
@@ -99,9 +118,19 @@ func main() {
 		TimeZone:   "Asia/Jakarta",
 	}))
 
+	// Initialize handlers
+	h := handlers.New(dbMaster, dbKepegawaian, cfg)
+	defer h.AuthMiddleware.Close()
+	detector, err := auditDetector(dbMaster, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load audit rules: %v", err)
+	}
+	h.AuditWriter.WithDetector(detector)
+	go h.AuditWriter.Run()
+
 	// Custom Middleware
 	app.Use(customMiddleware.RequestID())
-	app.Use(customMiddleware.AuditTrail(dbMaster))
+	app.Use(customMiddleware.AuditTrail(h.AuditWriter))
 
 	// Health check
 	app.Get("/health", func(c fiber.Ctx) error {
@@ -112,14 +141,25 @@ func main() {
 		})
 	})
 
-	// Initialize handlers
-	h := handlers.New(dbMaster, dbKepegawaian, cfg)
-
 	// Setup routes
 	routes.Setup(app, h)
 
+	// Background jobs
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go h.RBACMiddleware.ListenForChanges(bgCtx, dbMaster)
+	go h.Revocations.ListenForChanges(bgCtx, dbMaster)
+	go h.Revocations.PurgeExpired(bgCtx, 10*time.Minute)
+	go audit.RunAnchors(bgCtx, repositories.NewAuditRepository(dbMaster), cfg.Audit.AnchorFile, cfg.Audit.AnchorInterval)
+	go detector.Run(bgCtx)
+	go audit.RunMaintenance(bgCtx, repositories.NewAuditRepository(dbMaster), auditRetentionPolicy(cfg), cfg.Audit.MaintenanceInterval)
+
 	// Graceful shutdown
-	go gracefulShutdown(app, cfg)
+	shutdownDone := make(chan struct{})
+	go func() {
+		gracefulShutdown(app, cfg, h.AuditWriter)
+		close(shutdownDone)
+	}()
 
 	// Start server
 	addr := cfg.Host + ":" + cfg.Port
@@ -132,9 +172,12 @@ func main() {
 	if err := app.Listen(addr); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	// Listen kembali setelah shutdown dimulai; tunggu queue audit selesai ditulis
+	<-shutdownDone
 }
 
-func gracefulShutdown(app *fiber.App, cfg *config.Config) {
+func gracefulShutdown(app *fiber.App, cfg *config.Config, auditWriter *customMiddleware.AuditWriter) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
@@ -148,5 +191,10 @@ func gracefulShutdown(app *fiber.App, cfg *config.Config) {
 		log.Printf("Error during shutdown: %v", err)
 	}
 
+	// Request yang sedang berjalan sudah selesai sehingga tidak ada entry audit baru
+	if err := auditWriter.Close(ctx); err != nil {
+		log.Printf("Audit queue not fully flushed, remaining entries spilled: %v", err)
+	}
+
 	log.Println("Server stopped gracefully")
 }
\ No newline at end of file
@@ -0,0 +1,68 @@
+// Package apikey membuat dan memverifikasi API key untuk client mesin
+// (script pelaporan, backend portal). Format key: sk_<prefix>_<secret>.
+// Prefix disimpan apa adanya untuk lookup, sedangkan key utuh hanya disimpan
+// sebagai hash SHA-256 dan ditampilkan sekali saat dibuat.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Header adalah header HTTP yang membawa API key
+	Header = "X-API-Key"
+
+	keyScheme   = "sk"
+	prefixBytes = 6
+	secretBytes = 32
+)
+
+var (
+	// ErrInvalid dikembalikan untuk key yang salah format, tidak dikenal,
+	// sudah dicabut atau kadaluarsa. Sengaja tidak dibedakan ke client.
+	ErrInvalid = errors.New("invalid api key")
+)
+
+// Generate membuat API key baru dan mengembalikan key utuh, prefix dan hash-nya
+func Generate() (key, prefix, hash string, err error) {
+	prefixRaw := make([]byte, prefixBytes)
+	if _, err := rand.Read(prefixRaw); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate api key prefix: %w", err)
+	}
+	secretRaw := make([]byte, secretBytes)
+	if _, err := rand.Read(secretRaw); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate api key secret: %w", err)
+	}
+
+	prefix = hex.EncodeToString(prefixRaw)
+	key = keyScheme + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretRaw)
+	return key, prefix, Hash(key), nil
+}
+
+// Parse mengambil prefix dari key dan memvalidasi formatnya
+func Parse(key string) (string, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != keyScheme || len(parts[1]) != prefixBytes*2 || parts[2] == "" {
+		return "", ErrInvalid
+	}
+	return parts[1], nil
+}
+
+// Hash menghitung hash key untuk disimpan di database. Secret berentropi
+// tinggi sehingga SHA-256 cukup (tidak perlu KDF lambat seperti password).
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify membandingkan key dengan hash tersimpan secara constant-time
+func Verify(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,40 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAndVerify(t *testing.T) {
+	key, prefix, hash, err := Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, "sk_"+prefix+"_"))
+	assert.NotContains(t, hash, key)
+	assert.True(t, Verify(key, hash))
+	assert.False(t, Verify(key+"x", hash))
+
+	parsed, err := Parse(key)
+	require.NoError(t, err)
+	assert.Equal(t, prefix, parsed)
+
+	other, _, _, err := Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestParseRejectsMalformedKeys(t *testing.T) {
+	for _, key := range []string{
+		"",
+		"sk_abc",
+		"pk_0123456789ab_secret",
+		"sk_short_secret",
+		"sk_0123456789ab_",
+	} {
+		_, err := Parse(key)
+		assert.ErrorIs(t, err, ErrInvalid, key)
+	}
+}
@@ -0,0 +1,206 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GenesisHash adalah prev_hash untuk baris pertama hash chain
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// ChainEntry adalah isi satu baris audit_logs yang dicakup hash chain
+type ChainEntry struct {
+	Seq                    int64           `json:"seq"`
+	ID                     uuid.UUID       `json:"id"`
+	CreatedAt              time.Time       `json:"created_at"`
+	UserID                 *string         `json:"user_id"`
+	Username               *string         `json:"username"`
+	Action                 string          `json:"action"`
+	Resource               string          `json:"resource"`
+	ResourceID             *uuid.UUID      `json:"resource_id"`
+	IPAddress              *string         `json:"ip_address"`
+	UserAgent              *string         `json:"user_agent"`
+	Changes                json.RawMessage `json:"changes"`
+	Status                 string          `json:"status"`
+	ErrorMessage           *string         `json:"error_message"`
+	ImpersonatedUserID     *string         `json:"impersonated_user_id"`
+	ImpersonatedUsername   *string         `json:"impersonated_username"`
+	ImpersonationSessionID *uuid.UUID      `json:"impersonation_session_id"`
+	PrevHash               string          `json:"prev_hash"`
+	RowHash                string          `json:"row_hash"`
+}
+
+// ComputeHash menghitung SHA-256 atas isi entry dan PrevHash. Field
+// di-serialize sebagai array JSON berurutan tetap sehingga hasilnya sama
+// saat ditulis dan saat dibaca ulang dari database.
+func (e ChainEntry) ComputeHash() (string, error) {
+	payload, err := json.Marshal([]interface{}{
+		e.Seq,
+		e.ID,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.UserID,
+		e.Username,
+		e.Action,
+		e.Resource,
+		e.ResourceID,
+		CanonicalIP(e.IPAddress),
+		e.UserAgent,
+		e.Changes,
+		e.Status,
+		e.ErrorMessage,
+		e.ImpersonatedUserID,
+		e.ImpersonatedUsername,
+		e.ImpersonationSessionID,
+		e.PrevHash,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode chain entry: %w", err)
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CanonicalJSON mengubah v menjadi JSON dengan urutan key dan format angka
+// yang sama seperti hasil decode kolom JSONB, sehingga hash tidak bergantung
+// pada cara Postgres menyimpan changes
+func CanonicalJSON(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	if generic == nil {
+		return nil, nil
+	}
+	return json.Marshal(generic)
+}
+
+// CanonicalIP menormalkan alamat IP seperti kolom INET menampilkannya
+func CanonicalIP(ip *string) *string {
+	if ip == nil {
+		return nil
+	}
+	addr, err := netip.ParseAddr(*ip)
+	if err != nil {
+		return ip
+	}
+	s := addr.String()
+	return &s
+}
+
+// ==================== VERIFICATION ====================
+
+// Anchor adalah hash baris terakhir chain pada satu waktu. Anchor diekspor ke
+// file di luar database sebagai bukti saat pemeriksaan.
+type Anchor struct {
+	Seq        int64     `json:"seq"`
+	RowHash    string    `json:"row_hash"`
+	AnchoredAt time.Time `json:"anchored_at"`
+}
+
+// ChainBreak adalah link pertama yang tidak valid
+type ChainBreak struct {
+	Seq    int64     `json:"seq"`
+	ID     uuid.UUID `json:"id,omitempty"`
+	Reason string    `json:"reason"`
+}
+
+// VerifyResult adalah hasil verifikasi hash chain
+type VerifyResult struct {
+	Valid          bool        `json:"valid"`
+	Checked        int64       `json:"checked"`
+	LastSeq        int64       `json:"last_seq"`
+	LastHash       string      `json:"last_hash"`
+	AnchorsChecked int         `json:"anchors_checked"`
+	Break          *ChainBreak `json:"break,omitempty"`
+}
+
+// ChainWalker membaca baris audit_logs berurutan berdasarkan seq
+type ChainWalker interface {
+	WalkChain(ctx context.Context, fn func(ChainEntry) error) error
+}
+
+// errChainBroken menghentikan WalkChain setelah link rusak ditemukan
+var errChainBroken = errors.New("audit chain broken")
+
+// VerifyChain menelusuri chain mulai setelah start dan berhenti pada link
+// pertama yang rusak: seq yang hilang, prev_hash yang tidak menyambung, hash
+// yang tidak cocok dengan isi baris, atau hash yang berbeda dari anchor.
+// start adalah baris terakhir yang sudah diarsipkan; nilai nol berarti chain
+// diverifikasi dari GenesisHash. Anchor di dalam arsip dilewati.
+func VerifyChain(ctx context.Context, walker ChainWalker, start Anchor, anchors []Anchor) (*VerifyResult, error) {
+	expected := make(map[int64]string, len(anchors))
+	for _, a := range anchors {
+		if a.Seq > start.Seq {
+			expected[a.Seq] = a.RowHash
+		}
+	}
+
+	result := &VerifyResult{LastSeq: start.Seq, LastHash: start.RowHash}
+	if start.Seq == 0 {
+		result.LastHash = GenesisHash
+	}
+	fail := func(e ChainEntry, reason string) error {
+		result.Break = &ChainBreak{Seq: e.Seq, ID: e.ID, Reason: reason}
+		return errChainBroken
+	}
+
+	err := walker.WalkChain(ctx, func(e ChainEntry) error {
+		if e.Seq != result.LastSeq+1 {
+			return fail(e, fmt.Sprintf("seq %d missing", result.LastSeq+1))
+		}
+		if e.PrevHash != result.LastHash {
+			return fail(e, "prev_hash does not match previous row")
+		}
+		hash, err := e.ComputeHash()
+		if err != nil {
+			return err
+		}
+		if hash != e.RowHash {
+			return fail(e, "row content does not match row_hash")
+		}
+		if anchored, ok := expected[e.Seq]; ok {
+			if anchored != e.RowHash {
+				return fail(e, "row_hash differs from anchor")
+			}
+			result.AnchorsChecked++
+		}
+
+		result.Checked++
+		result.LastSeq = e.Seq
+		result.LastHash = e.RowHash
+		return nil
+	})
+	if err != nil && !errors.Is(err, errChainBroken) {
+		return nil, err
+	}
+
+	// Anchor di luar ujung chain berarti baris terakhir dihapus
+	if result.Break == nil {
+		for _, a := range anchors {
+			if _, ok := expected[a.Seq]; ok && a.Seq > result.LastSeq {
+				result.Break = &ChainBreak{Seq: a.Seq, Reason: "anchored row missing, chain truncated"}
+				break
+			}
+		}
+	}
+
+	result.Valid = result.Break == nil
+	return result, nil
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChain []ChainEntry
+
+func (f fakeChain) WalkChain(_ context.Context, fn func(ChainEntry) error) error {
+	for _, e := range f {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildChain membuat n entry yang saling menyambung seperti hasil AuditRepository.Log
+func buildChain(t *testing.T, n int) fakeChain {
+	t.Helper()
+	chain := fakeChain{}
+	prev := GenesisHash
+	for i := 1; i <= n; i++ {
+		changes, err := CanonicalJSON(map[string]interface{}{"status_code": 200, "path": "/api/v1/pegawai"})
+		require.NoError(t, err)
+
+		e := ChainEntry{
+			Seq:       int64(i),
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+			Username:  stringPtr("admin"),
+			Action:    ActionUpdate,
+			Resource:  "pegawai",
+			IPAddress: stringPtr("10.0.0.1"),
+			Changes:   changes,
+			Status:    "success",
+			PrevHash:  prev,
+		}
+		e.RowHash, err = e.ComputeHash()
+		require.NoError(t, err)
+		prev = e.RowHash
+		chain = append(chain, e)
+	}
+	return chain
+}
+
+func TestVerifyChainValid(t *testing.T) {
+	chain := buildChain(t, 3)
+
+	result, err := VerifyChain(context.Background(), chain, Anchor{}, []Anchor{{Seq: 2, RowHash: chain[1].RowHash}})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.Checked)
+	assert.Equal(t, chain[2].RowHash, result.LastHash)
+	assert.Equal(t, 1, result.AnchorsChecked)
+}
+
+func TestVerifyChainDetectsTampering(t *testing.T) {
+	t.Run("edited row", func(t *testing.T) {
+		chain := buildChain(t, 3)
+		chain[1].Action = ActionDelete
+
+		result, err := VerifyChain(context.Background(), chain, Anchor{}, nil)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.Break)
+		assert.Equal(t, int64(2), result.Break.Seq)
+		assert.Equal(t, int64(1), result.Checked)
+	})
+
+	t.Run("deleted row", func(t *testing.T) {
+		chain := buildChain(t, 3)
+		chain = append(chain[:1], chain[2:]...)
+
+		result, err := VerifyChain(context.Background(), chain, Anchor{}, nil)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.Break.Seq)
+	})
+
+	t.Run("rehashed chain differs from anchor", func(t *testing.T) {
+		chain := buildChain(t, 2)
+		anchor := Anchor{Seq: 2, RowHash: chain[1].RowHash}
+		chain[1].Status = "failed"
+		chain[1].RowHash, _ = chain[1].ComputeHash()
+
+		result, err := VerifyChain(context.Background(), chain, Anchor{}, []Anchor{anchor})
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, "row_hash differs from anchor", result.Break.Reason)
+	})
+
+	t.Run("truncated tail", func(t *testing.T) {
+		chain := buildChain(t, 3)
+		anchor := Anchor{Seq: 3, RowHash: chain[2].RowHash}
+
+		result, err := VerifyChain(context.Background(), chain[:2], Anchor{}, []Anchor{anchor})
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.Break.Seq)
+	})
+}
+
+func TestCanonicalJSONMatchesJSONBRoundTrip(t *testing.T) {
+	written, err := CanonicalJSON(map[string]interface{}{
+		"duration_ms": int64(12),
+		"diff":        map[string]FieldChange{"nama": {Before: "A", After: "B"}},
+	})
+	require.NoError(t, err)
+
+	// JSONB menyimpan ulang dengan urutan key dan spasi berbeda
+	read, err := CanonicalJSON(json.RawMessage(`{"diff": {"nama": {"after": "B", "before": "A"}}, "duration_ms": 12}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(written), string(read))
+	assert.Equal(t, string(written), string(read))
+}
+
+func TestAnchorFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anchors", "audit.jsonl")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, AppendAnchorFile(path, Anchor{Seq: 10, RowHash: "abc", AnchoredAt: now}))
+	require.NoError(t, AppendAnchorFile(path, Anchor{Seq: 20, RowHash: "def", AnchoredAt: now}))
+
+	anchors, err := ReadAnchorFile(path)
+	require.NoError(t, err)
+	require.Len(t, anchors, 2)
+	assert.Equal(t, int64(20), anchors[1].Seq)
+	assert.Equal(t, "def", anchors[1].RowHash)
+}
+
+func TestVerifyChainFromArchivedStart(t *testing.T) {
+	chain := buildChain(t, 4)
+	start := Anchor{Seq: 2, RowHash: chain[1].RowHash}
+	oldAnchor := Anchor{Seq: 1, RowHash: "archived"}
+
+	result, err := VerifyChain(context.Background(), chain[2:], start, []Anchor{oldAnchor})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(2), result.Checked)
+	assert.Equal(t, int64(4), result.LastSeq)
+}
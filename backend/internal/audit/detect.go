@@ -0,0 +1,595 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// Severity alert
+const (
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// Jenis rule deteksi aktivitas mencurigakan
+const (
+	RuleMassDelete        = "mass_delete"
+	RuleBulkRead          = "bulk_read"
+	RuleRepeatedForbidden = "repeated_forbidden"
+	RuleOffHours          = "off_hours"
+	RuleNewIP             = "new_ip"
+)
+
+// maxAlertResourceIDs membatasi jumlah ID record yang disimpan di detail alert
+const maxAlertResourceIDs = 100
+
+// Event adalah satu aktivitas dari audit stream yang dievaluasi rule
+type Event struct {
+	Time        time.Time
+	UserID      string
+	Username    string
+	Action      string
+	Resource    string
+	ResourceIDs []uuid.UUID
+	IPAddress   string
+	Status      string
+	StatusCode  int
+}
+
+// actorKey mengelompokkan event per user; request anonim dikelompokkan per IP
+func (e Event) actorKey() string {
+	if e.UserID != "" {
+		return e.UserID
+	}
+	return "ip:" + e.IPAddress
+}
+
+// Duration adalah time.Duration yang ditulis sebagai string ("10m") di file rule
+type Duration time.Duration
+
+// UnmarshalJSON membaca durasi format time.ParseDuration
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON menulis durasi sebagai string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// RuleConfig adalah konfigurasi satu rule. Field yang dipakai bergantung
+// pada Kind; rule dengan Kind yang sama boleh lebih dari satu, misalnya
+// mass_delete untuk pegawai dan untuk satker.
+type RuleConfig struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Severity string `json:"severity"`
+	// Threshold jumlah event (bulk_read: jumlah record) dalam Window yang
+	// memicu alert. Untuk off_hours dan new_ip, Window adalah jeda minimal
+	// antar alert untuk user yang sama.
+	Threshold int      `json:"threshold,omitempty"`
+	Window    Duration `json:"window,omitempty"`
+	// Actions dan Resources membatasi event yang dihitung; kosong berarti semua
+	Actions   []string `json:"actions,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+	// OfficeStart dan OfficeEnd jam kerja "HH:MM" Asia/Jakarta pada Workdays
+	// (0 = Minggu, 1 = Senin, ...)
+	OfficeStart string         `json:"office_start,omitempty"`
+	OfficeEnd   string         `json:"office_end,omitempty"`
+	Workdays    []time.Weekday `json:"workdays,omitempty"`
+	// Lookback rentang riwayat audit log untuk IP yang dianggap sudah dikenal
+	Lookback Duration `json:"lookback,omitempty"`
+}
+
+// DefaultRules adalah rule yang dipakai bila tidak ada file rule
+func DefaultRules() []RuleConfig {
+	return []RuleConfig{
+		{Name: "mass_delete_pegawai", Kind: RuleMassDelete, Severity: SeverityHigh, Threshold: 10, Window: Duration(10 * time.Minute), Actions: []string{ActionDelete}, Resources: []string{"pegawai"}},
+		{Name: "bulk_read_pegawai", Kind: RuleBulkRead, Severity: SeverityHigh, Threshold: 500, Window: Duration(10 * time.Minute), Resources: []string{"pegawai"}},
+		{Name: "repeated_forbidden", Kind: RuleRepeatedForbidden, Severity: SeverityMedium, Threshold: 10, Window: Duration(5 * time.Minute)},
+		{Name: "off_hours", Kind: RuleOffHours, Severity: SeverityLow, Window: Duration(time.Hour), Actions: []string{ActionCreate, ActionUpdate, ActionDelete, "export"},
+			OfficeStart: "07:00", OfficeEnd: "18:00", Workdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}},
+		{Name: "new_ip", Kind: RuleNewIP, Severity: SeverityMedium, Window: Duration(time.Hour), Lookback: Duration(90 * 24 * time.Hour)},
+	}
+}
+
+// LoadRules membaca rule dari file JSON berisi array RuleConfig. Path
+// kosong menghasilkan DefaultRules.
+func LoadRules(path string) ([]RuleConfig, error) {
+	if path == "" {
+		return DefaultRules(), nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit rules: %w", err)
+	}
+	var rules []RuleConfig
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("invalid audit rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// KnownIPStore menyediakan riwayat IP user untuk rule new_ip
+type KnownIPStore interface {
+	KnownIPs(ctx context.Context, userID string, since time.Time) ([]string, error)
+}
+
+// Rule mengevaluasi event dan mengembalikan alert bila pola terdeteksi
+type Rule interface {
+	Evaluate(ctx context.Context, e Event) (*models.AuditAlert, error)
+	// Expire membuang state yang sudah di luar window
+	Expire(now time.Time)
+}
+
+// BuildRules membuat Rule dari konfigurasi; rule Disabled dilewati
+func BuildRules(configs []RuleConfig, ips KnownIPStore) ([]Rule, error) {
+	rules := []Rule{}
+	for _, cfg := range configs {
+		if cfg.Disabled {
+			continue
+		}
+		if cfg.Name == "" {
+			cfg.Name = cfg.Kind
+		}
+		if !slices.Contains([]string{SeverityLow, SeverityMedium, SeverityHigh}, cfg.Severity) {
+			return nil, fmt.Errorf("rule %s: invalid severity %q", cfg.Name, cfg.Severity)
+		}
+
+		var rule Rule
+		var err error
+		switch cfg.Kind {
+		case RuleMassDelete, RuleBulkRead, RuleRepeatedForbidden:
+			rule, err = newThresholdRule(cfg)
+		case RuleOffHours:
+			rule, err = newOffHoursRule(cfg)
+		case RuleNewIP:
+			rule, err = newNewIPRule(cfg, ips)
+		default:
+			err = fmt.Errorf("unknown kind %q", cfg.Kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", cfg.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matches mengecek filter Actions dan Resources rule
+func (cfg RuleConfig) matches(e Event) bool {
+	return (len(cfg.Actions) == 0 || slices.Contains(cfg.Actions, e.Action)) &&
+		(len(cfg.Resources) == 0 || slices.Contains(cfg.Resources, e.Resource))
+}
+
+// newAlert mengisi field alert yang sama untuk semua rule
+func (cfg RuleConfig) newAlert(e Event, summary string) *models.AuditAlert {
+	alert := &models.AuditAlert{
+		Rule:       cfg.Name,
+		Severity:   cfg.Severity,
+		Summary:    summary,
+		Details:    map[string]interface{}{"kind": cfg.Kind},
+		EventCount: 1,
+		FirstSeen:  e.Time,
+		LastSeen:   e.Time,
+		Status:     models.AlertStatusOpen,
+	}
+	if e.UserID != "" {
+		alert.UserID = &e.UserID
+	}
+	if e.Username != "" {
+		alert.Username = &e.Username
+	}
+	if e.IPAddress != "" {
+		alert.IPAddress = &e.IPAddress
+	}
+	return alert
+}
+
+// actorLabel adalah nama user untuk ringkasan alert
+func actorLabel(e Event) string {
+	switch {
+	case e.Username != "":
+		return e.Username
+	case e.UserID != "":
+		return e.UserID
+	default:
+		return "anonim dari " + e.IPAddress
+	}
+}
+
+// ==================== THRESHOLD ====================
+
+// windowPoint adalah satu event yang dihitung di sliding window
+type windowPoint struct {
+	at  time.Time
+	n   int
+	ids []uuid.UUID
+}
+
+// thresholdRule memicu alert bila jumlah event satu user dalam Window
+// mencapai Threshold. Setelah alert, hitungan user tersebut dimulai ulang.
+type thresholdRule struct {
+	cfg    RuleConfig
+	match  func(Event) bool
+	weight func(Event) int
+
+	mu      sync.Mutex
+	windows map[string][]windowPoint
+}
+
+func newThresholdRule(cfg RuleConfig) (*thresholdRule, error) {
+	if cfg.Threshold <= 0 || cfg.Window <= 0 {
+		return nil, fmt.Errorf("threshold and window are required")
+	}
+
+	r := &thresholdRule{cfg: cfg, windows: map[string][]windowPoint{}}
+	r.weight = func(Event) int { return 1 }
+	switch cfg.Kind {
+	case RuleMassDelete:
+		r.match = func(e Event) bool { return e.Status == "success" && cfg.matches(e) }
+	case RuleBulkRead:
+		r.match = func(e Event) bool { return e.Action == ActionRead && cfg.matches(e) }
+		r.weight = func(e Event) int { return len(e.ResourceIDs) }
+	case RuleRepeatedForbidden:
+		r.match = func(e Event) bool { return e.StatusCode == 403 && cfg.matches(e) }
+	}
+	return r, nil
+}
+
+func (r *thresholdRule) Evaluate(_ context.Context, e Event) (*models.AuditAlert, error) {
+	if !r.match(e) {
+		return nil, nil
+	}
+	n := r.weight(e)
+	if n == 0 {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := e.actorKey()
+	points := append(pruneWindow(r.windows[key], e.Time.Add(-time.Duration(r.cfg.Window))), windowPoint{at: e.Time, n: n, ids: e.ResourceIDs})
+	total := 0
+	for _, p := range points {
+		total += p.n
+	}
+	if total < r.cfg.Threshold {
+		r.windows[key] = points
+		return nil, nil
+	}
+	delete(r.windows, key)
+
+	alert := r.cfg.newAlert(e, r.summary(e, total))
+	alert.EventCount = total
+	alert.FirstSeen = points[0].at
+	alert.Details["threshold"] = r.cfg.Threshold
+	alert.Details["window"] = r.cfg.Window
+
+	ids := []uuid.UUID{}
+	for _, p := range points {
+		for _, id := range p.ids {
+			if len(ids) < maxAlertResourceIDs && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) > 0 {
+		alert.Details["resource_ids"] = ids
+	}
+	return alert, nil
+}
+
+func (r *thresholdRule) summary(e Event, total int) string {
+	window := time.Duration(r.cfg.Window)
+	switch r.cfg.Kind {
+	case RuleMassDelete:
+		return fmt.Sprintf("%s menghapus %d data %s dalam %s", actorLabel(e), total, e.Resource, window)
+	case RuleBulkRead:
+		return fmt.Sprintf("%s membaca %d data %s dalam %s", actorLabel(e), total, e.Resource, window)
+	default:
+		return fmt.Sprintf("%s ditolak akses (403) %d kali dalam %s", actorLabel(e), total, window)
+	}
+}
+
+func (r *thresholdRule) Expire(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, points := range r.windows {
+		if points = pruneWindow(points, now.Add(-time.Duration(r.cfg.Window))); len(points) == 0 {
+			delete(r.windows, key)
+		} else {
+			r.windows[key] = points
+		}
+	}
+}
+
+// pruneWindow membuang point sebelum since
+func pruneWindow(points []windowPoint, since time.Time) []windowPoint {
+	i := 0
+	for i < len(points) && points[i].at.Before(since) {
+		i++
+	}
+	return points[i:]
+}
+
+// cooldown mencatat alert terakhir per user agar rule per event tidak
+// membanjiri alert
+type cooldown struct {
+	window time.Duration
+	mu     sync.Mutex
+	last   map[string]time.Time
+}
+
+// allow mengembalikan true dan mencatat waktu bila user belum di-alert dalam window
+func (c *cooldown) allow(key string, at time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if last, ok := c.last[key]; ok && at.Sub(last) < c.window {
+		return false
+	}
+	c.last[key] = at
+	return true
+}
+
+func (c *cooldown) expire(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, last := range c.last {
+		if now.Sub(last) >= c.window {
+			delete(c.last, key)
+		}
+	}
+}
+
+// ==================== OFF HOURS ====================
+
+// offHoursRule memicu alert untuk aktivitas di luar jam kerja Asia/Jakarta
+type offHoursRule struct {
+	cfg        RuleConfig
+	start, end time.Duration
+	cooldown   *cooldown
+}
+
+func newOffHoursRule(cfg RuleConfig) (*offHoursRule, error) {
+	start, err := parseClock(cfg.OfficeStart)
+	if err != nil {
+		return nil, fmt.Errorf("office_start: %w", err)
+	}
+	end, err := parseClock(cfg.OfficeEnd)
+	if err != nil {
+		return nil, fmt.Errorf("office_end: %w", err)
+	}
+	if end <= start {
+		return nil, fmt.Errorf("office_end must be after office_start")
+	}
+	return &offHoursRule{
+		cfg:      cfg,
+		start:    start,
+		end:      end,
+		cooldown: &cooldown{window: time.Duration(cfg.Window), last: map[string]time.Time{}},
+	}, nil
+}
+
+// parseClock membaca "HH:MM" sebagai durasi sejak tengah malam
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (r *offHoursRule) Evaluate(_ context.Context, e Event) (*models.AuditAlert, error) {
+	if e.Status != "success" || !r.cfg.matches(e) {
+		return nil, nil
+	}
+
+	local := e.Time.In(jakarta)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if slices.Contains(r.cfg.Workdays, local.Weekday()) && clock >= r.start && clock < r.end {
+		return nil, nil
+	}
+	if !r.cooldown.allow(e.actorKey(), e.Time) {
+		return nil, nil
+	}
+
+	summary := fmt.Sprintf("%s melakukan %s %s di luar jam kerja (%s WIB)",
+		actorLabel(e), e.Action, e.Resource, local.Format("Mon 02-01-2006 15:04"))
+	alert := r.cfg.newAlert(e, summary)
+	alert.Details["action"] = e.Action
+	alert.Details["resource"] = e.Resource
+	alert.Details["office_hours"] = r.cfg.OfficeStart + "-" + r.cfg.OfficeEnd
+	return alert, nil
+}
+
+func (r *offHoursRule) Expire(now time.Time) {
+	r.cooldown.expire(now)
+}
+
+// ==================== NEW IP ====================
+
+// newIPRule memicu alert saat user memakai IP yang tidak ada di riwayat
+// audit log-nya selama Lookback. User tanpa riwayat sama sekali tidak
+// di-alert karena belum ada pembanding.
+type newIPRule struct {
+	cfg      RuleConfig
+	store    KnownIPStore
+	cooldown *cooldown
+
+	mu    sync.Mutex
+	known map[string]map[string]bool
+}
+
+func newNewIPRule(cfg RuleConfig, store KnownIPStore) (*newIPRule, error) {
+	if store == nil {
+		return nil, fmt.Errorf("known IP store is required")
+	}
+	if cfg.Lookback <= 0 {
+		return nil, fmt.Errorf("lookback is required")
+	}
+	return &newIPRule{
+		cfg:      cfg,
+		store:    store,
+		cooldown: &cooldown{window: time.Duration(cfg.Window), last: map[string]time.Time{}},
+		known:    map[string]map[string]bool{},
+	}, nil
+}
+
+func (r *newIPRule) Evaluate(ctx context.Context, e Event) (*models.AuditAlert, error) {
+	if e.UserID == "" || e.IPAddress == "" || !r.cfg.matches(e) {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	known, ok := r.known[e.UserID]
+	if !ok {
+		ips, err := r.store.KnownIPs(ctx, e.UserID, e.Time.Add(-time.Duration(r.cfg.Lookback)))
+		if err != nil {
+			return nil, err
+		}
+		known = map[string]bool{}
+		for _, ip := range ips {
+			known[ip] = true
+		}
+		r.known[e.UserID] = known
+	}
+
+	if known[e.IPAddress] {
+		return nil, nil
+	}
+	firstSeen := len(known) == 0
+	known[e.IPAddress] = true
+	if firstSeen || !r.cooldown.allow(e.actorKey(), e.Time) {
+		return nil, nil
+	}
+
+	previous := make([]string, 0, len(known)-1)
+	for ip := range known {
+		if ip != e.IPAddress {
+			previous = append(previous, ip)
+		}
+	}
+	slices.Sort(previous)
+
+	alert := r.cfg.newAlert(e, fmt.Sprintf("%s mengakses dari IP baru %s", actorLabel(e), e.IPAddress))
+	alert.Details["known_ips"] = previous
+	return alert, nil
+}
+
+func (r *newIPRule) Expire(now time.Time) {
+	r.cooldown.expire(now)
+}
+
+// ==================== DETECTOR ====================
+
+// AlertStore menyimpan alert hasil deteksi
+type AlertStore interface {
+	CreateAlert(ctx context.Context, alert *models.AuditAlert) error
+}
+
+// detectorQueueSize adalah kapasitas antrean event Detector
+const detectorQueueSize = 10000
+
+// Detector mengevaluasi event audit terhadap rule di goroutine terpisah,
+// menyimpan alert dan mengirim notifikasi. State window disimpan di memori
+// per instance.
+type Detector struct {
+	rules    []Rule
+	store    AlertStore
+	notifier Notifier
+
+	events  chan Event
+	dropped atomic.Int64
+}
+
+// NewDetector membuat Detector; jalankan Run di goroutine terpisah
+func NewDetector(rules []Rule, store AlertStore, notifier Notifier) *Detector {
+	return &Detector{
+		rules:    rules,
+		store:    store,
+		notifier: notifier,
+		events:   make(chan Event, detectorQueueSize),
+	}
+}
+
+// Submit memasukkan event tanpa menunggu. Bila antrean penuh event dibuang
+// agar request tidak tertahan oleh deteksi.
+func (d *Detector) Submit(e Event) {
+	select {
+	case d.events <- e:
+	default:
+		d.dropped.Add(1)
+	}
+}
+
+// Dropped mengembalikan jumlah event yang dibuang karena antrean penuh
+func (d *Detector) Dropped() int64 {
+	return d.dropped.Load()
+}
+
+// Run mengevaluasi event sampai ctx selesai
+func (d *Detector) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, rule := range d.rules {
+				rule.Expire(now)
+			}
+		case e := <-d.events:
+			d.Evaluate(ctx, e)
+		}
+	}
+}
+
+// Evaluate menjalankan semua rule untuk satu event
+func (d *Detector) Evaluate(ctx context.Context, e Event) {
+	for _, rule := range d.rules {
+		alert, err := rule.Evaluate(ctx, e)
+		if err != nil {
+			logrus.WithError(err).Warn("Audit rule evaluation failed")
+			continue
+		}
+		if alert == nil {
+			continue
+		}
+
+		if err := d.store.CreateAlert(ctx, alert); err != nil {
+			logrus.WithError(err).WithField("rule", alert.Rule).Error("Failed to store audit alert")
+		}
+		if err := d.notifier.Notify(ctx, *alert); err != nil {
+			logrus.WithError(err).WithField("rule", alert.Rule).Error("Failed to send audit alert notification")
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+type fakeKnownIPs map[string][]string
+
+func (f fakeKnownIPs) KnownIPs(_ context.Context, userID string, _ time.Time) ([]string, error) {
+	return f[userID], nil
+}
+
+type fakeAlertStore struct {
+	alerts []models.AuditAlert
+}
+
+func (f *fakeAlertStore) CreateAlert(_ context.Context, alert *models.AuditAlert) error {
+	alert.ID = uuid.New()
+	f.alerts = append(f.alerts, *alert)
+	return nil
+}
+
+type fakeNotifier struct {
+	notified []string
+	err      error
+}
+
+func (f *fakeNotifier) Notify(_ context.Context, alert models.AuditAlert) error {
+	f.notified = append(f.notified, alert.Rule)
+	return f.err
+}
+
+// officeTime adalah Senin 19 Oktober 2026 pukul 10:00 WIB
+var officeTime = time.Date(2026, 10, 19, 10, 0, 0, 0, jakarta)
+
+func buildRule(t *testing.T, cfg RuleConfig, ips KnownIPStore) Rule {
+	t.Helper()
+	rules, err := BuildRules([]RuleConfig{cfg}, ips)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	return rules[0]
+}
+
+func TestMassDeleteRule(t *testing.T) {
+	rule := buildRule(t, RuleConfig{Name: "md", Kind: RuleMassDelete, Severity: SeverityHigh, Threshold: 3,
+		Window: Duration(time.Minute), Actions: []string{ActionDelete}, Resources: []string{"pegawai"}}, nil)
+	ctx := context.Background()
+
+	event := func(at time.Time) Event {
+		return Event{Time: at, UserID: "u1", Username: "budi", Action: ActionDelete, Resource: "pegawai",
+			ResourceIDs: []uuid.UUID{uuid.New()}, Status: "success"}
+	}
+
+	// Event di luar window tidak ikut dihitung
+	alert, err := rule.Evaluate(ctx, event(officeTime))
+	require.NoError(t, err)
+	assert.Nil(t, alert)
+	for i := 0; i < 2; i++ {
+		alert, err = rule.Evaluate(ctx, event(officeTime.Add(2*time.Minute+time.Duration(i)*time.Second)))
+		require.NoError(t, err)
+		assert.Nil(t, alert)
+	}
+
+	// Resource lain dan delete yang gagal diabaikan
+	other := event(officeTime.Add(2*time.Minute + 3*time.Second))
+	other.Resource = "satker"
+	alert, _ = rule.Evaluate(ctx, other)
+	assert.Nil(t, alert)
+	failed := event(officeTime.Add(2*time.Minute + 3*time.Second))
+	failed.Status = "failed"
+	alert, _ = rule.Evaluate(ctx, failed)
+	assert.Nil(t, alert)
+
+	alert, err = rule.Evaluate(ctx, event(officeTime.Add(2*time.Minute+5*time.Second)))
+	require.NoError(t, err)
+	require.NotNil(t, alert)
+	assert.Equal(t, "md", alert.Rule)
+	assert.Equal(t, SeverityHigh, alert.Severity)
+	assert.Equal(t, 3, alert.EventCount)
+	assert.Equal(t, officeTime.Add(2*time.Minute), alert.FirstSeen)
+	assert.Len(t, alert.Details["resource_ids"], 3)
+	assert.Equal(t, "u1", *alert.UserID)
+
+	// Hitungan dimulai ulang setelah alert
+	alert, _ = rule.Evaluate(ctx, event(officeTime.Add(2*time.Minute+6*time.Second)))
+	assert.Nil(t, alert)
+}
+
+func TestBulkReadRuleCountsRecords(t *testing.T) {
+	rule := buildRule(t, RuleConfig{Kind: RuleBulkRead, Severity: SeverityHigh, Threshold: 100,
+		Window: Duration(time.Minute), Resources: []string{"pegawai"}}, nil)
+	ctx := context.Background()
+
+	read := func(n int) Event {
+		ids := make([]uuid.UUID, n)
+		for i := range ids {
+			ids[i] = uuid.New()
+		}
+		return Event{Time: officeTime, UserID: "u1", Action: ActionRead, Resource: "pegawai", ResourceIDs: ids, Status: "success"}
+	}
+
+	alert, _ := rule.Evaluate(ctx, read(60))
+	assert.Nil(t, alert)
+	alert, _ = rule.Evaluate(ctx, read(50))
+	require.NotNil(t, alert)
+	assert.Equal(t, RuleBulkRead, alert.Rule)
+	assert.Equal(t, 110, alert.EventCount)
+	assert.Len(t, alert.Details["resource_ids"], maxAlertResourceIDs)
+}
+
+func TestRepeatedForbiddenRuleGroupsAnonymousByIP(t *testing.T) {
+	rule := buildRule(t, RuleConfig{Kind: RuleRepeatedForbidden, Severity: SeverityMedium, Threshold: 2,
+		Window: Duration(time.Minute)}, nil)
+	ctx := context.Background()
+
+	denied := func(ip string) Event {
+		return Event{Time: officeTime, IPAddress: ip, Action: "access_denied", Resource: "pegawai", StatusCode: 403, Status: "failed"}
+	}
+
+	alert, _ := rule.Evaluate(ctx, denied("10.0.0.1"))
+	assert.Nil(t, alert)
+	alert, _ = rule.Evaluate(ctx, denied("10.0.0.2"))
+	assert.Nil(t, alert)
+	alert, _ = rule.Evaluate(ctx, Event{Time: officeTime, IPAddress: "10.0.0.1", StatusCode: 404})
+	assert.Nil(t, alert)
+
+	alert, _ = rule.Evaluate(ctx, denied("10.0.0.1"))
+	require.NotNil(t, alert)
+	assert.Nil(t, alert.UserID)
+	assert.Equal(t, "10.0.0.1", *alert.IPAddress)
+}
+
+func TestOffHoursRule(t *testing.T) {
+	rule := buildRule(t, DefaultRules()[3], nil)
+	ctx := context.Background()
+
+	event := func(at time.Time) Event {
+		return Event{Time: at, UserID: "u1", Action: ActionUpdate, Resource: "pegawai", Status: "success"}
+	}
+
+	alert, _ := rule.Evaluate(ctx, event(officeTime))
+	assert.Nil(t, alert, "jam kerja")
+
+	// 22:30 WIB sama dengan 15:30 UTC; jam dihitung di Asia/Jakarta
+	night := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	alert, _ = rule.Evaluate(ctx, event(night))
+	require.NotNil(t, alert)
+	assert.Equal(t, SeverityLow, alert.Severity)
+	assert.Contains(t, alert.Summary, "22:30")
+
+	// Cooldown per user
+	alert, _ = rule.Evaluate(ctx, event(night.Add(10*time.Minute)))
+	assert.Nil(t, alert)
+
+	// Sabtu siang tetap di luar jam kerja
+	saturday := time.Date(2026, 10, 24, 10, 0, 0, 0, jakarta)
+	alert, _ = rule.Evaluate(ctx, event(saturday))
+	assert.NotNil(t, alert)
+
+	// Baca data tidak termasuk action rule bawaan
+	read := event(saturday.Add(2 * time.Hour))
+	read.Action = ActionRead
+	alert, _ = rule.Evaluate(ctx, read)
+	assert.Nil(t, alert)
+}
+
+func TestNewIPRule(t *testing.T) {
+	ips := fakeKnownIPs{"u1": {"10.0.0.1"}}
+	rule := buildRule(t, DefaultRules()[4], ips)
+	ctx := context.Background()
+
+	event := func(userID, ip string, at time.Time) Event {
+		return Event{Time: at, UserID: userID, IPAddress: ip, Action: ActionRead, Resource: "pegawai", Status: "success"}
+	}
+
+	alert, _ := rule.Evaluate(ctx, event("u1", "10.0.0.1", officeTime))
+	assert.Nil(t, alert)
+
+	alert, _ = rule.Evaluate(ctx, event("u1", "192.168.1.9", officeTime))
+	require.NotNil(t, alert)
+	assert.Equal(t, []string{"10.0.0.1"}, alert.Details["known_ips"])
+
+	// IP yang sudah pernah di-alert menjadi dikenal
+	alert, _ = rule.Evaluate(ctx, event("u1", "192.168.1.9", officeTime.Add(2*time.Hour)))
+	assert.Nil(t, alert)
+
+	// User tanpa riwayat belum punya pembanding
+	alert, _ = rule.Evaluate(ctx, event("u2", "172.16.0.1", officeTime))
+	assert.Nil(t, alert)
+	alert, _ = rule.Evaluate(ctx, event("u2", "172.16.0.2", officeTime))
+	assert.NotNil(t, alert)
+}
+
+func TestBuildRulesValidation(t *testing.T) {
+	_, err := BuildRules([]RuleConfig{{Name: "x", Kind: "unknown", Severity: SeverityLow}}, nil)
+	assert.Error(t, err)
+
+	_, err = BuildRules([]RuleConfig{{Name: "x", Kind: RuleMassDelete, Severity: "critical", Threshold: 1, Window: Duration(time.Minute)}}, nil)
+	assert.Error(t, err)
+
+	_, err = BuildRules([]RuleConfig{{Name: "x", Kind: RuleMassDelete, Severity: SeverityHigh}}, nil)
+	assert.Error(t, err, "threshold dan window wajib")
+
+	_, err = BuildRules([]RuleConfig{{Name: "x", Kind: RuleNewIP, Severity: SeverityHigh, Lookback: Duration(time.Hour)}}, nil)
+	assert.Error(t, err, "new_ip butuh KnownIPStore")
+
+	rules, err := BuildRules([]RuleConfig{{Name: "x", Kind: RuleMassDelete, Disabled: true}}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	rules, err = BuildRules(DefaultRules(), fakeKnownIPs{})
+	require.NoError(t, err)
+	assert.Len(t, rules, len(DefaultRules()))
+}
+
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultRules(), rules)
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	raw := `[{"name":"md_satker","kind":"mass_delete","severity":"medium","threshold":5,"window":"15m",
+		"actions":["delete"],"resources":["satker"]}]`
+	require.NoError(t, os.WriteFile(path, []byte(raw), 0o600))
+
+	rules, err = LoadRules(path)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, Duration(15*time.Minute), rules[0].Window)
+	assert.Equal(t, []string{"satker"}, rules[0].Resources)
+
+	encoded, err := json.Marshal(rules[0].Window)
+	require.NoError(t, err)
+	assert.Equal(t, `"15m0s"`, string(encoded))
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"window":15}]`), 0o600))
+	_, err = LoadRules(path)
+	assert.Error(t, err)
+}
+
+func TestDetectorStoresAndNotifies(t *testing.T) {
+	rule := buildRule(t, RuleConfig{Kind: RuleRepeatedForbidden, Severity: SeverityMedium, Threshold: 1,
+		Window: Duration(time.Minute)}, nil)
+	store := &fakeAlertStore{}
+	notifier := &fakeNotifier{err: errors.New("webhook down")}
+	d := NewDetector([]Rule{rule}, store, notifier)
+
+	d.Evaluate(context.Background(), Event{Time: officeTime, UserID: "u1", StatusCode: 403})
+	d.Evaluate(context.Background(), Event{Time: officeTime, UserID: "u1", StatusCode: 200})
+
+	require.Len(t, store.alerts, 1)
+	assert.NotEqual(t, uuid.Nil, store.alerts[0].ID)
+	assert.Equal(t, []string{RuleRepeatedForbidden}, notifier.notified)
+}
+
+func TestDetectorSubmitDropsWhenFull(t *testing.T) {
+	d := NewDetector(nil, &fakeAlertStore{}, &fakeNotifier{})
+	for i := 0; i < detectorQueueSize+5; i++ {
+		d.Submit(Event{Time: officeTime})
+	}
+	assert.Equal(t, int64(5), d.Dropped())
+}
+
+func TestThresholdRuleExpire(t *testing.T) {
+	rule := buildRule(t, RuleConfig{Kind: RuleRepeatedForbidden, Severity: SeverityMedium, Threshold: 2,
+		Window: Duration(time.Minute)}, nil)
+	_, _ = rule.Evaluate(context.Background(), Event{Time: officeTime, UserID: "u1", StatusCode: 403})
+
+	rule.Expire(officeTime.Add(2 * time.Minute))
+	assert.Empty(t, rule.(*thresholdRule).windows)
+}
@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+
+	"github.com/sikerma/backend/internal/utils"
+)
+
+// FieldChange adalah nilai satu field sebelum dan sesudah perubahan
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// ignoredFields tidak dimasukkan ke diff karena selalu berubah di setiap mutasi
+var ignoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// maskedFields melengkapi daftar field sensitif utils untuk data kepegawaian
+var maskedFields = []string{
+	"email",
+	"kk_no",
+	"ktp_no",
+	"karpeg_no",
+	"taspen_no",
+	"bpjs_kesehatan",
+	"bpjs_ketenagakerjaan",
+}
+
+// restrictedFields adalah field tambahan yang di-mask untuk resource yang
+// dibaca dengan permission tersendiri, karena audit_logs cukup dengan audit.read
+var restrictedFields = map[string][]string{
+	"hukdis": {"alasan", "nomor_sk"},
+}
+
+// MaskedFields mengembalikan field kepegawaian yang di-mask selain field sensitif utils
+func MaskedFields() []string {
+	return slices.Clone(maskedFields)
+}
+
+// RestrictedFields mengembalikan field tambahan yang di-mask untuk resource
+func RestrictedFields(resource string) []string {
+	return restrictedFields[resource]
+}
+
+// Diff membandingkan representasi JSON before dan after lalu mengembalikan
+// field yang berbeda. Nilai nil pada before berarti record baru dibuat, nil
+// pada after berarti record dihapus. Nilai field PII di-mask, sehingga diff
+// aman disimpan di audit_logs.
+func Diff(before, after interface{}) map[string]FieldChange {
+	return diff(before, after, maskedFields)
+}
+
+// diffResource seperti Diff dengan tambahan RestrictedFields resource
+func diffResource(resource string, before, after interface{}) map[string]FieldChange {
+	return diff(before, after, append(slices.Clone(maskedFields), restrictedFields[resource]...))
+}
+
+// diff adalah implementasi Diff dengan daftar field yang di-mask
+func diff(before, after interface{}, masked []string) map[string]FieldChange {
+	beforeFields := toFields(before)
+	afterFields := toFields(after)
+
+	changed := map[string]FieldChange{}
+	for key := range union(beforeFields, afterFields) {
+		if ignoredFields[key] {
+			continue
+		}
+		b, a := beforeFields[key], afterFields[key]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		changed[key] = FieldChange{Before: b, After: a}
+	}
+
+	return maskChanges(changed, masked)
+}
+
+// toFields mengubah struct menjadi map field JSON-nya
+func toFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return fields
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(raw, &fields)
+	return fields
+}
+
+// union mengembalikan gabungan key dari kedua map
+func union(a, b map[string]interface{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
+
+// maskChanges me-mask nilai field sensitif. Nilai kosong dibiarkan nil agar
+// auditor tetap bisa melihat field yang baru diisi atau dikosongkan.
+func maskChanges(changes map[string]FieldChange, fields []string) map[string]FieldChange {
+	before := map[string]interface{}{}
+	after := map[string]interface{}{}
+	for key, change := range changes {
+		if change.Before != nil {
+			before[key] = change.Before
+		}
+		if change.After != nil {
+			after[key] = change.After
+		}
+	}
+
+	before = utils.MaskPII(before, fields)
+	after = utils.MaskPII(after, fields)
+
+	masked := make(map[string]FieldChange, len(changes))
+	for key := range changes {
+		masked[key] = FieldChange{Before: before[key], After: after[key]}
+	}
+	return masked
+}
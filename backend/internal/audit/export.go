@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sikerma/backend/internal/models"
+	"github.com/sikerma/backend/internal/utils"
+)
+
+// Format ekspor audit log
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// exportColumns adalah urutan kolom CSV dan key setiap baris JSONL
+var exportColumns = []string{
+	"seq", "id", "created_at", "user_id", "username",
+	"impersonated_user_id", "impersonated_username",
+	"action", "resource", "resource_id", "status", "error_message",
+	"ip_address", "user_agent", "changes", "row_hash",
+}
+
+// exportMaskedFields ditambahkan ke field sensitif utils saat ekspor ter-mask
+var exportMaskedFields = append([]string{"ip_address"}, maskedFields...)
+
+// ExportWriter menulis audit log satu per satu ke CSV atau JSON Lines
+type ExportWriter struct {
+	format string
+	mask   bool
+	csv    *csv.Writer
+	json   *json.Encoder
+	rows   int64
+}
+
+// NewExportWriter membuat ExportWriter. Untuk CSV, header langsung ditulis.
+// mask = true menerapkan utils.MaskPII pada setiap baris.
+func NewExportWriter(w io.Writer, format string, mask bool) (*ExportWriter, error) {
+	e := &ExportWriter{format: format, mask: mask}
+	switch format {
+	case FormatCSV:
+		e.csv = csv.NewWriter(w)
+		if err := e.csv.Write(exportColumns); err != nil {
+			return nil, err
+		}
+	case FormatJSONL:
+		e.json = json.NewEncoder(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+	return e, nil
+}
+
+// Write menulis satu audit log
+func (e *ExportWriter) Write(log models.AuditLog) error {
+	record := exportRecord(log)
+	if e.mask {
+		record = utils.MaskPII(record, exportMaskedFields)
+	}
+	e.rows++
+
+	if e.json != nil {
+		return e.json.Encode(record)
+	}
+
+	line := make([]string, len(exportColumns))
+	for i, column := range exportColumns {
+		line[i] = csvCell(record[column])
+	}
+	return e.csv.Write(line)
+}
+
+// Flush menulis sisa buffer CSV
+func (e *ExportWriter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
+
+// Rows mengembalikan jumlah baris yang sudah ditulis
+func (e *ExportWriter) Rows() int64 {
+	return e.rows
+}
+
+// ContentType mengembalikan MIME type untuk format ekspor
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// exportRecord mengubah audit log menjadi map sesuai exportColumns. Nilai nil
+// tidak dimasukkan agar masking tidak mengubahnya menjadi "****".
+func exportRecord(log models.AuditLog) map[string]interface{} {
+	record := map[string]interface{}{
+		"id":         log.ID.String(),
+		"created_at": log.CreatedAt.UTC().Format(time.RFC3339Nano),
+		"action":     log.Action,
+		"resource":   log.Resource,
+		"status":     log.Status,
+	}
+	set := func(key string, value *string) {
+		if value != nil {
+			record[key] = *value
+		}
+	}
+	set("user_id", log.UserID)
+	set("username", log.Username)
+	set("impersonated_user_id", log.ImpersonatedUserID)
+	set("impersonated_username", log.ImpersonatedUsername)
+	set("error_message", log.ErrorMessage)
+	set("ip_address", log.IPAddress)
+	set("user_agent", log.UserAgent)
+	set("row_hash", log.RowHash)
+	if log.Seq != nil {
+		record["seq"] = *log.Seq
+	}
+	if log.ResourceID != nil {
+		record["resource_id"] = log.ResourceID.String()
+	}
+	if log.Changes != nil {
+		record["changes"] = log.Changes
+	}
+	return record
+}
+
+// csvCell mengubah nilai menjadi sel CSV. Nilai yang diawali karakter formula
+// spreadsheet diberi prefix ' agar tidak dieksekusi saat dibuka di Excel.
+func csvCell(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+	case map[string]interface{}:
+		raw, _ := json.Marshal(v)
+		s = string(raw)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// ParseTime menerima RFC3339 atau tanggal YYYY-MM-DD (zona Asia/Jakarta).
+// dateOnly bernilai true untuk format tanggal, sehingga batas akhir rentang
+// dapat dibuat inklusif.
+func ParseTime(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err = time.ParseInLocation("2006-01-02", value, jakarta)
+	return t, true, err
+}
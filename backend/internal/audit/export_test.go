@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+func exportFixture() models.AuditLog {
+	seq := int64(7)
+	return models.AuditLog{
+		ID:        uuid.New(),
+		Seq:       &seq,
+		CreatedAt: time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC),
+		Username:  stringPtr("=cmd|' /C calc'!A0"),
+		Action:    ActionUpdate,
+		Resource:  "pegawai",
+		Status:    "success",
+		IPAddress: stringPtr("10.20.30.40"),
+		Changes: map[string]interface{}{
+			"request_body": map[string]interface{}{"email": "budi@pa.go.id", "nama": "Budi"},
+		},
+	}
+}
+
+func TestExportWriterCSVMasked(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewExportWriter(&buf, FormatCSV, true)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(exportFixture()))
+	require.NoError(t, w.Flush())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, exportColumns, records[0])
+
+	row := map[string]string{}
+	for i, column := range exportColumns {
+		row[column] = records[1][i]
+	}
+	assert.Equal(t, "7", row["seq"])
+	assert.Equal(t, "****0.40", row["ip_address"])
+	assert.True(t, strings.HasPrefix(row["username"], "'="), "formula must be escaped")
+	assert.Contains(t, row["changes"], `"email":"b***@pa.go.id"`)
+	assert.Equal(t, int64(1), w.Rows())
+}
+
+func TestExportWriterJSONLUnmasked(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewExportWriter(&buf, FormatJSONL, false)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(exportFixture()))
+	require.NoError(t, w.Write(exportFixture()))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "10.20.30.40", record["ip_address"])
+	assert.Equal(t, "budi@pa.go.id", record["changes"].(map[string]interface{})["request_body"].(map[string]interface{})["email"])
+	assert.NotContains(t, record, "resource_id")
+}
+
+func TestNewExportWriterRejectsUnknownFormat(t *testing.T) {
+	_, err := NewExportWriter(&bytes.Buffer{}, "xlsx", true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// Notifier mengirim alert ke kanal di luar aplikasi
+type Notifier interface {
+	Notify(ctx context.Context, alert models.AuditAlert) error
+}
+
+// Notifiers meneruskan alert ke semua notifier; kegagalan satu notifier
+// tidak menghentikan yang lain
+type Notifiers []Notifier
+
+func (n Notifiers) Notify(ctx context.Context, alert models.AuditAlert) error {
+	var errs []error
+	for _, notifier := range n {
+		if err := notifier.Notify(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LogNotifier menulis alert ke log aplikasi
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(_ context.Context, alert models.AuditAlert) error {
+	fields := logrus.Fields{
+		"alert_id": alert.ID,
+		"rule":     alert.Rule,
+		"severity": alert.Severity,
+		"events":   alert.EventCount,
+	}
+	if alert.UserID != nil {
+		fields["user_id"] = *alert.UserID
+	}
+	if alert.IPAddress != nil {
+		fields["ip"] = *alert.IPAddress
+	}
+	logrus.WithFields(fields).Warn("Audit alert: " + alert.Summary)
+	return nil
+}
+
+// WebhookNotifier mengirim alert sebagai JSON POST ke URL webhook
+type WebhookNotifier struct {
+	url  string
+	http *http.Client
+}
+
+// NewWebhookNotifier membuat WebhookNotifier untuk url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, alert models.AuditAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach alert webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
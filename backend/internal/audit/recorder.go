@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// jakarta adalah zona waktu kantor, dipakai untuk batas bulan partisi dan
+// jam kerja
+var jakarta = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		return time.FixedZone("WIB", 7*60*60)
+	}
+	return loc
+}()
+
+// Action untuk perubahan dan akses yang dicatat repository
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionRead   = "read"
+	ActionRevert = "revert"
+)
+
+// Change adalah satu mutasi record yang dicatat repository. Fields sudah
+// di-mask; Snapshot menyimpan state lengkap tanpa masking untuk revert.
+type Change struct {
+	Action     string
+	Resource   string
+	ResourceID uuid.UUID
+	Fields     map[string]FieldChange
+	Snapshot   Snapshot
+}
+
+// Snapshot adalah representasi JSON record sebelum dan sesudah perubahan.
+// Nilai null berarti record belum ada atau sudah dihapus.
+type Snapshot struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Recorder mengumpulkan perubahan dan record yang dibaca selama satu request
+type Recorder struct {
+	mu       sync.Mutex
+	changes  []Change
+	accessed map[string][]uuid.UUID
+}
+
+// recorderKey adalah key context untuk *Recorder
+type recorderKey struct{}
+
+// WithRecorder memasang Recorder baru pada context
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	rec := &Recorder{}
+	return context.WithValue(ctx, recorderKey{}, rec), rec
+}
+
+// Record mencatat perubahan resource ke Recorder pada context. Dipanggil
+// repository setelah transaksi berhasil. Tanpa Recorder (misalnya job
+// background) pemanggilan diabaikan.
+func Record(ctx context.Context, action, resource string, id uuid.UUID, before, after interface{}) {
+	rec, ok := ctx.Value(recorderKey{}).(*Recorder)
+	if !ok {
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.changes = append(rec.changes, Change{
+		Action:     action,
+		Resource:   resource,
+		ResourceID: id,
+		Fields:     diffResource(resource, before, after),
+		Snapshot:   Snapshot{Before: rawRecord(before), After: rawRecord(after)},
+	})
+}
+
+// rawRecord mengubah record menjadi JSON; nil untuk record yang tidak ada
+func rawRecord(v interface{}) json.RawMessage {
+	if len(toFields(v)) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// Changes mengembalikan perubahan yang sudah dicatat
+func (r *Recorder) Changes() []Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Change(nil), r.changes...)
+}
+
+// RecordAccess mencatat ID record resource yang dikembalikan ke user. ID yang
+// sama dalam satu request hanya dicatat sekali.
+func RecordAccess(ctx context.Context, resource string, ids ...uuid.UUID) {
+	rec, ok := ctx.Value(recorderKey{}).(*Recorder)
+	if !ok || len(ids) == 0 {
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.accessed == nil {
+		rec.accessed = map[string][]uuid.UUID{}
+	}
+	for _, id := range ids {
+		if !slices.Contains(rec.accessed[resource], id) {
+			rec.accessed[resource] = append(rec.accessed[resource], id)
+		}
+	}
+}
+
+// Accessed mengembalikan ID record resource yang sudah dibaca, sesuai urutan
+// pencatatan
+func (r *Recorder) Accessed(resource string) []uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uuid.UUID(nil), r.accessed[resource]...)
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+func stringPtr(s string) *string { return &s }
+
+func TestDiffUpdate(t *testing.T) {
+	golonganLama, golonganBaru := uuid.New(), uuid.New()
+	before := &models.Pegawai{
+		ID:          uuid.New(),
+		NamaLengkap: "Budi Santoso",
+		NIK:         stringPtr("3201010101010001"),
+		GolonganID:  &golonganLama,
+		UpdatedAt:   time.Now().Add(-time.Hour),
+	}
+	after := *before
+	after.GolonganID = &golonganBaru
+	after.NIK = stringPtr("3201010101019999")
+	after.Telepon = stringPtr("081234567890")
+	after.UpdatedAt = time.Now()
+
+	diff := Diff(before, &after)
+
+	require.Contains(t, diff, "golongan_id")
+	assert.Equal(t, golonganLama.String(), diff["golongan_id"].Before)
+	assert.Equal(t, golonganBaru.String(), diff["golongan_id"].After)
+
+	// Field PII di-mask, nilai kosong tetap nil
+	assert.Equal(t, "************0001", diff["nik"].Before)
+	assert.Equal(t, "************9999", diff["nik"].After)
+	assert.Nil(t, diff["telepon"].Before)
+	assert.Equal(t, "****7890", diff["telepon"].After)
+
+	assert.NotContains(t, diff, "nama_lengkap")
+	assert.NotContains(t, diff, "updated_at")
+}
+
+func TestDiffCreateAndDelete(t *testing.T) {
+	satker := &models.Satker{ID: uuid.New(), Kode: "PA-01", Nama: "Pengadilan Agama", IsActive: true}
+
+	created := Diff(nil, satker)
+	assert.Nil(t, created["kode"].Before)
+	assert.Equal(t, "PA-01", created["kode"].After)
+
+	var deleted *models.Satker
+	removed := Diff(satker, deleted)
+	assert.Equal(t, "Pengadilan Agama", removed["nama"].Before)
+	assert.Nil(t, removed["nama"].After)
+}
+
+func TestRecord(t *testing.T) {
+	id := uuid.New()
+
+	// Tanpa recorder pemanggilan diabaikan
+	Record(context.Background(), ActionCreate, "satker", id, nil, &models.Satker{ID: id})
+
+	ctx, rec := WithRecorder(context.Background())
+	Record(ctx, ActionUpdate, "satker", id,
+		&models.Satker{ID: id, Nama: "Lama"},
+		&models.Satker{ID: id, Nama: "Baru"},
+	)
+
+	changes := rec.Changes()
+	require.Len(t, changes, 1)
+	assert.Equal(t, ActionUpdate, changes[0].Action)
+	assert.Equal(t, "satker", changes[0].Resource)
+	assert.Equal(t, id, changes[0].ResourceID)
+	assert.Equal(t, FieldChange{Before: "Lama", After: "Baru"}, changes[0].Fields["nama"])
+	assert.Len(t, changes[0].Fields, 1)
+	assert.Contains(t, string(changes[0].Snapshot.Before), `"nama":"Lama"`)
+	assert.Contains(t, string(changes[0].Snapshot.After), `"nama":"Baru"`)
+}
+
+func TestRecordMasksRestrictedFields(t *testing.T) {
+	id := uuid.New()
+	ctx, rec := WithRecorder(context.Background())
+	Record(ctx, ActionCreate, "hukdis", id, nil, &models.Hukdis{
+		ID:      id,
+		NomorSK: "W10-A/123/KP.05.2/2026",
+		Alasan:  stringPtr("Tidak masuk kerja tanpa keterangan"),
+		Pejabat: "Ketua",
+	})
+
+	changes := rec.Changes()
+	require.Len(t, changes, 1)
+	assert.Equal(t, "Ketua", changes[0].Fields["pejabat"].After)
+	assert.NotEqual(t, "W10-A/123/KP.05.2/2026", changes[0].Fields["nomor_sk"].After)
+	assert.NotContains(t, changes[0].Fields["alasan"].After, "tanpa keterangan")
+
+	// Field yang sama pada resource lain tidak di-mask
+	Record(ctx, ActionCreate, "riwayat_pangkat", id, nil, &models.RiwayatPangkat{ID: id, NomorSK: "SK-1"})
+	assert.Equal(t, "SK-1", rec.Changes()[1].Fields["nomor_sk"].After)
+}
+
+func TestRecordAccess(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+
+	// Tanpa Recorder pada context tidak terjadi apa-apa
+	RecordAccess(context.Background(), "pegawai", a)
+
+	ctx, rec := WithRecorder(context.Background())
+	RecordAccess(ctx, "pegawai", a, b)
+	RecordAccess(ctx, "pegawai", b)
+	RecordAccess(ctx, "satker", a)
+
+	assert.Equal(t, []uuid.UUID{a, b}, rec.Accessed("pegawai"))
+	assert.Equal(t, []uuid.UUID{a}, rec.Accessed("satker"))
+	assert.Empty(t, rec.Accessed("golongan"))
+	assert.Empty(t, rec.Changes())
+}
@@ -0,0 +1,287 @@
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// partitionNamePattern sesuai nama dari audit_logs_ensure_partition, misalnya audit_logs_y2026m10
+var partitionNamePattern = regexp.MustCompile(`^audit_logs_y(\d{4})m(\d{2})$`)
+
+// Partition adalah satu partisi bulanan audit_logs
+type Partition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// ParsePartition membaca rentang bulan dari nama partisi
+func ParsePartition(name string) (Partition, bool) {
+	m := partitionNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return Partition{}, false
+	}
+	from, err := time.ParseInLocation("200601", m[1]+m[2], jakarta)
+	if err != nil {
+		return Partition{}, false
+	}
+	return Partition{Name: name, From: from, To: from.AddDate(0, 1, 0)}, true
+}
+
+// MonthStart mengembalikan awal bulan t pada zona partisi
+func MonthStart(t time.Time) time.Time {
+	t = t.In(jakarta)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, jakarta)
+}
+
+// Archive adalah partisi yang sudah diekspor ke file sebelum di-drop
+type Archive struct {
+	Partition   string    `json:"partition"`
+	RangeFrom   time.Time `json:"range_from"`
+	RangeTo     time.Time `json:"range_to"`
+	File        string    `json:"file"`
+	SHA256      string    `json:"sha256"`
+	Rows        int64     `json:"rows"`
+	FirstSeq    *int64    `json:"first_seq,omitempty"`
+	LastSeq     *int64    `json:"last_seq,omitempty"`
+	LastRowHash *string   `json:"last_row_hash,omitempty"`
+}
+
+// PartitionStore mengelola partisi audit_logs di database
+type PartitionStore interface {
+	EnsurePartitions(ctx context.Context, from time.Time, months int) error
+	ListPartitions(ctx context.Context) ([]Partition, error)
+	StreamPartition(ctx context.Context, name string, fn func(ChainEntry) error) error
+	// DropArchivedPartition mencatat archive lalu melepas dan menghapus partisi
+	DropArchivedPartition(ctx context.Context, archive Archive) error
+}
+
+// RetentionPolicy mengatur partisi yang dibuat dan diarsipkan
+type RetentionPolicy struct {
+	// Months lama data disimpan di database; 0 berarti tidak pernah diarsipkan
+	Months int
+	// Ahead jumlah partisi bulan berikutnya yang disiapkan
+	Ahead      int
+	ArchiveDir string
+}
+
+// Apply menyiapkan partisi bulan berjalan sampai Ahead bulan ke depan, lalu
+// mengarsipkan dan men-drop partisi yang seluruh rentangnya lebih tua dari
+// Months bulan
+func (p RetentionPolicy) Apply(ctx context.Context, store PartitionStore, now time.Time) ([]Archive, error) {
+	current := MonthStart(now)
+	if err := store.EnsurePartitions(ctx, current, p.Ahead+1); err != nil {
+		return nil, err
+	}
+	if p.Months <= 0 {
+		return nil, nil
+	}
+
+	cutoff := current.AddDate(0, -p.Months, 0)
+	partitions, err := store.ListPartitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	archives := []Archive{}
+	for _, part := range partitions {
+		if part.To.After(cutoff) {
+			continue
+		}
+		archive, err := ArchivePartition(ctx, store, part, p.ArchiveDir)
+		if err != nil {
+			return archives, fmt.Errorf("failed to archive %s: %w", part.Name, err)
+		}
+		if err := store.DropArchivedPartition(ctx, *archive); err != nil {
+			return archives, err
+		}
+		archives = append(archives, *archive)
+	}
+	return archives, nil
+}
+
+// RunMaintenance menjalankan policy saat start dan setiap interval
+func RunMaintenance(ctx context.Context, store PartitionStore, policy RetentionPolicy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		archives, err := policy.Apply(ctx, store, time.Now())
+		if err != nil && ctx.Err() == nil {
+			logrus.WithError(err).Error("Audit log partition maintenance failed")
+		}
+		for _, a := range archives {
+			logrus.WithFields(logrus.Fields{"partition": a.Partition, "file": a.File, "rows": a.Rows}).Info("Archived audit log partition")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ArchivePartition menulis seluruh baris partisi ke file gzip JSONL di dir
+// beserta file checksum <file>.sha256 (format sha256sum). File berisi data
+// lengkap tanpa masking agar hash chain tetap dapat diverifikasi.
+func ArchivePartition(ctx context.Context, store PartitionStore, part Partition, dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	path := filepath.Join(dir, part.Name+".jsonl.gz")
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(f, hash))
+	enc := json.NewEncoder(gz)
+
+	archive := &Archive{Partition: part.Name, RangeFrom: part.From, RangeTo: part.To, File: path}
+	err = store.StreamPartition(ctx, part.Name, func(e ChainEntry) error {
+		if e.Seq > 0 {
+			seq, rowHash := e.Seq, e.RowHash
+			if archive.FirstSeq == nil {
+				archive.FirstSeq = &seq
+			}
+			archive.LastSeq, archive.LastRowHash = &seq, &rowHash
+		}
+		archive.Rows++
+		return enc.Encode(e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync archive file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	archive.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	checksum := fmt.Sprintf("%s  %s\n", archive.SHA256, filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(checksum), 0o640); err != nil {
+		return nil, fmt.Errorf("failed to write archive checksum: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("failed to finalize archive file: %w", err)
+	}
+
+	return archive, nil
+}
+
+// ReadArchive memverifikasi checksum file arsip terhadap <file>.sha256 lalu
+// memanggil fn untuk setiap baris. Link hash chain di dalam arsip juga dicek
+// sehingga arsip yang diubah setelah diekspor terdeteksi.
+func ReadArchive(path string, fn func(ChainEntry) error) error {
+	expected, err := readChecksum(path + ".sha256")
+	if err != nil {
+		return err
+	}
+	actual, err := ArchiveChecksum(path)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("archive checksum mismatch: expected %s, got %s", expected, actual)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	var prev *ChainEntry
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e ChainEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("invalid archive line: %w", err)
+		}
+		if e.Seq > 0 {
+			if err := checkArchivedLink(prev, e); err != nil {
+				return err
+			}
+			entry := e
+			prev = &entry
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// checkArchivedLink mengecek hash baris dan sambungannya dengan baris sebelumnya
+func checkArchivedLink(prev *ChainEntry, e ChainEntry) error {
+	hash, err := e.ComputeHash()
+	if err != nil {
+		return err
+	}
+	if hash != e.RowHash {
+		return fmt.Errorf("archived row seq %d does not match its row_hash", e.Seq)
+	}
+	if prev != nil && (e.Seq != prev.Seq+1 || e.PrevHash != prev.RowHash) {
+		return fmt.Errorf("archived chain broken at seq %d", e.Seq)
+	}
+	return nil
+}
+
+// readChecksum membaca hash dari file format sha256sum
+func readChecksum(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read archive checksum: %w", err)
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file %s", path)
+	}
+	return fields[0], nil
+}
+
+// ArchiveChecksum menghitung SHA-256 isi file arsip
+func ArchiveChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
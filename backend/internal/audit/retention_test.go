@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePartitionStore struct {
+	partitions []Partition
+	rows       map[string]fakeChain
+	ensured    []time.Time
+	dropped    []Archive
+}
+
+func (f *fakePartitionStore) EnsurePartitions(_ context.Context, from time.Time, months int) error {
+	for i := 0; i < months; i++ {
+		f.ensured = append(f.ensured, from.AddDate(0, i, 0))
+	}
+	return nil
+}
+
+func (f *fakePartitionStore) ListPartitions(_ context.Context) ([]Partition, error) {
+	return f.partitions, nil
+}
+
+func (f *fakePartitionStore) StreamPartition(ctx context.Context, name string, fn func(ChainEntry) error) error {
+	return f.rows[name].WalkChain(ctx, fn)
+}
+
+func (f *fakePartitionStore) DropArchivedPartition(_ context.Context, archive Archive) error {
+	f.dropped = append(f.dropped, archive)
+	return nil
+}
+
+func partition(t *testing.T, name string) Partition {
+	t.Helper()
+	p, ok := ParsePartition(name)
+	require.True(t, ok)
+	return p
+}
+
+func TestParsePartition(t *testing.T) {
+	p := partition(t, "audit_logs_y2026m10")
+	assert.Equal(t, "2026-10-01T00:00:00+07:00", p.From.Format(time.RFC3339))
+	assert.Equal(t, "2026-11-01T00:00:00+07:00", p.To.Format(time.RFC3339))
+
+	for _, name := range []string{"audit_logs", "audit_logs_y2026m1", "audit_logs_y2026m13", "audit_logs_y2026m10; DROP TABLE x"} {
+		_, ok := ParsePartition(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestArchivePartitionRoundTrip(t *testing.T) {
+	chain := buildChain(t, 5)
+	store := &fakePartitionStore{rows: map[string]fakeChain{"audit_logs_y2024m01": chain}}
+	dir := t.TempDir()
+
+	archive, err := ArchivePartition(context.Background(), store, partition(t, "audit_logs_y2024m01"), dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), archive.Rows)
+	assert.Equal(t, int64(1), *archive.FirstSeq)
+	assert.Equal(t, int64(5), *archive.LastSeq)
+	assert.Equal(t, chain[4].RowHash, *archive.LastRowHash)
+
+	sum, err := ArchiveChecksum(archive.File)
+	require.NoError(t, err)
+	assert.Equal(t, archive.SHA256, sum)
+
+	read := []ChainEntry{}
+	require.NoError(t, ReadArchive(archive.File, func(e ChainEntry) error {
+		read = append(read, e)
+		return nil
+	}))
+	require.Len(t, read, 5)
+	for i, e := range read {
+		assert.Equal(t, chain[i].RowHash, e.RowHash)
+		assert.True(t, chain[i].CreatedAt.Equal(e.CreatedAt))
+	}
+}
+
+func TestReadArchiveDetectsTampering(t *testing.T) {
+	store := &fakePartitionStore{rows: map[string]fakeChain{"audit_logs_y2024m01": buildChain(t, 3)}}
+	archive, err := ArchivePartition(context.Background(), store, partition(t, "audit_logs_y2024m01"), t.TempDir())
+	require.NoError(t, err)
+
+	noop := func(ChainEntry) error { return nil }
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		raw, err := os.ReadFile(archive.File)
+		require.NoError(t, err)
+		raw[len(raw)-1] ^= 0xff
+		tampered := filepath.Join(t.TempDir(), "tampered.jsonl.gz")
+		require.NoError(t, os.WriteFile(tampered, raw, 0o600))
+		checksum, err := os.ReadFile(archive.File + ".sha256")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(tampered+".sha256", checksum, 0o600))
+
+		assert.ErrorContains(t, ReadArchive(tampered, noop), "checksum mismatch")
+	})
+
+	t.Run("rewritten row with new checksum", func(t *testing.T) {
+		rows := buildChain(t, 3)
+		rows[1].Username = stringPtr("intruder")
+		tampered := filepath.Join(t.TempDir(), "rewritten.jsonl.gz")
+		writeRawArchive(t, tampered, rows)
+
+		assert.ErrorContains(t, ReadArchive(tampered, noop), "seq 2")
+	})
+}
+
+// writeRawArchive menulis arsip beserta checksum yang cocok tanpa melalui
+// ArchivePartition, seperti arsip yang diubah lalu checksum-nya dibuat ulang
+func writeRawArchive(t *testing.T, path string, rows []ChainEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	for _, e := range rows {
+		line, err := CanonicalJSON(e)
+		require.NoError(t, err)
+		_, err = gz.Write(append(line, '\n'))
+		require.NoError(t, err)
+	}
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	sum, err := ArchiveChecksum(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".sha256", []byte(sum+"  "+filepath.Base(path)+"\n"), 0o600))
+}
+
+func TestRetentionPolicyApply(t *testing.T) {
+	store := &fakePartitionStore{
+		partitions: []Partition{
+			partition(t, "audit_logs_y2024m08"),
+			partition(t, "audit_logs_y2024m09"),
+			partition(t, "audit_logs_y2024m10"),
+			partition(t, "audit_logs_y2026m10"),
+		},
+		rows: map[string]fakeChain{},
+	}
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, jakarta)
+	policy := RetentionPolicy{Months: 24, Ahead: 3, ArchiveDir: t.TempDir()}
+
+	archives, err := policy.Apply(context.Background(), store, now)
+	require.NoError(t, err)
+
+	require.Len(t, store.ensured, 4)
+	assert.Equal(t, "2026-10-01", store.ensured[0].Format("2006-01-02"))
+	assert.Equal(t, "2027-01-01", store.ensured[3].Format("2006-01-02"))
+
+	// Oktober 2024 masih di dalam 24 bulan terakhir
+	require.Len(t, archives, 2)
+	assert.Equal(t, "audit_logs_y2024m08", archives[0].Partition)
+	assert.Equal(t, "audit_logs_y2024m09", archives[1].Partition)
+	assert.Equal(t, archives, store.dropped)
+}
+
+func TestRetentionPolicyKeepForever(t *testing.T) {
+	store := &fakePartitionStore{partitions: []Partition{partition(t, "audit_logs_y2000m01")}}
+
+	archives, err := RetentionPolicy{Months: 0, Ahead: 1}.Apply(context.Background(), store, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, archives)
+	assert.Empty(t, store.dropped)
+	assert.Len(t, store.ensured, 2)
+}
@@ -0,0 +1,214 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sikerma/backend/internal/utils"
+)
+
+// Operasi revert, ditentukan dari action entry yang dibatalkan
+const (
+	// RevertUpdate mengembalikan field yang diubah ke nilai sebelumnya
+	RevertUpdate = "update"
+	// RevertRestore memulihkan record yang dihapus
+	RevertRestore = "restore"
+	// RevertDelete menghapus record yang dibuat
+	RevertDelete = "delete"
+)
+
+// revertableResources adalah resource yang snapshot-nya disimpan dan dapat
+// di-revert. Resource lain tidak menyimpan snapshot karena isinya tanpa masking.
+var revertableResources = map[string]bool{
+	"pegawai": true,
+	"satker":  true,
+}
+
+// Revertable menandai resource yang mendukung revert
+func Revertable(resource string) bool {
+	return revertableResources[resource]
+}
+
+var (
+	ErrRevertUnsupported = errors.New("audit entry cannot be reverted")
+	ErrRevertField       = errors.New("field cannot be reverted")
+)
+
+// RevertField adalah satu field yang akan dikembalikan. Nilai PII di-mask.
+type RevertField struct {
+	Field    string      `json:"field"`
+	Label    string      `json:"label"`
+	Current  interface{} `json:"current"`
+	Target   interface{} `json:"target"`
+	Conflict bool        `json:"conflict"`
+}
+
+// RevertPlan adalah hasil perbandingan snapshot entry audit dengan state
+// record saat ini. Field konflik adalah field yang sudah berubah lagi sejak
+// entry tersebut dicatat.
+type RevertPlan struct {
+	Operation  string        `json:"operation"`
+	Fields     []RevertField `json:"fields"`
+	Conflicts  []string      `json:"conflicts"`
+	Reason     string        `json:"reason,omitempty"`
+	Applicable bool          `json:"applicable"`
+	// Target berisi nilai tanpa masking yang diterapkan repository: field
+	// yang dikembalikan untuk RevertUpdate, record lengkap untuk RevertRestore
+	Target map[string]interface{} `json:"-"`
+	// Expected berisi nilai field dari snapshot after yang harus masih sama
+	// pada record saat revert diterapkan
+	Expected map[string]interface{} `json:"-"`
+}
+
+// Stale menandai record yang sudah berubah dari Expected sejak plan disusun.
+// Repository memanggilnya setelah mengunci record, di transaksi yang sama
+// dengan penulisan revert.
+func (p *RevertPlan) Stale(record interface{}) bool {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return true
+	}
+	current, _ := rawFields(raw)
+	for field, value := range p.Expected {
+		if !sameValue(current[field], value) {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanRevert menyusun revert untuk entry dengan action dan snapshot, terhadap
+// current (JSON record saat ini, nil bila tidak ada). only membatasi field
+// yang dikembalikan dan hanya berlaku untuk entry update.
+func PlanRevert(action string, snap Snapshot, current json.RawMessage, only []string) (*RevertPlan, error) {
+	before, hasBefore := rawFields(snap.Before)
+	after, hasAfter := rawFields(snap.After)
+	cur, exists := rawFields(current)
+
+	// Entry revert tercatat dengan action revert; operasinya dibaca dari snapshot
+	if action == ActionRevert {
+		switch {
+		case hasBefore && hasAfter:
+			action = ActionUpdate
+		case hasAfter:
+			action = ActionCreate
+		default:
+			action = ActionDelete
+		}
+	}
+
+	plan := &RevertPlan{Fields: []RevertField{}, Conflicts: []string{}, Target: map[string]interface{}{}, Expected: map[string]interface{}{}}
+	switch {
+	case action == ActionUpdate && hasBefore && hasAfter:
+		plan.Operation = RevertUpdate
+	case action == ActionCreate && hasAfter:
+		plan.Operation = RevertDelete
+	case action == ActionDelete && hasBefore:
+		plan.Operation = RevertRestore
+	default:
+		return nil, ErrRevertUnsupported
+	}
+	if len(only) > 0 && plan.Operation != RevertUpdate {
+		return nil, fmt.Errorf("%w: field selection only applies to updates", ErrRevertField)
+	}
+
+	changed := changedFields(before, after)
+	if len(only) > 0 {
+		for _, field := range only {
+			if !slices.Contains(changed, field) {
+				return nil, fmt.Errorf("%w: %s was not changed by this entry", ErrRevertField, field)
+			}
+		}
+		changed = only
+	}
+	sort.Strings(changed)
+
+	switch {
+	case plan.Operation == RevertRestore && !hasAfter && exists:
+		plan.Reason = "record dengan ID yang sama sudah ada"
+	case plan.Operation != RevertRestore && !exists:
+		plan.Reason = "record sudah dihapus"
+	}
+
+	currentValues := map[string]interface{}{}
+	targetValues := map[string]interface{}{}
+	for _, field := range changed {
+		conflict := exists && hasAfter && !sameValue(cur[field], after[field])
+		if conflict {
+			plan.Conflicts = append(plan.Conflicts, field)
+		}
+		plan.Fields = append(plan.Fields, RevertField{Field: field, Label: FieldLabel(field), Conflict: conflict})
+		if hasAfter {
+			plan.Expected[field] = after[field]
+		}
+		if v, ok := cur[field]; ok && v != nil {
+			currentValues[field] = v
+		}
+		if v, ok := before[field]; ok && v != nil {
+			targetValues[field] = v
+		}
+		if plan.Operation == RevertUpdate {
+			plan.Target[field] = before[field]
+		}
+	}
+	if plan.Operation == RevertRestore {
+		plan.Target = before
+	}
+
+	currentValues = utils.MaskPII(currentValues, maskedFields)
+	targetValues = utils.MaskPII(targetValues, maskedFields)
+	for i := range plan.Fields {
+		plan.Fields[i].Current = currentValues[plan.Fields[i].Field]
+		plan.Fields[i].Target = targetValues[plan.Fields[i].Field]
+	}
+
+	plan.Applicable = plan.Reason == "" && len(plan.Conflicts) == 0
+	return plan, nil
+}
+
+// rawFields mendekode JSON record; false bila record tidak ada
+func rawFields(raw json.RawMessage) (map[string]interface{}, bool) {
+	fields := map[string]interface{}{}
+	if len(raw) == 0 {
+		return fields, false
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return map[string]interface{}{}, false
+	}
+	return fields, true
+}
+
+// changedFields mengembalikan field yang berbeda antara before dan after
+func changedFields(before, after map[string]interface{}) []string {
+	fields := []string{}
+	for key := range union(before, after) {
+		if !ignoredFields[key] && !sameValue(before[key], after[key]) {
+			fields = append(fields, key)
+		}
+	}
+	return fields
+}
+
+// sameValue membandingkan nilai JSON; timestamp dibandingkan sebagai waktu
+// karena offset zona waktunya bisa berbeda antara input dan database
+func sameValue(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if !aok || !bok {
+		return false
+	}
+	at, err := time.Parse(time.RFC3339Nano, as)
+	if err != nil {
+		return false
+	}
+	bt, err := time.Parse(time.RFC3339Nano, bs)
+	return err == nil && at.Equal(bt)
+}
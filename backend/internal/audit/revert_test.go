@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+func mustRaw(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	return raw
+}
+
+func revertField(plan *RevertPlan, field string) *RevertField {
+	for i := range plan.Fields {
+		if plan.Fields[i].Field == field {
+			return &plan.Fields[i]
+		}
+	}
+	return nil
+}
+
+func TestPlanRevertUpdate(t *testing.T) {
+	id := uuid.New()
+	before := &models.Pegawai{ID: id, NamaLengkap: "Budi Santoso", NIK: stringPtr("3201010101010001"), UpdatedAt: time.Now().Add(-time.Hour)}
+	after := *before
+	after.NamaLengkap = "Budi Salah Input"
+	after.NIK = stringPtr("3201010101019999")
+	after.UpdatedAt = time.Now()
+	snap := Snapshot{Before: mustRaw(t, before), After: mustRaw(t, &after)}
+
+	plan, err := PlanRevert(ActionUpdate, snap, mustRaw(t, &after), nil)
+	require.NoError(t, err)
+	assert.Equal(t, RevertUpdate, plan.Operation)
+	assert.True(t, plan.Applicable)
+	assert.Empty(t, plan.Conflicts)
+	require.Len(t, plan.Fields, 2)
+
+	// Nilai tampilan di-mask, Target tetap nilai asli
+	nik := revertField(plan, "nik")
+	require.NotNil(t, nik)
+	assert.Equal(t, "************9999", nik.Current)
+	assert.Equal(t, "************0001", nik.Target)
+	assert.Equal(t, map[string]interface{}{"nama_lengkap": "Budi Santoso", "nik": "3201010101010001"}, plan.Target)
+
+	// Record berubah lagi setelah entry ini
+	current := after
+	current.NamaLengkap = "Budi Diubah Lagi"
+	plan, err = PlanRevert(ActionUpdate, snap, mustRaw(t, &current), nil)
+	require.NoError(t, err)
+	assert.False(t, plan.Applicable)
+	assert.Equal(t, []string{"nama_lengkap"}, plan.Conflicts)
+
+	// Field yang tidak konflik tetap dapat dikembalikan sendiri
+	plan, err = PlanRevert(ActionUpdate, snap, mustRaw(t, &current), []string{"nik"})
+	require.NoError(t, err)
+	assert.True(t, plan.Applicable)
+	assert.Equal(t, map[string]interface{}{"nik": "3201010101010001"}, plan.Target)
+
+	_, err = PlanRevert(ActionUpdate, snap, mustRaw(t, &current), []string{"tempat_lahir"})
+	assert.ErrorIs(t, err, ErrRevertField)
+
+	// Record sudah tidak ada
+	plan, err = PlanRevert(ActionUpdate, snap, nil, nil)
+	require.NoError(t, err)
+	assert.False(t, plan.Applicable)
+	assert.Equal(t, "record sudah dihapus", plan.Reason)
+}
+
+func TestPlanRevertCreateAndDelete(t *testing.T) {
+	satker := &models.Satker{ID: uuid.New(), Kode: "PA-01", Nama: "Pengadilan Agama", IsActive: true}
+
+	created := Snapshot{After: mustRaw(t, satker)}
+	plan, err := PlanRevert(ActionCreate, created, mustRaw(t, satker), nil)
+	require.NoError(t, err)
+	assert.Equal(t, RevertDelete, plan.Operation)
+	assert.True(t, plan.Applicable)
+
+	_, err = PlanRevert(ActionCreate, created, mustRaw(t, satker), []string{"nama"})
+	assert.ErrorIs(t, err, ErrRevertField)
+
+	deleted := Snapshot{Before: mustRaw(t, satker)}
+	plan, err = PlanRevert(ActionDelete, deleted, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, RevertRestore, plan.Operation)
+	assert.True(t, plan.Applicable)
+	assert.Equal(t, "PA-01", plan.Target["kode"])
+
+	plan, err = PlanRevert(ActionDelete, deleted, mustRaw(t, satker), nil)
+	require.NoError(t, err)
+	assert.False(t, plan.Applicable)
+	assert.Equal(t, "record dengan ID yang sama sudah ada", plan.Reason)
+
+	// Revert atas entry revert dibaca dari snapshot
+	plan, err = PlanRevert(ActionRevert, created, mustRaw(t, satker), nil)
+	require.NoError(t, err)
+	assert.Equal(t, RevertDelete, plan.Operation)
+
+	_, err = PlanRevert(ActionRead, Snapshot{}, nil, nil)
+	assert.ErrorIs(t, err, ErrRevertUnsupported)
+}
+
+func TestPlanRevertSoftDelete(t *testing.T) {
+	deletedAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	before := &models.Pegawai{ID: uuid.New(), NamaLengkap: "Siti", IsActive: true}
+	after := *before
+	after.IsActive = false
+	after.DeletedAt = &deletedAt
+
+	// deleted_at dari database dapat memakai offset lain untuk waktu yang sama
+	current := after
+	local := deletedAt.In(time.FixedZone("WIB", 7*3600))
+	current.DeletedAt = &local
+
+	plan, err := PlanRevert(ActionDelete, Snapshot{Before: mustRaw(t, before), After: mustRaw(t, &after)}, mustRaw(t, &current), nil)
+	require.NoError(t, err)
+	assert.Equal(t, RevertRestore, plan.Operation)
+	assert.True(t, plan.Applicable)
+	assert.Equal(t, []string{"deleted_at", "is_active"}, []string{plan.Fields[0].Field, plan.Fields[1].Field})
+	assert.Equal(t, true, plan.Target["is_active"])
+}
+
+func TestRevertPlanStale(t *testing.T) {
+	id := uuid.New()
+	before := &models.Pegawai{ID: id, NamaLengkap: "Budi Santoso", Email: stringPtr("budi@pa.go.id")}
+	after := *before
+	after.NamaLengkap = "Budi Salah Input"
+	after.UpdatedAt = time.Now()
+	snap := Snapshot{Before: mustRaw(t, before), After: mustRaw(t, &after)}
+
+	plan, err := PlanRevert(ActionUpdate, snap, mustRaw(t, &after), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"nama_lengkap": "Budi Salah Input"}, plan.Expected)
+
+	// Perubahan field lain atau updated_at tidak membuat plan basi
+	current := after
+	current.Email = stringPtr("budi.santoso@pa.go.id")
+	current.UpdatedAt = time.Now().Add(time.Minute)
+	assert.False(t, plan.Stale(&current))
+
+	// Field yang dikembalikan berubah setelah plan disusun
+	current.NamaLengkap = "Budi Diubah Lagi"
+	assert.True(t, plan.Stale(&current))
+}
+
+func TestRevertable(t *testing.T) {
+	assert.True(t, Revertable("pegawai"))
+	assert.True(t, Revertable("satker"))
+	for _, resource := range []string{"hukdis", "keluarga", "riwayat_pangkat", ""} {
+		assert.False(t, Revertable(resource), resource)
+	}
+}
@@ -0,0 +1,269 @@
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// TimelineChange adalah perubahan satu field dalam bahasa yang dibaca manusia
+type TimelineChange struct {
+	Field  string      `json:"field"`
+	Label  string      `json:"label"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// TimelineEntry adalah satu kejadian pada riwayat perubahan sebuah record
+type TimelineEntry struct {
+	AuditLogID   uuid.UUID        `json:"audit_log_id"`
+	At           time.Time        `json:"at"`
+	Action       string           `json:"action"`
+	Actor        string           `json:"actor"`
+	ActorUserID  *string          `json:"actor_user_id,omitempty"`
+	OnBehalfOf   *string          `json:"on_behalf_of,omitempty"`
+	Status       string           `json:"status"`
+	Summary      string           `json:"summary"`
+	Changes      []TimelineChange `json:"changes"`
+	IPAddress    *string          `json:"ip_address,omitempty"`
+	ErrorMessage *string          `json:"error_message,omitempty"`
+}
+
+// fieldLabels adalah nama field yang ditampilkan di timeline
+var fieldLabels = map[string]string{
+	"nip":                    "NIP",
+	"nip_lama":               "NIP Lama",
+	"nik":                    "NIK",
+	"npwp":                   "NPWP",
+	"nama":                   "Nama",
+	"nama_lengkap":           "Nama Lengkap",
+	"gelar_depan":            "Gelar Depan",
+	"gelar_belakang":         "Gelar Belakang",
+	"tempat_lahir":           "Tempat Lahir",
+	"tanggal_lahir":          "Tanggal Lahir",
+	"jenis_kelamin":          "Jenis Kelamin",
+	"agama_id":               "Agama",
+	"status_kawin_id":        "Status Kawin",
+	"satker_id":              "Satker",
+	"jabatan_id":             "Jabatan",
+	"unit_kerja_id":          "Unit Kerja",
+	"golongan_id":            "Golongan",
+	"eselon_id":              "Eselon",
+	"parent_id":              "Satker Induk",
+	"status_pegawai":         "Status Pegawai",
+	"status_kerja":           "Status Kerja",
+	"tmt_cpns":               "TMT CPNS",
+	"tmt_pns":                "TMT PNS",
+	"tmt_jabatan":            "TMT Jabatan",
+	"tmt_pangkat_terakhir":   "TMT Pangkat Terakhir",
+	"tmt_jabatan_terakhir":   "TMT Jabatan Terakhir",
+	"tmt":                    "TMT",
+	"nomor_sk":               "Nomor SK",
+	"tanggal_sk":             "Tanggal SK",
+	"file_sk":                "File SK",
+	"is_terakhir":            "Terakhir",
+	"pendidikan_id":          "Pendidikan",
+	"pendidikan_terakhir_id": "Pendidikan Terakhir",
+	"nama_institusi":         "Nama Institusi",
+	"file_ijazah":            "File Ijazah",
+	"hubungan":               "Hubungan",
+	"is_tanggungan":          "Tanggungan",
+	"jenis_hukdis_id":        "Jenis Hukuman Disiplin",
+	"tanggal_mulai":          "Tanggal Mulai",
+	"tanggal_selesai":        "Tanggal Selesai",
+	"alasan":                 "Alasan",
+	"jenis_diklat_id":        "Jenis Diklat",
+	"nama_diklat":            "Nama Diklat",
+	"penyelenggara":          "Penyelenggara",
+	"jam_jpl":                "Jam Pelajaran (JP)",
+	"nomor_sertifikat":       "Nomor Sertifikat",
+	"tanggal_sertifikat":     "Tanggal Sertifikat",
+	"bpjs_kesehatan":         "BPJS Kesehatan",
+	"bpjs_ketenagakerjaan":   "BPJS Ketenagakerjaan",
+	"kk_no":                  "Nomor KK",
+	"ktp_no":                 "Nomor KTP",
+	"is_active":              "Status Aktif",
+	"deleted_at":             "Tanggal Dihapus",
+}
+
+// FieldLabel mengembalikan label field, atau nama field yang dirapikan bila
+// tidak terdaftar
+func FieldLabel(field string) string {
+	if label, ok := fieldLabels[field]; ok {
+		return label
+	}
+	words := strings.Fields(strings.ReplaceAll(field, "_", " "))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// ReferenceIDs mengumpulkan UUID pada field *_id di diff audit log, untuk
+// diterjemahkan menjadi nama data referensi
+func ReferenceIDs(logs []models.AuditLog) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	ids := []uuid.UUID{}
+	for _, log := range logs {
+		for field, change := range diffOf(log) {
+			if !strings.HasSuffix(field, "_id") {
+				continue
+			}
+			for _, v := range []interface{}{change.Before, change.After} {
+				if s, ok := v.(string); ok {
+					if id, err := uuid.Parse(s); err == nil && !seen[id] {
+						seen[id] = true
+						ids = append(ids, id)
+					}
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// BuildTimeline mengubah audit log satu record menjadi timeline. labels
+// berisi nama data referensi untuk menggantikan UUID pada field *_id.
+func BuildTimeline(logs []models.AuditLog, labels map[uuid.UUID]string) []TimelineEntry {
+	entries := make([]TimelineEntry, 0, len(logs))
+	for _, log := range logs {
+		entry := TimelineEntry{
+			AuditLogID:   log.ID,
+			At:           log.CreatedAt,
+			Action:       log.Action,
+			Actor:        actorName(log.Username, log.UserID),
+			ActorUserID:  log.UserID,
+			Status:       log.Status,
+			IPAddress:    log.IPAddress,
+			ErrorMessage: log.ErrorMessage,
+			Changes:      []TimelineChange{},
+		}
+		if log.ImpersonatedUserID != nil {
+			onBehalfOf := actorName(log.ImpersonatedUsername, log.ImpersonatedUserID)
+			entry.OnBehalfOf = &onBehalfOf
+		}
+
+		diff := diffOf(log)
+		fields := make([]string, 0, len(diff))
+		for field := range diff {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			entry.Changes = append(entry.Changes, TimelineChange{
+				Field:  field,
+				Label:  FieldLabel(field),
+				Before: displayValue(field, diff[field].Before, labels),
+				After:  displayValue(field, diff[field].After, labels),
+			})
+		}
+
+		entry.Summary = summarize(entry)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// diffOf membaca changes.diff yang ditulis AuditTrail
+func diffOf(log models.AuditLog) map[string]FieldChange {
+	raw, ok := log.Changes["diff"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	diff := make(map[string]FieldChange, len(raw))
+	for field, v := range raw {
+		change, _ := v.(map[string]interface{})
+		diff[field] = FieldChange{Before: change["before"], After: change["after"]}
+	}
+	return diff
+}
+
+// displayValue mengganti UUID referensi dengan namanya
+func displayValue(field string, value interface{}, labels map[uuid.UUID]string) interface{} {
+	s, ok := value.(string)
+	if !ok || !strings.HasSuffix(field, "_id") {
+		return value
+	}
+	if id, err := uuid.Parse(s); err == nil {
+		if label, ok := labels[id]; ok {
+			return label
+		}
+	}
+	return value
+}
+
+// actorName memilih username, atau user ID bila username kosong
+func actorName(username, userID *string) string {
+	if username != nil && *username != "" {
+		return *username
+	}
+	if userID != nil && *userID != "" {
+		return *userID
+	}
+	return "sistem"
+}
+
+// summarize membuat kalimat ringkas kejadian, misalnya
+// "admin mengubah Golongan dari III/a - Penata Muda menjadi III/b - Penata Muda Tingkat I"
+func summarize(e TimelineEntry) string {
+	actor := e.Actor
+	if e.OnBehalfOf != nil {
+		actor += " (sebagai " + *e.OnBehalfOf + ")"
+	}
+
+	var sentence string
+	switch e.Action {
+	case ActionCreate:
+		sentence = actor + " membuat data"
+	case ActionDelete:
+		sentence = actor + " menghapus data"
+	case ActionUpdate:
+		switch len(e.Changes) {
+		case 0:
+			sentence = actor + " mengubah data"
+		case 1:
+			c := e.Changes[0]
+			sentence = fmt.Sprintf("%s mengubah %s dari %s menjadi %s", actor, c.Label, describe(c.Before), describe(c.After))
+		default:
+			labels := make([]string, len(e.Changes))
+			for i, c := range e.Changes {
+				labels[i] = c.Label
+			}
+			sentence = fmt.Sprintf("%s mengubah %d field: %s", actor, len(e.Changes), strings.Join(labels, ", "))
+		}
+	case ActionRevert:
+		sentence = fmt.Sprintf("%s membatalkan perubahan sebelumnya (%d field)", actor, len(e.Changes))
+	default:
+		sentence = actor + " melakukan " + e.Action
+	}
+
+	if e.Status != "success" {
+		sentence += " (gagal)"
+	}
+	return sentence
+}
+
+// describe menampilkan nilai field untuk kalimat ringkasan
+func describe(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "(kosong)"
+	case bool:
+		if value {
+			return "ya"
+		}
+		return "tidak"
+	case string:
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t.Format("02-01-2006")
+		}
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
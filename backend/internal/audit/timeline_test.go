@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	golonganLama, golonganBaru := uuid.New(), uuid.New()
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	logs := []models.AuditLog{
+		{
+			ID: uuid.New(), Action: ActionCreate, Status: "success", CreatedAt: at,
+			Username: stringPtr("operator"),
+			Changes: map[string]interface{}{"diff": map[string]interface{}{
+				"nama_lengkap": map[string]interface{}{"before": nil, "after": "Budi"},
+			}},
+		},
+		{
+			ID: uuid.New(), Action: ActionUpdate, Status: "success", CreatedAt: at.Add(time.Hour),
+			Username:             stringPtr("admin"),
+			ImpersonatedUserID:   stringPtr("user-2"),
+			ImpersonatedUsername: stringPtr("kasubag"),
+			Changes: map[string]interface{}{"diff": map[string]interface{}{
+				"golongan_id": map[string]interface{}{"before": golonganLama.String(), "after": golonganBaru.String()},
+			}},
+		},
+		{
+			ID: uuid.New(), Action: ActionUpdate, Status: "failed", CreatedAt: at.Add(2 * time.Hour),
+			UserID:  stringPtr("user-3"),
+			Changes: map[string]interface{}{"request_body": map[string]interface{}{"nama": "x"}},
+		},
+	}
+
+	ids := ReferenceIDs(logs)
+	assert.ElementsMatch(t, []uuid.UUID{golonganLama, golonganBaru}, ids)
+
+	labels := map[uuid.UUID]string{
+		golonganLama: "III/a - Penata Muda",
+		golonganBaru: "III/b - Penata Muda Tingkat I",
+	}
+	timeline := BuildTimeline(logs, labels)
+	require.Len(t, timeline, 3)
+
+	assert.Equal(t, "operator membuat data", timeline[0].Summary)
+
+	require.Len(t, timeline[1].Changes, 1)
+	assert.Equal(t, "Golongan", timeline[1].Changes[0].Label)
+	assert.Equal(t, "III/a - Penata Muda", timeline[1].Changes[0].Before)
+	assert.Equal(t, "admin (sebagai kasubag) mengubah Golongan dari III/a - Penata Muda menjadi III/b - Penata Muda Tingkat I", timeline[1].Summary)
+
+	assert.Equal(t, "user-3 mengubah data (gagal)", timeline[2].Summary)
+	assert.Empty(t, timeline[2].Changes)
+}
+
+func TestFieldLabel(t *testing.T) {
+	assert.Equal(t, "TMT Jabatan", FieldLabel("tmt_jabatan"))
+	assert.Equal(t, "Kode Pos", FieldLabel("kode_pos"))
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config menyimpan seluruh konfigurasi aplikasi
@@ -15,6 +17,9 @@ type Config struct {
 	Gotenberg    GotenbergConfig
 	JWT          JWTConfig
 	CORS         CORSConfig
+	RBAC         RBACConfig
+	Audit        AuditConfig
+	Storage      StorageConfig
 	Logger       LoggerConfig
 	Environment  string
 	// Convenience fields
@@ -49,6 +54,19 @@ type KeycloakConfig struct {
 	URL      string
 	Realm    string
 	JWKSURL  string
+	// Issuer yang diharapkan pada claim iss; default URL + "/realms/" + Realm
+	Issuer string
+	// Audience daftar client (comma-separated) yang diterima pada claim aud atau azp
+	Audience            string
+	ClockSkew           time.Duration
+	JWKSRefreshInterval time.Duration
+	// ClientID dan ClientSecret dipakai untuk password grant, refresh dan logout.
+	// Service account client ini butuh role realm-management manage-users untuk force logout.
+	ClientID     string
+	ClientSecret string
+	// AccessTokenLifespan batas atas umur access token di realm; pencabutan
+	// session/user disimpan selama ini
+	AccessTokenLifespan time.Duration
 }
 
 // GotenbergConfig konfigurasi Gotenberg untuk PDF generation
@@ -68,6 +86,47 @@ type CORSConfig struct {
 	Credentials bool
 }
 
+// RBACConfig konfigurasi pengecekan permission RBAC
+type RBACConfig struct {
+	PermissionCacheTTL time.Duration
+}
+
+// AuditConfig konfigurasi hash chain, partisi dan retensi audit log
+type AuditConfig struct {
+	// AnchorFile file JSONL tujuan ekspor anchor; sebaiknya di volume yang
+	// tidak dapat ditulis oleh user database
+	AnchorFile     string
+	AnchorInterval time.Duration
+	// RetentionMonths lama audit log disimpan di database sebelum partisinya
+	// diarsipkan ke ArchiveDir; 0 berarti tidak pernah diarsipkan
+	RetentionMonths     int
+	ArchiveDir          string
+	PartitionsAhead     int
+	MaintenanceInterval time.Duration
+	// QueueSize dan BatchSize mengatur queue penulisan audit log; batch
+	// yang gagal ditulis disimpan di SpillFile dan diputar ulang setiap
+	// ReplayInterval
+	QueueSize      int
+	BatchSize      int
+	FlushInterval  time.Duration
+	SpillFile      string
+	ReplayInterval time.Duration
+	// ReadResources resource yang akses bacanya dicatat pada route yang
+	// memakai ReadAuditMiddleware
+	ReadResources []string
+	// RulesFile file JSON rule deteksi aktivitas mencurigakan; kosong berarti
+	// memakai rule bawaan. AlertWebhookURL opsional.
+	RulesFile       string
+	AlertWebhookURL string
+}
+
+// StorageConfig konfigurasi penyimpanan file upload
+type StorageConfig struct {
+	Path string
+	// MaxDocumentSize batas ukuran dokumen kepegawaian (SK, ijazah) dalam byte
+	MaxDocumentSize int64
+}
+
 // LoggerConfig konfigurasi logger
 type LoggerConfig struct {
 	Level  string
@@ -99,6 +158,13 @@ func Load() *Config {
 			URL:     getEnv("KEYCLOAK_URL", "http://localhost:8081"),
 			Realm:   getEnv("KEYCLOAK_REALM", "pengadilan-agama"),
 			JWKSURL: getEnv("KEYCLOAK_JWKS_URL", "http://localhost:8081/realms/pengadilan-agama/protocol/openid-connect/certs"),
+			Issuer:              getEnv("KEYCLOAK_ISSUER", ""),
+			Audience:            getEnv("KEYCLOAK_AUDIENCE", "backend-api,portal-client,master-data-client,kepegawaian-client,sikerma-tools"),
+			ClockSkew:           getEnvAsDuration("KEYCLOAK_CLOCK_SKEW", 30*time.Second),
+			JWKSRefreshInterval: getEnvAsDuration("KEYCLOAK_JWKS_REFRESH_INTERVAL", 15*time.Minute),
+			ClientID:            getEnv("KEYCLOAK_CLIENT_ID", "sikerma-tools"),
+			ClientSecret:        getEnv("KEYCLOAK_CLIENT_SECRET", "sikerma-tools-secret-change-in-production"),
+			AccessTokenLifespan: getEnvAsDuration("KEYCLOAK_ACCESS_TOKEN_LIFESPAN", 15*time.Minute),
 		},
 		Gotenberg: GotenbergConfig{
 			URL: getEnv("GOTENBERG_URL", "http://localhost:3100"),
@@ -111,6 +177,29 @@ func Load() *Config {
 			Origins:     getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:3001,http://localhost:3002"),
 			Credentials: getEnvAsBool("CORS_CREDENTIALS", true),
 		},
+		RBAC: RBACConfig{
+			PermissionCacheTTL: getEnvAsDuration("RBAC_PERMISSION_CACHE_TTL", time.Minute),
+		},
+		Audit: AuditConfig{
+			AnchorFile:          getEnv("AUDIT_ANCHOR_FILE", "./data/audit-anchors.jsonl"),
+			AnchorInterval:      getEnvAsDuration("AUDIT_ANCHOR_INTERVAL", time.Hour),
+			RetentionMonths:     getEnvAsInt("AUDIT_RETENTION_MONTHS", 24),
+			ArchiveDir:          getEnv("AUDIT_ARCHIVE_DIR", "./data/audit-archive"),
+			PartitionsAhead:     getEnvAsInt("AUDIT_PARTITIONS_AHEAD", 3),
+			MaintenanceInterval: getEnvAsDuration("AUDIT_MAINTENANCE_INTERVAL", 24*time.Hour),
+			QueueSize:           getEnvAsInt("AUDIT_QUEUE_SIZE", 10000),
+			BatchSize:           getEnvAsInt("AUDIT_BATCH_SIZE", 500),
+			FlushInterval:       getEnvAsDuration("AUDIT_FLUSH_INTERVAL", time.Second),
+			SpillFile:           getEnv("AUDIT_SPILL_FILE", "./data/audit-spill.jsonl"),
+			ReplayInterval:      getEnvAsDuration("AUDIT_REPLAY_INTERVAL", 30*time.Second),
+			ReadResources:       getEnvAsList("AUDIT_READ_RESOURCES", "pegawai,hukdis"),
+			RulesFile:           getEnv("AUDIT_RULES_FILE", ""),
+			AlertWebhookURL:     getEnv("AUDIT_ALERT_WEBHOOK_URL", ""),
+		},
+		Storage: StorageConfig{
+			Path:            getEnv("FILE_STORAGE_PATH", "./data/files"),
+			MaxDocumentSize: int64(getEnvAsInt("UPLOAD_MAX_DOCUMENT_SIZE", 5*1024*1024)),
+		},
 		Logger: LoggerConfig{
 			Level:  getEnv("LOG_LEVEL", "debug"),
 			Format: getEnv("LOG_FORMAT", "json"),
@@ -121,6 +210,9 @@ func Load() *Config {
 	cfg.Host = cfg.Server.Host
 	cfg.Port = cfg.Server.Port
 	cfg.KeycloakURL = cfg.Keycloak.URL
+	if cfg.Keycloak.Issuer == "" {
+		cfg.Keycloak.Issuer = strings.TrimSuffix(cfg.Keycloak.URL, "/") + "/realms/" + cfg.Keycloak.Realm
+	}
 
 	return cfg
 }
@@ -151,4 +243,31 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvAsList mengambil environment variable berisi daftar dipisah koma.
+// Variable yang diset kosong menghasilkan daftar kosong.
+func getEnvAsList(key, defaultValue string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = defaultValue
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAsDuration mengambil environment variable sebagai time.Duration
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
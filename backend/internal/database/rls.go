@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// rlsClaimsKey adalah key context untuk RLSClaims
+type rlsClaimsKey struct{}
+
+// RLSClaims adalah identitas user yang dibaca policy RLS melalui
+// current_setting('request.jwt.claim.*')
+type RLSClaims struct {
+	UserID      string
+	UnitKerjaID string
+	SatkerID    string
+	Role        string
+}
+
+// WithRLSClaims menyimpan RLSClaims pada context request
+func WithRLSClaims(ctx context.Context, claims RLSClaims) context.Context {
+	return context.WithValue(ctx, rlsClaimsKey{}, claims)
+}
+
+// RLSClaimsFromContext mengambil RLSClaims dari context
+func RLSClaimsFromContext(ctx context.Context) (RLSClaims, bool) {
+	claims, ok := ctx.Value(rlsClaimsKey{}).(RLSClaims)
+	return claims, ok
+}
+
+// SystemRole adalah role RLS untuk query internal yang tidak boleh dibatasi
+// scope unit kerja (dianggap admin oleh is_admin())
+const SystemRole = "admin"
+
+// AsSystem mengganti claim RLS pada context dengan SystemRole, dengan user ID
+// tetap dari claim semula. Hanya untuk query yang hasilnya sudah dibatasi
+// oleh kode pemanggil, misalnya mencari data milik user sendiri.
+func AsSystem(ctx context.Context) context.Context {
+	claims, _ := RLSClaimsFromContext(ctx)
+	return WithRLSClaims(ctx, RLSClaims{UserID: claims.UserID, Role: SystemRole})
+}
+
+// WithRLS menjalankan fn dalam transaksi yang sudah diberi claim RLS dari context.
+// set_config dipanggil dengan is_local = true sehingga claim hilang saat transaksi
+// selesai dan tidak bocor ke request lain yang memakai koneksi yang sama.
+// Tanpa claim pada context, semua setting dikosongkan (policy memperlakukan
+// sebagai anonymous).
+func WithRLS(ctx context.Context, db *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	claims, _ := RLSClaimsFromContext(ctx)
+
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			SELECT set_config('request.jwt.claim.user_id', $1, true),
+			       set_config('request.jwt.claim.unit_kerja_id', $2, true),
+			       set_config('request.jwt.claim.satker_id', $3, true),
+			       set_config('request.jwt.claim.role', $4, true)
+		`, claims.UserID, claims.UnitKerjaID, claims.SatkerID, claims.Role)
+		if err != nil {
+			return fmt.Errorf("failed to apply rls claims: %w", err)
+		}
+
+		return fn(tx)
+	})
+}
+
+// QueryRLS seperti WithRLS namun mengembalikan hasil fn
+func QueryRLS[T any](ctx context.Context, db *pgxpool.Pool, fn func(tx pgx.Tx) (T, error)) (T, error) {
+	var result T
+	err := WithRLS(ctx, db, func(tx pgx.Tx) error {
+		var err error
+		result, err = fn(tx)
+		return err
+	})
+	return result, err
+}
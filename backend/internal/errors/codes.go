@@ -50,9 +50,14 @@ const (
 	NotFoundGolongan   = "NOT_FOUND_GOLONGAN"
 	NotFoundUnitKerja  = "NOT_FOUND_UNIT_KERJA"
 	NotFoundRiwayat    = "NOT_FOUND_RIWAYAT"
+	NotFoundKeluarga   = "NOT_FOUND_KELUARGA"
+	NotFoundHukdis     = "NOT_FOUND_HUKDIS"
 	NotFoundDocument   = "NOT_FOUND_DOCUMENT"
 	NotFoundUser       = "NOT_FOUND_USER"
 	NotFoundResource   = "NOT_FOUND_RESOURCE"
+	NotFoundRole       = "NOT_FOUND_ROLE"
+	NotFoundPermission = "NOT_FOUND_PERMISSION"
+	NotFoundAPIKey     = "NOT_FOUND_API_KEY"
 )
 
 // Conflict Errors (409)
@@ -62,6 +67,8 @@ const (
 	ConflictEmailExists   = "CONFLICT_EMAIL_EXISTS"
 	ConflictRelation      = "CONFLICT_RELATION"
 	ConflictState         = "CONFLICT_STATE"
+	ConflictRoleExists    = "CONFLICT_ROLE_EXISTS"
+	ConflictSystemRole    = "CONFLICT_SYSTEM_ROLE"
 )
 
 // Rate Limiting Errors (429)
@@ -126,9 +133,14 @@ var errorMessages = map[string]string{
 	NotFoundGolongan:  "Data golongan tidak ditemukan",
 	NotFoundUnitKerja: "Data unit kerja tidak ditemukan",
 	NotFoundRiwayat:   "Data riwayat tidak ditemukan",
+	NotFoundKeluarga:  "Data keluarga tidak ditemukan",
+	NotFoundHukdis:    "Data hukuman disiplin tidak ditemukan",
 	NotFoundDocument:  "Dokumen tidak ditemukan",
 	NotFoundUser:      "User tidak ditemukan",
 	NotFoundResource:  "Resource tidak ditemukan",
+	NotFoundRole:       "Role tidak ditemukan",
+	NotFoundPermission: "Permission tidak ditemukan",
+	NotFoundAPIKey:     "API key tidak ditemukan",
 
 	// Conflict
 	ConflictNIPExists:   "NIP sudah digunakan oleh pegawai lain",
@@ -136,6 +148,8 @@ var errorMessages = map[string]string{
 	ConflictEmailExists: "Email sudah digunakan oleh pegawai lain",
 	ConflictRelation:    "Relasi data tidak dapat dihapus karena masih digunakan",
 	ConflictState:       "Status data tidak dapat diubah",
+	ConflictRoleExists:  "Nama role sudah digunakan",
+	ConflictSystemRole:  "Role sistem tidak dapat diubah",
 
 	// Rate Limiting
 	RateLimitExceeded:       "Terlalu banyak permintaan, coba lagi dalam 1 menit",
@@ -212,7 +226,9 @@ func GetMessage(code string) string {
 
 // ToFiberResponse mengkonversi ErrorResponse ke Fiber response
 func (e *ErrorResponse) ToFiberResponse(c fiber.Ctx, statusCode int) error {
-	e.RequestID = c.Locals("requestId").(string)
+	if requestID, ok := c.Locals("requestID").(string); ok {
+		e.RequestID = requestID
+	}
 	return c.Status(statusCode).JSON(e)
 }
 
@@ -0,0 +1,543 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sikerma/backend/internal/audit"
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/models"
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+// ==================== AUDIT LOGS ====================
+
+// ListAuditLogs mengambil daftar audit logs. Filter: action, resource,
+// resource_id, user_id, username, status, ip_address (alamat atau CIDR),
+// from/to (RFC3339 atau YYYY-MM-DD, to inklusif untuk tanggal), changes
+// (objek JSON yang terkandung di changes) dan changed_field.
+func (h *Handlers) ListAuditLogs(c fiber.Ctx) error {
+	page := fiber.Query[int](c, "page", 1)
+	limit := fiber.Query[int](c, "limit", 50)
+
+	filter, badParam := auditLogFilter(c)
+	if badParam != "" {
+		return invalidIDResponse(c, badParam)
+	}
+
+	logs, total, err := h.auditRepo.List(c.Context(), filter, page, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    logs,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// auditLogFilter membaca filter audit log dari query string. Mengembalikan
+// nama parameter pertama yang tidak valid, atau string kosong.
+func auditLogFilter(c fiber.Ctx) (repositories.AuditLogFilter, string) {
+	return repositories.ParseAuditLogFilter(func(key string) string {
+		return c.Query(key)
+	})
+}
+
+// auditUnmaskPermission dibutuhkan untuk ekspor audit log tanpa masking PII
+const auditUnmaskPermission = "audit.unmask"
+
+// exportFlushRows adalah jumlah baris ekspor sebelum buffer dikirim ke client
+const exportFlushRows = 500
+
+// ExportAuditLogs men-stream audit log yang cocok dengan filter ListAuditLogs
+// sebagai CSV (format=csv) atau JSON Lines (format=jsonl). Data PII di-mask
+// kecuali unmask=true dan user memiliki permission audit.unmask. Setiap
+// ekspor dicatat sebagai audit entry setelah stream selesai.
+func (h *Handlers) ExportAuditLogs(c fiber.Ctx) error {
+	format := fiber.Query[string](c, "format", audit.FormatCSV)
+	if format != audit.FormatCSV && format != audit.FormatJSONL {
+		return invalidIDResponse(c, "format")
+	}
+
+	filter, badParam := auditLogFilter(c)
+	if badParam != "" {
+		return invalidIDResponse(c, badParam)
+	}
+
+	unmask := fiber.Query[bool](c, "unmask", false)
+	if unmask {
+		allowed, err := h.RBACMiddleware.HasPermission(c.Context(), middleware.GetUserID(c), auditUnmaskPermission)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return appErrors.Forbidden(appErrors.AuthzForbidden, map[string]interface{}{
+				"permission": auditUnmaskPermission,
+			}).ToFiberResponse(c, fiber.StatusForbidden)
+		}
+	}
+
+	entry := middleware.NewAuditEntry(c, "export", "audit_logs")
+	entry.Changes = map[string]interface{}{
+		"request_id": middleware.GetRequestID(c),
+		"format":     format,
+		"unmasked":   unmask,
+		"query":      string(c.Request().URI().QueryString()),
+	}
+
+	// Stream ditulis setelah handler selesai, jadi fiber.Ctx tidak boleh dipakai
+	ctx := context.WithoutCancel(c.Context())
+	filename := fmt.Sprintf("audit-logs-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Set(fiber.HeaderContentType, audit.ContentType(format))
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		exporter, err := audit.NewExportWriter(w, format, !unmask)
+		if err == nil {
+			err = h.auditRepo.Stream(ctx, filter, func(log models.AuditLog) error {
+				if err := exporter.Write(log); err != nil {
+					return err
+				}
+				if exporter.Rows()%exportFlushRows == 0 {
+					if err := exporter.Flush(); err != nil {
+						return err
+					}
+					return w.Flush()
+				}
+				return nil
+			})
+		}
+		if err == nil {
+			if err = exporter.Flush(); err == nil {
+				err = w.Flush()
+			}
+		}
+
+		if exporter != nil {
+			entry.Changes["rows"] = exporter.Rows()
+		}
+		if err != nil {
+			entry.Status = "failed"
+			msg := err.Error()
+			entry.ErrorMessage = &msg
+			logrus.WithError(err).Warn("Audit log export aborted")
+		}
+		h.AuditWriter.Write(ctx, entry)
+	})
+}
+
+// VerifyAuditChain menelusuri hash chain audit log yang masih di database
+// (mulai setelah partisi terakhir yang diarsipkan) dan anchor yang tersimpan,
+// lalu melaporkan link pertama yang rusak
+func (h *Handlers) VerifyAuditChain(c fiber.Ctx) error {
+	start, err := h.auditRepo.ChainStart(c.Context())
+	if err != nil {
+		return err
+	}
+	anchors, err := h.auditRepo.ListAnchors(c.Context())
+	if err != nil {
+		return err
+	}
+
+	result, err := audit.VerifyChain(c.Context(), h.auditRepo, start, anchors)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       result,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RECORD HISTORY ====================
+
+// GetPegawaiHistory mengembalikan timeline perubahan satu pegawai. Pegawai
+// harus berada dalam scope RLS user.
+func (h *Handlers) GetPegawaiHistory(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	if _, err := h.pegawaiRepo.GetByID(c.Context(), id.String()); err != nil {
+		if errors.Is(err, repositories.ErrPegawaiNotFound) {
+			return appErrors.NotFound(appErrors.NotFoundPegawai).ToFiberResponse(c, fiber.StatusNotFound)
+		}
+		return err
+	}
+
+	return h.recordHistory(c, "pegawai", id)
+}
+
+// GetPegawaiAccessLog menjawab siapa saja yang membaca data satu pegawai,
+// terbaru dulu. Filter audit log lain (username, from/to, dll) tetap berlaku.
+func (h *Handlers) GetPegawaiAccessLog(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	page := fiber.Query[int](c, "page", 1)
+	limit := fiber.Query[int](c, "limit", 50)
+
+	filter, badParam := auditLogFilter(c)
+	if badParam != "" {
+		return invalidIDResponse(c, badParam)
+	}
+
+	if _, err := h.pegawaiRepo.GetByID(c.Context(), id.String()); err != nil {
+		if errors.Is(err, repositories.ErrPegawaiNotFound) {
+			return appErrors.NotFound(appErrors.NotFoundPegawai).ToFiberResponse(c, fiber.StatusNotFound)
+		}
+		return err
+	}
+
+	// Entry read menyimpan semua ID yang dikembalikan di changes.resource_ids
+	contains, err := json.Marshal(map[string]interface{}{"resource_ids": []uuid.UUID{id}})
+	if err != nil {
+		return err
+	}
+	filter.Action = audit.ActionRead
+	filter.Resource = "pegawai"
+	filter.ResourceID = nil
+	filter.ChangesContain = contains
+
+	logs, total, err := h.auditRepo.List(c.Context(), filter, page, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    logs,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetSatkerHistory mengembalikan timeline perubahan satu satker, termasuk
+// satker yang sudah dihapus
+func (h *Handlers) GetSatkerHistory(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	return h.recordHistory(c, "satker", id)
+}
+
+// recordHistory membangun timeline dari audit log resource dan ID
+func (h *Handlers) recordHistory(c fiber.Ctx, resource string, id uuid.UUID) error {
+	logs, err := h.auditRepo.History(c.Context(), resource, id)
+	if err != nil {
+		return err
+	}
+
+	labels, err := h.auditRepo.LookupReferenceLabels(c.Context(), audit.ReferenceIDs(logs))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       audit.BuildTimeline(logs, labels),
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== AUDIT ALERTS ====================
+
+// ListAuditAlerts mengambil alert aktivitas mencurigakan terbaru. Filter:
+// status, rule, severity, user_id.
+func (h *Handlers) ListAuditAlerts(c fiber.Ctx) error {
+	page := fiber.Query[int](c, "page", 1)
+	limit := fiber.Query[int](c, "limit", 50)
+
+	filter := repositories.AuditAlertFilter{
+		Status:   c.Query("status"),
+		Rule:     c.Query("rule"),
+		Severity: c.Query("severity"),
+		UserID:   c.Query("user_id"),
+	}
+
+	alerts, total, err := h.auditRepo.ListAlerts(c.Context(), filter, page, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    alerts,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetAuditAlert mengambil detail satu alert
+func (h *Handlers) GetAuditAlert(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	alert, err := h.auditRepo.GetAlert(c.Context(), id)
+	if err != nil {
+		return alertError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       alert,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// AcknowledgeAuditAlert menandai alert open sedang ditangani
+func (h *Handlers) AcknowledgeAuditAlert(c fiber.Ctx) error {
+	return h.transitionAuditAlert(c, "alert_acknowledge", h.auditRepo.AcknowledgeAlert)
+}
+
+// ResolveAuditAlert menutup alert open atau acknowledged
+func (h *Handlers) ResolveAuditAlert(c fiber.Ctx) error {
+	return h.transitionAuditAlert(c, "alert_resolve", h.auditRepo.ResolveAlert)
+}
+
+// transitionAuditAlert membaca catatan opsional {"note": "..."} lalu
+// menjalankan perubahan status alert
+func (h *Handlers) transitionAuditAlert(c fiber.Ctx, action string,
+	transition func(ctx context.Context, id uuid.UUID, by string, note *string) (*models.AuditAlert, error)) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var req struct {
+		Note *string `json:"note"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":      true,
+				"message":    "Invalid request body",
+				"code":       400,
+				"request_id": middleware.GetRequestID(c),
+			})
+		}
+	}
+
+	middleware.SetAuditAction(c, action)
+	middleware.SetAuditResource(c, "audit_alert", id)
+	alert, err := transition(c.Context(), id, middleware.GetUserID(c), req.Note)
+	if err != nil {
+		return alertError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       alert,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// alertError memetakan error alert AuditRepository ke response 404/409
+func alertError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrAlertNotFound):
+		return appErrors.NotFound(appErrors.NotFoundResource).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrAlertStatus):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "status alert tidak dapat diubah",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	default:
+		return err
+	}
+}
+
+// ==================== AUDIT REVERT ====================
+
+// revertTarget mengembalikan repository resource yang mendukung revert
+func (h *Handlers) revertTarget(resource string) repositories.Revertable {
+	if !audit.Revertable(resource) {
+		return nil
+	}
+	switch resource {
+	case "pegawai":
+		return h.pegawaiRepo
+	case "satker":
+		return h.satkerRepo
+	default:
+		return nil
+	}
+}
+
+// planAuditRevert membandingkan snapshot audit log dengan state record saat ini
+func (h *Handlers) planAuditRevert(ctx context.Context, id uuid.UUID, fields []string) (*models.AuditLog, repositories.Revertable, *audit.RevertPlan, error) {
+	entry, err := h.auditRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	target := h.revertTarget(entry.Resource)
+	if target == nil || entry.ResourceID == nil || entry.Status != "success" {
+		return nil, nil, nil, audit.ErrRevertUnsupported
+	}
+
+	snap, err := h.auditRepo.GetSnapshot(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	current, err := target.CurrentRecord(ctx, *entry.ResourceID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	plan, err := audit.PlanRevert(entry.Action, *snap, current, fields)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return entry, target, plan, nil
+}
+
+// PreviewAuditRevert menampilkan field yang akan dikembalikan oleh revert
+// satu audit log beserta konflik dengan state saat ini. Query fields (dipisah
+// koma) membatasi field yang dikembalikan.
+func (h *Handlers) PreviewAuditRevert(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	var fields []string
+	if raw := c.Query("fields"); raw != "" {
+		fields = strings.Split(raw, ",")
+	}
+
+	entry, _, plan, err := h.planAuditRevert(c.Context(), id, fields)
+	if err != nil {
+		return revertError(c, err)
+	}
+	reverts, err := h.auditRepo.ListReverts(c.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"audit_log":   entry,
+			"plan":        plan,
+			"reverted_by": reverts,
+		},
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ApplyAuditRevert membatalkan perubahan dari satu audit log. Body opsional
+// {"fields": [...]} membatasi field yang dikembalikan. Revert ditolak bila
+// ada field yang sudah berubah lagi sejak entry tersebut; revert sendiri
+// dicatat sebagai entry "revert" dengan changes.reverted_audit_id.
+func (h *Handlers) ApplyAuditRevert(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var req struct {
+		Fields []string `json:"fields"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":      true,
+				"message":    "Invalid request body",
+				"code":       400,
+				"request_id": middleware.GetRequestID(c),
+			})
+		}
+	}
+
+	entry, target, plan, err := h.planAuditRevert(c.Context(), id, req.Fields)
+	if err != nil {
+		return revertError(c, err)
+	}
+	if !plan.Applicable {
+		details := map[string]interface{}{"reason": plan.Reason, "conflicts": plan.Conflicts}
+		if plan.Reason == "" {
+			details["reason"] = "data sudah berubah sejak perubahan ini"
+		}
+		return appErrors.Conflict(appErrors.ConflictState, details).ToFiberResponse(c, fiber.StatusConflict)
+	}
+
+	middleware.SetAuditAction(c, audit.ActionRevert)
+	middleware.SetAuditResource(c, entry.Resource, *entry.ResourceID)
+	middleware.AddAuditDetail(c, "reverted_audit_id", id)
+	middleware.AddAuditDetail(c, "revert_operation", plan.Operation)
+	if err := target.ApplyRevert(c.Context(), *entry.ResourceID, plan, middleware.GetUserID(c)); err != nil {
+		return revertError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Change reverted",
+		"data":       plan,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// revertError memetakan error revert ke response 400/404/409
+func revertError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrAuditLogNotFound):
+		return appErrors.NotFound(appErrors.NotFoundResource).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrPegawaiNotFound):
+		return appErrors.NotFound(appErrors.NotFoundPegawai).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrSatkerNotFound):
+		return appErrors.NotFound(appErrors.NotFoundResource).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, audit.ErrRevertField):
+		return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+			"param":  "fields",
+			"reason": err.Error(),
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	case errors.Is(err, audit.ErrRevertUnsupported):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "audit log ini tidak dapat di-revert",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	case errors.Is(err, repositories.ErrSnapshotNotFound):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "audit log ini tidak memiliki snapshot data",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	case errors.Is(err, repositories.ErrRevertStale):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "data berubah saat revert dijalankan",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	default:
+		return err
+	}
+}
@@ -1,15 +1,23 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
 
 	"github.com/sikerma/backend/internal/config"
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/keycloak"
 	"github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/models"
 	"github.com/sikerma/backend/internal/repositories"
+	"github.com/sikerma/backend/internal/storage"
 )
 
 // Handlers mengelola semua handlers aplikasi
@@ -18,37 +26,75 @@ type Handlers struct {
 	dbKepegawaian *pgxpool.Pool
 	cfg           *config.Config
 	AuthMiddleware *middleware.AuthMiddleware
+	RBACMiddleware *middleware.RBACMiddleware
+	RLSMiddleware  *middleware.RLSMiddleware
+	Impersonation  *middleware.ImpersonationMiddleware
+	Revocations    *middleware.RevocationList
+	AuditWriter    *middleware.AuditWriter
+	ReadAudit      *middleware.ReadAuditMiddleware
+	keycloak       keycloak.Client
+	fileStore      *storage.Store
 
 	// Repositories
-	satkerRepo       *repositories.SatkerRepository
-	jabatanRepo      *repositories.JabatanRepository
-	golonganRepo     *repositories.GolonganRepository
-	unitKerjaRepo    *repositories.UnitKerjaRepository
-	eselonRepo       *repositories.EselonRepository
-	pegawaiRepo      *repositories.PegawaiRepository
-	riwayatRepo      *repositories.RiwayatRepository
-	roleRepo         *repositories.RoleRepository
-	auditRepo        *repositories.AuditRepository
+	satkerRepo        *repositories.SatkerRepository
+	jabatanRepo       *repositories.JabatanRepository
+	golonganRepo      *repositories.GolonganRepository
+	unitKerjaRepo     *repositories.UnitKerjaRepository
+	eselonRepo        *repositories.EselonRepository
+	pegawaiRepo       *repositories.PegawaiRepository
+	riwayatRepo       *repositories.RiwayatRepository
+	keluargaRepo      *repositories.KeluargaRepository
+	hukdisRepo        *repositories.HukdisRepository
+	roleRepo          *repositories.RoleRepository
+	apiKeyRepo        *repositories.APIKeyRepository
+	impersonationRepo *repositories.ImpersonationRepository
+	auditRepo         *repositories.AuditRepository
 }
 
 // New membuat instance Handlers baru
 func New(dbMaster, dbKepegawaian *pgxpool.Pool, cfg *config.Config) *Handlers {
+	roleRepo := repositories.NewRoleRepository(dbMaster)
+	apiKeyRepo := repositories.NewAPIKeyRepository(dbMaster)
+	impersonationRepo := repositories.NewImpersonationRepository(dbMaster)
+
+	rbacMiddleware := middleware.NewRBACMiddleware(roleRepo, cfg.RBAC.PermissionCacheTTL)
+	roleHierarchy := middleware.NewRoleHierarchy(roleRepo, cfg.RBAC.PermissionCacheTTL)
+	rbacMiddleware.OnInvalidateAll(roleHierarchy.Invalidate)
+	revocations := middleware.NewRevocationList(repositories.NewTokenRevocationRepository(dbMaster))
+	auditRepo := repositories.NewAuditRepository(dbMaster)
+	auditWriter := middleware.NewAuditWriter(auditRepo, cfg.Audit)
+
 	return &Handlers{
 		dbMaster:      dbMaster,
 		dbKepegawaian: dbKepegawaian,
 		cfg:           cfg,
-		AuthMiddleware: middleware.NewAuthMiddleware(cfg.Keycloak.JWKSURL, cfg.Keycloak.Realm),
+		AuthMiddleware: middleware.NewAuthMiddleware(cfg.Keycloak).
+			WithAPIKeys(apiKeyRepo).
+			WithRoleHierarchy(roleHierarchy).
+			WithRevocations(revocations),
+		RBACMiddleware: rbacMiddleware,
+		RLSMiddleware:  middleware.NewRLSMiddleware(roleRepo),
+		Impersonation:  middleware.NewImpersonationMiddleware(impersonationRepo, roleRepo, roleHierarchy, rbacMiddleware),
+		Revocations:    revocations,
+		AuditWriter:    auditWriter,
+		ReadAudit:      middleware.NewReadAuditMiddleware(auditWriter, cfg.Audit.ReadResources),
+		keycloak:       keycloak.NewClient(cfg.Keycloak),
+		fileStore:      storage.New(cfg.Storage.Path),
 
 		// Initialize repositories
-		satkerRepo:    repositories.NewSatkerRepository(dbMaster),
-		jabatanRepo:   repositories.NewJabatanRepository(dbMaster),
-		golonganRepo:  repositories.NewGolonganRepository(dbMaster),
-		unitKerjaRepo: repositories.NewUnitKerjaRepository(dbMaster),
-		eselonRepo:    repositories.NewEselonRepository(dbMaster),
-		pegawaiRepo:   repositories.NewPegawaiRepository(dbKepegawaian),
-		riwayatRepo:   repositories.NewRiwayatRepository(dbKepegawaian, dbMaster),
-		roleRepo:      repositories.NewRoleRepository(dbMaster),
-		auditRepo:     repositories.NewAuditRepository(dbMaster),
+		satkerRepo:        repositories.NewSatkerRepository(dbMaster),
+		jabatanRepo:       repositories.NewJabatanRepository(dbMaster),
+		golonganRepo:      repositories.NewGolonganRepository(dbMaster),
+		unitKerjaRepo:     repositories.NewUnitKerjaRepository(dbMaster),
+		eselonRepo:        repositories.NewEselonRepository(dbMaster),
+		pegawaiRepo:       repositories.NewPegawaiRepository(dbKepegawaian),
+		riwayatRepo:       repositories.NewRiwayatRepository(dbKepegawaian, dbMaster),
+		keluargaRepo:      repositories.NewKeluargaRepository(dbKepegawaian),
+		hukdisRepo:        repositories.NewHukdisRepository(dbKepegawaian, dbMaster),
+		roleRepo:          roleRepo,
+		apiKeyRepo:        apiKeyRepo,
+		impersonationRepo: impersonationRepo,
+		auditRepo:         auditRepo,
 	}
 }
 
@@ -76,6 +122,7 @@ func (h *Handlers) HealthCheck(c fiber.Ctx) error {
 			"database_kepegawaian": kepegawaianStatus,
 			"keycloak":            h.cfg.Keycloak.URL,
 		},
+		"audit_writer": h.AuditWriter.Stats(),
 		"version": "1.0.0",
 		"request_id": middleware.GetRequestID(c),
 	})
@@ -83,20 +130,95 @@ func (h *Handlers) HealthCheck(c fiber.Ctx) error {
 
 // ==================== AUTH ====================
 
-// Login menangani login request
-// Untuk sekarang, ini hanya proxy ke Keycloak
+// Login menangani login dengan password grant Keycloak
+// Dipakai oleh tools non-browser; aplikasi web tetap memakai flow frontend Keycloak
 func (h *Handlers) Login(c fiber.Ctx) error {
-	// TODO: Implement Keycloak direct login grant
-	return c.Status(501).JSON(fiber.Map{
-		"error": true,
-		"message": "Login through Keycloak frontend flow only",
-		"code": 501,
-		"request_id": middleware.GetRequestID(c),
-	})
+	var req models.LoginRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": true,
+			"message": "Invalid request body",
+			"code": 400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	if req.Username == "" || req.Password == "" {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": []string{"username", "password"},
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	tokens, err := h.keycloak.PasswordGrant(c.Context(), req.Username, req.Password)
+	if err != nil {
+		return h.keycloakError(c, err)
+	}
+
+	return h.tokenResponse(c, tokens)
 }
 
-// Logout menangani logout request
+// RefreshToken menukar refresh token menjadi access token baru
+func (h *Handlers) RefreshToken(c fiber.Ctx) error {
+	var req models.RefreshTokenRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": true,
+			"message": "Invalid request body",
+			"code": 400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	if req.RefreshToken == "" {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": []string{"refresh_token"},
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	tokens, err := h.keycloak.RefreshToken(c.Context(), req.RefreshToken)
+	if err != nil {
+		return h.keycloakError(c, err)
+	}
+
+	return h.tokenResponse(c, tokens)
+}
+
+// Logout mencabut refresh token di Keycloak sehingga session berakhir. Bila
+// access token ikut dikirim di header Authorization, jti dan sid-nya dicabut
+// agar token yang masih berlaku langsung ditolak di semua instance.
 func (h *Handlers) Logout(c fiber.Ctx) error {
+	var req models.RefreshTokenRequest
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": true,
+				"message": "Invalid request body",
+				"code": 400,
+				"request_id": middleware.GetRequestID(c),
+			})
+		}
+	}
+
+	accessToken := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+	if req.RefreshToken == "" && accessToken == "" {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": []string{"refresh_token"},
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	if accessToken != "" {
+		if err := h.revokeAccessToken(c, accessToken); err != nil {
+			return err
+		}
+	}
+
+	// Token yang sudah tidak valid berarti session memang sudah berakhir
+	if req.RefreshToken != "" {
+		if err := h.keycloak.Logout(c.Context(), req.RefreshToken); err != nil && !errors.Is(err, keycloak.ErrInvalidGrant) {
+			return h.keycloakError(c, err)
+		}
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Logged out successfully",
@@ -104,25 +226,147 @@ func (h *Handlers) Logout(c fiber.Ctx) error {
 	})
 }
 
-// GetCurrentUser mengambil info user saat ini
-func (h *Handlers) GetCurrentUser(c fiber.Ctx) error {
-	userID := middleware.GetUserID(c)
-	userRole := middleware.GetUserRole(c)
-	username := c.Locals("username").(string)
-	email := c.Locals("email").(string)
-	name := c.Locals("name").(string)
-	roles := c.Locals("userRoles").([]string)
+// revokeAccessToken mencabut jti dan sid access token milik user yang logout.
+// Token yang tidak valid atau sudah kadaluarsa diabaikan.
+func (h *Handlers) revokeAccessToken(c fiber.Ctx, accessToken string) error {
+	token, err := h.AuthMiddleware.VerifyToken(c.Context(), accessToken)
+	if err != nil {
+		return nil
+	}
+	claims := middleware.ParseClaims(token)
+
+	revocations := []models.TokenRevocation{}
+	if claims.JTI != "" {
+		revocations = append(revocations, models.TokenRevocation{
+			Kind:      models.RevocationJTI,
+			Value:     claims.JTI,
+			ExpiresAt: claims.ExpiresAt,
+		})
+	}
+	if claims.SessionID != "" {
+		// Token lain dari session yang sama bisa terbit belakangan, jadi sid
+		// dicabut selama umur maksimal access token
+		expiresAt := time.Now().Add(h.cfg.Keycloak.AccessTokenLifespan + h.cfg.Keycloak.ClockSkew)
+		if claims.ExpiresAt.After(expiresAt) {
+			expiresAt = claims.ExpiresAt
+		}
+		revocations = append(revocations, models.TokenRevocation{
+			Kind:      models.RevocationSID,
+			Value:     claims.SessionID,
+			ExpiresAt: expiresAt,
+		})
+	}
+
+	for _, rev := range revocations {
+		rev.UserID = &claims.Subject
+		rev.RevokedBy = &claims.Subject
+		rev.Reason = optionalString("logout")
+		if err := h.Revocations.Revoke(c.Context(), rev); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tokenResponse membangun LoginResponse dari token Keycloak
+func (h *Handlers) tokenResponse(c fiber.Ctx, tokens *keycloak.TokenSet) error {
+	token, err := h.AuthMiddleware.VerifyToken(c.Context(), tokens.AccessToken)
+	if err != nil {
+		return fmt.Errorf("failed to verify issued access token: %w", err)
+	}
+	claims := middleware.ParseClaims(token)
+	roles := h.AuthMiddleware.ResolveRoles(c.Context(), claims)
+
+	permissions, err := h.RBACMiddleware.Permissions(c.Context(), claims.Subject)
+	if err != nil {
+		return err
+	}
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"data": fiber.Map{
-			"id":       userID,
-			"username": username,
-			"email":    email,
-			"name":     name,
-			"role":     userRole,
-			"roles":    roles,
+		"data": models.LoginResponse{
+			AccessToken:  tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			TokenType:    tokens.TokenType,
+			ExpiresIn:    tokens.ExpiresIn,
+			User: models.UserDTO{
+				ID:          claims.Subject,
+				Username:    claims.Username,
+				Email:       claims.Email,
+				NamaLengkap: claims.Name,
+				Roles:       roles.Roles,
+				Permissions: permissions,
+			},
+		},
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// keycloakError memetakan error Keycloak client ke response API
+func (h *Handlers) keycloakError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, keycloak.ErrInvalidCredentials):
+		return appErrors.Unauthorized(appErrors.AuthInvalidCredentials).ToFiberResponse(c, fiber.StatusUnauthorized)
+	case errors.Is(err, keycloak.ErrInvalidGrant):
+		return appErrors.Unauthorized(appErrors.AuthSessionExpired).ToFiberResponse(c, fiber.StatusUnauthorized)
+	default:
+		logrus.WithError(err).Error("Keycloak request failed")
+		return appErrors.ServiceUnavailable(appErrors.SvcAuthDown).ToFiberResponse(c, fiber.StatusServiceUnavailable)
+	}
+}
+
+// GetCurrentUser mengambil info user saat ini beserta permission efektif,
+// scope unit kerja/satker dan pegawai yang terhubung
+func (h *Handlers) GetCurrentUser(c fiber.Ctx) error {
+	userID := middleware.GetUserID(c)
+
+	permissions, err := h.RBACMiddleware.Permissions(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+	// Request dengan API key hanya memakai permission yang masuk scope key
+	if scopes, ok := middleware.GetAPIKeyScopes(c); ok {
+		scoped := make(map[string]bool, len(scopes))
+		for _, scope := range scopes {
+			if permissions[scope] {
+				scoped[scope] = true
+			}
+		}
+		permissions = scoped
+	}
+
+	pegawai, err := h.pegawaiRepo.FindLinked(c.Context(), middleware.GetUsername(c), middleware.GetUserEmail(c))
+	if err != nil {
+		return err
+	}
+
+	user := models.CurrentUserDTO{
+		UserDTO: models.UserDTO{
+			ID:          userID,
+			Username:    middleware.GetUsername(c),
+			Email:       middleware.GetUserEmail(c),
+			NamaLengkap: middleware.GetUserName(c),
+			Roles:       middleware.GetUserRoles(c),
+			Permissions: permissions,
 		},
+		Role:       middleware.GetUserRole(c),
+		AuthMethod: middleware.GetAuthMethod(c),
+		Pegawai:    pegawai,
+	}
+	if unitKerjaID := middleware.GetUnitKerjaID(c); unitKerjaID != "" {
+		user.UnitKerjaID = &unitKerjaID
+	}
+	if satkerID := middleware.GetSatkerID(c); satkerID != "" {
+		user.SatkerID = &satkerID
+	}
+	if imp := middleware.GetImpersonation(c); imp != nil {
+		user.ImpersonatedBy = &imp.ActorUsername
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       user,
 		"request_id": middleware.GetRequestID(c),
 	})
 }
@@ -187,16 +431,6 @@ func (h *Handlers) CreateSatker(c fiber.Ctx) error {
 		return err
 	}
 
-	// Audit log
-	go h.auditRepo.Log(context.Background(), repositories.AuditLogInput{
-		UserID:     middleware.GetUserID(c),
-		Action:     "create",
-		Resource:   "satker",
-		ResourceID: &satker.ID,
-		Changes:    fiber.Map{"input": input},
-		Status:     "success",
-	})
-
 	return c.Status(201).JSON(fiber.Map{
 		"success": true,
 		"message": "Satker created successfully",
@@ -421,10 +655,16 @@ func (h *Handlers) ListPegawai(c fiber.Ctx) error {
 	satkerID := fiber.Query[string](c, "satker_id", "")
 	jabatanID := fiber.Query[string](c, "jabatan_id", "")
 	golonganID := fiber.Query[string](c, "golongan_id", "")
+	pendidikanID := fiber.Query[string](c, "pendidikan_id", "")
 	statusPegawai := fiber.Query[string](c, "status_pegawai", "")
 	statusKerja := fiber.Query[string](c, "status_kerja", "")
+	if pendidikanID != "" {
+		if _, err := uuid.Parse(pendidikanID); err != nil {
+			return invalidIDResponse(c, "pendidikan_id")
+		}
+	}
 
-	pegawais, total, err := h.pegawaiRepo.List(c.Context(), page, limit, search, satkerID, jabatanID, golonganID, statusPegawai, statusKerja)
+	pegawais, total, err := h.pegawaiRepo.List(c.Context(), page, limit, search, satkerID, jabatanID, golonganID, pendidikanID, statusPegawai, statusKerja)
 	if err != nil {
 		return err
 	}
@@ -450,6 +690,9 @@ func (h *Handlers) GetPegawai(c fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	if err := h.riwayatRepo.AttachPendidikanTerakhir(c.Context(), pegawai); err != nil {
+		return err
+	}
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -541,71 +784,16 @@ func (h *Handlers) GetStatistikKepegawaian(c fiber.Ctx) error {
 	})
 }
 
-// ==================== RBAC ====================
-
-// ListRoles mengambil daftar roles
-func (h *Handlers) ListRoles(c fiber.Ctx) error {
-	roles, err := h.roleRepo.List(c.Context())
+// GetStatistikPendidikan mengambil jumlah pegawai aktif per pendidikan tertinggi
+func (h *Handlers) GetStatistikPendidikan(c fiber.Ctx) error {
+	statistik, err := h.riwayatRepo.StatistikPendidikan(c.Context())
 	if err != nil {
 		return err
 	}
 
 	return c.JSON(fiber.Map{
-		"success": true,
-		"data":    roles,
-		"request_id": middleware.GetRequestID(c),
-	})
-}
-
-// CreateRole membuat role baru
-func (h *Handlers) CreateRole(c fiber.Ctx) error {
-	var input repositories.CreateRoleInput
-	if err := c.Bind().Body(&input); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": true,
-			"message": "Invalid request body",
-			"code": 400,
-			"request_id": middleware.GetRequestID(c),
-		})
-	}
-
-	role, err := h.roleRepo.Create(c.Context(), input)
-	if err != nil {
-		return err
-	}
-
-	return c.Status(201).JSON(fiber.Map{
-		"success": true,
-		"message": "Role created successfully",
-		"data":    role,
-		"request_id": middleware.GetRequestID(c),
-	})
-}
-
-// ==================== AUDIT LOGS ====================
-
-// ListAuditLogs mengambil daftar audit logs
-func (h *Handlers) ListAuditLogs(c fiber.Ctx) error {
-	page := fiber.Query[int](c, "page", 1)
-	limit := fiber.Query[int](c, "limit", 50)
-	action := fiber.Query[string](c, "action", "")
-	resource := fiber.Query[string](c, "resource", "")
-	userID := fiber.Query[string](c, "user_id", "")
-
-	logs, total, err := h.auditRepo.List(c.Context(), page, limit, action, resource, userID)
-	if err != nil {
-		return err
-	}
-
-	return c.JSON(fiber.Map{
-		"success": true,
-		"data":    logs,
-		"pagination": fiber.Map{
-			"page":       page,
-			"limit":      limit,
-			"total":      total,
-			"total_pages": (total + int64(limit) - 1) / int64(limit),
-		},
+		"success":    true,
+		"data":       statistik,
 		"request_id": middleware.GetRequestID(c),
 	})
 }
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+// ==================== HUKDIS ====================
+
+// hukdisError memetakan error HukdisRepository ke response 404/400
+func hukdisError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrPegawaiNotFound):
+		return appErrors.NotFound(appErrors.NotFoundPegawai).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrHukdisNotFound):
+		return appErrors.NotFound(appErrors.NotFoundHukdis).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrJenisHukdisNotFound):
+		return referensiError(c, "jenis_hukdis_id", "jenis hukdis tidak ditemukan")
+	default:
+		return err
+	}
+}
+
+// validateHukdis memeriksa field SK dan periode hukuman. Tanggal SK dan
+// tanggal mulai tidak boleh di masa depan.
+func validateHukdis(input *repositories.HukdisInput) *appErrors.ErrorResponse {
+	input.NomorSK = strings.TrimSpace(input.NomorSK)
+	input.Pejabat = strings.TrimSpace(input.Pejabat)
+	input.Alasan = trimOptional(input.Alasan)
+
+	missing := []string{}
+	if input.JenisHukdisID == uuid.Nil {
+		missing = append(missing, "jenis_hukdis_id")
+	}
+	if input.NomorSK == "" {
+		missing = append(missing, "nomor_sk")
+	}
+	if input.TanggalSK.IsZero() {
+		missing = append(missing, "tanggal_sk")
+	}
+	if input.TanggalMulai.IsZero() {
+		missing = append(missing, "tanggal_mulai")
+	}
+	if input.Pejabat == "" {
+		missing = append(missing, "pejabat")
+	}
+	if len(missing) > 0 {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": missing,
+		})
+	}
+
+	now := time.Now()
+	for _, date := range []struct {
+		field string
+		value time.Time
+	}{{"tanggal_sk", input.TanggalSK}, {"tanggal_mulai", input.TanggalMulai}} {
+		if date.value.After(now) {
+			return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+				"field":  date.field,
+				"reason": "tanggal tidak boleh di masa depan",
+			})
+		}
+	}
+
+	if input.TanggalSelesai != nil && input.TanggalSelesai.Before(input.TanggalMulai) {
+		return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+			"field":  "tanggal_selesai",
+			"reason": "tanggal selesai tidak boleh sebelum tanggal mulai",
+		})
+	}
+	return nil
+}
+
+// ListHukdis mengambil hukuman disiplin pegawai
+func (h *Handlers) ListHukdis(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	list, err := h.hukdisRepo.List(c.Context(), pegawaiID)
+	if err != nil {
+		return hukdisError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       list,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetHukdisAktif mengambil hukuman disiplin yang berlaku pada query tanggal
+// (YYYY-MM-DD, default hari ini)
+func (h *Handlers) GetHukdisAktif(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	at := time.Now()
+	if value := fiber.Query[string](c, "tanggal", ""); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+				"field":  "tanggal",
+				"format": "YYYY-MM-DD",
+			}).ToFiberResponse(c, fiber.StatusBadRequest)
+		}
+		at = parsed
+	}
+
+	list, err := h.hukdisRepo.Aktif(c.Context(), pegawaiID, at)
+	if err != nil {
+		return hukdisError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       list,
+		"tanggal":    at.Format("2006-01-02"),
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetHukdis mengambil satu hukuman disiplin pegawai
+func (h *Handlers) GetHukdis(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("hukdisId"))
+	if err != nil {
+		return invalidIDResponse(c, "hukdisId")
+	}
+
+	hukdis, err := h.hukdisRepo.Get(c.Context(), pegawaiID, id)
+	if err != nil {
+		return hukdisError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       hukdis,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// CreateHukdis mencatat hukuman disiplin pegawai
+func (h *Handlers) CreateHukdis(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input repositories.HukdisInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateHukdis(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	hukdis, err := h.hukdisRepo.Create(c.Context(), pegawaiID, input, middleware.GetUserID(c))
+	if err != nil {
+		return hukdisError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Hukdis created successfully",
+		"data":       hukdis,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// UpdateHukdis mengubah hukuman disiplin pegawai
+func (h *Handlers) UpdateHukdis(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("hukdisId"))
+	if err != nil {
+		return invalidIDResponse(c, "hukdisId")
+	}
+
+	var input repositories.HukdisInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateHukdis(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	hukdis, err := h.hukdisRepo.Update(c.Context(), pegawaiID, id, input)
+	if err != nil {
+		return hukdisError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Hukdis updated successfully",
+		"data":       hukdis,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DeleteHukdis menghapus hukuman disiplin pegawai
+func (h *Handlers) DeleteHukdis(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("hukdisId"))
+	if err != nil {
+		return invalidIDResponse(c, "hukdisId")
+	}
+
+	if err := h.hukdisRepo.Delete(c.Context(), pegawaiID, id); err != nil {
+		return hukdisError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Hukdis deleted successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+// Batas waktu session impersonation
+const (
+	defaultImpersonationDuration = 30 * time.Minute
+	maxImpersonationDuration     = 4 * time.Hour
+)
+
+// ==================== IMPERSONATION ====================
+
+// ListImpersonations mengambil session impersonation terbaru
+func (h *Handlers) ListImpersonations(c fiber.Ctx) error {
+	activeOnly := fiber.Query[bool](c, "active", false)
+	limit := fiber.Query[int](c, "limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	sessions, err := h.impersonationRepo.List(c.Context(), activeOnly, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       sessions,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// StartImpersonation memulai session "act as" berbatas waktu. Request
+// berikutnya yang membawa header X-Impersonate-Session dievaluasi dengan role
+// dan scope unit kerja user target.
+func (h *Handlers) StartImpersonation(c fiber.Ctx) error {
+	if middleware.GetImpersonation(c) != nil {
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "tidak dapat memulai impersonation dari dalam session impersonation",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	}
+
+	var input repositories.StartImpersonationInput
+	if err := c.Bind().Body(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":      true,
+			"message":    "Invalid request body",
+			"code":       400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	input.TargetUserID = strings.TrimSpace(input.TargetUserID)
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.TargetUserID == "" || input.Reason == "" {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": []string{"target_user_id", "reason"},
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	duration := defaultImpersonationDuration
+	if input.DurationMinutes != 0 {
+		duration = time.Duration(input.DurationMinutes) * time.Minute
+	}
+	if duration <= 0 || duration > maxImpersonationDuration {
+		return appErrors.BadRequest(appErrors.ValOutOfRange, map[string]interface{}{
+			"field": "duration_minutes",
+			"max":   int(maxImpersonationDuration.Minutes()),
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	actorID := middleware.GetUserID(c)
+	if input.TargetUserID == actorID {
+		return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+			"reason": "tidak dapat melakukan impersonation terhadap diri sendiri",
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	// User yang juga boleh impersonation (admin/helpdesk lain) tidak boleh diperankan
+	privileged, err := h.RBACMiddleware.HasPermission(c.Context(), input.TargetUserID, middleware.ImpersonatePermission)
+	if err != nil {
+		return err
+	}
+	if privileged {
+		return appErrors.Forbidden(appErrors.AuthzForbidden, map[string]interface{}{
+			"reason": "user target memiliki akses impersonation",
+		}).ToFiberResponse(c, fiber.StatusForbidden)
+	}
+
+	middleware.SetAuditAction(c, "impersonation_start")
+	middleware.AddAuditDetail(c, "target_user_id", input.TargetUserID)
+	middleware.AddAuditDetail(c, "reason", input.Reason)
+	session, err := h.impersonationRepo.Start(c.Context(), input, duration, actorID, middleware.GetUsername(c))
+	if err != nil {
+		return err
+	}
+	middleware.SetAuditResource(c, "impersonation_session", session.ID)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Impersonation started, send the session id in the " + middleware.ImpersonationHeader + " header",
+		"data":       session,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// EndImpersonation mengakhiri session impersonation. Hanya aktor pemilik
+// session yang boleh mengakhiri, termasuk dari dalam session itu sendiri.
+func (h *Handlers) EndImpersonation(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	actorID := middleware.GetUserID(c)
+	if imp := middleware.GetImpersonation(c); imp != nil {
+		actorID = imp.ActorUserID
+	}
+
+	session, err := h.impersonationRepo.GetByID(c.Context(), id)
+	if err != nil {
+		return impersonationError(c, err)
+	}
+	if session.ActorUserID != actorID {
+		return appErrors.Forbidden(appErrors.AuthzForbidden, map[string]interface{}{
+			"reason": "session impersonation milik user lain",
+		}).ToFiberResponse(c, fiber.StatusForbidden)
+	}
+
+	middleware.SetAuditAction(c, "impersonation_end")
+	middleware.SetAuditResource(c, "impersonation_session", id)
+	middleware.AddAuditDetail(c, "target_user_id", session.TargetUserID)
+	session, err = h.impersonationRepo.End(c.Context(), id, actorID)
+	if err != nil {
+		return impersonationError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Impersonation ended",
+		"data":       session,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// impersonationError memetakan error ImpersonationRepository ke response 404/409
+func impersonationError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrImpersonationNotFound):
+		return appErrors.NotFound(appErrors.NotFoundResource).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrImpersonationInactive):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "session impersonation sudah berakhir",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	default:
+		return err
+	}
+}
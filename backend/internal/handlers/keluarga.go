@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"github.com/sikerma/backend/internal/audit"
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/models"
+	"github.com/sikerma/backend/internal/repositories"
+	"github.com/sikerma/backend/internal/utils"
+)
+
+// ==================== KELUARGA ====================
+
+// nikPattern adalah format NIK: 16 digit angka
+var nikPattern = regexp.MustCompile(`^\d{16}$`)
+
+// keluargaError memetakan error KeluargaRepository ke response 404
+func keluargaError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrPegawaiNotFound):
+		return appErrors.NotFound(appErrors.NotFoundPegawai).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrKeluargaNotFound):
+		return appErrors.NotFound(appErrors.NotFoundKeluarga).ToFiberResponse(c, fiber.StatusNotFound)
+	default:
+		return err
+	}
+}
+
+// maskKeluarga me-mask NIK dan PII lain anggota keluarga dengan field
+// yang sama seperti data pegawai
+func maskKeluarga(k *models.Keluarga) (map[string]interface{}, error) {
+	raw, err := json.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return utils.MaskPII(fields, audit.MaskedFields()), nil
+}
+
+// trimOptional merapikan string opsional; string kosong menjadi nil
+func trimOptional(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	return optionalString(strings.TrimSpace(*value))
+}
+
+// validateKeluarga memeriksa input anggota keluarga; nil bila input valid.
+// Tanggal lahir anak wajib karena menentukan status tanggungannya.
+func validateKeluarga(input *repositories.KeluargaInput) *appErrors.ErrorResponse {
+	input.Nama = strings.TrimSpace(input.Nama)
+	input.TempatLahir = trimOptional(input.TempatLahir)
+	input.JenisKelamin = trimOptional(input.JenisKelamin)
+	input.NIK = trimOptional(input.NIK)
+	input.Pendidikan = trimOptional(input.Pendidikan)
+	input.Pekerjaan = trimOptional(input.Pekerjaan)
+
+	missing := []string{}
+	if input.Hubungan == "" {
+		missing = append(missing, "hubungan")
+	}
+	if input.Nama == "" {
+		missing = append(missing, "nama")
+	}
+	if input.Hubungan == models.StatusKeluargaAnak && input.TanggalLahir == nil {
+		missing = append(missing, "tanggal_lahir")
+	}
+	if len(missing) > 0 {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": missing,
+		})
+	}
+
+	switch input.Hubungan {
+	case models.StatusKeluargaSuami, models.StatusKeluargaIstri, models.StatusKeluargaAnak,
+		models.StatusKeluargaAyah, models.StatusKeluargaIbu:
+	default:
+		return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+			"field":   "hubungan",
+			"allowed": []models.StatusKeluarga{models.StatusKeluargaSuami, models.StatusKeluargaIstri, models.StatusKeluargaAnak, models.StatusKeluargaAyah, models.StatusKeluargaIbu},
+		})
+	}
+
+	if input.JenisKelamin != nil && *input.JenisKelamin != "L" && *input.JenisKelamin != "P" {
+		return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+			"field":   "jenis_kelamin",
+			"allowed": []string{"L", "P"},
+		})
+	}
+
+	if input.NIK != nil && !nikPattern.MatchString(*input.NIK) {
+		return appErrors.BadRequest(appErrors.ValNIKFormat, map[string]interface{}{
+			"field": "nik",
+		})
+	}
+
+	if input.TanggalLahir != nil && input.TanggalLahir.After(time.Now()) {
+		return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+			"field":  "tanggal_lahir",
+			"reason": "tanggal tidak boleh di masa depan",
+		})
+	}
+
+	return nil
+}
+
+// ListKeluarga mengambil anggota keluarga pegawai
+func (h *Handlers) ListKeluarga(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	list, err := h.keluargaRepo.List(c.Context(), pegawaiID)
+	if err != nil {
+		return keluargaError(c, err)
+	}
+
+	data := make([]map[string]interface{}, 0, len(list))
+	for i := range list {
+		masked, err := maskKeluarga(&list[i])
+		if err != nil {
+			return err
+		}
+		data = append(data, masked)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       data,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetKeluarga mengambil satu anggota keluarga pegawai
+func (h *Handlers) GetKeluarga(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("keluargaId"))
+	if err != nil {
+		return invalidIDResponse(c, "keluargaId")
+	}
+
+	k, err := h.keluargaRepo.Get(c.Context(), pegawaiID, id)
+	if err != nil {
+		return keluargaError(c, err)
+	}
+	data, err := maskKeluarga(k)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       data,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// CreateKeluarga menambah anggota keluarga. is_tanggungan dihitung ulang
+// untuk seluruh keluarga pegawai.
+func (h *Handlers) CreateKeluarga(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input repositories.KeluargaInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateKeluarga(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	k, err := h.keluargaRepo.Create(c.Context(), pegawaiID, input, middleware.GetUserID(c))
+	if err != nil {
+		return keluargaError(c, err)
+	}
+	data, err := maskKeluarga(k)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Keluarga created successfully",
+		"data":       data,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// UpdateKeluarga mengubah anggota keluarga
+func (h *Handlers) UpdateKeluarga(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("keluargaId"))
+	if err != nil {
+		return invalidIDResponse(c, "keluargaId")
+	}
+
+	var input repositories.KeluargaInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateKeluarga(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	k, err := h.keluargaRepo.Update(c.Context(), pegawaiID, id, input)
+	if err != nil {
+		return keluargaError(c, err)
+	}
+	data, err := maskKeluarga(k)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Keluarga updated successfully",
+		"data":       data,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DeleteKeluarga menghapus anggota keluarga
+func (h *Handlers) DeleteKeluarga(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("keluargaId"))
+	if err != nil {
+		return invalidIDResponse(c, "keluargaId")
+	}
+
+	if err := h.keluargaRepo.Delete(c.Context(), pegawaiID, id); err != nil {
+		return keluargaError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Keluarga deleted successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetPerubahanTanggungan mengambil anggota keluarga yang status
+// tanggungannya berubah dalam satu bulan sejak query from (YYYY-MM-DD,
+// default hari ini)
+func (h *Handlers) GetPerubahanTanggungan(c fiber.Ctx) error {
+	from := time.Now()
+	if value := fiber.Query[string](c, "from", ""); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+				"field":  "from",
+				"format": "YYYY-MM-DD",
+			}).ToFiberResponse(c, fiber.StatusBadRequest)
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 1, 0)
+
+	report, err := h.keluargaRepo.PerubahanTanggungan(c.Context(), from, to)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    report,
+		"periode": fiber.Map{
+			"from": from.Format("2006-01-02"),
+			"to":   to.Format("2006-01-02"),
+		},
+		"request_id": middleware.GetRequestID(c),
+	})
+}
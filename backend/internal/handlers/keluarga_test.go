@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/repositories"
+	"github.com/sikerma/backend/internal/testutil"
+	"github.com/sikerma/backend/internal/utils"
+)
+
+// newKeluargaApp menyajikan ListKeluarga dan GetKeluarga di atas skema
+// kepegawaian testdata repositories, dengan satu pegawai dan satu anggota keluarga
+func newKeluargaApp(t *testing.T, nik string) (*fiber.App, uuid.UUID, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+
+	db := testutil.SetupTestDBWithScripts(t, "../repositories/testdata/00_riwayat_schema.sql")
+	t.Cleanup(func() { db.Cleanup(t) })
+
+	cfg, err := pgxpool.ParseConfig(db.ConnStr)
+	require.NoError(t, err)
+	cfg.ConnConfig.Database = "db_kepegawaian"
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	var pegawaiID, keluargaID uuid.UUID
+	err = pool.QueryRow(ctx,
+		`INSERT INTO pegawai (nip, nama_lengkap, satker_id) VALUES ('198501012010011001', 'Pegawai', $1) RETURNING id`, uuid.New(),
+	).Scan(&pegawaiID)
+	require.NoError(t, err)
+	err = pool.QueryRow(ctx,
+		`INSERT INTO keluarga (pegawai_id, status_keluarga, nama, tanggal_lahir, nik) VALUES ($1, 'Istri', 'Siti', '1988-02-01', $2) RETURNING id`,
+		pegawaiID, nik,
+	).Scan(&keluargaID)
+	require.NoError(t, err)
+
+	h := &Handlers{keluargaRepo: repositories.NewKeluargaRepository(pool)}
+	app := fiber.New()
+	app.Get("/pegawai/:id/keluarga", h.ListKeluarga)
+	app.Get("/pegawai/:id/keluarga/:keluargaId", h.GetKeluarga)
+	return app, pegawaiID, keluargaID
+}
+
+func TestKeluargaMaskNIK(t *testing.T) {
+	const nik = "3174014102880001"
+	app, pegawaiID, keluargaID := newKeluargaApp(t, nik)
+
+	get := func(t *testing.T, path string, data interface{}) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		body := struct {
+			Data interface{} `json:"data"`
+		}{Data: data}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+
+	t.Run("list", func(t *testing.T) {
+		var data []map[string]interface{}
+		get(t, "/pegawai/"+pegawaiID.String()+"/keluarga", &data)
+
+		require.Len(t, data, 1)
+		assert.Equal(t, utils.MaskNIK(nik), data[0]["nik"])
+		assert.Equal(t, "Siti", data[0]["nama"])
+	})
+
+	t.Run("get", func(t *testing.T) {
+		var data map[string]interface{}
+		get(t, "/pegawai/"+pegawaiID.String()+"/keluarga/"+keluargaID.String(), &data)
+
+		assert.Equal(t, utils.MaskNIK(nik), data["nik"])
+		assert.NotContains(t, data["tanggal_lahir"], "1988", "tanggal lahir di-mask seperti pegawai")
+	})
+}
@@ -0,0 +1,640 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/keycloak"
+	"github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/models"
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+// ==================== RBAC - ROLES ====================
+
+// ListRoles mengambil daftar roles
+func (h *Handlers) ListRoles(c fiber.Ctx) error {
+	roles, err := h.roleRepo.List(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       roles,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetRole mengambil detail role
+func (h *Handlers) GetRole(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	role, err := h.roleRepo.GetByID(c.Context(), id)
+	if err != nil {
+		return rbacError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       role,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// CreateRole membuat role baru
+func (h *Handlers) CreateRole(c fiber.Ctx) error {
+	var input repositories.CreateRoleInput
+	if err := c.Bind().Body(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":      true,
+			"message":    "Invalid request body",
+			"code":       400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	input.Nama = strings.TrimSpace(input.Nama)
+	if input.Nama == "" {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": []string{"nama"},
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	role, err := h.roleRepo.Create(c.Context(), input)
+	if err != nil {
+		return rbacError(c, err)
+	}
+	middleware.SetAuditResource(c, "app_role", role.ID)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Role created successfully",
+		"data":       role,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// UpdateRole mengubah nama/deskripsi role. Role sistem ditolak dengan 409.
+func (h *Handlers) UpdateRole(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input repositories.UpdateRoleInput
+	if err := c.Bind().Body(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":      true,
+			"message":    "Invalid request body",
+			"code":       400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	if input.Nama != nil && strings.TrimSpace(*input.Nama) == "" {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": []string{"nama"},
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	middleware.SetAuditResource(c, "app_role", id)
+	role, err := h.roleRepo.Update(c.Context(), id, input)
+	if err != nil {
+		return rbacError(c, err)
+	}
+	h.RBACMiddleware.InvalidateAll()
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Role updated successfully",
+		"data":       role,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DeactivateRole menonaktifkan role. Role sistem ditolak dengan 409.
+func (h *Handlers) DeactivateRole(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	middleware.SetAuditAction(c, "deactivate")
+	middleware.SetAuditResource(c, "app_role", id)
+	if err := h.roleRepo.Deactivate(c.Context(), id); err != nil {
+		return rbacError(c, err)
+	}
+	h.RBACMiddleware.InvalidateAll()
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Role deactivated successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ListIncludedRoles mengambil role yang diwarisi role (composite)
+func (h *Handlers) ListIncludedRoles(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	roles, err := h.roleRepo.ListIncludedRoles(c.Context(), id)
+	if err != nil {
+		return rbacError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       roles,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// IncludeRole menjadikan role mewarisi role lain beserta permission-nya
+func (h *Handlers) IncludeRole(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input struct {
+		RoleID uuid.UUID `json:"role_id"`
+	}
+	if err := c.Bind().Body(&input); err != nil || input.RoleID == uuid.Nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":      true,
+			"message":    "Invalid request body",
+			"code":       400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	middleware.SetAuditAction(c, "include_role")
+	middleware.SetAuditResource(c, "app_role", id)
+	middleware.AddAuditDetail(c, "includes_role_id", input.RoleID)
+	if err := h.roleRepo.IncludeRole(c.Context(), id, input.RoleID); err != nil {
+		return rbacError(c, err)
+	}
+	h.RBACMiddleware.InvalidateAll()
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Role included successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ExcludeRole menghapus pewarisan role
+func (h *Handlers) ExcludeRole(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	includedID, err := uuid.Parse(c.Params("includedId"))
+	if err != nil {
+		return invalidIDResponse(c, "includedId")
+	}
+
+	middleware.SetAuditAction(c, "exclude_role")
+	middleware.SetAuditResource(c, "app_role", id)
+	middleware.AddAuditDetail(c, "includes_role_id", includedID)
+	if err := h.roleRepo.ExcludeRole(c.Context(), id, includedID); err != nil {
+		return rbacError(c, err)
+	}
+	h.RBACMiddleware.InvalidateAll()
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Role excluded successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RBAC - PERMISSIONS ====================
+
+// ListPermissions mengambil katalog permission
+func (h *Handlers) ListPermissions(c fiber.Ctx) error {
+	resource := fiber.Query[string](c, "resource", "")
+
+	permissions, err := h.roleRepo.ListPermissions(c.Context(), resource)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       permissions,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ListRolePermissions mengambil permission yang dimiliki role
+func (h *Handlers) ListRolePermissions(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	permissions, err := h.roleRepo.ListRolePermissions(c.Context(), id)
+	if err != nil {
+		return rbacError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       permissions,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// AttachRolePermission menambahkan permission ke role
+func (h *Handlers) AttachRolePermission(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input struct {
+		PermissionID uuid.UUID `json:"permission_id"`
+	}
+	if err := c.Bind().Body(&input); err != nil || input.PermissionID == uuid.Nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":      true,
+			"message":    "Invalid request body",
+			"code":       400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	middleware.SetAuditAction(c, "attach_permission")
+	middleware.SetAuditResource(c, "app_role", id)
+	middleware.AddAuditDetail(c, "permission_id", input.PermissionID)
+	if err := h.roleRepo.AttachPermission(c.Context(), id, input.PermissionID); err != nil {
+		return rbacError(c, err)
+	}
+	h.RBACMiddleware.InvalidateAll()
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Permission attached successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DetachRolePermission melepas permission dari role
+func (h *Handlers) DetachRolePermission(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	permissionID, err := uuid.Parse(c.Params("permissionId"))
+	if err != nil {
+		return invalidIDResponse(c, "permissionId")
+	}
+
+	middleware.SetAuditAction(c, "detach_permission")
+	middleware.SetAuditResource(c, "app_role", id)
+	middleware.AddAuditDetail(c, "permission_id", permissionID)
+	if err := h.roleRepo.DetachPermission(c.Context(), id, permissionID); err != nil {
+		return rbacError(c, err)
+	}
+	h.RBACMiddleware.InvalidateAll()
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Permission detached successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RBAC - USER ROLES ====================
+
+// ListUserRoles mengambil role yang di-assign ke user Keycloak
+func (h *Handlers) ListUserRoles(c fiber.Ctx) error {
+	userID := c.Params("userId")
+
+	assignments, err := h.roleRepo.ListUserRoles(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       assignments,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// AssignUserRole memberikan role ke user Keycloak
+func (h *Handlers) AssignUserRole(c fiber.Ctx) error {
+	userID := c.Params("userId")
+
+	var input repositories.AssignRoleInput
+	if err := c.Bind().Body(&input); err != nil || input.RoleID == uuid.Nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":      true,
+			"message":    "Invalid request body",
+			"code":       400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	middleware.SetAuditAction(c, "assign")
+	middleware.SetAuditResource(c, "user_app_role", input.RoleID)
+	middleware.AddAuditDetail(c, "target_user_id", userID)
+	assignment, err := h.roleRepo.AssignRole(c.Context(), userID, input, middleware.GetUserID(c))
+	if err != nil {
+		return rbacError(c, err)
+	}
+	h.RBACMiddleware.Invalidate(userID)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Role assigned successfully",
+		"data":       assignment,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// RevokeUserRole mencabut role dari user Keycloak
+func (h *Handlers) RevokeUserRole(c fiber.Ctx) error {
+	userID := c.Params("userId")
+	roleID, err := uuid.Parse(c.Params("roleId"))
+	if err != nil {
+		return invalidIDResponse(c, "roleId")
+	}
+
+	middleware.SetAuditAction(c, "revoke")
+	middleware.SetAuditResource(c, "user_app_role", roleID)
+	middleware.AddAuditDetail(c, "target_user_id", userID)
+	if err := h.roleRepo.RevokeRole(c.Context(), userID, roleID); err != nil {
+		return rbacError(c, err)
+	}
+	h.RBACMiddleware.Invalidate(userID)
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Role revoked successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RBAC - USER SESSIONS ====================
+
+// ForceLogoutUser mencabut semua access token user yang sudah terbit dan
+// mengakhiri session-nya di Keycloak, misalnya saat pegawai diberhentikan
+func (h *Handlers) ForceLogoutUser(c fiber.Ctx) error {
+	userID := c.Params("userId")
+
+	var req models.ForceLogoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":      true,
+				"message":    "Invalid request body",
+				"code":       400,
+				"request_id": middleware.GetRequestID(c),
+			})
+		}
+	}
+
+	middleware.SetAuditAction(c, "force_logout")
+	middleware.AddAuditDetail(c, "target_user_id", userID)
+
+	now := time.Now()
+	revocation := models.TokenRevocation{
+		Kind:         models.RevocationSub,
+		Value:        userID,
+		UserID:       &userID,
+		IssuedBefore: &now,
+		RevokedBy:    optionalString(middleware.GetUserID(c)),
+		Reason:       optionalString(strings.TrimSpace(req.Reason)),
+		ExpiresAt:    now.Add(h.cfg.Keycloak.AccessTokenLifespan + h.cfg.Keycloak.ClockSkew),
+	}
+	if err := h.Revocations.Revoke(c.Context(), revocation); err != nil {
+		return err
+	}
+
+	// Tanpa mengakhiri session, user bisa memakai refresh token untuk token
+	// baru. Pencabutan lokal sudah tersimpan, jadi kegagalan Keycloak
+	// dilaporkan sebagai keberhasilan sebagian agar admin bisa mengulang.
+	if err := h.keycloak.LogoutUser(c.Context(), userID); err != nil {
+		if !errors.Is(err, keycloak.ErrUserNotFound) {
+			logrus.WithError(err).WithField("user_id", userID).Error("Force logout: Keycloak session logout failed, access tokens revoked only")
+			middleware.AddAuditDetail(c, "keycloak_logout", "failed")
+			return c.Status(fiber.StatusMultiStatus).JSON(fiber.Map{
+				"success":    true,
+				"message":    "Access tokens revoked, but Keycloak sessions could not be ended",
+				"data":       revocation,
+				"request_id": middleware.GetRequestID(c),
+			})
+		}
+		logrus.WithField("user_id", userID).Warn("Force logout: user not found in Keycloak, access tokens revoked only")
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "User logged out from all sessions",
+		"data":       revocation,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RBAC - API KEYS ====================
+
+// ListAPIKeys mengambil daftar API key (tanpa secret)
+func (h *Handlers) ListAPIKeys(c fiber.Ctx) error {
+	ownerUserID := fiber.Query[string](c, "owner_user_id", "")
+
+	keys, err := h.apiKeyRepo.List(c.Context(), ownerUserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       keys,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetAPIKey mengambil detail API key (tanpa secret)
+func (h *Handlers) GetAPIKey(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	key, err := h.apiKeyRepo.GetByID(c.Context(), id)
+	if err != nil {
+		return rbacError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       key,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// CreateAPIKey menerbitkan API key. Secret hanya ditampilkan di response ini.
+func (h *Handlers) CreateAPIKey(c fiber.Ctx) error {
+	var input repositories.CreateAPIKeyInput
+	if err := c.Bind().Body(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":      true,
+			"message":    "Invalid request body",
+			"code":       400,
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+
+	input.Nama = strings.TrimSpace(input.Nama)
+	missing := []string{}
+	if input.Nama == "" {
+		missing = append(missing, "nama")
+	}
+	if len(input.Scopes) == 0 {
+		missing = append(missing, "scopes")
+	}
+	if len(missing) > 0 {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": missing,
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+			"field": "expires_at",
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+	if input.OwnerUserID == "" {
+		input.OwnerUserID = middleware.GetUserID(c)
+	}
+
+	unknown, err := h.apiKeyRepo.UnknownScopes(c.Context(), input.Scopes)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+			"field":          "scopes",
+			"unknown_scopes": unknown,
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	key, err := h.apiKeyRepo.Create(c.Context(), input, middleware.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	middleware.SetAuditResource(c, "api_key", key.ID)
+	middleware.AddAuditDetail(c, "owner_user_id", key.OwnerUserID)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "API key created successfully. Simpan key sekarang, key tidak akan ditampilkan lagi.",
+		"data":       key,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// RotateAPIKey menerbitkan secret baru dan mencabut key lama
+func (h *Handlers) RotateAPIKey(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	middleware.SetAuditAction(c, "rotate")
+	middleware.SetAuditResource(c, "api_key", id)
+	key, err := h.apiKeyRepo.Rotate(c.Context(), id, middleware.GetUserID(c))
+	if err != nil {
+		return rbacError(c, err)
+	}
+	middleware.AddAuditDetail(c, "new_key_id", key.ID)
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "API key rotated successfully. Simpan key sekarang, key tidak akan ditampilkan lagi.",
+		"data":       key,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// RevokeAPIKey mencabut API key
+func (h *Handlers) RevokeAPIKey(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	middleware.SetAuditAction(c, "revoke")
+	middleware.SetAuditResource(c, "api_key", id)
+	if err := h.apiKeyRepo.Revoke(c.Context(), id, middleware.GetUserID(c)); err != nil {
+		return rbacError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "API key revoked successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RBAC - HELPERS ====================
+
+// rbacError memetakan error RoleRepository/APIKeyRepository ke response 404/409
+func rbacError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrRoleNotFound), errors.Is(err, repositories.ErrRoleNotAssigned):
+		return appErrors.NotFound(appErrors.NotFoundRole).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrPermissionNotFound):
+		return appErrors.NotFound(appErrors.NotFoundPermission).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrSystemRoleProtected):
+		return appErrors.Conflict(appErrors.ConflictSystemRole).ToFiberResponse(c, fiber.StatusConflict)
+	case errors.Is(err, repositories.ErrRoleExists):
+		return appErrors.Conflict(appErrors.ConflictRoleExists).ToFiberResponse(c, fiber.StatusConflict)
+	case errors.Is(err, repositories.ErrAPIKeyNotFound):
+		return appErrors.NotFound(appErrors.NotFoundAPIKey).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrAPIKeyRevoked):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "api key sudah dicabut",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	case errors.Is(err, repositories.ErrRoleCycle):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "composite role membentuk siklus",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	case errors.Is(err, repositories.ErrRoleInactive):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"reason": "role tidak aktif",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	default:
+		return err
+	}
+}
+
+// invalidIDResponse mengembalikan 400 untuk path parameter UUID yang tidak valid
+func invalidIDResponse(c fiber.Ctx, param string) error {
+	return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+		"param": param,
+	}).ToFiberResponse(c, fiber.StatusBadRequest)
+}
+
+// optionalString mengembalikan nil untuk string kosong
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
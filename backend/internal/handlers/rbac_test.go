@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/config"
+	"github.com/sikerma/backend/internal/keycloak"
+	"github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/models"
+)
+
+type fakeRevocationStore struct {
+	revocations []models.TokenRevocation
+}
+
+func (f *fakeRevocationStore) Revoke(_ context.Context, rev models.TokenRevocation) error {
+	f.revocations = append(f.revocations, rev)
+	return nil
+}
+
+func (f *fakeRevocationStore) ListActiveRevocations(_ context.Context) ([]models.TokenRevocation, error) {
+	return f.revocations, nil
+}
+
+func (f *fakeRevocationStore) DeleteExpired(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
+// newForceLogoutApp menyajikan ForceLogoutUser dengan Keycloak palsu yang
+// mengembalikan adminStatus dari admin logout endpoint
+func newForceLogoutApp(t *testing.T, adminStatus int) (*fiber.App, *fakeRevocationStore, *[]string) {
+	t.Helper()
+	calls := []string{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/realms/pengadilan-agama/protocol/openid-connect/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		calls = append(calls, "token:"+r.PostForm.Get("grant_type"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(keycloak.TokenSet{AccessToken: "service-account", TokenType: "Bearer"})
+	})
+	mux.HandleFunc("/admin/realms/pengadilan-agama/users/{id}/logout", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "logout:"+r.PathValue("id")+":"+r.Header.Get("Authorization"))
+		w.WriteHeader(adminStatus)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{Keycloak: config.KeycloakConfig{
+		URL:                 server.URL,
+		Realm:               "pengadilan-agama",
+		ClientID:            "sikerma-tools",
+		ClientSecret:        "secret",
+		AccessTokenLifespan: 15 * time.Minute,
+	}}
+	store := &fakeRevocationStore{}
+	h := &Handlers{
+		cfg:         cfg,
+		Revocations: middleware.NewRevocationList(store),
+		keycloak:    keycloak.NewClient(cfg.Keycloak),
+	}
+
+	app := fiber.New()
+	app.Post("/rbac/users/:userId/logout", h.ForceLogoutUser)
+	return app, store, &calls
+}
+
+func TestForceLogoutUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		adminStatus int
+		status      int
+	}{
+		{"session keycloak diakhiri", http.StatusNoContent, fiber.StatusOK},
+		{"user tidak ada di keycloak", http.StatusNotFound, fiber.StatusOK},
+		{"keycloak gagal setelah token dicabut", http.StatusForbidden, fiber.StatusMultiStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, store, calls := newForceLogoutApp(t, tt.adminStatus)
+
+			resp, err := app.Test(httptest.NewRequest("POST", "/rbac/users/user-1/logout", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			require.Len(t, store.revocations, 1)
+			assert.Equal(t, models.RevocationSub, store.revocations[0].Kind)
+			assert.Equal(t, "user-1", store.revocations[0].Value)
+			assert.Equal(t, []string{"token:client_credentials", "logout:user-1:Bearer service-account"}, *calls)
+		})
+	}
+}
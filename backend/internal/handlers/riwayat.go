@@ -0,0 +1,951 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/middleware"
+	"github.com/sikerma/backend/internal/models"
+	"github.com/sikerma/backend/internal/repositories"
+	"github.com/sikerma/backend/internal/storage"
+)
+
+// ==================== RIWAYAT ====================
+
+// riwayatError memetakan error RiwayatRepository ke response 404/400/409
+func riwayatError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrPegawaiNotFound):
+		return appErrors.NotFound(appErrors.NotFoundPegawai).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrRiwayatNotFound):
+		return appErrors.NotFound(appErrors.NotFoundRiwayat).ToFiberResponse(c, fiber.StatusNotFound)
+	case errors.Is(err, repositories.ErrGolonganNotFound):
+		return referensiError(c, "golongan_id", "golongan tidak ditemukan")
+	case errors.Is(err, repositories.ErrGolonganInactive):
+		return referensiError(c, "golongan_id", "golongan tidak aktif")
+	case errors.Is(err, repositories.ErrJabatanNotFound):
+		return referensiError(c, "jabatan_id", "jabatan tidak ditemukan")
+	case errors.Is(err, repositories.ErrUnitKerjaNotFound):
+		return referensiError(c, "unit_kerja_id", "unit kerja tidak ditemukan")
+	case errors.Is(err, repositories.ErrSatkerNotFound):
+		return referensiError(c, "satker_id", "satker tidak ditemukan")
+	case errors.Is(err, repositories.ErrUnitKerjaSatker):
+		return referensiError(c, "unit_kerja_id", "unit kerja tidak berada di satker tersebut")
+	case errors.Is(err, repositories.ErrPendidikanNotFound):
+		return referensiError(c, "pendidikan_id", "pendidikan tidak ditemukan")
+	case errors.Is(err, repositories.ErrJenisDiklatNotFound):
+		return referensiError(c, "jenis_diklat_id", "jenis diklat tidak ditemukan")
+	case errors.Is(err, repositories.ErrHukdisAktif):
+		return appErrors.Conflict(appErrors.ConflictState, map[string]interface{}{
+			"field":  "tmt",
+			"reason": "pegawai sedang menjalani hukuman disiplin pada TMT tersebut",
+		}).ToFiberResponse(c, fiber.StatusConflict)
+	default:
+		return err
+	}
+}
+
+// referensiError adalah response untuk ID data master pada body yang tidak valid
+func referensiError(c fiber.Ctx, field, reason string) error {
+	return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+		"field":  field,
+		"reason": reason,
+	}).ToFiberResponse(c, fiber.StatusBadRequest)
+}
+
+// invalidBodyResponse adalah response untuk body yang tidak dapat dibaca
+func invalidBodyResponse(c fiber.Ctx) error {
+	return c.Status(400).JSON(fiber.Map{
+		"error":      true,
+		"message":    "Invalid request body",
+		"code":       400,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RIWAYAT PANGKAT ====================
+
+// requiredSK mengembalikan field SK riwayat yang kosong
+func requiredSK(tmt time.Time, nomorSK string, tanggalSK time.Time, pejabat string) []string {
+	missing := []string{}
+	if tmt.IsZero() {
+		missing = append(missing, "tmt")
+	}
+	if nomorSK == "" {
+		missing = append(missing, "nomor_sk")
+	}
+	if tanggalSK.IsZero() {
+		missing = append(missing, "tanggal_sk")
+	}
+	if pejabat == "" {
+		missing = append(missing, "pejabat")
+	}
+	return missing
+}
+
+// validateSK memeriksa field SK riwayat; TMT dan tanggal SK tidak boleh di
+// masa depan. extra berisi field wajib lain yang kosong.
+func validateSK(tmt time.Time, nomorSK string, tanggalSK time.Time, pejabat string, extra ...string) *appErrors.ErrorResponse {
+	missing := append(append([]string{}, extra...), requiredSK(tmt, nomorSK, tanggalSK, pejabat)...)
+	if len(missing) > 0 {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": missing,
+		})
+	}
+
+	now := time.Now()
+	for _, date := range []struct {
+		field string
+		value time.Time
+	}{{"tmt", tmt}, {"tanggal_sk", tanggalSK}} {
+		if date.value.After(now) {
+			return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+				"field":  date.field,
+				"reason": "tanggal tidak boleh di masa depan",
+			})
+		}
+	}
+	return nil
+}
+
+// validateRiwayatPangkat memeriksa field wajib dan rentang nilai riwayat
+// pangkat; nil bila input valid
+func validateRiwayatPangkat(input *repositories.RiwayatPangkatInput) *appErrors.ErrorResponse {
+	input.Pangkat = strings.TrimSpace(input.Pangkat)
+	input.NomorSK = strings.TrimSpace(input.NomorSK)
+	input.Pejabat = strings.TrimSpace(input.Pejabat)
+
+	var missing []string
+	if input.GolonganID == uuid.Nil {
+		missing = append(missing, "golongan_id")
+	}
+	if invalid := validateSK(input.TMT, input.NomorSK, input.TanggalSK, input.Pejabat, missing...); invalid != nil {
+		return invalid
+	}
+
+	switch {
+	case input.GajiPokok < 0:
+		return appErrors.BadRequest(appErrors.ValOutOfRange, map[string]interface{}{
+			"field": "gaji_pokok",
+			"min":   0,
+		})
+	case input.MasaKerjaTahun < 0:
+		return appErrors.BadRequest(appErrors.ValOutOfRange, map[string]interface{}{
+			"field": "masa_kerja_tahun",
+			"min":   0,
+		})
+	case input.MasaKerjaBulan < 0 || input.MasaKerjaBulan > 11:
+		return appErrors.BadRequest(appErrors.ValOutOfRange, map[string]interface{}{
+			"field": "masa_kerja_bulan",
+			"min":   0,
+			"max":   11,
+		})
+	}
+
+	if input.JenisKenaikan != nil {
+		switch *input.JenisKenaikan {
+		case models.JenisKenaikanReguler, models.JenisKenaikanPilihan,
+			models.JenisKenaikanPenyesuaianIjazah, models.JenisKenaikanLainnya:
+		default:
+			return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+				"field":   "jenis_kenaikan",
+				"allowed": []models.JenisKenaikanPangkat{models.JenisKenaikanReguler, models.JenisKenaikanPilihan, models.JenisKenaikanPenyesuaianIjazah, models.JenisKenaikanLainnya},
+			})
+		}
+	}
+
+	return nil
+}
+
+// ListRiwayatPangkat mengambil riwayat pangkat pegawai
+func (h *Handlers) ListRiwayatPangkat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	list, err := h.riwayatRepo.ListPangkat(c.Context(), pegawaiID)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       list,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetRiwayatPangkat mengambil satu riwayat pangkat pegawai
+func (h *Handlers) GetRiwayatPangkat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	rp, err := h.riwayatRepo.GetPangkat(c.Context(), pegawaiID, id)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       rp,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// CreateRiwayatPangkat menambah riwayat pangkat. Riwayat dengan TMT terbaru
+// menjadi golongan dan TMT pangkat terakhir pegawai.
+func (h *Handlers) CreateRiwayatPangkat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input repositories.RiwayatPangkatInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateRiwayatPangkat(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	rp, err := h.riwayatRepo.CreatePangkat(c.Context(), pegawaiID, input, middleware.GetUserID(c))
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat pangkat created successfully",
+		"data":       rp,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// UpdateRiwayatPangkat mengubah riwayat pangkat
+func (h *Handlers) UpdateRiwayatPangkat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	var input repositories.RiwayatPangkatInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateRiwayatPangkat(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	rp, err := h.riwayatRepo.UpdatePangkat(c.Context(), pegawaiID, id, input)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat pangkat updated successfully",
+		"data":       rp,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DeleteRiwayatPangkat menghapus riwayat pangkat
+func (h *Handlers) DeleteRiwayatPangkat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	if err := h.riwayatRepo.DeletePangkat(c.Context(), pegawaiID, id); err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat pangkat deleted successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RIWAYAT JABATAN ====================
+
+// validateRiwayatJabatan memeriksa field wajib riwayat jabatan; nil bila
+// input valid. nama_jabatan hanya wajib bila jabatan_id kosong.
+func validateRiwayatJabatan(input *repositories.RiwayatJabatanInput) *appErrors.ErrorResponse {
+	input.NamaJabatan = strings.TrimSpace(input.NamaJabatan)
+	input.NomorSK = strings.TrimSpace(input.NomorSK)
+	input.Pejabat = strings.TrimSpace(input.Pejabat)
+
+	var missing []string
+	if input.JabatanID == nil && input.NamaJabatan == "" {
+		missing = append(missing, "nama_jabatan")
+	}
+	if invalid := validateSK(input.TMT, input.NomorSK, input.TanggalSK, input.Pejabat, missing...); invalid != nil {
+		return invalid
+	}
+
+	if input.JenisJabatan != nil {
+		switch *input.JenisJabatan {
+		case models.JenisJabatanStruktural, models.JenisJabatanFungsionalTertentu,
+			models.JenisJabatanFungsionalUmum, models.JenisJabatanPelaksana:
+		default:
+			return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+				"field":   "jenis_jabatan",
+				"allowed": []models.JenisJabatan{models.JenisJabatanStruktural, models.JenisJabatanFungsionalTertentu, models.JenisJabatanFungsionalUmum, models.JenisJabatanPelaksana},
+			})
+		}
+	}
+
+	return nil
+}
+
+// ListRiwayatJabatan mengambil riwayat jabatan pegawai
+func (h *Handlers) ListRiwayatJabatan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	list, err := h.riwayatRepo.ListJabatan(c.Context(), pegawaiID)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       list,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetRiwayatJabatan mengambil satu riwayat jabatan pegawai
+func (h *Handlers) GetRiwayatJabatan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	rj, err := h.riwayatRepo.GetJabatan(c.Context(), pegawaiID, id)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       rj,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// CreateRiwayatJabatan menambah riwayat jabatan. Riwayat dengan TMT terbaru
+// menjadi jabatan, unit kerja dan satker pegawai.
+func (h *Handlers) CreateRiwayatJabatan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input repositories.RiwayatJabatanInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateRiwayatJabatan(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	rj, err := h.riwayatRepo.CreateJabatan(c.Context(), pegawaiID, input, middleware.GetUserID(c))
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat jabatan created successfully",
+		"data":       rj,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// UpdateRiwayatJabatan mengubah riwayat jabatan
+func (h *Handlers) UpdateRiwayatJabatan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	var input repositories.RiwayatJabatanInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateRiwayatJabatan(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	rj, err := h.riwayatRepo.UpdateJabatan(c.Context(), pegawaiID, id, input)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat jabatan updated successfully",
+		"data":       rj,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DeleteRiwayatJabatan menghapus riwayat jabatan
+func (h *Handlers) DeleteRiwayatJabatan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	if err := h.riwayatRepo.DeleteJabatan(c.Context(), pegawaiID, id); err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat jabatan deleted successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// ==================== RIWAYAT PENDIDIKAN ====================
+
+// validateRiwayatPendidikan memeriksa field wajib, tahun dan tanggal ijazah
+// riwayat pendidikan; nil bila input valid. Tahun 0 berarti tidak diisi.
+func validateRiwayatPendidikan(input *repositories.RiwayatPendidikanInput) *appErrors.ErrorResponse {
+	input.NamaInstitusi = strings.TrimSpace(input.NamaInstitusi)
+
+	missing := []string{}
+	if input.PendidikanID == uuid.Nil {
+		missing = append(missing, "pendidikan_id")
+	}
+	if input.NamaInstitusi == "" {
+		missing = append(missing, "nama_institusi")
+	}
+	if len(missing) > 0 {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": missing,
+		})
+	}
+
+	now := time.Now()
+	for _, year := range []struct {
+		field string
+		value int
+	}{{"tahun_masuk", input.TahunMasuk}, {"tahun_lulus", input.TahunLulus}} {
+		if year.value != 0 && (year.value < 1900 || year.value > now.Year()) {
+			return appErrors.BadRequest(appErrors.ValOutOfRange, map[string]interface{}{
+				"field": year.field,
+				"min":   1900,
+				"max":   now.Year(),
+			})
+		}
+	}
+	if input.TahunMasuk != 0 && input.TahunLulus != 0 && input.TahunLulus < input.TahunMasuk {
+		return appErrors.BadRequest(appErrors.ValOutOfRange, map[string]interface{}{
+			"field": "tahun_lulus",
+			"min":   input.TahunMasuk,
+		})
+	}
+
+	if input.TanggalIjazah != nil && input.TanggalIjazah.After(now) {
+		return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+			"field":  "tanggal_ijazah",
+			"reason": "tanggal tidak boleh di masa depan",
+		})
+	}
+
+	return nil
+}
+
+// ListRiwayatPendidikan mengambil riwayat pendidikan pegawai
+func (h *Handlers) ListRiwayatPendidikan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	list, err := h.riwayatRepo.ListPendidikan(c.Context(), pegawaiID)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       list,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetRiwayatPendidikan mengambil satu riwayat pendidikan pegawai
+func (h *Handlers) GetRiwayatPendidikan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	rp, err := h.riwayatRepo.GetPendidikan(c.Context(), pegawaiID, id)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       rp,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// CreateRiwayatPendidikan menambah riwayat pendidikan. Jenjang tertinggi
+// menjadi pendidikan terakhir pegawai.
+func (h *Handlers) CreateRiwayatPendidikan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input repositories.RiwayatPendidikanInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateRiwayatPendidikan(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	rp, err := h.riwayatRepo.CreatePendidikan(c.Context(), pegawaiID, input, middleware.GetUserID(c))
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat pendidikan created successfully",
+		"data":       rp,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// UpdateRiwayatPendidikan mengubah riwayat pendidikan
+func (h *Handlers) UpdateRiwayatPendidikan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	var input repositories.RiwayatPendidikanInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateRiwayatPendidikan(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	rp, err := h.riwayatRepo.UpdatePendidikan(c.Context(), pegawaiID, id, input)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat pendidikan updated successfully",
+		"data":       rp,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DeleteRiwayatPendidikan menghapus riwayat pendidikan beserta file ijazahnya
+func (h *Handlers) DeleteRiwayatPendidikan(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	fileIjazah, err := h.riwayatRepo.DeletePendidikan(c.Context(), pegawaiID, id)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+	if fileIjazah != nil {
+		h.removeFile(*fileIjazah)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat pendidikan deleted successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// UploadIjazah menyimpan scan ijazah riwayat pendidikan dari field multipart
+// "file" dan mengganti file sebelumnya
+func (h *Handlers) UploadIjazah(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	maxSize := h.cfg.Storage.MaxDocumentSize
+	header, err := c.FormFile("file")
+	if err != nil {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": []string{"file"},
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+	if header.Size > maxSize {
+		return fileSizeResponse(c, maxSize)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return appErrors.InternalError(appErrors.SysFileUpload).ToFiberResponse(c, fiber.StatusInternalServerError)
+	}
+	defer file.Close()
+
+	path, err := h.fileStore.Save("ijazah/"+pegawaiID.String(), file, maxSize, storage.DocumentTypes)
+	switch {
+	case errors.Is(err, storage.ErrFileTooLarge):
+		return fileSizeResponse(c, maxSize)
+	case errors.Is(err, storage.ErrFileType):
+		return appErrors.BadRequest(appErrors.ValFileType, map[string]interface{}{
+			"allowed": []string{"pdf", "jpg", "png"},
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	case err != nil:
+		return appErrors.InternalError(appErrors.SysFileUpload).ToFiberResponse(c, fiber.StatusInternalServerError)
+	}
+
+	rp, oldFile, err := h.riwayatRepo.SetFileIjazah(c.Context(), pegawaiID, id, path)
+	if err != nil {
+		h.removeFile(path)
+		return riwayatError(c, err)
+	}
+	if oldFile != nil && *oldFile != path {
+		h.removeFile(*oldFile)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Ijazah uploaded successfully",
+		"data":       rp,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DownloadIjazah mengirim scan ijazah riwayat pendidikan
+func (h *Handlers) DownloadIjazah(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	rp, err := h.riwayatRepo.GetPendidikan(c.Context(), pegawaiID, id)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+	if rp.FileIjazah == nil {
+		return appErrors.NotFound(appErrors.NotFoundDocument).ToFiberResponse(c, fiber.StatusNotFound)
+	}
+
+	file, err := h.fileStore.Open(*rp.FileIjazah)
+	if errors.Is(err, os.ErrNotExist) {
+		return appErrors.NotFound(appErrors.NotFoundDocument).ToFiberResponse(c, fiber.StatusNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	ext := filepath.Ext(*rp.FileIjazah)
+	c.Set(fiber.HeaderContentType, mime.TypeByExtension(ext))
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="ijazah-`+rp.ID.String()+ext+`"`)
+	// File ditutup oleh fasthttp setelah response terkirim
+	return c.SendStream(file, int(info.Size()))
+}
+
+// removeFile menghapus file yang tidak lagi direferensikan. Kegagalan hanya
+// dicatat karena data di database sudah berubah.
+func (h *Handlers) removeFile(path string) {
+	if err := h.fileStore.Remove(path); err != nil {
+		logrus.WithError(err).WithField("path", path).Warn("Failed to remove stored file")
+	}
+}
+
+// fileSizeResponse adalah response untuk file upload yang melebihi batas
+func fileSizeResponse(c fiber.Ctx, maxSize int64) error {
+	return appErrors.BadRequest(appErrors.ValFileSize, map[string]interface{}{
+		"max_size": maxSize,
+	}).ToFiberResponse(c, fiber.StatusBadRequest)
+}
+
+// ==================== RIWAYAT DIKLAT ====================
+
+// validateRiwayatDiklat memeriksa field wajib, JP dan tanggal riwayat diklat;
+// nil bila input valid
+func validateRiwayatDiklat(input *repositories.RiwayatDiklatInput) *appErrors.ErrorResponse {
+	input.NamaDiklat = strings.TrimSpace(input.NamaDiklat)
+	input.Penyelenggara = trimOptional(input.Penyelenggara)
+	input.Tempat = trimOptional(input.Tempat)
+	input.NomorSertifikat = trimOptional(input.NomorSertifikat)
+
+	missing := []string{}
+	if input.JenisDiklatID == uuid.Nil {
+		missing = append(missing, "jenis_diklat_id")
+	}
+	if input.NamaDiklat == "" {
+		missing = append(missing, "nama_diklat")
+	}
+	if len(missing) > 0 {
+		return appErrors.BadRequest(appErrors.ValRequiredField, map[string]interface{}{
+			"fields": missing,
+		})
+	}
+
+	if input.JamJPL != nil && *input.JamJPL < 0 {
+		return appErrors.BadRequest(appErrors.ValOutOfRange, map[string]interface{}{
+			"field": "jam_jpl",
+			"min":   0,
+		})
+	}
+
+	now := time.Now()
+	for _, date := range []struct {
+		field string
+		value *time.Time
+	}{{"tanggal_mulai", input.TanggalMulai}, {"tanggal_sertifikat", input.TanggalSertifikat}} {
+		if date.value != nil && date.value.After(now) {
+			return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+				"field":  date.field,
+				"reason": "tanggal tidak boleh di masa depan",
+			})
+		}
+	}
+	if input.TanggalMulai != nil && input.TanggalSelesai != nil && input.TanggalSelesai.Before(*input.TanggalMulai) {
+		return appErrors.BadRequest(appErrors.ValInvalidDate, map[string]interface{}{
+			"field":  "tanggal_selesai",
+			"reason": "tanggal selesai tidak boleh sebelum tanggal mulai",
+		})
+	}
+
+	return nil
+}
+
+// ListRiwayatDiklat mengambil riwayat diklat pegawai
+func (h *Handlers) ListRiwayatDiklat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	list, err := h.riwayatRepo.ListDiklat(c.Context(), pegawaiID)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       list,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetRiwayatDiklat mengambil satu riwayat diklat pegawai
+func (h *Handlers) GetRiwayatDiklat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	d, err := h.riwayatRepo.GetDiklat(c.Context(), pegawaiID, id)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       d,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// CreateRiwayatDiklat menambah riwayat diklat
+func (h *Handlers) CreateRiwayatDiklat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+
+	var input repositories.RiwayatDiklatInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateRiwayatDiklat(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	d, err := h.riwayatRepo.CreateDiklat(c.Context(), pegawaiID, input, middleware.GetUserID(c))
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat diklat created successfully",
+		"data":       d,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// UpdateRiwayatDiklat mengubah riwayat diklat
+func (h *Handlers) UpdateRiwayatDiklat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	var input repositories.RiwayatDiklatInput
+	if err := c.Bind().Body(&input); err != nil {
+		return invalidBodyResponse(c)
+	}
+	if invalid := validateRiwayatDiklat(&input); invalid != nil {
+		return invalid.ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	d, err := h.riwayatRepo.UpdateDiklat(c.Context(), pegawaiID, id, input)
+	if err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat diklat updated successfully",
+		"data":       d,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// DeleteRiwayatDiklat menghapus riwayat diklat
+func (h *Handlers) DeleteRiwayatDiklat(c fiber.Ctx) error {
+	pegawaiID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidIDResponse(c, "id")
+	}
+	id, err := uuid.Parse(c.Params("riwayatId"))
+	if err != nil {
+		return invalidIDResponse(c, "riwayatId")
+	}
+
+	if err := h.riwayatRepo.DeleteDiklat(c.Context(), pegawaiID, id); err != nil {
+		return riwayatError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Riwayat diklat deleted successfully",
+		"request_id": middleware.GetRequestID(c),
+	})
+}
+
+// GetKepatuhanJP merekap per satker pegawai yang belum memenuhi target JP
+// diklat pada query tahun (default tahun berjalan), opsional untuk satu satker_id
+func (h *Handlers) GetKepatuhanJP(c fiber.Ctx) error {
+	tahun := fiber.Query[int](c, "tahun", time.Now().Year())
+	if tahun < 1900 || tahun > time.Now().Year() {
+		return appErrors.BadRequest(appErrors.ValOutOfRange, map[string]interface{}{
+			"field": "tahun",
+			"min":   1900,
+			"max":   time.Now().Year(),
+		}).ToFiberResponse(c, fiber.StatusBadRequest)
+	}
+
+	var satkerID *uuid.UUID
+	if value := fiber.Query[string](c, "satker_id", ""); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return invalidIDResponse(c, "satker_id")
+		}
+		satkerID = &id
+	}
+
+	rekap, err := h.riwayatRepo.KepatuhanJP(c.Context(), tahun, satkerID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"data":       rekap,
+		"tahun":      tahun,
+		"target_jp":  repositories.TargetJPTahunan,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
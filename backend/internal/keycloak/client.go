@@ -0,0 +1,209 @@
+package keycloak
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sikerma/backend/internal/config"
+)
+
+var (
+	// ErrInvalidCredentials dikembalikan bila username/password ditolak Keycloak
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInvalidGrant dikembalikan bila refresh token tidak valid, kadaluarsa atau sudah dicabut
+	ErrInvalidGrant = errors.New("invalid or expired refresh token")
+	// ErrUserNotFound dikembalikan admin API bila user tidak ada di realm
+	ErrUserNotFound = errors.New("keycloak user not found")
+)
+
+// TokenSet adalah respons token endpoint Keycloak
+type TokenSet struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
+	IDToken          string `json:"id_token,omitempty"`
+	Scope            string `json:"scope,omitempty"`
+}
+
+// Client adalah operasi token Keycloak yang dipakai backend
+type Client interface {
+	// PasswordGrant menukar username/password menjadi token (direct access grant)
+	PasswordGrant(ctx context.Context, username, password string) (*TokenSet, error)
+	// RefreshToken menukar refresh token menjadi token baru
+	RefreshToken(ctx context.Context, refreshToken string) (*TokenSet, error)
+	// Logout mencabut refresh token (dan session-nya) di end-session endpoint
+	Logout(ctx context.Context, refreshToken string) error
+	// LogoutUser mengakhiri semua session user lewat admin API
+	LogoutUser(ctx context.Context, userID string) error
+}
+
+// HTTPClient implementasi Client melalui endpoint OpenID Connect Keycloak
+type HTTPClient struct {
+	tokenURL     string
+	logoutURL    string
+	adminURL     string
+	clientID     string
+	clientSecret string
+	http         *http.Client
+}
+
+// oauthError adalah format error OAuth2 dari Keycloak
+type oauthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// NewClient membuat Keycloak client berdasarkan konfigurasi realm
+func NewClient(cfg config.KeycloakConfig) *HTTPClient {
+	root := strings.TrimSuffix(cfg.URL, "/")
+	base := root + "/realms/" + cfg.Realm + "/protocol/openid-connect"
+	return &HTTPClient{
+		tokenURL:     base + "/token",
+		logoutURL:    base + "/logout",
+		adminURL:     root + "/admin/realms/" + cfg.Realm,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		http:         &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// PasswordGrant menukar username/password menjadi token
+func (k *HTTPClient) PasswordGrant(ctx context.Context, username, password string) (*TokenSet, error) {
+	form := url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
+		"scope":      {"openid"},
+	}
+
+	tokens, err := k.requestToken(ctx, form)
+	if errors.Is(err, ErrInvalidGrant) {
+		return nil, ErrInvalidCredentials
+	}
+	return tokens, err
+}
+
+// RefreshToken menukar refresh token menjadi token baru
+func (k *HTTPClient) RefreshToken(ctx context.Context, refreshToken string) (*TokenSet, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+
+	return k.requestToken(ctx, form)
+}
+
+// Logout mencabut refresh token di end-session endpoint
+func (k *HTTPClient) Logout(ctx context.Context, refreshToken string) error {
+	form := url.Values{
+		"refresh_token": {refreshToken},
+	}
+
+	resp, err := k.post(ctx, k.logoutURL, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Keycloak mengembalikan 204 No Content bila berhasil
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	return k.decodeError(resp)
+}
+
+// LogoutUser mengakhiri semua session user (termasuk refresh token) memakai
+// token service account client ini
+func (k *HTTPClient) LogoutUser(ctx context.Context, userID string) error {
+	tokens, err := k.requestToken(ctx, url.Values{"grant_type": {"client_credentials"}})
+	if err != nil {
+		return fmt.Errorf("failed to get service account token: %w", err)
+	}
+
+	endpoint := k.adminURL + "/users/" + url.PathEscape(userID) + "/logout"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create keycloak request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach keycloak: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrUserNotFound
+	default:
+		return k.decodeError(resp)
+	}
+}
+
+// requestToken memanggil token endpoint dan mendekode TokenSet
+func (k *HTTPClient) requestToken(ctx context.Context, form url.Values) (*TokenSet, error) {
+	resp, err := k.post(ctx, k.tokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, k.decodeError(resp)
+	}
+
+	var tokens TokenSet
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	return &tokens, nil
+}
+
+// post mengirim form request dengan kredensial client
+func (k *HTTPClient) post(ctx context.Context, endpoint string, form url.Values) (*http.Response, error) {
+	form.Set("client_id", k.clientID)
+	if k.clientSecret != "" {
+		form.Set("client_secret", k.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create keycloak request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach keycloak: %w", err)
+	}
+
+	return resp, nil
+}
+
+// decodeError memetakan error OAuth2 Keycloak ke sentinel error
+func (k *HTTPClient) decodeError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var oauthErr oauthError
+	_ = json.Unmarshal(body, &oauthErr)
+
+	if oauthErr.Error == "invalid_grant" {
+		return ErrInvalidGrant
+	}
+
+	return fmt.Errorf("keycloak returned status %d: %s %s", resp.StatusCode, oauthErr.Error, oauthErr.ErrorDescription)
+}
@@ -0,0 +1,180 @@
+package keycloak
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/config"
+)
+
+const realmPath = "/realms/pengadilan-agama/protocol/openid-connect"
+
+// newFakeKeycloak menyajikan token, logout dan admin logout endpoint minimal
+func newFakeKeycloak(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	revoked := []string{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(realmPath+"/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "sikerma-tools", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("grant_type") {
+		case "password":
+			if r.PostForm.Get("username") != "operator" || r.PostForm.Get("password") != "rahasia" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"Invalid user credentials"}`))
+				return
+			}
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"Token is not active"}`))
+				return
+			}
+		case "client_credentials":
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"unsupported_grant_type"}`))
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(TokenSet{
+			AccessToken:  "access-2",
+			RefreshToken: "refresh-2",
+			TokenType:    "Bearer",
+			ExpiresIn:    900,
+		})
+	})
+	mux.HandleFunc(realmPath+"/logout", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		revoked = append(revoked, r.PostForm.Get("refresh_token"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/admin/realms/pengadilan-agama/users/{id}/logout", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer access-2", r.Header.Get("Authorization"))
+		if r.PathValue("id") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		revoked = append(revoked, "user:"+r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &revoked
+}
+
+func newTestClient(url string) *HTTPClient {
+	return NewClient(config.KeycloakConfig{
+		URL:          url,
+		Realm:        "pengadilan-agama",
+		ClientID:     "sikerma-tools",
+		ClientSecret: "secret",
+	})
+}
+
+func TestPasswordGrant(t *testing.T) {
+	server, _ := newFakeKeycloak(t)
+	client := newTestClient(server.URL)
+	ctx := context.Background()
+
+	tokens, err := client.PasswordGrant(ctx, "operator", "rahasia")
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", tokens.AccessToken)
+	assert.Equal(t, "refresh-2", tokens.RefreshToken)
+	assert.EqualValues(t, 900, tokens.ExpiresIn)
+
+	_, err = client.PasswordGrant(ctx, "operator", "salah")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestRefreshToken(t *testing.T) {
+	server, _ := newFakeKeycloak(t)
+	client := newTestClient(server.URL)
+	ctx := context.Background()
+
+	tokens, err := client.RefreshToken(ctx, "refresh-1")
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", tokens.AccessToken)
+
+	_, err = client.RefreshToken(ctx, "revoked")
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+func TestLogout(t *testing.T) {
+	server, revoked := newFakeKeycloak(t)
+	client := newTestClient(server.URL)
+
+	require.NoError(t, client.Logout(context.Background(), "refresh-1"))
+	assert.Equal(t, []string{"refresh-1"}, *revoked)
+}
+
+func TestLogoutUser(t *testing.T) {
+	server, revoked := newFakeKeycloak(t)
+	client := newTestClient(server.URL)
+	ctx := context.Background()
+
+	require.NoError(t, client.LogoutUser(ctx, "user-1"))
+	assert.Equal(t, []string{"user:user-1"}, *revoked)
+
+	assert.ErrorIs(t, client.LogoutUser(ctx, "missing"), ErrUserNotFound)
+}
+
+func TestKeycloakUnavailable(t *testing.T) {
+	server, _ := newFakeKeycloak(t)
+	server.Close()
+
+	_, err := newTestClient(server.URL).PasswordGrant(context.Background(), "operator", "rahasia")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLogoutUserTokenThenLogout(t *testing.T) {
+	calls := []string{}
+	adminStatus := http.StatusNoContent
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(realmPath+"/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		calls = append(calls, "token:"+r.PostForm.Get("grant_type"))
+		if r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized_client","error_description":"Client not enabled to retrieve service account"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(TokenSet{AccessToken: "service-account", TokenType: "Bearer", ExpiresIn: 300})
+	})
+	mux.HandleFunc("/admin/realms/pengadilan-agama/users/{id}/logout", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "logout:"+r.PathValue("id")+":"+r.Header.Get("Authorization"))
+		w.WriteHeader(adminStatus)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	require.NoError(t, newTestClient(server.URL).LogoutUser(ctx, "user-1"))
+	assert.Equal(t, []string{"token:client_credentials", "logout:user-1:Bearer service-account"}, calls)
+
+	// Token service account ditolak: admin API tidak dipanggil
+	calls = nil
+	client := NewClient(config.KeycloakConfig{URL: server.URL, Realm: "pengadilan-agama", ClientID: "sikerma-tools", ClientSecret: "salah"})
+	require.Error(t, client.LogoutUser(ctx, "user-1"))
+	assert.Equal(t, []string{"token:client_credentials"}, calls)
+
+	// Service account tanpa manage-users
+	calls = nil
+	adminStatus = http.StatusForbidden
+	require.Error(t, newTestClient(server.URL).LogoutUser(ctx, "user-1"))
+	assert.Len(t, calls, 2)
+}
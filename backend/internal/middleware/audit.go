@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -11,8 +12,8 @@ import (
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/sikerma/backend/internal/audit"
 	"github.com/sikerma/backend/internal/repositories"
 	"github.com/sikerma/backend/internal/utils"
 )
@@ -29,7 +30,7 @@ type AuditConfig struct {
 	Methods []string
 	// Enable PII masking
 	EnablePIIMasking bool
-	// Async logging (tidak blocking request)
+	// Async logging lewat queue AuditWriter (tidak blocking request)
 	AsyncLogging bool
 }
 
@@ -58,14 +59,12 @@ func DefaultAuditConfig() AuditConfig {
 
 // AuditTrail middleware untuk mencatat audit trail ke database
 // dengan PII masking untuk data sensitif
-func AuditTrail(db *pgxpool.Pool, config ...AuditConfig) fiber.Handler {
+func AuditTrail(writer *AuditWriter, config ...AuditConfig) fiber.Handler {
 	cfg := DefaultAuditConfig()
 	if len(config) > 0 {
 		cfg = config[0]
 	}
 
-	auditRepo := repositories.NewAuditRepository(db)
-
 	return func(c fiber.Ctx) error {
 		startTime := time.Now()
 
@@ -88,11 +87,20 @@ func AuditTrail(db *pgxpool.Pool, config ...AuditConfig) fiber.Handler {
 			}
 		}
 
+		// Repository mencatat state sebelum/sesudah ke recorder pada context
+		var recorder *audit.Recorder
+		if shouldCaptureBody {
+			var ctx context.Context
+			ctx, recorder = audit.WithRecorder(c.Context())
+			c.SetContext(ctx)
+		}
+
 		if shouldCaptureBody && len(c.Body()) > 0 {
 			// Read and restore body
 			bodyBytes := c.Body()
 			if err := json.Unmarshal(bodyBytes, &requestBody); err == nil && cfg.EnablePIIMasking {
 				requestBody = utils.MaskSensitiveData(requestBody)
+				requestBody = utils.MaskPII(requestBody, restrictedPathFields(path))
 			}
 		}
 
@@ -102,24 +110,39 @@ func AuditTrail(db *pgxpool.Pool, config ...AuditConfig) fiber.Handler {
 		// Capture response info
 		duration := time.Since(startTime)
 		statusCode := c.Response().StatusCode()
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			statusCode = fiberErr.Code
+		}
 
-		// Determine action based on method
+		// Determine action based on method. Request baca yang ditolak (403)
+		// tetap dicatat agar percobaan akses berulang dapat dideteksi.
 		action := getActionFromMethod(method)
+		if action == "" && statusCode == fiber.StatusForbidden {
+			action = auditActionAccessDenied
+		}
 		if action == "" {
 			return err
 		}
+		override, actionOverridden := c.Locals(auditActionKey).(string)
+		actionOverridden = actionOverridden && override != ""
+		if actionOverridden {
+			action = override
+		}
 
-		// Build audit log
 		requestID := GetRequestID(c)
-		userID := GetUserID(c)
-		username := getUsername(c)
 
 		// Determine resource from path
 		resource := getResourceFromPath(path)
+		if override, ok := c.Locals(auditResourceKey).(string); ok && override != "" {
+			resource = override
+		}
 
 		// Get resource ID if available
 		var resourceID *uuid.UUID
-		if id := c.Params("id"); id != "" {
+		if override, ok := c.Locals(auditResourceIDKey).(uuid.UUID); ok {
+			resourceID = &override
+		} else if id := c.Params("id"); id != "" {
 			if parsedID, parseErr := uuid.Parse(id); parseErr == nil {
 				resourceID = &parsedID
 			}
@@ -127,19 +150,17 @@ func AuditTrail(db *pgxpool.Pool, config ...AuditConfig) fiber.Handler {
 
 		// Build changes
 		changes := map[string]interface{}{
-			"request_id":   requestID,
-			"method":       method,
-			"path":         path,
-			"status_code":  statusCode,
-			"duration_ms":  duration.Milliseconds(),
-			"request_body": requestBody,
+			"request_id":  requestID,
+			"method":      method,
+			"path":        path,
+			"status_code": statusCode,
+			"duration_ms": duration.Milliseconds(),
+		}
+		if details, ok := c.Locals(auditDetailsKey).(map[string]interface{}); ok {
+			for key, value := range details {
+				changes[key] = value
+			}
 		}
-
-		// Get IP address
-		ipAddress := c.IP()
-
-		// Get user agent
-		userAgent := c.Get(fiber.HeaderUserAgent)
 
 		// Determine status
 		status := "success"
@@ -152,49 +173,199 @@ func AuditTrail(db *pgxpool.Pool, config ...AuditConfig) fiber.Handler {
 			}
 		}
 
-		// Create audit input
-		auditInput := repositories.AuditLogInput{
-			UserID:       userID,
-			Username:     username,
-			Action:       action,
-			Resource:     resource,
-			ResourceID:   resourceID,
-			IPAddress:    &ipAddress,
-			UserAgent:    &userAgent,
-			Changes:      changes,
-			Status:       status,
-			ErrorMessage: errorMessage,
+		// Satu entry per mutasi yang dicatat repository; request tanpa mutasi
+		// tercatat sebagai satu entry dengan body request
+		var entries []repositories.AuditLogInput
+		base := NewAuditEntry(c, action, resource)
+		base.ResourceID = resourceID
+		base.Status = status
+		base.ErrorMessage = errorMessage
+
+		var recorded []audit.Change
+		if recorder != nil {
+			recorded = recorder.Changes()
+		}
+		for _, change := range recorded {
+			entry := base
+			if !actionOverridden {
+				entry.Action = change.Action
+			}
+			entry.Resource = change.Resource
+			entry.ResourceID = &change.ResourceID
+			entry.Changes = withDiff(changes, change.Fields)
+			if audit.Revertable(change.Resource) {
+				entry.Snapshot = &change.Snapshot
+			}
+			entries = append(entries, entry)
+		}
+		if len(entries) == 0 {
+			base.Changes = changes
+			base.Changes["request_body"] = requestBody
+			entries = append(entries, base)
 		}
 
 		// Log to console for debugging
 		fmt.Printf("[AUDIT] RequestID: %s | User: %s | Action: %s | Resource: %s | Status: %s | Duration: %v\n",
-			requestID, username, action, resource, status, duration)
+			requestID, base.Username, action, resource, status, duration)
 
 		// Save to database
 		if cfg.AsyncLogging {
-			// Async logging - tidak blocking request
-			go func() {
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				if logErr := auditRepo.Log(ctx, auditInput); logErr != nil {
-					fmt.Printf("[AUDIT ERROR] Failed to save audit log: %v\n", logErr)
-				}
-			}()
+			writer.Enqueue(entries...)
 		} else {
-			// Sync logging
-			if logErr := auditRepo.Log(c.Context(), auditInput); logErr != nil {
-				fmt.Printf("[AUDIT ERROR] Failed to save audit log: %v\n", logErr)
-			}
+			writer.Write(c.Context(), entries...)
+		}
+
+		return err
+	}
+}
+
+// ============================================
+// Read Audit
+// ============================================
+
+// ReadAuditMiddleware mencatat siapa membaca record sensitif. Repository
+// mencatat ID yang dikembalikan lewat audit.RecordAccess, lalu semua ID
+// dalam satu request digabung menjadi satu entry "read".
+type ReadAuditMiddleware struct {
+	writer    *AuditWriter
+	resources map[string]bool
+}
+
+// NewReadAuditMiddleware membuat ReadAuditMiddleware untuk resource yang
+// diaktifkan di konfigurasi
+func NewReadAuditMiddleware(writer *AuditWriter, resources []string) *ReadAuditMiddleware {
+	enabled := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		enabled[resource] = true
+	}
+	return &ReadAuditMiddleware{writer: writer, resources: enabled}
+}
+
+// Audit mencatat akses baca resource pada route ini. Bila resource tidak
+// diaktifkan, request diteruskan tanpa dicatat.
+func (m *ReadAuditMiddleware) Audit(resource string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !m.resources[resource] {
+			return c.Next()
+		}
+
+		ctx, recorder := audit.WithRecorder(c.Context())
+		c.SetContext(ctx)
+
+		err := c.Next()
+
+		ids := recorder.Accessed(resource)
+		if len(ids) == 0 {
+			return err
+		}
+
+		entry := NewAuditEntry(c, audit.ActionRead, resource)
+		if len(ids) == 1 {
+			entry.ResourceID = &ids[0]
+		}
+		statusCode := c.Response().StatusCode()
+		if err != nil || statusCode >= 400 {
+			entry.Status = "failed"
+		}
+
+		// Nilai query (misalnya search berisi NIK) di-mask seperti body request
+		query := map[string]interface{}{}
+		for key, value := range c.Queries() {
+			query[key] = value
+		}
+		entry.Changes = map[string]interface{}{
+			"request_id":   GetRequestID(c),
+			"method":       c.Method(),
+			"path":         c.Path(),
+			"query":        utils.MaskPII(query, []string{"search"}),
+			"status_code":  statusCode,
+			"resource_ids": ids,
+			"count":        len(ids),
 		}
 
+		m.writer.Enqueue(entry)
 		return err
 	}
 }
 
+// NewAuditEntry mengisi aktor, impersonation, IP dan user agent request untuk
+// audit entry yang ditulis langsung oleh handler, misalnya untuk request GET
+// yang tidak dicatat AuditTrail. Status default "success".
+func NewAuditEntry(c fiber.Ctx, action, resource string) repositories.AuditLogInput {
+	ipAddress := c.IP()
+	userAgent := c.Get(fiber.HeaderUserAgent)
+
+	entry := repositories.AuditLogInput{
+		UserID:    GetUserID(c),
+		Username:  getUsername(c),
+		Action:    action,
+		Resource:  resource,
+		IPAddress: &ipAddress,
+		UserAgent: &userAgent,
+		Status:    "success",
+	}
+
+	// Saat impersonation, user_id tetap aktor sebenarnya
+	if imp := GetImpersonation(c); imp != nil {
+		entry.UserID, entry.Username = imp.ActorUserID, imp.ActorUsername
+		entry.ImpersonatedUserID, entry.ImpersonatedUsername = &imp.TargetUserID, &imp.TargetUsername
+		entry.ImpersonationSessionID = &imp.SessionID
+	}
+
+	return entry
+}
+
+// withDiff menyalin metadata request dan menambahkan diff field di key "diff"
+func withDiff(meta map[string]interface{}, diff map[string]audit.FieldChange) map[string]interface{} {
+	changes := make(map[string]interface{}, len(meta)+1)
+	for key, value := range meta {
+		changes[key] = value
+	}
+	changes["diff"] = diff
+	return changes
+}
+
+// ============================================
+// Audit Overrides
+// ============================================
+
+// Locals key untuk override data audit dari handler
+const (
+	auditActionKey     = "auditAction"
+	auditResourceKey   = "auditResource"
+	auditResourceIDKey = "auditResourceID"
+	auditDetailsKey    = "auditDetails"
+)
+
+// SetAuditAction mengganti action audit yang diturunkan dari HTTP method,
+// misalnya "assign" atau "revoke"
+func SetAuditAction(c fiber.Ctx, action string) {
+	c.Locals(auditActionKey, action)
+}
+
+// SetAuditResource mengganti resource dan resource ID audit yang diturunkan dari path
+func SetAuditResource(c fiber.Ctx, resource string, resourceID uuid.UUID) {
+	c.Locals(auditResourceKey, resource)
+	c.Locals(auditResourceIDKey, resourceID)
+}
+
+// AddAuditDetail menambahkan informasi tambahan ke field changes audit log
+func AddAuditDetail(c fiber.Ctx, key string, value interface{}) {
+	details, ok := c.Locals(auditDetailsKey).(map[string]interface{})
+	if !ok {
+		details = map[string]interface{}{}
+		c.Locals(auditDetailsKey, details)
+	}
+	details[key] = value
+}
+
 // ============================================
 // Helper Functions
 // ============================================
 
+// auditActionAccessDenied adalah action untuk request non-mutasi yang ditolak
+const auditActionAccessDenied = "access_denied"
+
 // getActionFromMethod mengembalikan action berdasarkan HTTP method
 func getActionFromMethod(method string) string {
 	switch method {
@@ -234,6 +405,16 @@ func getResourceFromPath(path string) string {
 	return "unknown"
 }
 
+// restrictedPathFields mengumpulkan audit.RestrictedFields dari setiap segmen
+// path, karena sub-resource seperti /pegawai/:id/hukdis tercatat sebagai pegawai
+func restrictedPathFields(path string) []string {
+	var fields []string
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		fields = append(fields, audit.RestrictedFields(part)...)
+	}
+	return fields
+}
+
 // getUsername mendapatkan username dari context
 func getUsername(c fiber.Ctx) string {
 	if username, ok := c.Locals("username").(string); ok {
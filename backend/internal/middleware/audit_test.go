@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/models"
+)
+
+func TestReadAuditAggregatesPerRequest(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	store := &fakeAuditStore{}
+	writer := NewAuditWriter(store, testAuditWriterConfig(t))
+	go writer.Run()
+
+	readAudit := NewReadAuditMiddleware(writer, []string{"pegawai"})
+	app := fiber.New()
+	app.Get("/pegawai", readAudit.Audit("pegawai"), func(c fiber.Ctx) error {
+		c.Locals("username", "operator")
+		audit.RecordAccess(c.Context(), "pegawai", first, second)
+		audit.RecordAccess(c.Context(), "pegawai", second)
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/satker", readAudit.Audit("satker"), func(c fiber.Ctx) error {
+		audit.RecordAccess(c.Context(), "satker", first)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/pegawai?search=3201010101010001", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	resp, err = app.Test(httptest.NewRequest("GET", "/satker", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.NoError(t, writer.Close(context.Background()))
+
+	// Satker tidak diaktifkan sehingga hanya request pegawai yang tercatat
+	entries := store.entries()
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, audit.ActionRead, entry.Action)
+	assert.Equal(t, "pegawai", entry.Resource)
+	assert.Equal(t, "operator", entry.Username)
+	assert.Nil(t, entry.ResourceID)
+	assert.Equal(t, []uuid.UUID{first, second}, entry.Changes["resource_ids"])
+	assert.Equal(t, 2, entry.Changes["count"])
+	assert.NotEqual(t, "3201010101010001", entry.Changes["query"].(map[string]interface{})["search"])
+}
+
+type recordingAlertStore struct {
+	mu     sync.Mutex
+	alerts []models.AuditAlert
+}
+
+func (s *recordingAlertStore) CreateAlert(_ context.Context, alert *models.AuditAlert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, *alert)
+	return nil
+}
+
+func (s *recordingAlertStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.alerts)
+}
+
+func TestAuditTrailFeedsForbiddenReadsToDetector(t *testing.T) {
+	rules, err := audit.BuildRules([]audit.RuleConfig{{
+		Name: "forbidden", Kind: audit.RuleRepeatedForbidden, Severity: audit.SeverityMedium,
+		Threshold: 2, Window: audit.Duration(time.Minute),
+	}}, nil)
+	require.NoError(t, err)
+	alerts := &recordingAlertStore{}
+	detector := audit.NewDetector(rules, alerts, audit.Notifiers{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go detector.Run(ctx)
+
+	store := &fakeAuditStore{}
+	writer := NewAuditWriter(store, testAuditWriterConfig(t)).WithDetector(detector)
+	go writer.Run()
+
+	app := fiber.New()
+	app.Use(AuditTrail(writer))
+	app.Get("/pegawai", func(c fiber.Ctx) error {
+		c.Locals("userID", "u1")
+		return c.SendStatus(fiber.StatusForbidden)
+	})
+	app.Get("/satker", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for _, path := range []string{"/satker", "/pegawai", "/pegawai"} {
+		_, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close(context.Background()))
+
+	// GET yang berhasil tidak dicatat AuditTrail
+	entries := store.entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, auditActionAccessDenied, entries[0].Action)
+	assert.Equal(t, "failed", entries[0].Status)
+	assert.Equal(t, "u1", entries[0].UserID)
+	assert.Eventually(t, func() bool { return alerts.count() == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestRestrictedPathFields(t *testing.T) {
+	id := uuid.New().String()
+	assert.ElementsMatch(t, []string{"alasan", "nomor_sk"}, restrictedPathFields("/api/v1/pegawai/"+id+"/hukdis"))
+	assert.Empty(t, restrictedPathFields("/api/v1/pegawai/"+id+"/riwayat-pangkat"))
+}
@@ -0,0 +1,412 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/config"
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+const (
+	// auditEnqueueWait adalah lama Enqueue menunggu saat queue penuh sebelum
+	// entry ditulis ke file spill
+	auditEnqueueWait = 100 * time.Millisecond
+	// auditFlushTimeout membatasi satu penulisan batch ke database
+	auditFlushTimeout = 10 * time.Second
+)
+
+// AuditStore menyimpan batch audit log ke database
+type AuditStore interface {
+	LogBatch(ctx context.Context, entries []repositories.AuditLogInput) error
+}
+
+// queuedAuditEntry adalah entry di queue dan di file spill
+type queuedAuditEntry struct {
+	Entry      repositories.AuditLogInput `json:"entry"`
+	OccurredAt time.Time                  `json:"occurred_at"`
+}
+
+// AuditWriterStats adalah kondisi queue AuditWriter untuk health check
+type AuditWriterStats struct {
+	Depth    int   `json:"depth"`
+	Capacity int   `json:"capacity"`
+	Written  int64 `json:"written"`
+	Spilled  int64 `json:"spilled"`
+	Replayed int64 `json:"replayed"`
+	Dropped  int64 `json:"dropped"`
+}
+
+// AuditWriter menampung audit log di queue berukuran tetap dan menulisnya ke
+// database per batch. Batch yang gagal ditulis (db_master tidak tersedia)
+// disimpan ke file spill dan diputar ulang secara berkala. Entry hanya
+// hilang (Dropped) bila file spill juga tidak dapat ditulis.
+type AuditWriter struct {
+	store    AuditStore
+	cfg      config.AuditConfig
+	detector *audit.Detector
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan queuedAuditEntry
+	done   chan struct{}
+
+	spillMu sync.Mutex
+
+	written  atomic.Int64
+	spilled  atomic.Int64
+	replayed atomic.Int64
+	dropped  atomic.Int64
+}
+
+// NewAuditWriter membuat AuditWriter; jalankan Run di goroutine terpisah
+func NewAuditWriter(store AuditStore, cfg config.AuditConfig) *AuditWriter {
+	return &AuditWriter{
+		store: store,
+		cfg:   cfg,
+		queue: make(chan queuedAuditEntry, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// WithDetector meneruskan setiap entry baru ke detector aktivitas
+// mencurigakan. Entry hasil replay spill tidak diteruskan karena sudah
+// dievaluasi saat pertama kali dicatat.
+func (w *AuditWriter) WithDetector(d *audit.Detector) *AuditWriter {
+	w.detector = d
+	return w
+}
+
+// Enqueue memasukkan entry ke queue tanpa menunggu penulisan database. Bila
+// queue penuh, Enqueue menunggu sebentar lalu menulis entry ke file spill.
+func (w *AuditWriter) Enqueue(entries ...repositories.AuditLogInput) {
+	now := time.Now()
+	w.detect(now, entries)
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var overflow []queuedAuditEntry
+	for _, entry := range entries {
+		item := queuedAuditEntry{Entry: entry, OccurredAt: now}
+		if w.closed {
+			overflow = append(overflow, item)
+			continue
+		}
+
+		select {
+		case w.queue <- item:
+			continue
+		default:
+		}
+
+		timer := time.NewTimer(auditEnqueueWait)
+		select {
+		case w.queue <- item:
+			timer.Stop()
+		case <-timer.C:
+			overflow = append(overflow, item)
+		}
+	}
+
+	if len(overflow) > 0 {
+		w.spill(overflow)
+	}
+}
+
+// Write langsung menulis entry ke database; bila gagal entry disimpan ke
+// file spill
+func (w *AuditWriter) Write(ctx context.Context, entries ...repositories.AuditLogInput) {
+	now := time.Now()
+	w.detect(now, entries)
+	items := make([]queuedAuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, queuedAuditEntry{Entry: entry, OccurredAt: now})
+	}
+	w.flush(ctx, items)
+}
+
+// Run menulis queue ke database per BatchSize entry atau setiap
+// FlushInterval, dan memutar ulang file spill setiap ReplayInterval. Run
+// selesai setelah Close dipanggil dan queue habis.
+func (w *AuditWriter) Run() {
+	defer close(w.done)
+
+	flushTicker := time.NewTicker(w.cfg.FlushInterval)
+	defer flushTicker.Stop()
+	replayTicker := time.NewTicker(w.cfg.ReplayInterval)
+	defer replayTicker.Stop()
+
+	w.replay()
+
+	batch := make([]queuedAuditEntry, 0, w.cfg.BatchSize)
+	flushBatch := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), auditFlushTimeout)
+		defer cancel()
+		w.flush(ctx, batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case item, ok := <-w.queue:
+			if !ok {
+				flushBatch()
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) >= w.cfg.BatchSize {
+				flushBatch()
+			}
+		case <-flushTicker.C:
+			flushBatch()
+		case <-replayTicker.C:
+			w.replay()
+		}
+	}
+}
+
+// Close berhenti menerima entry baru dan menunggu Run menulis sisa queue.
+// Bila ctx habis lebih dulu, sisa queue ditulis ke file spill.
+func (w *AuditWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	var rest []queuedAuditEntry
+	for item := range w.queue {
+		rest = append(rest, item)
+	}
+	w.spill(rest)
+	return ctx.Err()
+}
+
+// Stats mengembalikan kedalaman queue dan jumlah entry per hasil
+func (w *AuditWriter) Stats() AuditWriterStats {
+	return AuditWriterStats{
+		Depth:    len(w.queue),
+		Capacity: cap(w.queue),
+		Written:  w.written.Load(),
+		Spilled:  w.spilled.Load(),
+		Replayed: w.replayed.Load(),
+		Dropped:  w.dropped.Load(),
+	}
+}
+
+// detect mengirim entries ke detector bila ada
+func (w *AuditWriter) detect(at time.Time, entries []repositories.AuditLogInput) {
+	if w.detector == nil {
+		return
+	}
+	for _, entry := range entries {
+		w.detector.Submit(auditEvent(entry, at))
+	}
+}
+
+// auditEvent mengubah entry audit log menjadi event untuk rule deteksi.
+// Status HTTP dan daftar ID record diambil dari changes yang diisi
+// AuditTrail dan ReadAuditMiddleware.
+func auditEvent(entry repositories.AuditLogInput, at time.Time) audit.Event {
+	e := audit.Event{
+		Time:     at,
+		UserID:   entry.UserID,
+		Username: entry.Username,
+		Action:   entry.Action,
+		Resource: entry.Resource,
+		Status:   entry.Status,
+	}
+	if entry.IPAddress != nil {
+		e.IPAddress = *entry.IPAddress
+	}
+	if code, ok := entry.Changes["status_code"].(int); ok {
+		e.StatusCode = code
+	}
+	switch ids := entry.Changes["resource_ids"].(type) {
+	case []uuid.UUID:
+		e.ResourceIDs = ids
+	default:
+		if entry.ResourceID != nil {
+			e.ResourceIDs = []uuid.UUID{*entry.ResourceID}
+		}
+	}
+	return e
+}
+
+// flush menulis items sebagai satu batch; bila gagal items disimpan ke file spill
+func (w *AuditWriter) flush(ctx context.Context, items []queuedAuditEntry) {
+	if len(items) == 0 {
+		return
+	}
+	if err := w.store.LogBatch(ctx, auditEntries(items, false)); err != nil {
+		logrus.WithError(err).WithField("entries", len(items)).Warn("Failed to write audit logs, spilling to file")
+		w.spill(items)
+		return
+	}
+	w.written.Add(int64(len(items)))
+}
+
+// spill menambahkan items ke file spill
+func (w *AuditWriter) spill(items []queuedAuditEntry) {
+	if len(items) == 0 {
+		return
+	}
+
+	w.spillMu.Lock()
+	defer w.spillMu.Unlock()
+
+	if err := appendAuditSpill(w.cfg.SpillFile, items); err != nil {
+		logrus.WithError(err).WithField("entries", len(items)).Error("Failed to spill audit logs, entries dropped")
+		w.dropped.Add(int64(len(items)))
+		return
+	}
+	w.spilled.Add(int64(len(items)))
+}
+
+// replay memutar ulang file spill ke database. File dipindah ke
+// <spill>.replay lebih dulu sehingga spill baru tidak tercampur; bila
+// database masih gagal, sisa entry ditulis kembali ke file replay dan dicoba
+// lagi pada putaran berikutnya. Batch yang sudah commit sebelum proses mati
+// dapat tercatat dua kali.
+func (w *AuditWriter) replay() {
+	replayFile := w.cfg.SpillFile + ".replay"
+
+	w.spillMu.Lock()
+	_, err := os.Stat(replayFile)
+	if errors.Is(err, os.ErrNotExist) {
+		err = os.Rename(w.cfg.SpillFile, replayFile)
+	}
+	w.spillMu.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to prepare audit spill replay")
+		return
+	}
+
+	items, err := readAuditSpill(replayFile)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read audit spill file")
+		return
+	}
+
+	for start := 0; start < len(items); start += w.cfg.BatchSize {
+		end := min(start+w.cfg.BatchSize, len(items))
+		ctx, cancel := context.WithTimeout(context.Background(), auditFlushTimeout)
+		err := w.store.LogBatch(ctx, auditEntries(items[start:end], true))
+		cancel()
+		if err != nil {
+			logrus.WithError(err).WithField("pending", len(items)-start).Warn("Audit spill replay postponed")
+			if err := writeAuditSpill(replayFile, items[start:]); err != nil {
+				logrus.WithError(err).Error("Failed to rewrite audit spill file")
+			}
+			return
+		}
+		w.replayed.Add(int64(end - start))
+	}
+
+	if err := os.Remove(replayFile); err != nil {
+		logrus.WithError(err).Error("Failed to remove replayed audit spill file")
+	}
+	if len(items) > 0 {
+		logrus.WithField("entries", len(items)).Info("Replayed spilled audit logs")
+	}
+}
+
+// auditEntries mengambil entry dari items. Entry hasil replay diberi
+// changes.occurred_at karena created_at-nya adalah waktu replay.
+func auditEntries(items []queuedAuditEntry, replayed bool) []repositories.AuditLogInput {
+	entries := make([]repositories.AuditLogInput, 0, len(items))
+	for _, item := range items {
+		entry := item.Entry
+		if replayed {
+			changes := make(map[string]interface{}, len(entry.Changes)+1)
+			for key, value := range entry.Changes {
+				changes[key] = value
+			}
+			changes["occurred_at"] = item.OccurredAt.Format(time.RFC3339Nano)
+			entry.Changes = changes
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// appendAuditSpill menambahkan items ke akhir file JSONL
+func appendAuditSpill(path string, items []queuedAuditEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// writeAuditSpill mengganti isi file dengan items
+func writeAuditSpill(path string, items []queuedAuditEntry) error {
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := appendAuditSpill(tmp, items); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readAuditSpill membaca seluruh entry dari file JSONL. Baris terakhir yang
+// terpotong (proses mati saat menulis) dilewati.
+func readAuditSpill(path string) ([]queuedAuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	items := []queuedAuditEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var item queuedAuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"file": path, "line": line}).Warn("Skipping invalid audit spill line")
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/config"
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+type fakeAuditStore struct {
+	mu      sync.Mutex
+	down    bool
+	batches [][]repositories.AuditLogInput
+}
+
+func (f *fakeAuditStore) LogBatch(_ context.Context, entries []repositories.AuditLogInput) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return errors.New("db_master unavailable")
+	}
+	f.batches = append(f.batches, entries)
+	return nil
+}
+
+func (f *fakeAuditStore) setDown(down bool) {
+	f.mu.Lock()
+	f.down = down
+	f.mu.Unlock()
+}
+
+func (f *fakeAuditStore) entries() []repositories.AuditLogInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all := []repositories.AuditLogInput{}
+	for _, batch := range f.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func testAuditWriterConfig(t *testing.T) config.AuditConfig {
+	return config.AuditConfig{
+		QueueSize:      100,
+		BatchSize:      10,
+		FlushInterval:  time.Hour,
+		SpillFile:      filepath.Join(t.TempDir(), "audit-spill.jsonl"),
+		ReplayInterval: time.Hour,
+	}
+}
+
+func auditInputs(n int) []repositories.AuditLogInput {
+	entries := make([]repositories.AuditLogInput, n)
+	for i := range entries {
+		entries[i] = repositories.AuditLogInput{
+			Username: "admin",
+			Action:   "update",
+			Resource: "pegawai",
+			Status:   "success",
+			Changes:  map[string]interface{}{"n": float64(i)},
+		}
+	}
+	return entries
+}
+
+func TestAuditWriterBatchesAndDrainsOnClose(t *testing.T) {
+	store := &fakeAuditStore{}
+	writer := NewAuditWriter(store, testAuditWriterConfig(t))
+	go writer.Run()
+
+	writer.Enqueue(auditInputs(25)...)
+	require.NoError(t, writer.Close(context.Background()))
+
+	assert.Len(t, store.entries(), 25)
+	for _, batch := range store.batches {
+		assert.LessOrEqual(t, len(batch), 10)
+	}
+	stats := writer.Stats()
+	assert.Equal(t, int64(25), stats.Written)
+	assert.Zero(t, stats.Depth)
+	assert.Zero(t, stats.Dropped)
+
+	// Entry setelah Close tidak hilang tetapi masuk file spill
+	writer.Enqueue(auditInputs(1)...)
+	assert.Equal(t, int64(1), writer.Stats().Spilled)
+}
+
+func TestAuditWriterSpillsAndReplays(t *testing.T) {
+	store := &fakeAuditStore{down: true}
+	cfg := testAuditWriterConfig(t)
+
+	writer := NewAuditWriter(store, cfg)
+	go writer.Run()
+	writer.Enqueue(auditInputs(15)...)
+	require.NoError(t, writer.Close(context.Background()))
+
+	assert.Empty(t, store.entries())
+	assert.Equal(t, int64(15), writer.Stats().Spilled)
+
+	// Instance berikutnya memutar ulang file spill saat start
+	store.setDown(false)
+	next := NewAuditWriter(store, cfg)
+	go next.Run()
+	require.NoError(t, next.Close(context.Background()))
+
+	replayed := store.entries()
+	require.Len(t, replayed, 15)
+	assert.Equal(t, int64(15), next.Stats().Replayed)
+	assert.Equal(t, float64(3), replayed[3].Changes["n"])
+	assert.NotEmpty(t, replayed[3].Changes["occurred_at"])
+	assert.NoFileExists(t, cfg.SpillFile)
+	assert.NoFileExists(t, cfg.SpillFile+".replay")
+}
+
+func TestAuditWriterReplayKeepsPendingOnFailure(t *testing.T) {
+	store := &fakeAuditStore{}
+	cfg := testAuditWriterConfig(t)
+	require.NoError(t, appendAuditSpill(cfg.SpillFile, []queuedAuditEntry{
+		{Entry: auditInputs(1)[0], OccurredAt: time.Now()},
+		{Entry: auditInputs(1)[0], OccurredAt: time.Now()},
+	}))
+
+	store.setDown(true)
+	writer := NewAuditWriter(store, cfg)
+	writer.replay()
+
+	pending, err := readAuditSpill(cfg.SpillFile + ".replay")
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	store.setDown(false)
+	writer.replay()
+	assert.Len(t, store.entries(), 2)
+	assert.NoFileExists(t, cfg.SpillFile+".replay")
+}
+
+func TestAuditWriterQueueFullSpills(t *testing.T) {
+	store := &fakeAuditStore{}
+	cfg := testAuditWriterConfig(t)
+	cfg.QueueSize = 2
+
+	// Run belum berjalan sehingga queue tidak dikosongkan
+	writer := NewAuditWriter(store, cfg)
+	writer.Enqueue(auditInputs(5)...)
+
+	stats := writer.Stats()
+	assert.Equal(t, 2, stats.Depth)
+	assert.Equal(t, int64(3), stats.Spilled)
+}
@@ -1,28 +1,179 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sikerma/backend/internal/apikey"
+	"github.com/sikerma/backend/internal/config"
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/models"
 )
 
+// forcedRefreshInterval membatasi refresh JWKS paksa ketika token memakai kid
+// yang belum dikenal, agar token palsu tidak bisa membanjiri Keycloak
+const forcedRefreshInterval = 10 * time.Second
+
 // AuthMiddleware untuk verifikasi JWT dari Keycloak
 type AuthMiddleware struct {
-	jwksURL string
-	issuer   string
-	audience string
+	jwksURL   string
+	issuer    string
+	audiences []string
+	clockSkew time.Duration
+
+	cache  *jwk.Cache
+	cancel context.CancelFunc
+
+	refreshMu   sync.Mutex
+	lastRefresh time.Time
+
+	apiKeys     APIKeyAuthenticator
+	roles       *RoleHierarchy
+	revocations *RevocationList
+}
+
+// APIKeyAuthenticator memverifikasi API key dari header X-API-Key
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, rawKey, clientIP string) (*models.APIKey, error)
+}
+
+// NewAuthMiddleware membuat auth middleware baru. Key set Keycloak di-cache dan
+// di-refresh di background; bila Keycloak sedang tidak bisa dihubungi, key
+// terakhir yang valid tetap dipakai.
+func NewAuthMiddleware(cfg config.KeycloakConfig) *AuthMiddleware {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	am := &AuthMiddleware{
+		jwksURL:   cfg.JWKSURL,
+		issuer:    cfg.Issuer,
+		audiences: splitList(cfg.Audience),
+		clockSkew: cfg.ClockSkew,
+		cancel:    cancel,
+	}
+
+	am.cache = jwk.NewCache(ctx, jwk.WithErrSink(jwkErrSink{}))
+	registerOptions := []jwk.RegisterOption{}
+	if cfg.JWKSRefreshInterval > 0 {
+		registerOptions = append(registerOptions, jwk.WithRefreshInterval(cfg.JWKSRefreshInterval))
+	}
+	if err := am.cache.Register(cfg.JWKSURL, registerOptions...); err != nil {
+		logrus.WithError(err).Error("Failed to register JWKS URL")
+	}
+
+	// Prefetch; kegagalan di sini tidak fatal karena Get akan mencoba lagi
+	if _, err := am.cache.Refresh(ctx, cfg.JWKSURL); err != nil {
+		logrus.WithError(err).Warn("Initial JWKS fetch failed, will retry on demand")
+	}
+
+	return am
+}
+
+// WithAPIKeys mengaktifkan autentikasi API key sebagai alternatif Bearer JWT
+func (am *AuthMiddleware) WithAPIKeys(authenticator APIKeyAuthenticator) *AuthMiddleware {
+	am.apiKeys = authenticator
+	return am
+}
+
+// WithRoleHierarchy memetakan role token ke prioritas dan composite app_roles.
+// Tanpa hierarki, role token dipakai apa adanya dan role utama selalu "user".
+func (am *AuthMiddleware) WithRoleHierarchy(roles *RoleHierarchy) *AuthMiddleware {
+	am.roles = roles
+	return am
+}
+
+// WithRevocations menolak access token yang sudah dicabut (logout atau force logout)
+func (am *AuthMiddleware) WithRevocations(revocations *RevocationList) *AuthMiddleware {
+	am.revocations = revocations
+	return am
+}
+
+// ResolveRoles memetakan role realm dan client pada claims ke hierarki app_roles
+func (am *AuthMiddleware) ResolveRoles(ctx context.Context, claims TokenClaims) ResolvedRoles {
+	return am.roles.Resolve(ctx, claims.Roles, claims.ClientRoles)
+}
+
+// Close menghentikan refresh JWKS di background
+func (am *AuthMiddleware) Close() {
+	am.cancel()
+}
+
+// VerifyToken memverifikasi signature dan claims (iss, aud/azp, exp, nbf) token
+func (am *AuthMiddleware) VerifyToken(ctx context.Context, tokenString string) (jwt.Token, error) {
+	keySet, err := am.keySetFor(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt.ParseString(tokenString,
+		jwt.WithKeySet(keySet, jws.WithInferAlgorithmFromKey(true)),
+		jwt.WithValidate(true),
+		jwt.WithIssuer(am.issuer),
+		jwt.WithAcceptableSkew(am.clockSkew),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithValidator(jwt.ValidatorFunc(am.validateAudience)),
+	)
+}
+
+// keySetFor mengambil key set dari cache, me-refresh paksa bila kid token
+// belum ada (misalnya setelah rotasi key di Keycloak)
+func (am *AuthMiddleware) keySetFor(ctx context.Context, tokenString string) (jwk.Set, error) {
+	keySet, err := am.cache.Get(ctx, am.jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get JWKS: %w", err)
+	}
+
+	kid := tokenKeyID(tokenString)
+	if kid == "" {
+		return keySet, nil
+	}
+	if _, ok := keySet.LookupKeyID(kid); ok {
+		return keySet, nil
+	}
+
+	am.refreshMu.Lock()
+	defer am.refreshMu.Unlock()
+	if time.Since(am.lastRefresh) < forcedRefreshInterval {
+		return keySet, nil
+	}
+	am.lastRefresh = time.Now()
+
+	refreshed, err := am.cache.Refresh(ctx, am.jwksURL)
+	if err != nil {
+		logrus.WithError(err).Warn("JWKS refresh for unknown key id failed, using cached keys")
+		return keySet, nil
+	}
+	return refreshed, nil
 }
 
-// NewAuthMiddleware membuat auth middleware baru
-func NewAuthMiddleware(jwksURL, realm string) *AuthMiddleware {
-	return &AuthMiddleware{
-		jwksURL: jwksURL,
-		issuer:   fmt.Sprintf("http://localhost:8081/realms/%s", realm),
-		audience: "account",
+// validateAudience menerima token bila salah satu aud atau azp ada di daftar audience
+func (am *AuthMiddleware) validateAudience(_ context.Context, token jwt.Token) jwt.ValidationError {
+	if len(am.audiences) == 0 {
+		return nil
+	}
+
+	for _, aud := range token.Audience() {
+		if containsString(am.audiences, aud) {
+			return nil
+		}
 	}
+
+	if azpValue, ok := token.Get("azp"); ok {
+		if azp, ok := azpValue.(string); ok && containsString(am.audiences, azp) {
+			return nil
+		}
+	}
+
+	return jwt.ErrInvalidAudience()
 }
 
 // Authenticate verifikasi JWT token dari Authorization header
@@ -32,6 +183,8 @@ func (am *AuthMiddleware) Authenticate() fiber.Handler {
 		publicPaths := []string{
 			"/health",
 			"/api/v1/auth/login",
+			"/api/v1/auth/refresh",
+			"/api/v1/auth/logout",
 			"/api/v1/public",
 		}
 
@@ -43,6 +196,12 @@ func (am *AuthMiddleware) Authenticate() fiber.Handler {
 
 		// Get token from Authorization header
 		authHeader := c.Get("Authorization")
+
+		// Client mesin mengirim X-API-Key tanpa Bearer token
+		if rawKey := c.Get(apikey.Header); authHeader == "" && rawKey != "" && am.apiKeys != nil {
+			return am.authenticateAPIKey(c, rawKey)
+		}
+
 		if authHeader == "" {
 			return c.Status(401).JSON(fiber.Map{
 				"error": true,
@@ -65,128 +224,99 @@ func (am *AuthMiddleware) Authenticate() fiber.Handler {
 
 		tokenString := parts[1]
 
-		// Fetch JWKS from Keycloak
-		jwks, err := jwk.Fetch(c.Context(), am.jwksURL)
+		// Verify JWT terhadap key set Keycloak
+		parsedToken, err := am.VerifyToken(c.Context(), tokenString)
 		if err != nil {
-			fmt.Printf("[ERROR] Failed to fetch JWKS: %v\n", err)
+			logrus.WithError(err).WithField("request_id", GetRequestID(c)).Warn("Token verification failed")
+			message := "Invalid token"
+			if errors.Is(err, jwt.ErrTokenExpired()) {
+				message = "Token expired"
+			}
 			return c.Status(401).JSON(fiber.Map{
 				"error": true,
-				"message": "Failed to verify token",
+				"message": message,
 				"code": 401,
 				"request_id": GetRequestID(c),
 			})
 		}
 
-		// Parse and verify JWT
-		parsedToken, err := jwt.ParseString(tokenString,
-			jwt.WithKeySet(jwks),
-		)
-		if err != nil {
-			fmt.Printf("[ERROR] Failed to parse JWT: %v\n", err)
+		// Extract claims
+		claims := ParseClaims(parsedToken)
+		if claims.Subject == "" {
 			return c.Status(401).JSON(fiber.Map{
 				"error": true,
-				"message": "Invalid token",
+				"message": "Invalid token: missing subject",
 				"code": 401,
 				"request_id": GetRequestID(c),
 			})
 		}
 
-		// Extract claims
-		sub := parsedToken.Subject()
-		if sub == "" {
+		if am.revocations.IsRevoked(claims) {
 			return c.Status(401).JSON(fiber.Map{
 				"error": true,
-				"message": "Invalid token: missing subject",
+				"message": "Token revoked",
 				"code": 401,
 				"request_id": GetRequestID(c),
 			})
 		}
 
-		// Extract roles from realm_access claim
-		roles := make([]string, 0)
-		if realmAccessValue, ok := parsedToken.Get("realm_access"); ok {
-			if realmAccess, ok := realmAccessValue.(map[string]interface{}); ok {
-				if rolesList, ok := realmAccess["roles"].([]interface{}); ok {
-					for _, role := range rolesList {
-						if roleStr, ok := role.(string); ok {
-							roles = append(roles, roleStr)
-						}
-					}
-				}
-			}
-		}
-
-		// Extract preferred_username
-		preferredUsername := ""
-		if usernameValue, ok := parsedToken.Get("preferred_username"); ok {
-			if username, ok := usernameValue.(string); ok {
-				preferredUsername = username
-			}
-		}
-
-		// Extract email
-		email := ""
-		if emailValue, ok := parsedToken.Get("email"); ok {
-			if emailClaim, ok := emailValue.(string); ok {
-				email = emailClaim
-			}
+		// Set user info to context
+		c.Locals("userID", claims.Subject)
+		roles := am.ResolveRoles(c.Context(), claims)
+		c.Locals("userRole", roles.Primary)
+		c.Locals("userRoles", roles.Roles)
+		c.Locals("username", claims.Username)
+		c.Locals("email", claims.Email)
+		c.Locals("name", claims.Name)
+		if claims.UnitKerjaID != "" {
+			c.Locals("unitKerjaID", claims.UnitKerjaID)
 		}
-
-		// Extract name
-		name := ""
-		if nameValue, ok := parsedToken.Get("name"); ok {
-			if nameClaim, ok := nameValue.(string); ok {
-				name = nameClaim
-			}
+		if claims.SatkerID != "" {
+			c.Locals("satkerID", claims.SatkerID)
 		}
 
-		// Set user info to context
-		c.Locals("userID", sub)
-		c.Locals("userRole", am.getHighestRole(roles))
-		c.Locals("userRoles", roles)
-		c.Locals("username", preferredUsername)
-		c.Locals("email", email)
-		c.Locals("name", name)
-
 		return c.Next()
 	}
 }
 
-// getHighestRole mengambil role tertinggi berdasarkan prioritas
-func (am *AuthMiddleware) getHighestRole(roles []string) string {
-	rolePriority := map[string]int{
-		"admin":      100,
-		"supervisor": 80,
-		"officer":    60,
-		"staff":      40,
-		"user":       20,
+// authenticateAPIKey mengisi locals yang sama dengan Bearer JWT berdasarkan
+// owner API key, ditambah scope key yang dibatasi RequirePermission
+func (am *AuthMiddleware) authenticateAPIKey(c fiber.Ctx, rawKey string) error {
+	key, err := am.apiKeys.AuthenticateAPIKey(c.Context(), rawKey, c.IP())
+	if errors.Is(err, apikey.ErrInvalid) {
+		return c.Status(401).JSON(fiber.Map{
+			"error":      true,
+			"message":    "Invalid API key",
+			"code":       401,
+			"request_id": GetRequestID(c),
+		})
 	}
-
-	highestRole := "user"
-	highestPriority := 0
-
-	for _, role := range roles {
-		if priority, ok := rolePriority[role]; ok && priority > highestPriority {
-			highestPriority = priority
-			highestRole = role
-		}
+	if err != nil {
+		logrus.WithError(err).WithField("request_id", GetRequestID(c)).Error("API key verification failed")
+		return appErrors.InternalError(appErrors.SysDatabaseError).ToFiberResponse(c, fiber.StatusInternalServerError)
 	}
 
-	return highestRole
+	c.Locals("userID", key.OwnerUserID)
+	roles := am.roles.Resolve(c.Context(), key.OwnerRoles, nil)
+	c.Locals("userRole", roles.Primary)
+	c.Locals("userRoles", roles.Roles)
+	c.Locals("username", "apikey:"+key.Nama)
+	c.Locals("authMethod", "api_key")
+	c.Locals("apiKeyID", key.ID.String())
+	c.Locals("apiKeyScopes", key.Scopes)
+
+	return c.Next()
 }
 
-// RequireRole middleware untuk memeriksa apakah user memiliki role yang diperlukan
+// RequireRole middleware untuk memeriksa apakah user memiliki salah satu role
+// yang diperlukan, termasuk role yang diwarisi lewat composite. Tidak ada
+// bypass khusus admin: admin lolos karena mewarisi role lain di app_roles.
 func RequireRole(allowedRoles []string) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		userRole := GetUserRole(c)
-
-		// Admin bypasses role checks
-		if userRole == "admin" {
-			return c.Next()
-		}
+		userRoles := GetUserRoles(c)
 
 		for _, role := range allowedRoles {
-			if userRole == role {
+			if containsString(userRoles, role) {
 				return c.Next()
 			}
 		}
@@ -200,22 +330,124 @@ func RequireRole(allowedRoles []string) fiber.Handler {
 	}
 }
 
-// RequirePermission middleware untuk memeriksa permissions spesifik
-// Ini akan mengambil permission dari database RBAC
-func RequirePermission(permission string) fiber.Handler {
-	return func(c fiber.Ctx) error {
-		userID := GetUserID(c)
-		userRole := GetUserRole(c)
+// TokenClaims adalah claim identitas user dari access token Keycloak
+type TokenClaims struct {
+	Subject  string
+	Username string
+	Email    string
+	Name     string
+	Roles    []string
+	// ClientRoles berisi resource_access.<client>.roles per client ID
+	ClientRoles map[string][]string
+	// UnitKerjaID dan SatkerID berasal dari custom claim (protocol mapper atribut user)
+	UnitKerjaID string
+	SatkerID    string
+	// JTI, SessionID (sid), IssuedAt dan ExpiresAt dipakai untuk pencabutan token
+	JTI       string
+	SessionID string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// ParseClaims mengambil claim identitas dari token yang sudah diverifikasi
+func ParseClaims(token jwt.Token) TokenClaims {
+	claims := TokenClaims{
+		Subject:     token.Subject(),
+		Roles:       make([]string, 0),
+		ClientRoles: make(map[string][]string),
+	}
+
+	// Realm role dari realm_access.roles
+	if realmAccess, ok := token.Get("realm_access"); ok {
+		claims.Roles = append(claims.Roles, accessRoles(realmAccess)...)
+	}
 
-		// Admin bypasses permission checks
-		if userRole == "admin" {
-			return c.Next()
+	// Client role dari resource_access.<client>.roles
+	if resourceAccessValue, ok := token.Get("resource_access"); ok {
+		if resourceAccess, ok := resourceAccessValue.(map[string]interface{}); ok {
+			for client, access := range resourceAccess {
+				if roles := accessRoles(access); len(roles) > 0 {
+					claims.ClientRoles[client] = roles
+				}
+			}
 		}
+	}
 
-		// TODO: Implement RBAC permission check from database
-		// Untuk saat ini, kita allow semua request
-		fmt.Printf("[INFO] Checking permission %s for user %s (role: %s)\n", permission, userID, userRole)
+	claims.Username = stringClaim(token, "preferred_username")
+	claims.Email = stringClaim(token, "email")
+	claims.Name = stringClaim(token, "name")
+	claims.UnitKerjaID = stringClaim(token, "unit_kerja_id")
+	claims.SatkerID = stringClaim(token, "satker_id")
+	claims.JTI = token.JwtID()
+	claims.SessionID = stringClaim(token, "sid")
+	claims.IssuedAt = token.IssuedAt()
+	claims.ExpiresAt = token.Expiration()
 
-		return c.Next()
+	return claims
+}
+
+// accessRoles membaca array "roles" dari objek realm_access/resource_access
+func accessRoles(value interface{}) []string {
+	roles := []string{}
+	access, ok := value.(map[string]interface{})
+	if !ok {
+		return roles
+	}
+	list, ok := access["roles"].([]interface{})
+	if !ok {
+		return roles
 	}
+	for _, role := range list {
+		if roleStr, ok := role.(string); ok {
+			roles = append(roles, roleStr)
+		}
+	}
+	return roles
+}
+
+// stringClaim mengambil claim bertipe string, kosong bila tidak ada
+func stringClaim(token jwt.Token, name string) string {
+	if value, ok := token.Get(name); ok {
+		if str, ok := value.(string); ok {
+			return str
+		}
+	}
+	return ""
+}
+
+// tokenKeyID membaca header kid dari JWS tanpa verifikasi
+func tokenKeyID(tokenString string) string {
+	msg, err := jws.ParseString(tokenString)
+	if err != nil || len(msg.Signatures()) == 0 {
+		return ""
+	}
+	return msg.Signatures()[0].ProtectedHeaders().KeyID()
+}
+
+// splitList memecah string comma-separated menjadi slice tanpa elemen kosong
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// containsString mengecek apakah value ada di dalam list
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// jwkErrSink meneruskan error refresh JWKS di background ke logger
+type jwkErrSink struct{}
+
+func (jwkErrSink) Error(err error) {
+	logrus.WithError(err).Warn("Background JWKS refresh failed, keeping last good keys")
 }
@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/apikey"
+	"github.com/sikerma/backend/internal/config"
+	"github.com/sikerma/backend/internal/models"
+)
+
+const testIssuer = "http://keycloak.test/realms/pengadilan-agama"
+
+// jwksServer menyajikan JWKS yang key-nya bisa diganti untuk mensimulasikan rotasi
+type jwksServer struct {
+	mu     sync.Mutex
+	set    jwk.Set
+	down   bool
+	server *httptest.Server
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+	s := &jwksServer{set: jwk.NewSet()}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.set)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// rotate membuat key baru dan menjadikannya satu-satunya key di JWKS
+func (s *jwksServer) rotate(t *testing.T, kid string) jwk.Key {
+	t.Helper()
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	private, err := jwk.FromRaw(raw)
+	require.NoError(t, err)
+	require.NoError(t, private.Set(jwk.KeyIDKey, kid))
+	require.NoError(t, private.Set(jwk.AlgorithmKey, jwa.RS256))
+
+	public, err := private.PublicKey()
+	require.NoError(t, err)
+
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(public))
+
+	s.mu.Lock()
+	s.set = set
+	s.mu.Unlock()
+	return private
+}
+
+func (s *jwksServer) setDown(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+func signToken(t *testing.T, key jwk.Key, mutate func(jwt.Token)) string {
+	t.Helper()
+	token := jwt.New()
+	require.NoError(t, token.Set(jwt.SubjectKey, "user-1"))
+	require.NoError(t, token.Set(jwt.IssuerKey, testIssuer))
+	require.NoError(t, token.Set(jwt.AudienceKey, []string{"backend-api"}))
+	require.NoError(t, token.Set(jwt.ExpirationKey, time.Now().Add(5*time.Minute)))
+	require.NoError(t, token.Set("preferred_username", "198001012005011001"))
+	if mutate != nil {
+		mutate(token)
+	}
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.RS256, key))
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func newTestAuthMiddleware(t *testing.T, jwksURL string) *AuthMiddleware {
+	t.Helper()
+	am := NewAuthMiddleware(config.KeycloakConfig{
+		JWKSURL:             jwksURL,
+		Issuer:              testIssuer,
+		Audience:            "backend-api,portal-client",
+		ClockSkew:           30 * time.Second,
+		JWKSRefreshInterval: time.Hour,
+	})
+	t.Cleanup(am.Close)
+	return am
+}
+
+func TestVerifyTokenClaims(t *testing.T) {
+	keys := newJWKSServer(t)
+	key := keys.rotate(t, "kid-1")
+	am := newTestAuthMiddleware(t, keys.server.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		mutate  func(jwt.Token)
+		wantErr bool
+	}{
+		{name: "valid token", mutate: nil},
+		{name: "wrong issuer", mutate: func(tok jwt.Token) {
+			_ = tok.Set(jwt.IssuerKey, "http://localhost:8081/realms/other")
+		}, wantErr: true},
+		{name: "unknown audience", mutate: func(tok jwt.Token) {
+			_ = tok.Set(jwt.AudienceKey, []string{"account"})
+		}, wantErr: true},
+		{name: "audience via azp", mutate: func(tok jwt.Token) {
+			_ = tok.Set(jwt.AudienceKey, []string{"account"})
+			_ = tok.Set("azp", "portal-client")
+		}},
+		{name: "expired within skew", mutate: func(tok jwt.Token) {
+			_ = tok.Set(jwt.ExpirationKey, time.Now().Add(-10*time.Second))
+		}},
+		{name: "expired beyond skew", mutate: func(tok jwt.Token) {
+			_ = tok.Set(jwt.ExpirationKey, time.Now().Add(-2*time.Minute))
+		}, wantErr: true},
+		{name: "not yet valid", mutate: func(tok jwt.Token) {
+			_ = tok.Set(jwt.NotBeforeKey, time.Now().Add(2*time.Minute))
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := am.VerifyToken(ctx, signToken(t, key, tt.mutate))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenKeyRotation(t *testing.T) {
+	keys := newJWKSServer(t)
+	oldKey := keys.rotate(t, "kid-1")
+	am := newTestAuthMiddleware(t, keys.server.URL)
+	ctx := context.Background()
+
+	_, err := am.VerifyToken(ctx, signToken(t, oldKey, nil))
+	require.NoError(t, err)
+
+	newKey := keys.rotate(t, "kid-2")
+	_, err = am.VerifyToken(ctx, signToken(t, newKey, nil))
+	assert.NoError(t, err, "unknown kid should trigger a JWKS refresh")
+}
+
+func TestVerifyTokenKeycloakDown(t *testing.T) {
+	keys := newJWKSServer(t)
+	key := keys.rotate(t, "kid-1")
+	am := newTestAuthMiddleware(t, keys.server.URL)
+	ctx := context.Background()
+
+	keys.setDown(true)
+	_, err := am.cache.Refresh(ctx, keys.server.URL)
+	require.Error(t, err)
+
+	_, err = am.VerifyToken(ctx, signToken(t, key, nil))
+	assert.NoError(t, err, "last good keys should still be served")
+}
+
+func TestAuthenticateSetsLocals(t *testing.T) {
+	keys := newJWKSServer(t)
+	key := keys.rotate(t, "kid-1")
+	am := newTestAuthMiddleware(t, keys.server.URL)
+
+	app := fiber.New()
+	app.Get("/api/v1/me", am.Authenticate(), func(c fiber.Ctx) error {
+		return c.SendString(GetUserID(c) + "|" + c.Locals("username").(string))
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, key, nil))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "/api/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, key, func(tok jwt.Token) {
+		_ = tok.Set(jwt.IssuerKey, "http://evil.test/realms/pengadilan-agama")
+	}))
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+type fakeAPIKeys struct {
+	keys map[string]*models.APIKey
+}
+
+func (f *fakeAPIKeys) AuthenticateAPIKey(_ context.Context, rawKey, _ string) (*models.APIKey, error) {
+	if key, ok := f.keys[rawKey]; ok {
+		return key, nil
+	}
+	return nil, apikey.ErrInvalid
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	keys := newJWKSServer(t)
+	key := keys.rotate(t, "kid-1")
+	am := newTestAuthMiddleware(t, keys.server.URL).
+		WithAPIKeys(&fakeAPIKeys{keys: map[string]*models.APIKey{
+			"sk_valid": {Nama: "laporan", OwnerUserID: "svc-laporan", OwnerRoles: []string{"staff"}, Scopes: []string{"kepegawaian.read"}},
+		}}).
+		WithRoleHierarchy(NewRoleHierarchy(newTestRoleSource(), time.Minute))
+
+	app := fiber.New()
+	app.Get("/api/v1/me", am.Authenticate(), func(c fiber.Ctx) error {
+		scopes, _ := GetAPIKeyScopes(c)
+		return c.JSON(fiber.Map{"user": GetUserID(c), "role": GetUserRole(c), "scopes": scopes})
+	})
+
+	do := func(header, value string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/v1/me", nil)
+		req.Header.Set(header, value)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("valid key populates locals", func(t *testing.T) {
+		resp := do(apikey.Header, "sk_valid")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "svc-laporan", body["user"])
+		assert.Equal(t, "staff", body["role"])
+		assert.Equal(t, []interface{}{"kepegawaian.read"}, body["scopes"])
+	})
+
+	t.Run("unknown key is rejected", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusUnauthorized, do(apikey.Header, "sk_other").StatusCode)
+	})
+
+	t.Run("bearer token still accepted", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusOK, do("Authorization", "Bearer "+signToken(t, key, nil)).StatusCode)
+	})
+}
+
+func TestAuthenticateResolvesClientRoles(t *testing.T) {
+	keys := newJWKSServer(t)
+	key := keys.rotate(t, "kid-1")
+	am := newTestAuthMiddleware(t, keys.server.URL).
+		WithRoleHierarchy(NewRoleHierarchy(newTestRoleSource(), time.Minute))
+
+	app := fiber.New()
+	app.Get("/api/v1/me", am.Authenticate(), RequireRole([]string{"staff"}), func(c fiber.Ctx) error {
+		return c.SendString(GetUserRole(c))
+	})
+
+	token := signToken(t, key, func(tok jwt.Token) {
+		_ = tok.Set("realm_access", map[string]interface{}{"roles": []interface{}{"user"}})
+		_ = tok.Set("resource_access", map[string]interface{}{
+			"master-data-client": map[string]interface{}{"roles": []interface{}{"operator_satker"}},
+		})
+	})
+	req := httptest.NewRequest("GET", "/api/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "operator_satker", string(body))
+}
+
+func TestAuthenticateRejectsRevokedToken(t *testing.T) {
+	keys := newJWKSServer(t)
+	key := keys.rotate(t, "kid-1")
+	revocations := NewRevocationList(&fakeRevocationStore{})
+	am := newTestAuthMiddleware(t, keys.server.URL).WithRevocations(revocations)
+
+	app := fiber.New()
+	app.Get("/api/v1/me", am.Authenticate(), func(c fiber.Ctx) error {
+		return c.SendString(GetUserID(c))
+	})
+
+	token := signToken(t, key, func(tok jwt.Token) {
+		_ = tok.Set(jwt.JwtIDKey, "jti-1")
+		_ = tok.Set("sid", "session-1")
+	})
+	request := func() int {
+		req := httptest.NewRequest("GET", "/api/v1/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, request())
+
+	require.NoError(t, revocations.Revoke(context.Background(), models.TokenRevocation{
+		Kind: models.RevocationSID, Value: "session-1", ExpiresAt: time.Now().Add(time.Minute),
+	}))
+	assert.Equal(t, fiber.StatusUnauthorized, request())
+}
+
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	appErrors "github.com/sikerma/backend/internal/errors"
+	"github.com/sikerma/backend/internal/models"
+)
+
+const (
+	// ImpersonationHeader berisi ID session impersonation yang sedang dipakai
+	ImpersonationHeader = "X-Impersonate-Session"
+	// ImpersonatePermission dibutuhkan aktor selama session berlangsung
+	ImpersonatePermission = "impersonation.start"
+)
+
+// Impersonation adalah identitas aktor sebenarnya pada request yang
+// dievaluasi sebagai user lain
+type Impersonation struct {
+	SessionID      uuid.UUID
+	ActorUserID    string
+	ActorUsername  string
+	TargetUserID   string
+	TargetUsername string
+}
+
+// ImpersonationStore mengambil session impersonation; FindByID
+// mengembalikan nil tanpa error bila session tidak ada
+type ImpersonationStore interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*models.ImpersonationSession, error)
+}
+
+// UserRoleSource mengambil nama app_roles yang di-assign ke user
+type UserRoleSource interface {
+	GetUserRoleNames(ctx context.Context, userID string) ([]string, error)
+}
+
+// ImpersonationMiddleware mengganti identitas request dengan user target
+// bila header X-Impersonate-Session berisi session aktif milik user yang login
+type ImpersonationMiddleware struct {
+	store      ImpersonationStore
+	roleSource UserRoleSource
+	roles      *RoleHierarchy
+	rbac       *RBACMiddleware
+}
+
+// NewImpersonationMiddleware membuat instance ImpersonationMiddleware baru
+func NewImpersonationMiddleware(store ImpersonationStore, roleSource UserRoleSource, roles *RoleHierarchy, rbac *RBACMiddleware) *ImpersonationMiddleware {
+	return &ImpersonationMiddleware{store: store, roleSource: roleSource, roles: roles, rbac: rbac}
+}
+
+// Apply memasang role dan scope user target pada request. Aktor tetap
+// tercatat lewat GetImpersonation untuk audit. Harus dipasang setelah
+// Authenticate dan sebelum ApplyScope.
+func (m *ImpersonationMiddleware) Apply() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		header := c.Get(ImpersonationHeader)
+		if header == "" || GetUserID(c) == "" {
+			return c.Next()
+		}
+
+		if GetAuthMethod(c) == "api_key" {
+			return impersonationForbidden(c, "api key tidak dapat melakukan impersonation")
+		}
+
+		sessionID, err := uuid.Parse(header)
+		if err != nil {
+			return appErrors.BadRequest(appErrors.ValInvalidFormat, map[string]interface{}{
+				"header": ImpersonationHeader,
+			}).ToFiberResponse(c, fiber.StatusBadRequest)
+		}
+
+		session, err := m.store.FindByID(c.Context(), sessionID)
+		if err != nil {
+			logrus.WithError(err).WithField("session_id", sessionID).Error("Failed to load impersonation session")
+			return appErrors.InternalError(appErrors.SysDatabaseError).ToFiberResponse(c, fiber.StatusInternalServerError)
+		}
+		if session == nil {
+			return impersonationForbidden(c, "session impersonation tidak ditemukan")
+		}
+
+		actorID := GetUserID(c)
+		if session.ActorUserID != actorID {
+			return impersonationForbidden(c, "session impersonation milik user lain")
+		}
+		if !session.IsActive(time.Now()) {
+			return impersonationForbidden(c, "session impersonation sudah berakhir")
+		}
+
+		// Permission aktor dicek ulang agar pencabutan role langsung menghentikan session
+		allowed, err := m.rbac.HasPermission(c.Context(), actorID, ImpersonatePermission)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", actorID).Error("Failed to resolve user permissions")
+			return appErrors.InternalError(appErrors.SysDatabaseError).ToFiberResponse(c, fiber.StatusInternalServerError)
+		}
+		if !allowed {
+			return impersonationForbidden(c, "permission impersonation sudah dicabut")
+		}
+
+		roleNames, err := m.roleSource.GetUserRoleNames(c.Context(), session.TargetUserID)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", session.TargetUserID).Error("Failed to load impersonated user roles")
+			return appErrors.InternalError(appErrors.SysDatabaseError).ToFiberResponse(c, fiber.StatusInternalServerError)
+		}
+		roles := m.roles.Resolve(c.Context(), roleNames, nil)
+
+		targetUsername := session.TargetUserID
+		if session.TargetUsername != nil && *session.TargetUsername != "" {
+			targetUsername = *session.TargetUsername
+		}
+
+		c.Locals(impersonationKey, &Impersonation{
+			SessionID:      session.ID,
+			ActorUserID:    actorID,
+			ActorUsername:  GetUsername(c),
+			TargetUserID:   session.TargetUserID,
+			TargetUsername: targetUsername,
+		})
+
+		// Scope unit kerja dikosongkan agar ApplyScope memakai scope user target
+		c.Locals("userID", session.TargetUserID)
+		c.Locals("userRole", roles.Primary)
+		c.Locals("userRoles", roles.Roles)
+		c.Locals("username", targetUsername)
+		c.Locals("email", "")
+		c.Locals("name", "")
+		c.Locals("unitKerjaID", "")
+		c.Locals("satkerID", "")
+
+		return c.Next()
+	}
+}
+
+// impersonationKey adalah locals key untuk *Impersonation
+const impersonationKey = "impersonation"
+
+// GetImpersonation mengambil info impersonation request, nil bila request
+// tidak sedang bertindak sebagai user lain
+func GetImpersonation(c fiber.Ctx) *Impersonation {
+	if imp, ok := c.Locals(impersonationKey).(*Impersonation); ok {
+		return imp
+	}
+	return nil
+}
+
+// impersonationForbidden mengembalikan 403 dengan alasan penolakan impersonation
+func impersonationForbidden(c fiber.Ctx, reason string) error {
+	return appErrors.Forbidden(appErrors.AuthzForbidden, map[string]interface{}{
+		"reason": reason,
+	}).ToFiberResponse(c, fiber.StatusForbidden)
+}
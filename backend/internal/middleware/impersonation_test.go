@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+type fakeImpersonationStore struct {
+	sessions map[uuid.UUID]*models.ImpersonationSession
+}
+
+func (f *fakeImpersonationStore) FindByID(_ context.Context, id uuid.UUID) (*models.ImpersonationSession, error) {
+	if s, ok := f.sessions[id]; ok {
+		return s, nil
+	}
+	return nil, nil
+}
+
+type fakeUserRoles map[string][]string
+
+func (f fakeUserRoles) GetUserRoleNames(_ context.Context, userID string) ([]string, error) {
+	return f[userID], nil
+}
+
+func TestImpersonationApply(t *testing.T) {
+	now := time.Now()
+	active := &models.ImpersonationSession{
+		ID: uuid.New(), ActorUserID: "helpdesk-1", TargetUserID: "operator-1",
+		TargetUsername: stringPtr("operator.satker"), StartedAt: now, ExpiresAt: now.Add(time.Hour),
+	}
+	expired := &models.ImpersonationSession{
+		ID: uuid.New(), ActorUserID: "helpdesk-1", TargetUserID: "operator-1",
+		StartedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour),
+	}
+	store := &fakeImpersonationStore{sessions: map[uuid.UUID]*models.ImpersonationSession{
+		active.ID: active, expired.ID: expired,
+	}}
+	rbac := NewRBACMiddleware(&fakeResolver{permissions: map[string][]string{
+		"helpdesk-1": {ImpersonatePermission},
+	}}, time.Minute)
+	hierarchy := NewRoleHierarchy(newTestRoleSource(), time.Minute)
+	m := NewImpersonationMiddleware(store, fakeUserRoles{"operator-1": {"staff"}}, hierarchy, rbac)
+
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("userID", c.Get("X-Test-User"))
+		c.Locals("username", "helpdesk")
+		c.Locals("unitKerjaID", "unit-helpdesk")
+		if c.Get("X-Test-Method") != "" {
+			c.Locals("authMethod", c.Get("X-Test-Method"))
+		}
+		return c.Next()
+	})
+	app.Get("/whoami", m.Apply(), func(c fiber.Ctx) error {
+		actor := "-"
+		if imp := GetImpersonation(c); imp != nil {
+			actor = imp.ActorUsername
+		}
+		return c.SendString(strings.Join([]string{GetUserID(c), GetUserRole(c), GetUnitKerjaID(c), actor}, "|"))
+	})
+
+	request := func(user, session, method string) (int, string) {
+		req := httptest.NewRequest("GET", "/whoami", nil)
+		req.Header.Set("X-Test-User", user)
+		req.Header.Set("X-Test-Method", method)
+		if session != "" {
+			req.Header.Set(ImpersonationHeader, session)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := request("helpdesk-1", "", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "helpdesk-1||unit-helpdesk|-", body)
+
+	status, body = request("helpdesk-1", active.ID.String(), "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "operator-1|staff||helpdesk", body)
+
+	status, _ = request("helpdesk-1", expired.ID.String(), "")
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	status, _ = request("helpdesk-2", active.ID.String(), "")
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	status, _ = request("helpdesk-1", active.ID.String(), "api_key")
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	status, _ = request("helpdesk-1", uuid.NewString(), "")
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	status, _ = request("helpdesk-1", "bukan-uuid", "")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	// Permission aktor dicabut di tengah session
+	rbac.resolver.(*fakeResolver).permissions["helpdesk-1"] = nil
+	rbac.Invalidate("helpdesk-1")
+	status, _ = request("helpdesk-1", active.ID.String(), "")
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
@@ -89,6 +89,46 @@ func GetUserRole(c fiber.Ctx) string {
 	return ""
 }
 
+// GetUserRoles mengambil semua role user dari context
+func GetUserRoles(c fiber.Ctx) []string {
+	if roles, ok := c.Locals("userRoles").([]string); ok {
+		return roles
+	}
+	return []string{}
+}
+
+// GetUsername mengambil username (preferred_username) dari context
+func GetUsername(c fiber.Ctx) string {
+	if username, ok := c.Locals("username").(string); ok {
+		return username
+	}
+	return ""
+}
+
+// GetUserEmail mengambil email user dari context
+func GetUserEmail(c fiber.Ctx) string {
+	if email, ok := c.Locals("email").(string); ok {
+		return email
+	}
+	return ""
+}
+
+// GetUserName mengambil nama lengkap user dari context
+func GetUserName(c fiber.Ctx) string {
+	if name, ok := c.Locals("name").(string); ok {
+		return name
+	}
+	return ""
+}
+
+// GetAuthMethod mengambil cara autentikasi request: "bearer" atau "api_key"
+func GetAuthMethod(c fiber.Ctx) string {
+	if method, ok := c.Locals("authMethod").(string); ok {
+		return method
+	}
+	return "bearer"
+}
+
 // GetRequestID dari context
 func GetRequestID(c fiber.Ctx) string {
 	if requestID, ok := c.Locals("requestID").(string); ok {
@@ -111,4 +151,10 @@ func GetSatkerID(c fiber.Ctx) string {
 		return satkerID
 	}
 	return ""
-}
\ No newline at end of file
+}
+
+// GetAPIKeyScopes mengambil scope API key; ok false bila request tidak memakai API key
+func GetAPIKeyScopes(c fiber.Ctx) ([]string, bool) {
+	scopes, ok := c.Locals("apiKeyScopes").([]string)
+	return scopes, ok
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// listenRetryInterval adalah jeda sebelum LISTEN dicoba lagi setelah koneksi putus
+const listenRetryInterval = 5 * time.Second
+
+// listenNotifications menjalankan LISTEN pada channel dan memanggil handle untuk
+// setiap payload. Bila koneksi terputus, notifikasi bisa terlewat sehingga
+// resync dipanggil sebelum mencoba lagi. Berjalan sampai ctx dibatalkan.
+func listenNotifications(ctx context.Context, db *pgxpool.Pool, channel string, handle func(payload string), resync func()) {
+	for {
+		err := listenOnce(ctx, db, channel, handle)
+		if ctx.Err() != nil {
+			return
+		}
+
+		resync()
+		logrus.WithError(err).WithField("channel", channel).Warn("Notification listener disconnected, retrying")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryInterval):
+		}
+	}
+}
+
+// listenOnce menjalankan LISTEN pada koneksi khusus sampai terjadi error
+func listenOnce(ctx context.Context, db *pgxpool.Pool, channel string, handle func(payload string)) error {
+	poolConn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Koneksi LISTEN tidak dikembalikan ke pool
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle(notification.Payload)
+	}
+}
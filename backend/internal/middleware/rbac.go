@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	appErrors "github.com/sikerma/backend/internal/errors"
+)
+
+// RBACChangeChannel adalah channel LISTEN/NOTIFY yang dikirim trigger db_master
+// setiap kali assignment role atau permission berubah. Payload berisi user_id
+// yang terdampak, atau string kosong jika seluruh cache harus dibuang.
+const RBACChangeChannel = "rbac_changed"
+
+// PermissionResolver mengambil daftar kode permission milik user
+type PermissionResolver interface {
+	GetUserPermissions(ctx context.Context, userID string) ([]string, error)
+}
+
+// permissionEntry menyimpan permission user beserta waktu kadaluarsanya
+type permissionEntry struct {
+	permissions map[string]bool
+	expiresAt   time.Time
+}
+
+// RBACMiddleware memeriksa permission user berdasarkan tabel RBAC di db_master
+type RBACMiddleware struct {
+	resolver PermissionResolver
+	ttl      time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]permissionEntry
+
+	// onInvalidateAll dipanggil setiap kali seluruh cache dibuang, agar cache
+	// lain yang bergantung pada definisi RBAC ikut diperbarui
+	onInvalidateAll []func()
+}
+
+// NewRBACMiddleware membuat RBAC middleware baru dengan cache per user
+func NewRBACMiddleware(resolver PermissionResolver, ttl time.Duration) *RBACMiddleware {
+	return &RBACMiddleware{
+		resolver: resolver,
+		ttl:      ttl,
+		entries:  make(map[string]permissionEntry),
+	}
+}
+
+// Permissions mengambil set permission user, dari cache bila masih berlaku
+func (m *RBACMiddleware) Permissions(ctx context.Context, userID string) (map[string]bool, error) {
+	now := time.Now()
+
+	m.mu.RLock()
+	entry, ok := m.entries[userID]
+	m.mu.RUnlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.permissions, nil
+	}
+
+	codes, err := m.resolver.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		permissions[code] = true
+	}
+
+	if m.ttl > 0 {
+		m.mu.Lock()
+		m.entries[userID] = permissionEntry{permissions: permissions, expiresAt: now.Add(m.ttl)}
+		m.mu.Unlock()
+	}
+
+	return permissions, nil
+}
+
+// HasPermission mengecek apakah user memiliki permission tertentu
+func (m *RBACMiddleware) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	permissions, err := m.Permissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return permissions[permission], nil
+}
+
+// Invalidate membuang cache permission untuk satu user
+func (m *RBACMiddleware) Invalidate(userID string) {
+	m.mu.Lock()
+	delete(m.entries, userID)
+	m.mu.Unlock()
+}
+
+// InvalidateAll membuang seluruh cache permission
+func (m *RBACMiddleware) InvalidateAll() {
+	m.mu.Lock()
+	m.entries = make(map[string]permissionEntry)
+	hooks := m.onInvalidateAll
+	m.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// OnInvalidateAll mendaftarkan fn yang dipanggil setiap InvalidateAll, termasuk
+// ketika definisi RBAC berubah lewat LISTEN/NOTIFY
+func (m *RBACMiddleware) OnInvalidateAll(fn func()) {
+	m.mu.Lock()
+	m.onInvalidateAll = append(m.onInvalidateAll, fn)
+	m.mu.Unlock()
+}
+
+// RequirePermission middleware untuk memeriksa permission spesifik.
+// Tidak ada bypass berbasis nama role: role admin cukup diberi seluruh permission.
+func (m *RBACMiddleware) RequirePermission(permission string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := GetUserID(c)
+		if userID == "" {
+			return appErrors.NewError(appErrors.AuthInvalidToken).ToFiberResponse(c, fiber.StatusUnauthorized)
+		}
+
+		// API key hanya boleh memakai permission yang tercantum di scope-nya,
+		// di samping permission milik owner
+		if scopes, ok := GetAPIKeyScopes(c); ok && !containsString(scopes, permission) {
+			return appErrors.Forbidden(appErrors.AuthzForbidden, map[string]interface{}{
+				"permission": permission,
+				"reason":     "api key scope",
+			}).ToFiberResponse(c, fiber.StatusForbidden)
+		}
+
+		allowed, err := m.HasPermission(c.Context(), userID, permission)
+		if err != nil {
+			logrus.WithError(err).WithField("user_id", userID).Error("Failed to resolve user permissions")
+			return appErrors.InternalError(appErrors.SysDatabaseError).ToFiberResponse(c, fiber.StatusInternalServerError)
+		}
+
+		if !allowed {
+			return appErrors.Forbidden(appErrors.AuthzForbidden, map[string]interface{}{
+				"permission": permission,
+			}).ToFiberResponse(c, fiber.StatusForbidden)
+		}
+
+		return c.Next()
+	}
+}
+
+// ListenForChanges mendengarkan notifikasi perubahan RBAC dari db_master dan
+// membuang cache yang terdampak. Berjalan sampai ctx dibatalkan.
+func (m *RBACMiddleware) ListenForChanges(ctx context.Context, db *pgxpool.Pool) {
+	listenNotifications(ctx, db, RBACChangeChannel, func(payload string) {
+		if payload == "" {
+			m.InvalidateAll()
+		} else {
+			m.Invalidate(payload)
+		}
+	}, m.InvalidateAll)
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appErrors "github.com/sikerma/backend/internal/errors"
+)
+
+type fakeResolver struct {
+	mu          sync.Mutex
+	permissions map[string][]string
+	err         error
+	calls       int
+}
+
+func (f *fakeResolver) GetUserPermissions(_ context.Context, userID string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.permissions[userID], nil
+}
+
+func newRBACTestApp(rbac *RBACMiddleware, permission string) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		if userID := c.Get("X-Test-User"); userID != "" {
+			c.Locals("userID", userID)
+		}
+		return c.Next()
+	})
+	app.Get("/resource", rbac.RequirePermission(permission), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func doRBACRequest(t *testing.T, app *fiber.App, userID string) (int, appErrors.ErrorResponse) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/resource", nil)
+	if userID != "" {
+		req.Header.Set("X-Test-User", userID)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body appErrors.ErrorResponse
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func TestRequirePermission(t *testing.T) {
+	resolver := &fakeResolver{permissions: map[string][]string{
+		"admin-user": {"kepegawaian.read", "kepegawaian.delete"},
+		"staff-user": {"kepegawaian.read"},
+	}}
+	rbac := NewRBACMiddleware(resolver, time.Minute)
+	app := newRBACTestApp(rbac, "kepegawaian.delete")
+
+	t.Run("granted permission passes", func(t *testing.T) {
+		status, _ := doRBACRequest(t, app, "admin-user")
+		assert.Equal(t, fiber.StatusOK, status)
+	})
+
+	t.Run("missing permission is forbidden", func(t *testing.T) {
+		status, body := doRBACRequest(t, app, "staff-user")
+		assert.Equal(t, fiber.StatusForbidden, status)
+		assert.Equal(t, appErrors.AuthzForbidden, body.Error.Code)
+		assert.Equal(t, "kepegawaian.delete", body.Error.Details["permission"])
+	})
+
+	t.Run("unauthenticated request is rejected", func(t *testing.T) {
+		status, _ := doRBACRequest(t, app, "")
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+	})
+}
+
+func TestRequirePermissionResolverError(t *testing.T) {
+	rbac := NewRBACMiddleware(&fakeResolver{err: errors.New("db down")}, time.Minute)
+	app := newRBACTestApp(rbac, "kepegawaian.read")
+
+	status, body := doRBACRequest(t, app, "any-user")
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	assert.Equal(t, appErrors.SysDatabaseError, body.Error.Code)
+}
+
+func TestPermissionCacheInvalidation(t *testing.T) {
+	resolver := &fakeResolver{permissions: map[string][]string{
+		"user-1": {"rbac.read"},
+	}}
+	rbac := NewRBACMiddleware(resolver, time.Hour)
+	ctx := context.Background()
+
+	ok, err := rbac.HasPermission(ctx, "user-1", "rbac.create")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	resolver.mu.Lock()
+	resolver.permissions["user-1"] = []string{"rbac.read", "rbac.create"}
+	resolver.mu.Unlock()
+
+	// Masih memakai cache sampai di-invalidate
+	ok, _ = rbac.HasPermission(ctx, "user-1", "rbac.create")
+	assert.False(t, ok)
+	assert.Equal(t, 1, resolver.calls)
+
+	rbac.Invalidate("user-1")
+	ok, _ = rbac.HasPermission(ctx, "user-1", "rbac.create")
+	assert.True(t, ok)
+	assert.Equal(t, 2, resolver.calls)
+
+	rbac.InvalidateAll()
+	_, _ = rbac.HasPermission(ctx, "user-1", "rbac.create")
+	assert.Equal(t, 3, resolver.calls)
+}
+
+func TestRequirePermissionAPIKeyScopes(t *testing.T) {
+	resolver := &fakeResolver{permissions: map[string][]string{
+		"svc-laporan": {"kepegawaian.read", "kepegawaian.delete"},
+	}}
+	rbac := NewRBACMiddleware(resolver, time.Minute)
+
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("userID", "svc-laporan")
+		c.Locals("apiKeyScopes", []string{"kepegawaian.read"})
+		return c.Next()
+	})
+	app.Get("/read", rbac.RequirePermission("kepegawaian.read"), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/delete", rbac.RequirePermission("kepegawaian.delete"), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/read", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// Owner punya permission delete, tapi scope key tidak mencakupnya
+	resp, err = app.Test(httptest.NewRequest("GET", "/delete", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
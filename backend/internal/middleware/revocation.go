@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// TokenRevokedChannel adalah channel LISTEN/NOTIFY yang dikirim trigger
+// token_revocations agar semua instance memuat ulang daftar pencabutan
+const TokenRevokedChannel = "token_revoked"
+
+// RevocationStore menyimpan daftar pencabutan token yang dibagi antar instance
+type RevocationStore interface {
+	Revoke(ctx context.Context, rev models.TokenRevocation) error
+	ListActiveRevocations(ctx context.Context) ([]models.TokenRevocation, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// subjectRevocation menolak token user yang terbit sebelum issuedBefore
+type subjectRevocation struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+// RevocationList adalah salinan in-memory dari token_revocations yang
+// diperiksa Authenticate pada setiap request. Salinan dimuat ulang lewat
+// LISTEN/NOTIFY sehingga pencabutan dari instance lain langsung berlaku.
+type RevocationList struct {
+	store RevocationStore
+
+	mu       sync.RWMutex
+	jti      map[string]time.Time
+	sid      map[string]time.Time
+	subjects map[string]subjectRevocation
+}
+
+// NewRevocationList membuat RevocationList kosong; panggil Reload untuk memuat data
+func NewRevocationList(store RevocationStore) *RevocationList {
+	return &RevocationList{
+		store:    store,
+		jti:      make(map[string]time.Time),
+		sid:      make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+	}
+}
+
+// Reload memuat ulang seluruh pencabutan aktif dari store. Bila gagal, salinan
+// lama tetap dipakai.
+func (l *RevocationList) Reload(ctx context.Context) error {
+	revocations, err := l.store.ListActiveRevocations(ctx)
+	if err != nil {
+		return err
+	}
+
+	jti := make(map[string]time.Time)
+	sid := make(map[string]time.Time)
+	subjects := make(map[string]subjectRevocation)
+	for _, rev := range revocations {
+		addRevocation(rev, jti, sid, subjects)
+	}
+
+	l.mu.Lock()
+	l.jti, l.sid, l.subjects = jti, sid, subjects
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Revoke menyimpan pencabutan ke store dan langsung menerapkannya pada
+// instance ini tanpa menunggu notifikasi
+func (l *RevocationList) Revoke(ctx context.Context, rev models.TokenRevocation) error {
+	if err := l.store.Revoke(ctx, rev); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	addRevocation(rev, l.jti, l.sid, l.subjects)
+	l.mu.Unlock()
+
+	return nil
+}
+
+// addRevocation memasukkan rev ke map sesuai jenisnya, mempertahankan
+// expires_at dan issued_before yang paling akhir
+func addRevocation(rev models.TokenRevocation, jti, sid map[string]time.Time, subjects map[string]subjectRevocation) {
+	switch rev.Kind {
+	case models.RevocationJTI:
+		if rev.ExpiresAt.After(jti[rev.Value]) {
+			jti[rev.Value] = rev.ExpiresAt
+		}
+	case models.RevocationSID:
+		if rev.ExpiresAt.After(sid[rev.Value]) {
+			sid[rev.Value] = rev.ExpiresAt
+		}
+	case models.RevocationSub:
+		if rev.IssuedBefore == nil {
+			return
+		}
+		current := subjects[rev.Value]
+		if rev.IssuedBefore.After(current.issuedBefore) {
+			current.issuedBefore = *rev.IssuedBefore
+		}
+		if rev.ExpiresAt.After(current.expiresAt) {
+			current.expiresAt = rev.ExpiresAt
+		}
+		subjects[rev.Value] = current
+	}
+}
+
+// IsRevoked mengecek apakah token dengan claims tersebut sudah dicabut
+func (l *RevocationList) IsRevoked(claims TokenClaims) bool {
+	if l == nil {
+		return false
+	}
+
+	now := time.Now()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if claims.JTI != "" {
+		if expiresAt, ok := l.jti[claims.JTI]; ok && now.Before(expiresAt) {
+			return true
+		}
+	}
+	if claims.SessionID != "" {
+		if expiresAt, ok := l.sid[claims.SessionID]; ok && now.Before(expiresAt) {
+			return true
+		}
+	}
+	if rev, ok := l.subjects[claims.Subject]; ok && now.Before(rev.expiresAt) {
+		// iat hanya presisi detik: token yang terbit pada detik yang sama ikut ditolak
+		return claims.IssuedAt.IsZero() || !claims.IssuedAt.After(rev.issuedBefore)
+	}
+
+	return false
+}
+
+// ListenForChanges memuat daftar pencabutan lalu memuat ulang setiap kali
+// instance lain mencabut token. Berjalan sampai ctx dibatalkan.
+func (l *RevocationList) ListenForChanges(ctx context.Context, db *pgxpool.Pool) {
+	reload := func() {
+		if err := l.Reload(ctx); err != nil && ctx.Err() == nil {
+			logrus.WithError(err).Warn("Failed to reload token revocations")
+		}
+	}
+
+	reload()
+	listenNotifications(ctx, db, TokenRevokedChannel, func(string) { reload() }, reload)
+}
+
+// PurgeExpired menghapus pencabutan yang token-nya sudah kadaluarsa setiap
+// interval. Berjalan sampai ctx dibatalkan.
+func (l *RevocationList) PurgeExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := l.store.DeleteExpired(ctx)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to purge expired token revocations")
+			continue
+		}
+		if deleted > 0 {
+			logrus.WithField("deleted", deleted).Debug("Purged expired token revocations")
+		}
+
+		if err := l.Reload(ctx); err != nil && ctx.Err() == nil {
+			logrus.WithError(err).Warn("Failed to reload token revocations")
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+type fakeRevocationStore struct {
+	revocations []models.TokenRevocation
+}
+
+func (f *fakeRevocationStore) Revoke(_ context.Context, rev models.TokenRevocation) error {
+	f.revocations = append(f.revocations, rev)
+	return nil
+}
+
+func (f *fakeRevocationStore) ListActiveRevocations(_ context.Context) ([]models.TokenRevocation, error) {
+	return f.revocations, nil
+}
+
+func (f *fakeRevocationStore) DeleteExpired(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
+func TestRevocationListIsRevoked(t *testing.T) {
+	now := time.Now()
+	forcedAt := now.Add(-time.Minute)
+	store := &fakeRevocationStore{revocations: []models.TokenRevocation{
+		{Kind: models.RevocationJTI, Value: "jti-revoked", ExpiresAt: now.Add(time.Minute)},
+		{Kind: models.RevocationJTI, Value: "jti-expired", ExpiresAt: now.Add(-time.Second)},
+		{Kind: models.RevocationSID, Value: "sid-revoked", ExpiresAt: now.Add(time.Minute)},
+		{Kind: models.RevocationSub, Value: "user-dismissed", IssuedBefore: &forcedAt, ExpiresAt: now.Add(time.Minute)},
+	}}
+	list := NewRevocationList(store)
+	require.NoError(t, list.Reload(context.Background()))
+
+	tests := []struct {
+		name    string
+		claims  TokenClaims
+		revoked bool
+	}{
+		{"revoked jti", TokenClaims{Subject: "user-1", JTI: "jti-revoked"}, true},
+		{"revocation past token exp", TokenClaims{Subject: "user-1", JTI: "jti-expired"}, false},
+		{"revoked session", TokenClaims{Subject: "user-1", JTI: "jti-other", SessionID: "sid-revoked"}, true},
+		{"token before force logout", TokenClaims{Subject: "user-dismissed", IssuedAt: forcedAt.Add(-time.Minute)}, true},
+		{"token after force logout", TokenClaims{Subject: "user-dismissed", IssuedAt: now}, false},
+		{"unrelated token", TokenClaims{Subject: "user-2", JTI: "jti-2", SessionID: "sid-2"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.revoked, list.IsRevoked(tt.claims))
+		})
+	}
+
+	var nilList *RevocationList
+	assert.False(t, nilList.IsRevoked(TokenClaims{JTI: "jti-revoked"}))
+}
+
+func TestRevocationListRevokeAppliesLocally(t *testing.T) {
+	store := &fakeRevocationStore{}
+	list := NewRevocationList(store)
+
+	err := list.Revoke(context.Background(), models.TokenRevocation{
+		Kind: models.RevocationJTI, Value: "jti-1", ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, store.revocations, 1)
+	assert.True(t, list.IsRevoked(TokenClaims{JTI: "jti-1"}))
+}
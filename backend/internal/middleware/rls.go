@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sikerma/backend/internal/database"
+	appErrors "github.com/sikerma/backend/internal/errors"
+)
+
+// ScopeResolver mengambil unit kerja dan satker user bila tidak ada di token
+type ScopeResolver interface {
+	GetUserScope(ctx context.Context, userID string) (unitKerjaID, satkerID string, err error)
+}
+
+// RLSMiddleware menyiapkan claim RLS untuk setiap request terautentikasi
+type RLSMiddleware struct {
+	resolver ScopeResolver
+}
+
+// NewRLSMiddleware membuat instance RLSMiddleware baru
+func NewRLSMiddleware(resolver ScopeResolver) *RLSMiddleware {
+	return &RLSMiddleware{resolver: resolver}
+}
+
+// ApplyScope melengkapi unit kerja/satker user (token claim lebih dulu, lalu
+// assignment role) dan menyimpan RLSClaims ke context request agar repository
+// bisa menjalankan query dengan database.WithRLS.
+// Harus dipasang setelah Authenticate.
+func (m *RLSMiddleware) ApplyScope() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := GetUserID(c)
+		if userID == "" {
+			return c.Next()
+		}
+
+		unitKerjaID := GetUnitKerjaID(c)
+		satkerID := GetSatkerID(c)
+
+		if unitKerjaID == "" || satkerID == "" {
+			resolvedUnit, resolvedSatker, err := m.resolver.GetUserScope(c.Context(), userID)
+			if err != nil {
+				logrus.WithError(err).WithField("user_id", userID).Error("Failed to resolve user scope")
+				return appErrors.InternalError(appErrors.SysDatabaseError).ToFiberResponse(c, fiber.StatusInternalServerError)
+			}
+			if unitKerjaID == "" {
+				unitKerjaID = resolvedUnit
+			}
+			if satkerID == "" {
+				satkerID = resolvedSatker
+			}
+		}
+
+		if unitKerjaID != "" {
+			c.Locals("unitKerjaID", unitKerjaID)
+		}
+		if satkerID != "" {
+			c.Locals("satkerID", satkerID)
+		}
+
+		c.SetContext(database.WithRLSClaims(c.Context(), database.RLSClaims{
+			UserID:      userID,
+			UnitKerjaID: unitKerjaID,
+			SatkerID:    satkerID,
+			Role:        GetUserRole(c),
+		}))
+
+		return c.Next()
+	}
+}
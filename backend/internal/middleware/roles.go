@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// defaultRole dipakai bila tidak ada role token yang terdaftar di app_roles
+const defaultRole = "user"
+
+// RoleSource mengambil definisi role aktif dari app_roles
+type RoleSource interface {
+	ListRoleDefinitions(ctx context.Context) ([]models.RoleDefinition, error)
+}
+
+// ResolvedRoles adalah hasil pemetaan role token ke hierarki app_roles
+type ResolvedRoles struct {
+	// Roles berisi role token beserta semua role yang diwarisi lewat composite.
+	// Client role yang tidak terdaftar ditulis sebagai "<client>:<role>".
+	Roles []string
+	// Primary adalah role dengan prioritas tertinggi
+	Primary string
+}
+
+// RoleHierarchy memetakan role realm/client Keycloak ke app_roles, termasuk
+// prioritas dan composite. Definisi di-cache dan dimuat ulang setelah TTL atau
+// Invalidate; bila database gagal, definisi terakhir tetap dipakai.
+type RoleHierarchy struct {
+	source RoleSource
+	ttl    time.Duration
+
+	mu        sync.RWMutex
+	roles     map[string]models.RoleDefinition
+	expiresAt time.Time
+}
+
+// NewRoleHierarchy membuat RoleHierarchy baru
+func NewRoleHierarchy(source RoleSource, ttl time.Duration) *RoleHierarchy {
+	return &RoleHierarchy{source: source, ttl: ttl}
+}
+
+// Invalidate memaksa definisi role dimuat ulang pada resolusi berikutnya
+func (h *RoleHierarchy) Invalidate() {
+	h.mu.Lock()
+	h.expiresAt = time.Time{}
+	h.mu.Unlock()
+}
+
+// definitions mengambil definisi role dari cache atau database
+func (h *RoleHierarchy) definitions(ctx context.Context) map[string]models.RoleDefinition {
+	h.mu.RLock()
+	roles, expiresAt := h.roles, h.expiresAt
+	h.mu.RUnlock()
+	if roles != nil && time.Now().Before(expiresAt) {
+		return roles
+	}
+
+	list, err := h.source.ListRoleDefinitions(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load role definitions, using cached hierarchy")
+		return roles
+	}
+
+	loaded := make(map[string]models.RoleDefinition, len(list))
+	for _, def := range list {
+		loaded[def.Nama] = def
+	}
+
+	h.mu.Lock()
+	h.roles = loaded
+	h.expiresAt = time.Now().Add(h.ttl)
+	h.mu.Unlock()
+
+	return loaded
+}
+
+// Resolve memetakan realm role dan client role (resource_access) ke app_roles.
+// Realm role cocok dengan app_roles tanpa keycloak_client; client role cocok
+// dengan app_roles yang keycloak_client-nya sama dengan client tersebut.
+func (h *RoleHierarchy) Resolve(ctx context.Context, realmRoles []string, clientRoles map[string][]string) ResolvedRoles {
+	var defs map[string]models.RoleDefinition
+	if h != nil {
+		defs = h.definitions(ctx)
+	}
+
+	seen := map[string]bool{}
+	resolved := ResolvedRoles{Roles: []string{}}
+	matched := []string{}
+
+	add := func(role string, known bool) {
+		if seen[role] {
+			return
+		}
+		seen[role] = true
+		resolved.Roles = append(resolved.Roles, role)
+		if known {
+			matched = append(matched, role)
+		}
+	}
+
+	for _, role := range realmRoles {
+		def, ok := defs[role]
+		add(role, ok && def.KeycloakClient == nil)
+	}
+
+	clients := make([]string, 0, len(clientRoles))
+	for client := range clientRoles {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	for _, client := range clients {
+		for _, role := range clientRoles[client] {
+			if def, ok := defs[role]; ok && def.KeycloakClient != nil && *def.KeycloakClient == client {
+				add(role, true)
+			} else {
+				add(client+":"+role, false)
+			}
+		}
+	}
+
+	// Ekspansi composite; seen mencegah loop bila data composite bersiklus
+	for i := 0; i < len(matched); i++ {
+		for _, child := range defs[matched[i]].Includes {
+			if _, ok := defs[child]; ok {
+				add(child, true)
+			}
+		}
+	}
+
+	resolved.Primary = defaultRole
+	highest := -1
+	for _, role := range matched {
+		if priority := defs[role].Priority; priority > highest {
+			highest = priority
+			resolved.Primary = role
+		}
+	}
+
+	return resolved
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+type fakeRoleSource struct {
+	mu          sync.Mutex
+	definitions []models.RoleDefinition
+	err         error
+	calls       int
+}
+
+func (f *fakeRoleSource) ListRoleDefinitions(_ context.Context) ([]models.RoleDefinition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.definitions, nil
+}
+
+func stringPtr(s string) *string { return &s }
+
+func newTestRoleSource() *fakeRoleSource {
+	return &fakeRoleSource{definitions: []models.RoleDefinition{
+		{Nama: "admin", Priority: 100, Includes: []string{"supervisor"}},
+		{Nama: "supervisor", Priority: 80, Includes: []string{"staff"}},
+		{Nama: "operator_satker", Priority: 70, KeycloakClient: stringPtr("master-data-client"), Includes: []string{"staff"}},
+		{Nama: "staff", Priority: 40, Includes: []string{"user"}},
+		{Nama: "user", Priority: 20},
+	}}
+}
+
+func TestRoleHierarchyResolve(t *testing.T) {
+	hierarchy := NewRoleHierarchy(newTestRoleSource(), time.Minute)
+	ctx := context.Background()
+
+	t.Run("composite roles are inherited", func(t *testing.T) {
+		resolved := hierarchy.Resolve(ctx, []string{"supervisor", "offline_access"}, nil)
+		assert.Equal(t, "supervisor", resolved.Primary)
+		assert.Equal(t, []string{"supervisor", "offline_access", "staff", "user"}, resolved.Roles)
+	})
+
+	t.Run("client role maps to app role of that client", func(t *testing.T) {
+		resolved := hierarchy.Resolve(ctx, []string{"user"}, map[string][]string{
+			"master-data-client": {"operator_satker"},
+			"portal-client":      {"operator_satker"},
+		})
+		assert.Equal(t, "operator_satker", resolved.Primary)
+		assert.Contains(t, resolved.Roles, "staff")
+		assert.Contains(t, resolved.Roles, "portal-client:operator_satker")
+	})
+
+	t.Run("realm role cannot claim a client-scoped app role", func(t *testing.T) {
+		resolved := hierarchy.Resolve(ctx, []string{"operator_satker"}, nil)
+		assert.Equal(t, defaultRole, resolved.Primary)
+		assert.Equal(t, []string{"operator_satker"}, resolved.Roles)
+	})
+
+	t.Run("unknown roles fall back to default", func(t *testing.T) {
+		resolved := hierarchy.Resolve(ctx, []string{"uma_authorization"}, nil)
+		assert.Equal(t, defaultRole, resolved.Primary)
+	})
+}
+
+func TestRoleHierarchyCache(t *testing.T) {
+	source := newTestRoleSource()
+	hierarchy := NewRoleHierarchy(source, time.Hour)
+	ctx := context.Background()
+
+	hierarchy.Resolve(ctx, []string{"admin"}, nil)
+	hierarchy.Resolve(ctx, []string{"admin"}, nil)
+	assert.Equal(t, 1, source.calls)
+
+	// Database gagal setelah invalidasi: definisi terakhir tetap dipakai
+	source.err = errors.New("db down")
+	hierarchy.Invalidate()
+	resolved := hierarchy.Resolve(ctx, []string{"admin"}, nil)
+	assert.Equal(t, 2, source.calls)
+	assert.Equal(t, "admin", resolved.Primary)
+}
+
+func TestRBACInvalidateAllHook(t *testing.T) {
+	source := newTestRoleSource()
+	hierarchy := NewRoleHierarchy(source, time.Hour)
+	rbac := NewRBACMiddleware(&fakeResolver{}, time.Minute)
+	rbac.OnInvalidateAll(hierarchy.Invalidate)
+
+	hierarchy.Resolve(context.Background(), nil, nil)
+	rbac.InvalidateAll()
+	hierarchy.Resolve(context.Background(), nil, nil)
+	assert.Equal(t, 2, source.calls)
+}
@@ -198,11 +198,13 @@ type RefJenisHukdis struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// RefJenisDiklat
+// RefJenisDiklat. HitungJP menandai jenis diklat yang JP-nya dihitung
+// sebagai pengembangan kompetensi tahunan.
 type RefJenisDiklat struct {
 	ID        uuid.UUID `json:"id" db:"id"`
 	Kode      string    `json:"kode" db:"kode"`
 	Nama      string    `json:"nama" db:"nama"`
+	HitungJP  bool      `json:"hitung_jp" db:"hitung_jp"`
 	IsActive  bool      `json:"is_active" db:"is_active"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
@@ -239,6 +241,9 @@ type Pegawai struct {
 	GolonganID   *uuid.UUID `json:"golongan_id,omitempty" db:"golongan_id"`
 	EselonID     *uuid.UUID `json:"eselon_id,omitempty" db:"eselon_id"`
 
+	// Pendidikan tertinggi, diturunkan dari riwayat pendidikan
+	PendidikanTerakhirID *uuid.UUID `json:"pendidikan_terakhir_id,omitempty" db:"pendidikan_terakhir_id"`
+
 	// Status
 	StatusPegawai StatusPegawai `json:"status_pegawai" db:"status_pegawai"` // PNS, CPNS, PPPK, HONORER
 	StatusKerja   StatusKerja   `json:"status_kerja" db:"status_kerja"`     // aktif, cuti, pensiun, dll
@@ -282,6 +287,8 @@ type Pegawai struct {
 	Eselon      *Eselon         `json:"eselon,omitempty"`
 	Agama       *RefAgama       `json:"agama,omitempty"`
 	StatusKawin *RefStatusKawin `json:"status_kawin,omitempty"`
+
+	PendidikanTerakhir *RefPendidikan `json:"pendidikan_terakhir,omitempty"`
 }
 
 // RiwayatPangkat - dengan field baru
@@ -371,6 +378,47 @@ type Keluarga struct {
 	CreatedBy     *uuid.UUID     `json:"created_by,omitempty" db:"created_by"`
 }
 
+// Hukdis adalah hukuman disiplin pegawai. Hukuman berlaku sejak
+// TanggalMulai sampai TanggalSelesai; tanpa TanggalSelesai berlaku seterusnya.
+type Hukdis struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	PegawaiID      uuid.UUID  `json:"pegawai_id" db:"pegawai_id"`
+	JenisHukdisID  uuid.UUID  `json:"jenis_hukdis_id" db:"jenis_hukdis_id"`
+	NomorSK        string     `json:"nomor_sk" db:"nomor_sk"`
+	TanggalSK      time.Time  `json:"tanggal_sk" db:"tanggal_sk"`
+	TanggalMulai   time.Time  `json:"tanggal_mulai" db:"tanggal_mulai"`
+	TanggalSelesai *time.Time `json:"tanggal_selesai,omitempty" db:"tanggal_selesai"`
+	Alasan         *string    `json:"alasan,omitempty" db:"alasan"`
+	Pejabat        string     `json:"pejabat" db:"pejabat"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+
+	// Relations
+	JenisHukdis *RefJenisHukdis `json:"jenis_hukdis,omitempty"`
+}
+
+// Diklat adalah riwayat pendidikan dan pelatihan pegawai beserta sertifikatnya
+type Diklat struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	PegawaiID         uuid.UUID  `json:"pegawai_id" db:"pegawai_id"`
+	JenisDiklatID     uuid.UUID  `json:"jenis_diklat_id" db:"jenis_diklat_id"`
+	NamaDiklat        string     `json:"nama_diklat" db:"nama_diklat"`
+	Penyelenggara     *string    `json:"penyelenggara,omitempty" db:"penyelenggara"`
+	Tempat            *string    `json:"tempat,omitempty" db:"tempat"`
+	TanggalMulai      *time.Time `json:"tanggal_mulai,omitempty" db:"tanggal_mulai"`
+	TanggalSelesai    *time.Time `json:"tanggal_selesai,omitempty" db:"tanggal_selesai"`
+	JamJPL            *int       `json:"jam_jpl,omitempty" db:"jam_jpl"`
+	NomorSertifikat   *string    `json:"nomor_sertifikat,omitempty" db:"nomor_sertifikat"`
+	TanggalSertifikat *time.Time `json:"tanggal_sertifikat,omitempty" db:"tanggal_sertifikat"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	CreatedBy         *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+
+	// Relations
+	JenisDiklat *RefJenisDiklat `json:"jenis_diklat,omitempty"`
+}
+
 // TemplateDokumen
 type TemplateDokumen struct {
 	ID         uuid.UUID              `json:"id" db:"id"`
@@ -388,15 +436,26 @@ type TemplateDokumen struct {
 
 // AppRole
 type AppRole struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	RoleCode    string    `json:"role_code" db:"role_code"`
-	Nama        string    `json:"nama" db:"nama"`
-	AppSource   string    `json:"app_source" db:"app_source"` // portal, master-data, kepegawaian
-	Deskripsi   string    `json:"deskripsi,omitempty" db:"deskripsi"`
-	IsSystem    bool      `json:"is_system" db:"is_system"`
-	IsActive    bool      `json:"is_active" db:"is_active"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID `json:"id" db:"id"`
+	RoleCode       string    `json:"role_code" db:"role_code"`
+	Nama           string    `json:"nama" db:"nama"`
+	AppSource      string    `json:"app_source" db:"app_source"` // portal, master-data, kepegawaian
+	Deskripsi      string    `json:"deskripsi,omitempty" db:"deskripsi"`
+	IsSystem       bool      `json:"is_system" db:"is_system"`
+	Priority       int       `json:"priority" db:"priority"`
+	KeycloakClient *string   `json:"keycloak_client,omitempty" db:"keycloak_client"` // NULL = realm role
+	IsActive       bool      `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RoleDefinition adalah role aktif beserta prioritas dan role yang diwarisinya,
+// dipakai untuk memetakan role token Keycloak ke hierarki app_roles
+type RoleDefinition struct {
+	Nama           string
+	KeycloakClient *string
+	Priority       int
+	Includes       []string
 }
 
 // AppPermission
@@ -422,28 +481,132 @@ type RolePermission struct {
 
 // UserAppRole
 type UserAppRole struct {
-	ID         uuid.UUID  `json:"id" db:"id"`
-	UserID     string     `json:"user_id" db:"user_id"` // Keycloak user ID
-	RoleID     uuid.UUID  `json:"role_id" db:"role_id"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"` // Keycloak user ID
+	RoleID      uuid.UUID  `json:"role_id" db:"role_id"`
 	UnitKerjaID *uuid.UUID `json:"unit_kerja_id,omitempty" db:"unit_kerja_id"`
-	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	AssignedBy  *string    `json:"assigned_by,omitempty" db:"assigned_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+
+	// Joined
+	RoleNama string `json:"role_nama,omitempty" db:"-"`
+}
+
+// APIKey adalah kredensial jangka panjang untuk client mesin. Secret hanya
+// disimpan sebagai hash; Key terisi sekali saat key dibuat atau dirotasi.
+type APIKey struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Nama        string     `json:"nama" db:"nama"`
+	Prefix      string     `json:"prefix" db:"prefix"`
+	SecretHash  string     `json:"-" db:"secret_hash"`
+	OwnerUserID string     `json:"owner_user_id" db:"owner_user_id"` // Keycloak user ID / service account
+	Scopes      []string   `json:"scopes" db:"scopes"`               // nama app_permissions
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP  *string    `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy   *string    `json:"revoked_by,omitempty" db:"revoked_by"`
+	RotatedFrom *uuid.UUID `json:"rotated_from,omitempty" db:"rotated_from"`
+	CreatedBy   *string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+
+	// Hanya saat dibuat/dirotasi
+	Key string `json:"key,omitempty" db:"-"`
+	// Joined: nama app_roles milik owner
+	OwnerRoles []string `json:"-" db:"-"`
+}
+
+// Jenis pencabutan token
+const (
+	RevocationJTI = "jti" // satu access token
+	RevocationSID = "sid" // semua token dari satu session Keycloak
+	RevocationSub = "sub" // semua token user yang terbit sebelum IssuedBefore
+)
+
+// TokenRevocation mencabut access token Keycloak sebelum kadaluarsa
+type TokenRevocation struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	Kind         string     `json:"kind" db:"kind"`
+	Value        string     `json:"value" db:"value"`
+	UserID       *string    `json:"user_id,omitempty" db:"user_id"`
+	IssuedBefore *time.Time `json:"issued_before,omitempty" db:"issued_before"`
+	Reason       *string    `json:"reason,omitempty" db:"reason"`
+	RevokedBy    *string    `json:"revoked_by,omitempty" db:"revoked_by"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ImpersonationSession adalah session berbatas waktu saat aktor bertindak
+// sebagai user lain
+type ImpersonationSession struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	ActorUserID    string     `json:"actor_user_id" db:"actor_user_id"`
+	ActorUsername  *string    `json:"actor_username,omitempty" db:"actor_username"`
+	TargetUserID   string     `json:"target_user_id" db:"target_user_id"`
+	TargetUsername *string    `json:"target_username,omitempty" db:"target_username"`
+	Reason         string     `json:"reason" db:"reason"`
+	StartedAt      time.Time  `json:"started_at" db:"started_at"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	EndedAt        *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	EndedBy        *string    `json:"ended_by,omitempty" db:"ended_by"`
+}
+
+// IsActive mengecek apakah session belum diakhiri dan belum kadaluarsa
+func (s *ImpersonationSession) IsActive(now time.Time) bool {
+	return s.EndedAt == nil && now.Before(s.ExpiresAt)
 }
 
 // ==================== AUDIT MODELS ====================
 
 // AuditLog
 type AuditLog struct {
-	ID            uuid.UUID              `json:"id" db:"id"`
-	UserID        *string                `json:"user_id,omitempty" db:"user_id"`
-	Username      *string                `json:"username,omitempty" db:"username"`
-	Action         string                 `json:"action" db:"action"`
-	Resource       string                 `json:"resource" db:"resource"`
-	ResourceID     *uuid.UUID             `json:"resource_id,omitempty" db:"resource_id"`
+	ID           uuid.UUID              `json:"id" db:"id"`
+	UserID       *string                `json:"user_id,omitempty" db:"user_id"`
+	Username     *string                `json:"username,omitempty" db:"username"`
+	Action       string                 `json:"action" db:"action"`
+	Resource     string                 `json:"resource" db:"resource"`
+	ResourceID   *uuid.UUID             `json:"resource_id,omitempty" db:"resource_id"`
+	IPAddress    *string                `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent    *string                `json:"user_agent,omitempty" db:"user_agent"`
+	Changes      map[string]interface{} `json:"changes,omitempty" db:"changes"`
+	Status       string                 `json:"status" db:"status"`
+	ErrorMessage *string                `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+	// Terisi bila aktor (UserID) bertindak atas nama user lain
+	ImpersonatedUserID     *string    `json:"impersonated_user_id,omitempty" db:"impersonated_user_id"`
+	ImpersonatedUsername   *string    `json:"impersonated_username,omitempty" db:"impersonated_username"`
+	ImpersonationSessionID *uuid.UUID `json:"impersonation_session_id,omitempty" db:"impersonation_session_id"`
+	// Posisi baris pada hash chain; nil untuk baris sebelum chain diaktifkan
+	Seq     *int64  `json:"seq,omitempty" db:"seq"`
+	RowHash *string `json:"row_hash,omitempty" db:"row_hash"`
+}
+
+// Status alert aktivitas mencurigakan
+const (
+	AlertStatusOpen         = "open"
+	AlertStatusAcknowledged = "acknowledged"
+	AlertStatusResolved     = "resolved"
+)
+
+// AuditAlert adalah aktivitas mencurigakan yang terdeteksi rule atas audit stream
+type AuditAlert struct {
+	ID             uuid.UUID              `json:"id" db:"id"`
+	Rule           string                 `json:"rule" db:"rule"`
+	Severity       string                 `json:"severity" db:"severity"`
+	UserID         *string                `json:"user_id,omitempty" db:"user_id"`
+	Username       *string                `json:"username,omitempty" db:"username"`
 	IPAddress      *string                `json:"ip_address,omitempty" db:"ip_address"`
-	UserAgent      *string                `json:"user_agent,omitempty" db:"user_agent"`
-	Changes        map[string]interface{} `json:"changes,omitempty" db:"changes"`
+	Summary        string                 `json:"summary" db:"summary"`
+	Details        map[string]interface{} `json:"details,omitempty" db:"details"`
+	EventCount     int                    `json:"event_count" db:"event_count"`
+	FirstSeen      time.Time              `json:"first_seen" db:"first_seen"`
+	LastSeen       time.Time              `json:"last_seen" db:"last_seen"`
 	Status         string                 `json:"status" db:"status"`
-	ErrorMessage   *string                `json:"error_message,omitempty" db:"error_message"`
+	AcknowledgedBy *string                `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+	AcknowledgedAt *time.Time             `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	ResolvedBy     *string                `json:"resolved_by,omitempty" db:"resolved_by"`
+	ResolvedAt     *time.Time             `json:"resolved_at,omitempty" db:"resolved_at"`
+	Note           *string                `json:"note,omitempty" db:"note"`
 	CreatedAt      time.Time              `json:"created_at" db:"created_at"`
 }
 
@@ -500,6 +663,16 @@ type LoginResponse struct {
 	User         UserDTO `json:"user"`
 }
 
+// RefreshTokenRequest
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// ForceLogoutRequest
+type ForceLogoutRequest struct {
+	Reason string `json:"reason"`
+}
+
 // UserDTO
 type UserDTO struct {
 	ID          string          `json:"id"`
@@ -510,6 +683,32 @@ type UserDTO struct {
 	Permissions map[string]bool `json:"permissions,omitempty"`
 }
 
+// CurrentUserDTO - Response /auth/me untuk bootstrap frontend
+type CurrentUserDTO struct {
+	UserDTO
+	Role        string          `json:"role"`
+	AuthMethod  string          `json:"auth_method"`
+	UnitKerjaID *string         `json:"unit_kerja_id"`
+	SatkerID    *string         `json:"satker_id"`
+	Pegawai     *PegawaiSummary `json:"pegawai"`
+	// ImpersonatedBy berisi username aktor bila request memakai session impersonation
+	ImpersonatedBy *string `json:"impersonated_by,omitempty"`
+}
+
+// PegawaiSummary - Ringkasan pegawai yang terhubung dengan akun user
+type PegawaiSummary struct {
+	ID            uuid.UUID  `json:"id"`
+	NIP           string     `json:"nip"`
+	NamaLengkap   string     `json:"nama_lengkap"`
+	GelarDepan    *string    `json:"gelar_depan,omitempty"`
+	GelarBelakang *string    `json:"gelar_belakang,omitempty"`
+	Foto          *string    `json:"foto,omitempty"`
+	SatkerID      uuid.UUID  `json:"satker_id"`
+	UnitKerjaID   *uuid.UUID `json:"unit_kerja_id,omitempty"`
+	JabatanID     *uuid.UUID `json:"jabatan_id,omitempty"`
+	GolonganID    *uuid.UUID `json:"golongan_id,omitempty"`
+}
+
 // DropdownOption - Generic dropdown response
 type DropdownOption struct {
 	Value string `json:"value"`
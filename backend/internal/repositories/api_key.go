@@ -0,0 +1,249 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sikerma/backend/internal/apikey"
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== API KEYS ====================
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyRevoked  = errors.New("api key already revoked")
+)
+
+// lastUsedInterval membatasi frekuensi update last_used_at per key
+const lastUsedInterval = time.Minute
+
+// rowQuerier dipenuhi oleh *pgxpool.Pool maupun pgx.Tx
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// APIKeyRepository mengelola API key client mesin
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAPIKeyRepository membuat instance APIKeyRepository baru
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// CreateAPIKeyInput untuk menerbitkan API key baru
+type CreateAPIKeyInput struct {
+	Nama        string     `json:"nama" validate:"required,max=100"`
+	OwnerUserID string     `json:"owner_user_id"`
+	Scopes      []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+const apiKeyColumns = `id, nama, prefix, secret_hash, owner_user_id, scopes, expires_at, last_used_at, last_used_ip,
+			  revoked_at, revoked_by, rotated_from, created_by, created_at`
+
+// scanAPIKey memindai satu baris apiKeyColumns
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var k models.APIKey
+	err := row.Scan(&k.ID, &k.Nama, &k.Prefix, &k.SecretHash, &k.OwnerUserID, &k.Scopes, &k.ExpiresAt,
+		&k.LastUsedAt, &k.LastUsedIP, &k.RevokedAt, &k.RevokedBy, &k.RotatedFrom, &k.CreatedBy, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// UnknownScopes mengembalikan scope yang tidak terdaftar di app_permissions
+func (r *APIKeyRepository) UnknownScopes(ctx context.Context, scopes []string) ([]string, error) {
+	query := `SELECT s FROM unnest($1::text[]) AS s
+			  WHERE NOT EXISTS (SELECT 1 FROM app_permissions p WHERE p.nama = s)`
+
+	rows, err := r.db.Query(ctx, query, scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate api key scopes: %w", err)
+	}
+	defer rows.Close()
+
+	unknown := []string{}
+	for rows.Next() {
+		var scope string
+		if err := rows.Scan(&scope); err != nil {
+			return nil, fmt.Errorf("failed to scan scope: %w", err)
+		}
+		unknown = append(unknown, scope)
+	}
+
+	return unknown, rows.Err()
+}
+
+// Create menerbitkan API key baru. Key utuh hanya ada di hasil kembalian.
+func (r *APIKeyRepository) Create(ctx context.Context, input CreateAPIKeyInput, createdBy string) (*models.APIKey, error) {
+	return r.insert(ctx, r.db, input, nil, createdBy)
+}
+
+// insert membuat baris api_keys baru dengan secret acak
+func (r *APIKeyRepository) insert(ctx context.Context, q rowQuerier, input CreateAPIKeyInput, rotatedFrom *uuid.UUID, createdBy string) (*models.APIKey, error) {
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO api_keys (nama, prefix, secret_hash, owner_user_id, scopes, expires_at, rotated_from, created_by)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING ` + apiKeyColumns
+
+	created, err := scanAPIKey(q.QueryRow(ctx, query,
+		input.Nama, prefix, hash, input.OwnerUserID, input.Scopes, input.ExpiresAt, rotatedFrom, createdBy,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	created.Key = key
+	return created, nil
+}
+
+// List mengambil daftar API key, opsional difilter per owner
+func (r *APIKeyRepository) List(ctx context.Context, ownerUserID string) ([]models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys
+			  WHERE ($1 = '' OR owner_user_id = $1)
+			  ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, ownerUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *k)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetByID mengambil API key berdasarkan ID
+func (r *APIKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return k, nil
+}
+
+// Rotate menerbitkan key baru dengan nama, owner, scope dan expiry yang sama
+// lalu mencabut key lama dalam satu transaksi
+func (r *APIKeyRepository) Rotate(ctx context.Context, id uuid.UUID, actor string) (*models.APIKey, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	old, err := scanAPIKey(tx.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1 FOR UPDATE`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	if old.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	rotated, err := r.insert(ctx, tx, CreateAPIKeyInput{
+		Nama:        old.Nama,
+		OwnerUserID: old.OwnerUserID,
+		Scopes:      old.Scopes,
+		ExpiresAt:   old.ExpiresAt,
+	}, &old.ID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE api_keys SET revoked_at = NOW(), revoked_by = $2 WHERE id = $1`, id, actor); err != nil {
+		return nil, fmt.Errorf("failed to revoke rotated api key: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit api key rotation: %w", err)
+	}
+
+	return rotated, nil
+}
+
+// Revoke mencabut API key
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uuid.UUID, actor string) error {
+	result, err := r.db.Exec(ctx,
+		`UPDATE api_keys SET revoked_at = NOW(), revoked_by = $2 WHERE id = $1 AND revoked_at IS NULL`, id, actor)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrAPIKeyRevoked
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey memverifikasi key dari header X-API-Key dan mengembalikan
+// key beserta role owner. Key yang tidak dikenal, dicabut atau kadaluarsa
+// menghasilkan apikey.ErrInvalid.
+func (r *APIKeyRepository) AuthenticateAPIKey(ctx context.Context, rawKey, clientIP string) (*models.APIKey, error) {
+	prefix, err := apikey.Parse(rawKey)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + apiKeyColumns + `,
+			  COALESCE((SELECT array_agg(ar.nama ORDER BY ar.nama)
+			            FROM user_app_roles uar
+			            JOIN app_roles ar ON ar.id = uar.role_id AND ar.is_active = true
+			            WHERE uar.user_id = k.owner_user_id), '{}')
+			  FROM api_keys k
+			  WHERE prefix = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+	var k models.APIKey
+	err = r.db.QueryRow(ctx, query, prefix).Scan(&k.ID, &k.Nama, &k.Prefix, &k.SecretHash, &k.OwnerUserID, &k.Scopes,
+		&k.ExpiresAt, &k.LastUsedAt, &k.LastUsedIP, &k.RevokedAt, &k.RevokedBy, &k.RotatedFrom, &k.CreatedBy,
+		&k.CreatedAt, &k.OwnerRoles)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apikey.ErrInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	if !apikey.Verify(rawKey, k.SecretHash) {
+		return nil, apikey.ErrInvalid
+	}
+
+	if k.LastUsedAt == nil || time.Since(*k.LastUsedAt) > lastUsedInterval {
+		_, err := r.db.Exec(ctx, `UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2 WHERE id = $1`, k.ID, clientIP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update api key usage: %w", err)
+		}
+	}
+
+	return &k, nil
+}
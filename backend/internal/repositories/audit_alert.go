@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== AUDIT ALERTS ====================
+
+var (
+	ErrAlertNotFound = errors.New("audit alert not found")
+	ErrAlertStatus   = errors.New("audit alert status does not allow this transition")
+)
+
+// AuditAlertFilter filter daftar alert; field kosong tidak dipakai
+type AuditAlertFilter struct {
+	Status   string
+	Rule     string
+	Severity string
+	UserID   string
+}
+
+const auditAlertColumns = `id, rule, severity, user_id, username, host(ip_address), summary, details,
+			  event_count, first_seen, last_seen, status, acknowledged_by, acknowledged_at,
+			  resolved_by, resolved_at, note, created_at`
+
+// scanAuditAlert memindai satu baris auditAlertColumns
+func scanAuditAlert(row pgx.Row) (*models.AuditAlert, error) {
+	var a models.AuditAlert
+	err := row.Scan(&a.ID, &a.Rule, &a.Severity, &a.UserID, &a.Username, &a.IPAddress, &a.Summary, &a.Details,
+		&a.EventCount, &a.FirstSeen, &a.LastSeen, &a.Status, &a.AcknowledgedBy, &a.AcknowledgedAt,
+		&a.ResolvedBy, &a.ResolvedAt, &a.Note, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateAlert menyimpan alert baru dan mengisi ID, Status dan CreatedAt
+func (r *AuditRepository) CreateAlert(ctx context.Context, alert *models.AuditAlert) error {
+	query := `INSERT INTO audit_alerts (rule, severity, user_id, username, ip_address, summary, details,
+			  event_count, first_seen, last_seen)
+			  VALUES ($1, $2, $3, $4, $5::inet, $6, $7, $8, $9, $10)
+			  RETURNING id, status, created_at`
+
+	err := r.db.QueryRow(ctx, query,
+		alert.Rule, alert.Severity, alert.UserID, alert.Username, alert.IPAddress, alert.Summary, alert.Details,
+		alert.EventCount, alert.FirstSeen, alert.LastSeen,
+	).Scan(&alert.ID, &alert.Status, &alert.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create audit alert: %w", err)
+	}
+	return nil
+}
+
+// KnownIPs mengambil IP yang pernah dipakai user di audit log sejak since
+func (r *AuditRepository) KnownIPs(ctx context.Context, userID string, since time.Time) ([]string, error) {
+	query := `SELECT DISTINCT host(ip_address) FROM audit_logs
+			  WHERE user_id = $1 AND created_at >= $2 AND ip_address IS NOT NULL`
+
+	rows, err := r.db.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query known IPs: %w", err)
+	}
+	defer rows.Close()
+
+	ips := []string{}
+	for rows.Next() {
+		var ip string
+		if err := rows.Scan(&ip); err != nil {
+			return nil, fmt.Errorf("failed to scan known IP: %w", err)
+		}
+		ips = append(ips, ip)
+	}
+
+	return ips, rows.Err()
+}
+
+// ListAlerts mengambil alert terbaru dengan filter
+func (r *AuditRepository) ListAlerts(ctx context.Context, filter AuditAlertFilter, page, limit int) ([]models.AuditAlert, int64, error) {
+	offset := (page - 1) * limit
+
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		where += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if filter.Rule != "" {
+		add("rule = $%d", filter.Rule)
+	}
+	if filter.Severity != "" {
+		add("severity = $%d", filter.Severity)
+	}
+	if filter.UserID != "" {
+		add("user_id = $%d", filter.UserID)
+	}
+
+	var total int64
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM audit_alerts"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit alerts: %w", err)
+	}
+
+	query := `SELECT ` + auditAlertColumns + ` FROM audit_alerts` + where +
+		fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []models.AuditAlert{}
+	for rows.Next() {
+		a, err := scanAuditAlert(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit alert: %w", err)
+		}
+		alerts = append(alerts, *a)
+	}
+
+	return alerts, total, rows.Err()
+}
+
+// GetAlert mengambil alert berdasarkan ID
+func (r *AuditRepository) GetAlert(ctx context.Context, id uuid.UUID) (*models.AuditAlert, error) {
+	a, err := scanAuditAlert(r.db.QueryRow(ctx, `SELECT `+auditAlertColumns+` FROM audit_alerts WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit alert: %w", err)
+	}
+	return a, nil
+}
+
+// AcknowledgeAlert menandai alert open sedang ditangani oleh by
+func (r *AuditRepository) AcknowledgeAlert(ctx context.Context, id uuid.UUID, by string, note *string) (*models.AuditAlert, error) {
+	query := `UPDATE audit_alerts SET status = 'acknowledged', acknowledged_by = $2, acknowledged_at = NOW(),
+			  note = COALESCE($3, note)
+			  WHERE id = $1 AND status = 'open'
+			  RETURNING ` + auditAlertColumns
+
+	return r.transitionAlert(ctx, id, query, by, note)
+}
+
+// ResolveAlert menutup alert open atau acknowledged. Alert yang langsung
+// di-resolve juga dicatat sebagai acknowledged oleh by.
+func (r *AuditRepository) ResolveAlert(ctx context.Context, id uuid.UUID, by string, note *string) (*models.AuditAlert, error) {
+	query := `UPDATE audit_alerts SET status = 'resolved', resolved_by = $2, resolved_at = NOW(),
+			  acknowledged_by = COALESCE(acknowledged_by, $2), acknowledged_at = COALESCE(acknowledged_at, NOW()),
+			  note = COALESCE($3, note)
+			  WHERE id = $1 AND status IN ('open', 'acknowledged')
+			  RETURNING ` + auditAlertColumns
+
+	return r.transitionAlert(ctx, id, query, by, note)
+}
+
+// transitionAlert menjalankan UPDATE status; tidak ada baris berarti alert
+// tidak ada atau statusnya tidak sesuai
+func (r *AuditRepository) transitionAlert(ctx context.Context, id uuid.UUID, query, by string, note *string) (*models.AuditAlert, error) {
+	a, err := scanAuditAlert(r.db.QueryRow(ctx, query, id, by, note))
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := r.GetAlert(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrAlertStatus
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update audit alert: %w", err)
+	}
+	return a, nil
+}
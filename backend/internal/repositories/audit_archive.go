@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sikerma/backend/internal/audit"
+)
+
+// EnsurePartitions membuat partisi audit_logs untuk months bulan mulai dari
+// bulan from bila belum ada
+func (r *AuditRepository) EnsurePartitions(ctx context.Context, from time.Time, months int) error {
+	for i := 0; i < months; i++ {
+		month := from.AddDate(0, i, 0).Format("2006-01-02")
+		if _, err := r.db.Exec(ctx, `SELECT audit_logs_ensure_partition($1::date)`, month); err != nil {
+			return fmt.Errorf("failed to create audit partition for %s: %w", month, err)
+		}
+	}
+	return nil
+}
+
+// ListPartitions mengambil partisi bulanan audit_logs terurut dari yang tertua
+func (r *AuditRepository) ListPartitions(ctx context.Context) ([]audit.Partition, error) {
+	query := `SELECT c.relname FROM pg_inherits i
+			  JOIN pg_class c ON c.oid = i.inhrelid
+			  WHERE i.inhparent = 'audit_logs'::regclass
+			  ORDER BY c.relname`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit partitions: %w", err)
+	}
+	defer rows.Close()
+
+	partitions := []audit.Partition{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan audit partition: %w", err)
+		}
+		// Partisi yang tidak dibuat oleh audit_logs_ensure_partition dibiarkan
+		if p, ok := audit.ParsePartition(name); ok {
+			partitions = append(partitions, p)
+		}
+	}
+
+	return partitions, rows.Err()
+}
+
+// StreamPartition membaca seluruh baris satu partisi berurutan berdasarkan seq
+func (r *AuditRepository) StreamPartition(ctx context.Context, name string, fn func(audit.ChainEntry) error) error {
+	if _, ok := audit.ParsePartition(name); !ok {
+		return fmt.Errorf("invalid audit partition name %q", name)
+	}
+
+	query := `SELECT ` + chainEntryColumns + `
+			  FROM ` + pgx.Identifier{name}.Sanitize() + `
+			  ORDER BY seq NULLS FIRST, created_at`
+
+	return r.queryChainEntries(ctx, query, fn)
+}
+
+// DropArchivedPartition mencatat archive ke audit_archives lalu melepas dan
+// menghapus partisinya beserta snapshot revert-nya dalam satu transaksi
+func (r *AuditRepository) DropArchivedPartition(ctx context.Context, archive audit.Archive) error {
+	if _, ok := audit.ParsePartition(archive.Partition); !ok {
+		return fmt.Errorf("invalid audit partition name %q", archive.Partition)
+	}
+	table := pgx.Identifier{archive.Partition}.Sanitize()
+
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		query := `INSERT INTO audit_archives (partition_name, range_from, range_to, file, sha256,
+				  row_count, first_seq, last_seq, last_row_hash)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+		_, err := tx.Exec(ctx, query,
+			archive.Partition, archive.RangeFrom, archive.RangeTo, archive.File, archive.SHA256,
+			archive.Rows, archive.FirstSeq, archive.LastSeq, archive.LastRowHash,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record audit archive: %w", err)
+		}
+
+		// Snapshot revert ikut masa retensi audit log-nya
+		if _, err := tx.Exec(ctx, `DELETE FROM audit_snapshots WHERE created_at < $1`, archive.RangeTo); err != nil {
+			return fmt.Errorf("failed to delete audit snapshots: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `ALTER TABLE audit_logs DETACH PARTITION `+table); err != nil {
+			return fmt.Errorf("failed to detach audit partition: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DROP TABLE `+table); err != nil {
+			return fmt.Errorf("failed to drop audit partition: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListArchives mengambil partisi yang sudah diarsipkan
+func (r *AuditRepository) ListArchives(ctx context.Context) ([]audit.Archive, error) {
+	query := `SELECT partition_name, range_from, range_to, file, sha256, row_count,
+			  first_seq, last_seq, last_row_hash
+			  FROM audit_archives
+			  ORDER BY range_from`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit archives: %w", err)
+	}
+	defer rows.Close()
+
+	archives := []audit.Archive{}
+	for rows.Next() {
+		var a audit.Archive
+		err := rows.Scan(&a.Partition, &a.RangeFrom, &a.RangeTo, &a.File, &a.SHA256, &a.Rows,
+			&a.FirstSeq, &a.LastSeq, &a.LastRowHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit archive: %w", err)
+		}
+		archives = append(archives, a)
+	}
+
+	return archives, rows.Err()
+}
+
+// ChainStart mengembalikan baris hash chain terakhir yang sudah diarsipkan.
+// Anchor kosong berarti belum ada arsip dan verifikasi dimulai dari genesis.
+func (r *AuditRepository) ChainStart(ctx context.Context) (audit.Anchor, error) {
+	query := `SELECT last_seq, last_row_hash, archived_at FROM audit_archives
+			  WHERE last_seq IS NOT NULL
+			  ORDER BY last_seq DESC
+			  LIMIT 1`
+
+	var a audit.Anchor
+	err := r.db.QueryRow(ctx, query).Scan(&a.Seq, &a.RowHash, &a.AnchoredAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return audit.Anchor{}, nil
+	}
+	if err != nil {
+		return audit.Anchor{}, fmt.Errorf("failed to query audit chain start: %w", err)
+	}
+	return a, nil
+}
+
+// RestoreEntries memuat baris dari file arsip ke audit_logs_restored. Baris
+// yang sudah pernah dimuat dari file yang sama dihapus lebih dulu sehingga
+// restore dapat diulang.
+func (r *AuditRepository) RestoreEntries(ctx context.Context, file string, entries []audit.ChainEntry) (int64, error) {
+	columns := append(append([]string{}, chainEntryCopyColumns...), "archive_file")
+
+	var restored int64
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM audit_logs_restored WHERE archive_file = $1`, file); err != nil {
+			return fmt.Errorf("failed to clear restored audit logs: %w", err)
+		}
+
+		rows := make([][]interface{}, 0, len(entries))
+		for _, e := range entries {
+			row, err := chainEntryRow(e)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, append(row, file))
+		}
+
+		n, err := tx.CopyFrom(ctx, pgx.Identifier{"audit_logs_restored"}, columns, pgx.CopyFromRows(rows))
+		if err != nil {
+			return fmt.Errorf("failed to restore audit logs: %w", err)
+		}
+		restored = n
+		return nil
+	})
+
+	return restored, err
+}
@@ -0,0 +1,286 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== AUDIT REVERT ====================
+
+var (
+	ErrAuditLogNotFound = errors.New("audit log not found")
+	ErrSnapshotNotFound = errors.New("audit snapshot not found")
+	ErrRevertStale      = errors.New("record changed while the revert was applied")
+)
+
+// Revertable adalah resource yang perubahannya dapat dibatalkan dari audit log
+type Revertable interface {
+	// CurrentRecord mengembalikan JSON record saat ini, nil bila tidak ada
+	CurrentRecord(ctx context.Context, id uuid.UUID) (json.RawMessage, error)
+	// ApplyRevert menjalankan plan.Operation dengan nilai dari plan.Target.
+	// ErrRevertStale bila record berubah dari plan.Expected sebelum dikunci.
+	ApplyRevert(ctx context.Context, id uuid.UUID, plan *audit.RevertPlan, userID string) error
+}
+
+// GetByID mengambil satu audit log
+func (r *AuditRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AuditLog, error) {
+	log, err := scanAuditLog(r.db.QueryRow(ctx, `SELECT `+auditLogColumns+` FROM audit_logs WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAuditLogNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	return log, nil
+}
+
+// GetSnapshot mengambil state record tanpa masking yang dicatat bersama audit log
+func (r *AuditRepository) GetSnapshot(ctx context.Context, auditLogID uuid.UUID) (*audit.Snapshot, error) {
+	var snap audit.Snapshot
+	var before, after []byte
+	err := r.db.QueryRow(ctx, `SELECT before, after FROM audit_snapshots WHERE audit_log_id = $1`, auditLogID).Scan(&before, &after)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit snapshot: %w", err)
+	}
+	snap.Before, snap.After = before, after
+	return &snap, nil
+}
+
+// ListReverts mengambil entry revert yang membatalkan audit log auditLogID
+func (r *AuditRepository) ListReverts(ctx context.Context, auditLogID uuid.UUID) ([]models.AuditLog, error) {
+	contains, err := json.Marshal(map[string]interface{}{"reverted_audit_id": auditLogID})
+	if err != nil {
+		return nil, err
+	}
+	logs, _, err := r.List(ctx, AuditLogFilter{Action: audit.ActionRevert, ChangesContain: contains}, 1, 100)
+	return logs, err
+}
+
+// revertInput menyusun input update dari record saat ini dengan field target
+// dikembalikan. Field yang tidak dapat diubah lewat input ditolak.
+func revertInput(current interface{}, target map[string]interface{}, input interface{}) error {
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+
+	allowed := jsonFieldNames(input)
+	for field, value := range target {
+		if !allowed[field] {
+			return fmt.Errorf("%w: %s", audit.ErrRevertField, field)
+		}
+		if value == nil {
+			delete(fields, field)
+		} else {
+			fields[field] = value
+		}
+	}
+
+	if raw, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, input)
+}
+
+// jsonFieldNames mengembalikan nama field JSON struct yang ditunjuk v
+func jsonFieldNames(v interface{}) map[string]bool {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// ==================== PEGAWAI ====================
+
+// CurrentRecord mengambil pegawai termasuk yang sudah dihapus (soft delete)
+func (r *PegawaiRepository) CurrentRecord(ctx context.Context, id uuid.UUID) (json.RawMessage, error) {
+	pegawai, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Pegawai, error) {
+		return getPegawai(ctx, tx, id, false)
+	})
+	if errors.Is(err, ErrPegawaiNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(pegawai)
+}
+
+// ApplyRevert membatalkan perubahan pegawai. Pegawai yang dibuat dihapus
+// (soft delete) dan pegawai yang dihapus diaktifkan kembali. Pemeriksaan
+// konflik dan penulisan berjalan dalam satu transaksi.
+func (r *PegawaiRepository) ApplyRevert(ctx context.Context, id uuid.UUID, plan *audit.RevertPlan, _ string) error {
+	action := audit.ActionUpdate
+	var before, after *models.Pegawai
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		before, err = getPegawai(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		if plan.Stale(before) {
+			return ErrRevertStale
+		}
+
+		switch plan.Operation {
+		case audit.RevertUpdate:
+			var input UpdatePegawaiInput
+			if err := revertInput(before, plan.Target, &input); err != nil {
+				return err
+			}
+			after, err = updatePegawai(ctx, tx, id, input)
+			return err
+		case audit.RevertRestore:
+			if before.DeletedAt == nil {
+				return ErrRevertStale
+			}
+			isActive, _ := plan.Target["is_active"].(bool)
+			after, err = restorePegawai(ctx, tx, id, isActive)
+			return err
+		case audit.RevertDelete:
+			action = audit.ActionDelete
+			after, err = deletePegawai(ctx, tx, id)
+			return err
+		default:
+			return audit.ErrRevertUnsupported
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(ctx, action, "pegawai", id, before, after)
+	return nil
+}
+
+// restorePegawai membatalkan soft delete pegawai yang sudah dikunci pemanggil
+func restorePegawai(ctx context.Context, tx pgx.Tx, id uuid.UUID, isActive bool) (*models.Pegawai, error) {
+	query := `UPDATE pegawai p SET is_active = $2, deleted_at = NULL, deleted_by = NULL, updated_at = NOW()
+			  WHERE p.id = $1
+			  RETURNING ` + pegawaiColumns
+
+	after, err := scanPegawai(tx.QueryRow(ctx, query, id, isActive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore pegawai: %w", err)
+	}
+	return after, nil
+}
+
+// ==================== SATKER ====================
+
+// CurrentRecord mengambil satker saat ini
+func (r *SatkerRepository) CurrentRecord(ctx context.Context, id uuid.UUID) (json.RawMessage, error) {
+	satker, err := r.GetByID(ctx, id.String())
+	if errors.Is(err, ErrSatkerNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(satker)
+}
+
+// ApplyRevert membatalkan perubahan satker. Satker yang dihapus dibuat ulang
+// dengan ID dan data terakhirnya. Pemeriksaan konflik dan penulisan berjalan
+// dalam satu transaksi.
+func (r *SatkerRepository) ApplyRevert(ctx context.Context, id uuid.UUID, plan *audit.RevertPlan, userID string) error {
+	if plan.Operation == audit.RevertRestore {
+		var satker models.Satker
+		if err := revertInput(models.Satker{}, plan.Target, &satker); err != nil {
+			return err
+		}
+		satker.ID = id
+		return r.restore(ctx, &satker, userID)
+	}
+
+	action := audit.ActionUpdate
+	var before, after *models.Satker
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		before, err = getSatker(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+		if plan.Stale(before) {
+			return ErrRevertStale
+		}
+
+		switch plan.Operation {
+		case audit.RevertUpdate:
+			var input UpdateSatkerInput
+			if err := revertInput(before, plan.Target, &input); err != nil {
+				return err
+			}
+			after, err = updateSatker(ctx, tx, id, input, userID)
+			return err
+		case audit.RevertDelete:
+			action = audit.ActionDelete
+			return deleteSatker(ctx, tx, id)
+		default:
+			return audit.ErrRevertUnsupported
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(ctx, action, "satker", id, before, after)
+	return nil
+}
+
+// restore membuat ulang satker yang terhapus
+func (r *SatkerRepository) restore(ctx context.Context, satker *models.Satker, userID string) error {
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		satker.UpdatedBy = actorUUID(userID)
+
+		query := `INSERT INTO satker (id, kode, nama, parent_id, level, alamat, telepon, email, is_active,
+				  created_at, created_by, updated_by)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				  RETURNING updated_at`
+
+		err := tx.QueryRow(ctx, query,
+			satker.ID, satker.Kode, satker.Nama, satker.ParentID, satker.Level,
+			satker.Alamat, satker.Telepon, satker.Email, satker.IsActive,
+			satker.CreatedAt, satker.CreatedBy, satker.UpdatedBy,
+		).Scan(&satker.UpdatedAt)
+		if isUniqueViolation(err) {
+			return ErrRevertStale
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore satker: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.ActionCreate, "satker", satker.ID, nil, satker)
+	return nil
+}
@@ -0,0 +1,361 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== HUKDIS ====================
+
+var (
+	ErrHukdisNotFound      = errors.New("hukdis not found")
+	ErrJenisHukdisNotFound = errors.New("jenis hukdis not found")
+	// ErrHukdisAktif dikembalikan CheckAktif dan CreatePangkat/UpdatePangkat
+	// bila pegawai sedang menjalani hukuman disiplin
+	ErrHukdisAktif = errors.New("pegawai has active hukdis")
+)
+
+// HukdisRepository mengelola hukuman disiplin pegawai
+type HukdisRepository struct {
+	dbKepegawaian *pgxpool.Pool
+	dbMaster      *pgxpool.Pool
+}
+
+// NewHukdisRepository membuat instance HukdisRepository baru
+func NewHukdisRepository(dbKepegawaian, dbMaster *pgxpool.Pool) *HukdisRepository {
+	return &HukdisRepository{
+		dbKepegawaian: dbKepegawaian,
+		dbMaster:      dbMaster,
+	}
+}
+
+// HukdisInput input untuk mencatat dan mengubah hukuman disiplin
+type HukdisInput struct {
+	JenisHukdisID  uuid.UUID  `json:"jenis_hukdis_id"`
+	NomorSK        string     `json:"nomor_sk"`
+	TanggalSK      time.Time  `json:"tanggal_sk"`
+	TanggalMulai   time.Time  `json:"tanggal_mulai"`
+	TanggalSelesai *time.Time `json:"tanggal_selesai,omitempty"`
+	Alasan         *string    `json:"alasan,omitempty"`
+	Pejabat        string     `json:"pejabat"`
+}
+
+const hukdisColumns = `id, pegawai_id, jenis_hukdis_id, nomor_sk, tanggal_sk, tanggal_mulai, tanggal_selesai,
+			  alasan, pejabat, created_at, updated_at, created_by`
+
+// scanHukdis memindai satu baris hukdisColumns
+func scanHukdis(row pgx.Row) (*models.Hukdis, error) {
+	var h models.Hukdis
+	err := row.Scan(&h.ID, &h.PegawaiID, &h.JenisHukdisID, &h.NomorSK, &h.TanggalSK, &h.TanggalMulai, &h.TanggalSelesai,
+		&h.Alasan, &h.Pejabat, &h.CreatedAt, &h.UpdatedAt, &h.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// getHukdis mengambil hukdis milik pegawai di dalam transaksi
+func getHukdis(ctx context.Context, tx pgx.Tx, pegawaiID, id uuid.UUID, forUpdate bool) (*models.Hukdis, error) {
+	query := `SELECT ` + hukdisColumns + ` FROM hukdis WHERE id = $1 AND pegawai_id = $2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	h, err := scanHukdis(tx.QueryRow(ctx, query, id, pegawaiID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrHukdisNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hukdis: %w", err)
+	}
+	return h, nil
+}
+
+// queryHukdis menjalankan query hukdisColumns dan memindai seluruh barisnya
+func queryHukdis(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]models.Hukdis, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hukdis: %w", err)
+	}
+	defer rows.Close()
+
+	list := []models.Hukdis{}
+	for rows.Next() {
+		h, err := scanHukdis(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hukdis: %w", err)
+		}
+		list = append(list, *h)
+	}
+	return list, rows.Err()
+}
+
+// hukdisAktif mengambil hukdis pegawai yang berlaku pada tanggal at.
+// tanggal_selesai adalah hari terakhir hukuman; kosong berarti belum selesai.
+func hukdisAktif(ctx context.Context, tx pgx.Tx, pegawaiID uuid.UUID, at time.Time) ([]models.Hukdis, error) {
+	query := `SELECT ` + hukdisColumns + ` FROM hukdis
+			  WHERE pegawai_id = $1 AND tanggal_mulai <= $2::date
+			  AND (tanggal_selesai IS NULL OR tanggal_selesai >= $2::date)
+			  ORDER BY tanggal_mulai DESC, created_at DESC`
+	return queryHukdis(ctx, tx, query, pegawaiID, at)
+}
+
+// checkHukdis menolak perubahan yang berlaku pada tanggal at, misalnya
+// kenaikan pangkat, bila pegawai sedang menjalani hukuman disiplin
+func checkHukdis(ctx context.Context, tx pgx.Tx, pegawaiID uuid.UUID, at time.Time) error {
+	aktif, err := hukdisAktif(ctx, tx, pegawaiID, at)
+	if err != nil {
+		return err
+	}
+	if len(aktif) > 0 {
+		return ErrHukdisAktif
+	}
+	return nil
+}
+
+// jenisHukdisByIDs mengambil ref_jenis_hukdis dari db_master
+func (r *HukdisRepository) jenisHukdisByIDs(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]*models.RefJenisHukdis, error) {
+	query := `SELECT id, kode, nama, COALESCE(is_active, false), created_at, updated_at
+			  FROM ref_jenis_hukdis WHERE id = ANY($1)`
+
+	rows, err := r.dbMaster.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ref jenis hukdis: %w", err)
+	}
+	defer rows.Close()
+
+	jenis := map[uuid.UUID]*models.RefJenisHukdis{}
+	for rows.Next() {
+		var j models.RefJenisHukdis
+		if err := rows.Scan(&j.ID, &j.Kode, &j.Nama, &j.IsActive, &j.CreatedAt, &j.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ref jenis hukdis: %w", err)
+		}
+		jenis[j.ID] = &j
+	}
+
+	return jenis, rows.Err()
+}
+
+// resolveJenisHukdis memvalidasi jenis_hukdis_id input terhadap ref_jenis_hukdis
+func (r *HukdisRepository) resolveJenisHukdis(ctx context.Context, id uuid.UUID) (*models.RefJenisHukdis, error) {
+	jenis, err := r.jenisHukdisByIDs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	j, ok := jenis[id]
+	if !ok {
+		return nil, ErrJenisHukdisNotFound
+	}
+	return j, nil
+}
+
+// attachJenisHukdis mengisi relasi jenis hukdis
+func (r *HukdisRepository) attachJenisHukdis(ctx context.Context, list []models.Hukdis) error {
+	ids := make([]uuid.UUID, 0, len(list))
+	for _, h := range list {
+		ids = append(ids, h.JenisHukdisID)
+	}
+	jenis, err := r.jenisHukdisByIDs(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		list[i].JenisHukdis = jenis[list[i].JenisHukdisID]
+	}
+	return nil
+}
+
+// recordHukdisAccess mencatat hukdis yang dikembalikan untuk read audit
+func recordHukdisAccess(ctx context.Context, list []models.Hukdis) {
+	ids := make([]uuid.UUID, 0, len(list))
+	for _, h := range list {
+		ids = append(ids, h.ID)
+	}
+	audit.RecordAccess(ctx, "hukdis", ids...)
+}
+
+// List mengambil hukdis pegawai, terbaru lebih dulu
+func (r *HukdisRepository) List(ctx context.Context, pegawaiID uuid.UUID) ([]models.Hukdis, error) {
+	list, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) ([]models.Hukdis, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		query := `SELECT ` + hukdisColumns + ` FROM hukdis
+				  WHERE pegawai_id = $1
+				  ORDER BY tanggal_mulai DESC, created_at DESC`
+		return queryHukdis(ctx, tx, query, pegawaiID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordHukdisAccess(ctx, list)
+	if err := r.attachJenisHukdis(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Get mengambil satu hukdis pegawai
+func (r *HukdisRepository) Get(ctx context.Context, pegawaiID, id uuid.UUID) (*models.Hukdis, error) {
+	h, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.Hukdis, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		return getHukdis(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.RecordAccess(ctx, "hukdis", h.ID)
+	jenis, err := r.jenisHukdisByIDs(ctx, h.JenisHukdisID)
+	if err != nil {
+		return nil, err
+	}
+	h.JenisHukdis = jenis[h.JenisHukdisID]
+	return h, nil
+}
+
+// Aktif mengambil hukdis pegawai yang berlaku pada tanggal at
+func (r *HukdisRepository) Aktif(ctx context.Context, pegawaiID uuid.UUID, at time.Time) ([]models.Hukdis, error) {
+	list, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) ([]models.Hukdis, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		return hukdisAktif(ctx, tx, pegawaiID, at)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordHukdisAccess(ctx, list)
+	if err := r.attachJenisHukdis(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CheckAktif mengembalikan ErrHukdisAktif bila pegawai menjalani hukuman
+// disiplin pada tanggal at, untuk perubahan yang harus ditolak selama hukuman
+func (r *HukdisRepository) CheckAktif(ctx context.Context, pegawaiID uuid.UUID, at time.Time) error {
+	return database.WithRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) error {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return err
+		}
+		return checkHukdis(ctx, tx, pegawaiID, at)
+	})
+}
+
+// Create mencatat hukdis pegawai
+func (r *HukdisRepository) Create(ctx context.Context, pegawaiID uuid.UUID, input HukdisInput, userID string) (*models.Hukdis, error) {
+	jenis, err := r.resolveJenisHukdis(ctx, input.JenisHukdisID)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.Hukdis, error) {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return nil, err
+		}
+
+		query := `INSERT INTO hukdis (pegawai_id, jenis_hukdis_id, nomor_sk, tanggal_sk, tanggal_mulai, tanggal_selesai,
+				  alasan, pejabat, created_by)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				  RETURNING ` + hukdisColumns
+
+		h, err := scanHukdis(tx.QueryRow(ctx, query,
+			pegawaiID, input.JenisHukdisID, input.NomorSK, input.TanggalSK, input.TanggalMulai, input.TanggalSelesai,
+			input.Alasan, input.Pejabat, actorUUID(userID),
+		))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create hukdis: %w", err)
+		}
+		return h, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionCreate, "hukdis", h.ID, nil, h)
+	h.JenisHukdis = jenis
+	return h, nil
+}
+
+// Update mengubah hukdis pegawai
+func (r *HukdisRepository) Update(ctx context.Context, pegawaiID, id uuid.UUID, input HukdisInput) (*models.Hukdis, error) {
+	jenis, err := r.resolveJenisHukdis(ctx, input.JenisHukdisID)
+	if err != nil {
+		return nil, err
+	}
+
+	var before *models.Hukdis
+	h, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.Hukdis, error) {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return nil, err
+		}
+		var err error
+		before, err = getHukdis(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return nil, err
+		}
+
+		query := `UPDATE hukdis
+				  SET jenis_hukdis_id = $2, nomor_sk = $3, tanggal_sk = $4, tanggal_mulai = $5, tanggal_selesai = $6,
+					  alasan = $7, pejabat = $8
+				  WHERE id = $1
+				  RETURNING ` + hukdisColumns
+
+		h, err := scanHukdis(tx.QueryRow(ctx, query,
+			id, input.JenisHukdisID, input.NomorSK, input.TanggalSK, input.TanggalMulai, input.TanggalSelesai,
+			input.Alasan, input.Pejabat,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update hukdis: %w", err)
+		}
+		return h, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "hukdis", h.ID, before, h)
+	h.JenisHukdis = jenis
+	return h, nil
+}
+
+// Delete menghapus hukdis pegawai
+func (r *HukdisRepository) Delete(ctx context.Context, pegawaiID, id uuid.UUID) error {
+	var before *models.Hukdis
+	err := database.WithRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) error {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return err
+		}
+		var err error
+		before, err = getHukdis(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM hukdis WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete hukdis: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.ActionDelete, "hukdis", id, before, nil)
+	return nil
+}
@@ -0,0 +1,135 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+func TestHukdisAktif(t *testing.T) {
+	suite := SetupRiwayatTest(t)
+	repo := repositories.NewHukdisRepository(suite.Kepegawaian, suite.Master)
+	ctx := context.Background()
+
+	teguran := suite.CreateJenisHukdis(t, "HD-R1", "Teguran Lisan")
+	penundaan := suite.CreateJenisHukdis(t, "HD-S1", "Penundaan Kenaikan Pangkat")
+	pegawaiID := suite.CreatePegawai(t, "198604042011011001")
+
+	input := func(jenisID uuid.UUID, mulai time.Time, selesai *time.Time) repositories.HukdisInput {
+		return repositories.HukdisInput{
+			JenisHukdisID:  jenisID,
+			NomorSK:        "SK/HD/" + mulai.Format("2006"),
+			TanggalSK:      mulai,
+			TanggalMulai:   mulai,
+			TanggalSelesai: selesai,
+			Pejabat:        "Ketua Pengadilan Tinggi Agama",
+		}
+	}
+	selesai := date(2023, 12, 31)
+	terbatas, err := repo.Create(ctx, pegawaiID, input(penundaan, date(2023, 1, 1), &selesai), "")
+	require.NoError(t, err)
+	terbuka, err := repo.Create(ctx, pegawaiID, input(teguran, date(2024, 6, 1), nil), "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want []uuid.UUID
+	}{
+		{"sebelum tanggal mulai", date(2022, 12, 31), nil},
+		{"tanggal mulai termasuk", date(2023, 1, 1), []uuid.UUID{terbatas.ID}},
+		{"tanggal selesai termasuk", date(2023, 12, 31), []uuid.UUID{terbatas.ID}},
+		{"sehari setelah selesai", date(2024, 1, 1), nil},
+		{"tanpa tanggal selesai berlaku sejak mulai", date(2024, 6, 1), []uuid.UUID{terbuka.ID}},
+		{"tanpa tanggal selesai berlaku seterusnya", date(2030, 1, 1), []uuid.UUID{terbuka.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := repo.Aktif(ctx, pegawaiID, tt.at)
+			require.NoError(t, err)
+
+			var ids []uuid.UUID
+			for _, h := range list {
+				ids = append(ids, h.ID)
+				assert.NotNil(t, h.JenisHukdis)
+			}
+			assert.Equal(t, tt.want, ids)
+
+			err = repo.CheckAktif(ctx, pegawaiID, tt.at)
+			if tt.want == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, repositories.ErrHukdisAktif)
+			}
+		})
+	}
+}
+
+func TestHukdisTolakKenaikanPangkat(t *testing.T) {
+	suite := SetupRiwayatTest(t)
+	hukdisRepo := repositories.NewHukdisRepository(suite.Kepegawaian, suite.Master)
+	riwayatRepo := repositories.NewRiwayatRepository(suite.Kepegawaian, suite.Master)
+	ctx := context.Background()
+
+	iiia := suite.CreateGolongan(t, "III/a", "Penata Muda", 31, true)
+	iiib := suite.CreateGolongan(t, "III/b", "Penata Muda Tingkat I", 32, true)
+	penundaan := suite.CreateJenisHukdis(t, "HD-S1", "Penundaan Kenaikan Pangkat")
+	pegawaiID := suite.CreatePegawai(t, "198808082013011001")
+
+	input := func(golonganID uuid.UUID, tmt time.Time) repositories.RiwayatPangkatInput {
+		return repositories.RiwayatPangkatInput{
+			GolonganID: golonganID,
+			TMT:        tmt,
+			NomorSK:    "SK/" + tmt.Format("2006"),
+			TanggalSK:  tmt.AddDate(0, -1, 0),
+			Pejabat:    "Sekretaris Mahkamah Agung",
+		}
+	}
+
+	awal, err := riwayatRepo.CreatePangkat(ctx, pegawaiID, input(iiia, date(2019, 4, 1)), "")
+	require.NoError(t, err)
+
+	selesai := date(2023, 12, 31)
+	_, err = hukdisRepo.Create(ctx, pegawaiID, repositories.HukdisInput{
+		JenisHukdisID:  penundaan,
+		NomorSK:        "SK/HD/2023",
+		TanggalSK:      date(2022, 12, 15),
+		TanggalMulai:   date(2023, 1, 1),
+		TanggalSelesai: &selesai,
+		Pejabat:        "Ketua Pengadilan Tinggi Agama",
+	}, "")
+	require.NoError(t, err)
+
+	t.Run("kenaikan pangkat selama hukuman ditolak", func(t *testing.T) {
+		_, err := riwayatRepo.CreatePangkat(ctx, pegawaiID, input(iiib, date(2023, 10, 1)), "")
+		assert.ErrorIs(t, err, repositories.ErrHukdisAktif)
+
+		assert.Equal(t, []uuid.UUID{awal.ID}, suite.Terakhir(t, "riwayat_pangkat", pegawaiID))
+		golonganID, tmt := suite.PangkatPegawai(t, pegawaiID)
+		assert.Equal(t, iiia, *golonganID)
+		assert.Equal(t, date(2019, 4, 1), tmt.UTC())
+	})
+
+	t.Run("update tmt ke masa hukuman ditolak", func(t *testing.T) {
+		_, err := riwayatRepo.UpdatePangkat(ctx, pegawaiID, awal.ID, input(iiia, date(2023, 4, 1)))
+		assert.ErrorIs(t, err, repositories.ErrHukdisAktif)
+
+		_, tmt := suite.PangkatPegawai(t, pegawaiID)
+		assert.Equal(t, date(2019, 4, 1), tmt.UTC())
+	})
+
+	t.Run("kenaikan pangkat setelah hukuman selesai diterima", func(t *testing.T) {
+		rp, err := riwayatRepo.CreatePangkat(ctx, pegawaiID, input(iiib, date(2024, 4, 1)), "")
+		require.NoError(t, err)
+
+		assert.Equal(t, []uuid.UUID{rp.ID}, suite.Terakhir(t, "riwayat_pangkat", pegawaiID))
+		golonganID, _ := suite.PangkatPegawai(t, pegawaiID)
+		assert.Equal(t, iiib, *golonganID)
+	})
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== IMPERSONATION ====================
+
+var (
+	ErrImpersonationNotFound = errors.New("impersonation session not found")
+	ErrImpersonationInactive = errors.New("impersonation session already ended or expired")
+)
+
+// ImpersonationRepository mengelola session impersonation di db_master
+type ImpersonationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewImpersonationRepository membuat instance ImpersonationRepository baru
+func NewImpersonationRepository(db *pgxpool.Pool) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+// StartImpersonationInput untuk memulai session impersonation
+type StartImpersonationInput struct {
+	TargetUserID    string  `json:"target_user_id" validate:"required"`
+	TargetUsername  *string `json:"target_username,omitempty"`
+	Reason          string  `json:"reason" validate:"required"`
+	DurationMinutes int     `json:"duration_minutes"`
+}
+
+const impersonationColumns = `id, actor_user_id, actor_username, target_user_id, target_username, reason,
+			  started_at, expires_at, ended_at, ended_by`
+
+// scanImpersonation memindai satu baris impersonationColumns
+func scanImpersonation(row pgx.Row) (*models.ImpersonationSession, error) {
+	var s models.ImpersonationSession
+	err := row.Scan(&s.ID, &s.ActorUserID, &s.ActorUsername, &s.TargetUserID, &s.TargetUsername, &s.Reason,
+		&s.StartedAt, &s.ExpiresAt, &s.EndedAt, &s.EndedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Start membuat session impersonation baru. Session aktif lain milik aktor
+// yang sama diakhiri sehingga satu aktor hanya memerankan satu user.
+func (r *ImpersonationRepository) Start(ctx context.Context, input StartImpersonationInput, duration time.Duration, actorID, actorUsername string) (*models.ImpersonationSession, error) {
+	var session *models.ImpersonationSession
+
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE impersonation_sessions SET ended_at = NOW(), ended_by = $1
+			  WHERE actor_user_id = $1 AND ended_at IS NULL AND expires_at > NOW()`, actorID)
+		if err != nil {
+			return fmt.Errorf("failed to end previous impersonation: %w", err)
+		}
+
+		query := `INSERT INTO impersonation_sessions (actor_user_id, actor_username, target_user_id, target_username, reason, expires_at)
+				  VALUES ($1, $2, $3, $4, $5, NOW() + $6::interval)
+				  RETURNING ` + impersonationColumns
+
+		session, err = scanImpersonation(tx.QueryRow(ctx, query,
+			actorID, actorUsername, input.TargetUserID, input.TargetUsername, input.Reason, duration.String(),
+		))
+		if err != nil {
+			return fmt.Errorf("failed to start impersonation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// GetByID mengambil session impersonation berdasarkan ID
+func (r *ImpersonationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ImpersonationSession, error) {
+	s, err := r.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrImpersonationNotFound
+	}
+	return s, nil
+}
+
+// FindByID mengambil session impersonation berdasarkan ID; nil bila tidak ada
+func (r *ImpersonationRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.ImpersonationSession, error) {
+	s, err := scanImpersonation(r.db.QueryRow(ctx, `SELECT `+impersonationColumns+` FROM impersonation_sessions WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get impersonation session: %w", err)
+	}
+	return s, nil
+}
+
+// List mengambil session impersonation terbaru, opsional hanya yang masih aktif
+func (r *ImpersonationRepository) List(ctx context.Context, activeOnly bool, limit int) ([]models.ImpersonationSession, error) {
+	query := `SELECT ` + impersonationColumns + ` FROM impersonation_sessions
+			  WHERE NOT $1 OR (ended_at IS NULL AND expires_at > NOW())
+			  ORDER BY started_at DESC
+			  LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, activeOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query impersonation sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.ImpersonationSession{}
+	for rows.Next() {
+		s, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan impersonation session: %w", err)
+		}
+		sessions = append(sessions, *s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// End mengakhiri session impersonation yang masih aktif
+func (r *ImpersonationRepository) End(ctx context.Context, id uuid.UUID, endedBy string) (*models.ImpersonationSession, error) {
+	query := `UPDATE impersonation_sessions SET ended_at = NOW(), ended_by = $2
+			  WHERE id = $1 AND ended_at IS NULL AND expires_at > NOW()
+			  RETURNING ` + impersonationColumns
+
+	s, err := scanImpersonation(r.db.QueryRow(ctx, query, id, endedBy))
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrImpersonationInactive
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to end impersonation: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,389 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
+	"github.com/sikerma/backend/internal/models"
+	"github.com/sikerma/backend/internal/tanggungan"
+	"github.com/sikerma/backend/internal/utils"
+)
+
+// ==================== KELUARGA ====================
+
+var ErrKeluargaNotFound = errors.New("keluarga not found")
+
+// KeluargaRepository mengelola data keluarga pegawai. is_tanggungan tidak
+// diisi user tetapi dihitung ulang dari aturan tunjangan keluarga setiap
+// data keluarga pegawai berubah.
+type KeluargaRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewKeluargaRepository membuat instance KeluargaRepository baru
+func NewKeluargaRepository(db *pgxpool.Pool) *KeluargaRepository {
+	return &KeluargaRepository{db: db}
+}
+
+// KeluargaInput input untuk membuat dan mengubah anggota keluarga
+type KeluargaInput struct {
+	Hubungan     models.StatusKeluarga `json:"hubungan"`
+	Nama         string                `json:"nama"`
+	TempatLahir  *string               `json:"tempat_lahir,omitempty"`
+	TanggalLahir *time.Time            `json:"tanggal_lahir,omitempty"`
+	JenisKelamin *string               `json:"jenis_kelamin,omitempty"`
+	NIK          *string               `json:"nik,omitempty"`
+	Pendidikan   *string               `json:"pendidikan,omitempty"`
+	Pekerjaan    *string               `json:"pekerjaan,omitempty"`
+}
+
+// PerubahanTanggungan adalah anggota keluarga yang status tanggungannya
+// berubah pada Tanggal. NIK anggota keluarga di-mask.
+type PerubahanTanggungan struct {
+	PegawaiID    uuid.UUID       `json:"pegawai_id"`
+	NIP          string          `json:"nip"`
+	NamaPegawai  string          `json:"nama_pegawai"`
+	Keluarga     models.Keluarga `json:"keluarga"`
+	Tanggal      time.Time       `json:"tanggal"`
+	IsTanggungan bool            `json:"is_tanggungan"`
+	Alasan       string          `json:"alasan"`
+}
+
+const keluargaColumns = `id, pegawai_id, status_keluarga, nama, tempat_lahir, tanggal_lahir, jenis_kelamin, nik,
+			  pendidikan, pekerjaan, COALESCE(is_tanggungan, false), created_at, updated_at, created_by`
+
+// scanKeluarga memindai satu baris keluargaColumns
+func scanKeluarga(row pgx.Row) (*models.Keluarga, error) {
+	var k models.Keluarga
+	err := row.Scan(&k.ID, &k.PegawaiID, &k.Hubungan, &k.Nama, &k.TempatLahir, &k.TanggalLahir, &k.JenisKelamin, &k.NIK,
+		&k.Pendidikan, &k.Pekerjaan, &k.IsTanggungan, &k.CreatedAt, &k.UpdatedAt, &k.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// getKeluarga mengambil anggota keluarga milik pegawai di dalam transaksi
+func getKeluarga(ctx context.Context, tx pgx.Tx, pegawaiID, id uuid.UUID, forUpdate bool) (*models.Keluarga, error) {
+	query := `SELECT ` + keluargaColumns + ` FROM keluarga WHERE id = $1 AND pegawai_id = $2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	k, err := scanKeluarga(tx.QueryRow(ctx, query, id, pegawaiID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrKeluargaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keluarga: %w", err)
+	}
+	return k, nil
+}
+
+// listKeluarga mengambil seluruh anggota keluarga pegawai di dalam transaksi
+func listKeluarga(ctx context.Context, tx pgx.Tx, pegawaiID uuid.UUID) ([]models.Keluarga, error) {
+	query := `SELECT ` + keluargaColumns + ` FROM keluarga
+			  WHERE pegawai_id = $1
+			  ORDER BY CASE status_keluarga WHEN 'Suami' THEN 1 WHEN 'Istri' THEN 1 WHEN 'Anak' THEN 2 ELSE 3 END,
+			  tanggal_lahir NULLS LAST, created_at`
+
+	rows, err := tx.Query(ctx, query, pegawaiID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query keluarga: %w", err)
+	}
+	defer rows.Close()
+
+	list := []models.Keluarga{}
+	for rows.Next() {
+		k, err := scanKeluarga(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan keluarga: %w", err)
+		}
+		list = append(list, *k)
+	}
+	return list, rows.Err()
+}
+
+// List mengambil anggota keluarga pegawai: suami/istri, anak, lalu orang tua
+func (r *KeluargaRepository) List(ctx context.Context, pegawaiID uuid.UUID) ([]models.Keluarga, error) {
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) ([]models.Keluarga, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		return listKeluarga(ctx, tx, pegawaiID)
+	})
+}
+
+// Get mengambil satu anggota keluarga pegawai
+func (r *KeluargaRepository) Get(ctx context.Context, pegawaiID, id uuid.UUID) (*models.Keluarga, error) {
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Keluarga, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		return getKeluarga(ctx, tx, pegawaiID, id, false)
+	})
+}
+
+// Create menambah anggota keluarga dan menghitung ulang tanggungan pegawai
+func (r *KeluargaRepository) Create(ctx context.Context, pegawaiID uuid.UUID, input KeluargaInput, userID string) (*models.Keluarga, error) {
+	var changed []keluargaChange
+	k, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Keluarga, error) {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return nil, err
+		}
+
+		query := `INSERT INTO keluarga (pegawai_id, status_keluarga, nama, tempat_lahir, tanggal_lahir, jenis_kelamin,
+				  nik, pendidikan, pekerjaan, is_tanggungan, created_by)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, false, $10)
+				  RETURNING id`
+
+		var id uuid.UUID
+		err := tx.QueryRow(ctx, query,
+			pegawaiID, input.Hubungan, input.Nama, input.TempatLahir, input.TanggalLahir, input.JenisKelamin,
+			input.NIK, input.Pendidikan, input.Pekerjaan, actorUUID(userID),
+		).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create keluarga: %w", err)
+		}
+
+		if changed, err = syncTanggungan(ctx, tx, pegawaiID, id); err != nil {
+			return nil, err
+		}
+		return getKeluarga(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionCreate, "keluarga", k.ID, nil, k)
+	recordTanggungan(ctx, changed)
+	return k, nil
+}
+
+// Update mengubah anggota keluarga dan menghitung ulang tanggungan pegawai
+func (r *KeluargaRepository) Update(ctx context.Context, pegawaiID, id uuid.UUID, input KeluargaInput) (*models.Keluarga, error) {
+	var before *models.Keluarga
+	var changed []keluargaChange
+	k, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Keluarga, error) {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return nil, err
+		}
+		var err error
+		before, err = getKeluarga(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return nil, err
+		}
+
+		query := `UPDATE keluarga
+				  SET status_keluarga = $2, nama = $3, tempat_lahir = $4, tanggal_lahir = $5, jenis_kelamin = $6,
+					  nik = $7, pendidikan = $8, pekerjaan = $9
+				  WHERE id = $1`
+
+		_, err = tx.Exec(ctx, query,
+			id, input.Hubungan, input.Nama, input.TempatLahir, input.TanggalLahir, input.JenisKelamin,
+			input.NIK, input.Pendidikan, input.Pekerjaan,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update keluarga: %w", err)
+		}
+
+		if changed, err = syncTanggungan(ctx, tx, pegawaiID, id); err != nil {
+			return nil, err
+		}
+		return getKeluarga(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "keluarga", k.ID, before, k)
+	recordTanggungan(ctx, changed)
+	return k, nil
+}
+
+// Delete menghapus anggota keluarga. Anggota lain dapat menggantikan
+// tanggungannya.
+func (r *KeluargaRepository) Delete(ctx context.Context, pegawaiID, id uuid.UUID) error {
+	var before *models.Keluarga
+	var changed []keluargaChange
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return err
+		}
+		var err error
+		before, err = getKeluarga(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM keluarga WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete keluarga: %w", err)
+		}
+
+		changed, err = syncTanggungan(ctx, tx, pegawaiID, uuid.Nil)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.ActionDelete, "keluarga", id, before, nil)
+	recordTanggungan(ctx, changed)
+	return nil
+}
+
+// PerubahanTanggungan mencari anggota keluarga pegawai aktif yang status
+// tanggungannya berubah setelah from sampai dengan to, karena anak mencapai
+// batas usia atau digantikan anak berikutnya
+func (r *KeluargaRepository) PerubahanTanggungan(ctx context.Context, from, to time.Time) ([]PerubahanTanggungan, error) {
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) ([]PerubahanTanggungan, error) {
+		// Batas usia sekolah dihitung di aplikasi; query cukup memilih
+		// pegawai yang anaknya berulang tahun ke-21 atau ke-25 di periode
+		query := `SELECT p.id, p.nip, p.nama_lengkap FROM pegawai p
+				  WHERE p.is_active = true AND p.deleted_at IS NULL
+				  AND EXISTS (
+					  SELECT 1 FROM keluarga k
+					  WHERE k.pegawai_id = p.id AND k.status_keluarga = 'Anak' AND k.tanggal_lahir IS NOT NULL
+					  AND ((k.tanggal_lahir + make_interval(years => $3)) > $1::date AND (k.tanggal_lahir + make_interval(years => $3)) <= $2::date
+						OR (k.tanggal_lahir + make_interval(years => $4)) > $1::date AND (k.tanggal_lahir + make_interval(years => $4)) <= $2::date)
+				  )
+				  ORDER BY p.nama_lengkap`
+
+		rows, err := tx.Query(ctx, query, from, to, tanggungan.BatasUsiaAnak, tanggungan.BatasUsiaAnakSekolah)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query pegawai: %w", err)
+		}
+		type pegawaiRow struct {
+			id   uuid.UUID
+			nip  string
+			nama string
+		}
+		var pegawais []pegawaiRow
+		for rows.Next() {
+			var p pegawaiRow
+			if err := rows.Scan(&p.id, &p.nip, &p.nama); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan pegawai: %w", err)
+			}
+			pegawais = append(pegawais, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		report := []PerubahanTanggungan{}
+		for _, p := range pegawais {
+			keluarga, err := listKeluarga(ctx, tx, p.id)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]models.Keluarga, len(keluarga))
+			for _, k := range keluarga {
+				if k.NIK != nil {
+					masked := utils.MaskNIK(*k.NIK)
+					k.NIK = &masked
+				}
+				byID[k.ID] = k
+			}
+
+			for _, c := range tanggungan.Changes(keluarga, from, to) {
+				report = append(report, PerubahanTanggungan{
+					PegawaiID:    p.id,
+					NIP:          p.nip,
+					NamaPegawai:  p.nama,
+					Keluarga:     byID[c.KeluargaID],
+					Tanggal:      c.Tanggal,
+					IsTanggungan: c.IsTanggungan,
+					Alasan:       c.Alasan,
+				})
+			}
+		}
+		return report, nil
+	})
+}
+
+// SyncTanggunganAll menghitung ulang is_tanggungan seluruh keluarga pegawai
+// pada hari ini. Perlu dijalankan berkala karena status anak berubah
+// seiring usianya. Mengembalikan jumlah anggota keluarga yang berubah.
+func (r *KeluargaRepository) SyncTanggunganAll(ctx context.Context) (int, error) {
+	ctx = database.AsSystem(ctx)
+
+	ids, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) ([]uuid.UUID, error) {
+		rows, err := tx.Query(ctx, `SELECT DISTINCT pegawai_id FROM keluarga ORDER BY pegawai_id`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query keluarga: %w", err)
+		}
+		return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, id := range ids {
+		err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+			if _, err := getPegawai(ctx, tx, id, true); err != nil {
+				return err
+			}
+			changed, err := syncTanggungan(ctx, tx, id, uuid.Nil)
+			total += len(changed)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// keluargaChange adalah anggota keluarga yang status tanggungannya diubah
+// syncTanggungan, untuk dicatat di audit log
+type keluargaChange struct {
+	before, after *models.Keluarga
+}
+
+// syncTanggungan menghitung ulang is_tanggungan seluruh keluarga pegawai dan
+// menyimpan yang berubah. Perubahan pada anggota skip (yang sedang dibuat
+// atau diubah) tidak dikembalikan karena sudah tercatat di audit log-nya
+// sendiri.
+func syncTanggungan(ctx context.Context, tx pgx.Tx, pegawaiID, skip uuid.UUID) ([]keluargaChange, error) {
+	keluarga, err := listKeluarga(ctx, tx, pegawaiID)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []keluargaChange
+	status := tanggungan.Evaluate(keluarga, time.Now())
+	for i := range keluarga {
+		before := keluarga[i]
+		if status[before.ID] == before.IsTanggungan {
+			continue
+		}
+
+		query := `UPDATE keluarga SET is_tanggungan = $2 WHERE id = $1 RETURNING ` + keluargaColumns
+		after, err := scanKeluarga(tx.QueryRow(ctx, query, before.ID, status[before.ID]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync tanggungan: %w", err)
+		}
+		if before.ID != skip {
+			changed = append(changed, keluargaChange{before: &before, after: after})
+		}
+	}
+	return changed, nil
+}
+
+// recordTanggungan mencatat perubahan tanggungan anggota keluarga lain
+func recordTanggungan(ctx context.Context, changed []keluargaChange) {
+	for _, c := range changed {
+		audit.Record(ctx, audit.ActionUpdate, "keluarga", c.after.ID, c.before, c.after)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/sikerma/backend/internal/database"
 	"github.com/sikerma/backend/internal/models"
 )
 
@@ -220,32 +222,39 @@ func (r *UnitKerjaRepository) List(ctx context.Context, page, limit int, search
 		countQuery += fmt.Sprintf(" AND (kode ILIKE $%d OR nama ILIKE $%d)", 1, 2)
 	}
 	var total int64
-	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count unit_kerja: %w", err)
-	}
-
-	// Get data
-	query += fmt.Sprintf(" ORDER BY kode LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, limit, offset)
+	unitKerjas := []models.UnitKerja{}
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, countQuery, args...).Scan(&total)
+		if err != nil {
+			return fmt.Errorf("failed to count unit_kerja: %w", err)
+		}
 
-	rows, err := r.db.Query(ctx, query, args...)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to query unit_kerja: %w", err)
-	}
-	defer rows.Close()
+		// Get data
+		query += fmt.Sprintf(" ORDER BY kode LIMIT $%d OFFSET $%d", argCount, argCount+1)
+		args = append(args, limit, offset)
 
-	unitKerjas := []models.UnitKerja{}
-	for rows.Next() {
-		var unitKerja models.UnitKerja
-		err := rows.Scan(
-			&unitKerja.ID, &unitKerja.Kode, &unitKerja.Nama, &unitKerja.Singkatan,
-			&unitKerja.ParentID, &unitKerja.IsActive, &unitKerja.CreatedAt, &unitKerja.UpdatedAt,
-		)
+		rows, err := tx.Query(ctx, query, args...)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan unit_kerja: %w", err)
+			return fmt.Errorf("failed to query unit_kerja: %w", err)
 		}
-		unitKerjas = append(unitKerjas, unitKerja)
+		defer rows.Close()
+
+		for rows.Next() {
+			var unitKerja models.UnitKerja
+			err := rows.Scan(
+				&unitKerja.ID, &unitKerja.Kode, &unitKerja.Nama, &unitKerja.Singkatan,
+				&unitKerja.ParentID, &unitKerja.IsActive, &unitKerja.CreatedAt, &unitKerja.UpdatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to scan unit_kerja: %w", err)
+			}
+			unitKerjas = append(unitKerjas, unitKerja)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
 	return unitKerjas, total, nil
@@ -253,25 +262,27 @@ func (r *UnitKerjaRepository) List(ctx context.Context, page, limit int, search
 
 // GetDropdown mengambil data dropdown
 func (r *UnitKerjaRepository) GetDropdown(ctx context.Context) ([]DropdownItem, error) {
-	query := `SELECT id, kode, nama FROM unit_kerja WHERE is_active = true ORDER BY kode`
-
-	rows, err := r.db.Query(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query dropdown: %w", err)
-	}
-	defer rows.Close()
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) ([]DropdownItem, error) {
+		query := `SELECT id, kode, nama FROM unit_kerja WHERE is_active = true ORDER BY kode`
 
-	items := []DropdownItem{}
-	for rows.Next() {
-		var item DropdownItem
-		err := rows.Scan(&item.Value, &item.Label, &item.Label)
+		rows, err := tx.Query(ctx, query)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan dropdown: %w", err)
+			return nil, fmt.Errorf("failed to query dropdown: %w", err)
+		}
+		defer rows.Close()
+
+		items := []DropdownItem{}
+		for rows.Next() {
+			var item DropdownItem
+			err := rows.Scan(&item.Value, &item.Label, &item.Label)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan dropdown: %w", err)
+			}
+			items = append(items, item)
 		}
-		items = append(items, item)
-	}
 
-	return items, nil
+		return items, nil
+	})
 }
 
 // ==================== ESELON ====================
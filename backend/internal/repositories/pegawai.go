@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,9 +10,14 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
 	"github.com/sikerma/backend/internal/models"
 )
 
+// ErrPegawaiNotFound dikembalikan bila pegawai tidak ditemukan atau di luar scope RLS
+var ErrPegawaiNotFound = errors.New("pegawai not found")
+
 // ==================== PEGAWAI ====================
 
 // PegawaiRepository mengelola operasi database untuk Pegawai
@@ -25,7 +31,7 @@ func NewPegawaiRepository(db *pgxpool.Pool) *PegawaiRepository {
 }
 
 // List mengambil daftar pegawai dengan pagination dan filter
-func (r *PegawaiRepository) List(ctx context.Context, page, limit int, search, satkerID, jabatanID, golonganID, statusPegawai, statusKerja string) ([]models.Pegawai, int64, error) {
+func (r *PegawaiRepository) List(ctx context.Context, page, limit int, search, satkerID, jabatanID, golonganID, pendidikanID, statusPegawai, statusKerja string) ([]models.Pegawai, int64, error) {
 	offset := (page - 1) * limit
 
 	// Query menggunakan kolom baru dari schema
@@ -33,7 +39,7 @@ func (r *PegawaiRepository) List(ctx context.Context, page, limit int, search, s
 			  p.tempat_lahir, p.tanggal_lahir, p.jenis_kelamin,
 			  p.agama_id, p.status_kawin_id, p.nik, p.email, p.telepon,
 			  p.alamat, p.alamat_domisili, p.foto, p.satker_id, p.jabatan_id, p.unit_kerja_id,
-			  p.golongan_id, p.eselon_id, p.status_pegawai, p.status_kerja,
+			  p.golongan_id, p.eselon_id, p.pendidikan_terakhir_id, p.status_pegawai, p.status_kerja,
 			  p.tmt_cpns, p.tmt_pns, p.tmt_jabatan, p.tmt_pangkat_terakhir, p.tmt_jabatan_terakhir,
 			  p.karpeg_no, p.karpeg_file, p.taspen_no, p.npwp,
 			  p.bpjs_kesehatan, p.bpjs_ketenagakerjaan, p.kk_no, p.kk_file, p.ktp_no, p.ktp_file,
@@ -67,6 +73,12 @@ func (r *PegawaiRepository) List(ctx context.Context, page, limit int, search, s
 		argCount++
 	}
 
+	if pendidikanID != "" {
+		query += fmt.Sprintf(" AND p.pendidikan_terakhir_id = $%d", argCount)
+		args = append(args, uuid.MustParse(pendidikanID))
+		argCount++
+	}
+
 	if statusPegawai != "" {
 		query += fmt.Sprintf(" AND p.status_pegawai = $%d", argCount)
 		args = append(args, statusPegawai)
@@ -108,6 +120,12 @@ func (r *PegawaiRepository) List(ctx context.Context, page, limit int, search, s
 		countArgCount++
 	}
 
+	if pendidikanID != "" {
+		countQuery += fmt.Sprintf(" AND p.pendidikan_terakhir_id = $%d", countArgCount)
+		countArgs = append(countArgs, uuid.MustParse(pendidikanID))
+		countArgCount++
+	}
+
 	if statusPegawai != "" {
 		countQuery += fmt.Sprintf(" AND p.status_pegawai = $%d", countArgCount)
 		countArgs = append(countArgs, statusPegawai)
@@ -121,84 +139,105 @@ func (r *PegawaiRepository) List(ctx context.Context, page, limit int, search, s
 	}
 
 	var total int64
-	err := r.db.QueryRow(ctx, countQuery, countArgs...).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count pegawai: %w", err)
-	}
+	pegawais := []models.Pegawai{}
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, countQuery, countArgs...).Scan(&total)
+		if err != nil {
+			return fmt.Errorf("failed to count pegawai: %w", err)
+		}
 
-	// Get data
-	query += fmt.Sprintf(" ORDER BY p.nama_lengkap LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, limit, offset)
+		// Get data
+		query += fmt.Sprintf(" ORDER BY p.nama_lengkap LIMIT $%d OFFSET $%d", argCount, argCount+1)
+		args = append(args, limit, offset)
+
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query pegawai: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var pegawai models.Pegawai
+			var statusPegawai models.StatusPegawai
+			var statusKerja models.StatusKerja
+
+			err := rows.Scan(
+				&pegawai.ID, &pegawai.NIP, &pegawai.NIPLama, &pegawai.NamaLengkap, &pegawai.GelarDepan, &pegawai.GelarBelakang,
+				&pegawai.TempatLahir, &pegawai.TanggalLahir, &pegawai.JenisKelamin,
+				&pegawai.AgamaID, &pegawai.StatusKawinID, &pegawai.NIK, &pegawai.Email, &pegawai.Telepon,
+				&pegawai.Alamat, &pegawai.AlamatDomisili, &pegawai.Foto, &pegawai.SatkerID, &pegawai.JabatanID, &pegawai.UnitKerjaID,
+				&pegawai.GolonganID, &pegawai.EselonID, &pegawai.PendidikanTerakhirID, &statusPegawai, &statusKerja,
+				&pegawai.TMTCpns, &pegawai.TMTPns, &pegawai.TMTJabatan, &pegawai.TMTPangkatTerakhir, &pegawai.TMTJabatanTerakhir,
+				&pegawai.KarpegNo, &pegawai.KarpegFile, &pegawai.TaspenNo, &pegawai.NPWP,
+				&pegawai.BPJSSehatan, &pegawai.BPJSKetenagakerjaan, &pegawai.KKNo, &pegawai.KKFile, &pegawai.KTPNo, &pegawai.KTPFile,
+				&pegawai.SikepID, &pegawai.IsActive, &pegawai.CreatedAt, &pegawai.UpdatedAt, &pegawai.CreatedBy, &pegawai.UpdatedBy, &pegawai.DeletedAt, &pegawai.DeletedBy,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to scan pegawai: %w", err)
+			}
+			pegawai.StatusPegawai = statusPegawai
+			pegawai.StatusKerja = statusKerja
+			pegawais = append(pegawais, pegawai)
+		}
 
-	rows, err := r.db.Query(ctx, query, args...)
+		return rows.Err()
+	})
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to query pegawai: %w", err)
+		return nil, 0, err
 	}
-	defer rows.Close()
 
-	pegawais := []models.Pegawai{}
-	for rows.Next() {
-		var pegawai models.Pegawai
-		var statusPegawai models.StatusPegawai
-		var statusKerja models.StatusKerja
-
-		err := rows.Scan(
-			&pegawai.ID, &pegawai.NIP, &pegawai.NIPLama, &pegawai.NamaLengkap, &pegawai.GelarDepan, &pegawai.GelarBelakang,
-			&pegawai.TempatLahir, &pegawai.TanggalLahir, &pegawai.JenisKelamin,
-			&pegawai.AgamaID, &pegawai.StatusKawinID, &pegawai.NIK, &pegawai.Email, &pegawai.Telepon,
-			&pegawai.Alamat, &pegawai.AlamatDomisili, &pegawai.Foto, &pegawai.SatkerID, &pegawai.JabatanID, &pegawai.UnitKerjaID,
-			&pegawai.GolonganID, &pegawai.EselonID, &statusPegawai, &statusKerja,
-			&pegawai.TMTCpns, &pegawai.TMTPns, &pegawai.TMTJabatan, &pegawai.TMTPangkatTerakhir, &pegawai.TMTJabatanTerakhir,
-			&pegawai.KarpegNo, &pegawai.KarpegFile, &pegawai.TaspenNo, &pegawai.NPWP,
-			&pegawai.BPJSSehatan, &pegawai.BPJSKetenagakerjaan, &pegawai.KKNo, &pegawai.KKFile, &pegawai.KTPNo, &pegawai.KTPFile,
-			&pegawai.SikepID, &pegawai.IsActive, &pegawai.CreatedAt, &pegawai.UpdatedAt, &pegawai.CreatedBy, &pegawai.UpdatedBy, &pegawai.DeletedAt, &pegawai.DeletedBy,
-		)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan pegawai: %w", err)
-		}
-		pegawai.StatusPegawai = statusPegawai
-		pegawai.StatusKerja = statusKerja
-		pegawais = append(pegawais, pegawai)
+	ids := make([]uuid.UUID, 0, len(pegawais))
+	for _, p := range pegawais {
+		ids = append(ids, p.ID)
 	}
+	audit.RecordAccess(ctx, "pegawai", ids...)
 
 	return pegawais, total, nil
 }
 
 // GetByID mengambil detail pegawai dengan relasi
 func (r *PegawaiRepository) GetByID(ctx context.Context, id string) (*models.Pegawai, error) {
-	query := `SELECT p.id, p.nip, p.nip_lama, p.nama_lengkap, p.gelar_depan, p.gelar_belakang,
-			  p.tempat_lahir, p.tanggal_lahir, p.jenis_kelamin,
-			  p.agama_id, p.status_kawin_id, p.nik, p.email, p.telepon,
-			  p.alamat, p.alamat_domisili, p.foto, p.satker_id, p.jabatan_id, p.unit_kerja_id,
-			  p.golongan_id, p.eselon_id, p.status_pegawai, p.status_kerja,
-			  p.tmt_cpns, p.tmt_pns, p.tmt_jabatan, p.tmt_pangkat_terakhir, p.tmt_jabatan_terakhir,
-			  p.karpeg_no, p.karpeg_file, p.taspen_no, p.npwp,
-			  p.bpjs_kesehatan, p.bpjs_ketenagakerjaan, p.kk_no, p.kk_file, p.ktp_no, p.ktp_file,
-			  p.sikep_id, p.is_active, p.created_at, p.updated_at, p.created_by, p.updated_by, p.deleted_at, p.deleted_by
-			  FROM pegawai p
-			  WHERE p.id = $1`
+	pegawai, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Pegawai, error) {
+		return getPegawai(ctx, tx, uuid.MustParse(id), false)
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	audit.RecordAccess(ctx, "pegawai", pegawai.ID)
+	return pegawai, nil
+}
+
+// pegawaiColumns adalah kolom lengkap models.Pegawai dengan alias tabel p
+const pegawaiColumns = `p.id, p.nip, p.nip_lama, p.nama_lengkap, p.gelar_depan, p.gelar_belakang,
+				  p.tempat_lahir, p.tanggal_lahir, p.jenis_kelamin,
+				  p.agama_id, p.status_kawin_id, p.nik, p.email, p.telepon,
+				  p.alamat, p.alamat_domisili, p.foto, p.satker_id, p.jabatan_id, p.unit_kerja_id,
+				  p.golongan_id, p.eselon_id, p.pendidikan_terakhir_id, p.status_pegawai, p.status_kerja,
+				  p.tmt_cpns, p.tmt_pns, p.tmt_jabatan, p.tmt_pangkat_terakhir, p.tmt_jabatan_terakhir,
+				  p.karpeg_no, p.karpeg_file, p.taspen_no, p.npwp,
+				  p.bpjs_kesehatan, p.bpjs_ketenagakerjaan, p.kk_no, p.kk_file, p.ktp_no, p.ktp_file,
+				  p.sikep_id, p.is_active, p.created_at, p.updated_at, p.created_by, p.updated_by, p.deleted_at, p.deleted_by`
+
+// scanPegawai memindai satu baris pegawaiColumns
+func scanPegawai(row pgx.Row) (*models.Pegawai, error) {
 	var pegawai models.Pegawai
 	var statusPegawai models.StatusPegawai
 	var statusKerja models.StatusKerja
 
-	err := r.db.QueryRow(ctx, query, uuid.MustParse(id)).Scan(
+	err := row.Scan(
 		&pegawai.ID, &pegawai.NIP, &pegawai.NIPLama, &pegawai.NamaLengkap, &pegawai.GelarDepan, &pegawai.GelarBelakang,
 		&pegawai.TempatLahir, &pegawai.TanggalLahir, &pegawai.JenisKelamin,
 		&pegawai.AgamaID, &pegawai.StatusKawinID, &pegawai.NIK, &pegawai.Email, &pegawai.Telepon,
 		&pegawai.Alamat, &pegawai.AlamatDomisili, &pegawai.Foto, &pegawai.SatkerID, &pegawai.JabatanID, &pegawai.UnitKerjaID,
-		&pegawai.GolonganID, &pegawai.EselonID, &statusPegawai, &statusKerja,
+		&pegawai.GolonganID, &pegawai.EselonID, &pegawai.PendidikanTerakhirID, &statusPegawai, &statusKerja,
 		&pegawai.TMTCpns, &pegawai.TMTPns, &pegawai.TMTJabatan, &pegawai.TMTPangkatTerakhir, &pegawai.TMTJabatanTerakhir,
 		&pegawai.KarpegNo, &pegawai.KarpegFile, &pegawai.TaspenNo, &pegawai.NPWP,
 		&pegawai.BPJSSehatan, &pegawai.BPJSKetenagakerjaan, &pegawai.KKNo, &pegawai.KKFile, &pegawai.KTPNo, &pegawai.KTPFile,
 		&pegawai.SikepID, &pegawai.IsActive, &pegawai.CreatedAt, &pegawai.UpdatedAt, &pegawai.CreatedBy, &pegawai.UpdatedBy, &pegawai.DeletedAt, &pegawai.DeletedBy,
 	)
-
-	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("pegawai not found")
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pegawai: %w", err)
+		return nil, err
 	}
 
 	pegawai.StatusPegawai = statusPegawai
@@ -207,129 +246,188 @@ func (r *PegawaiRepository) GetByID(ctx context.Context, id string) (*models.Peg
 	return &pegawai, nil
 }
 
-// GetByNIP mengambil detail pegawai berdasarkan NIP
-func (r *PegawaiRepository) GetByNIP(ctx context.Context, nip string) (*models.Pegawai, error) {
-	query := `SELECT p.id, p.nip, p.nip_lama, p.nama_lengkap, p.gelar_depan, p.gelar_belakang,
-			  p.tempat_lahir, p.tanggal_lahir, p.jenis_kelamin,
-			  p.agama_id, p.status_kawin_id, p.nik, p.email, p.telepon,
-			  p.alamat, p.alamat_domisili, p.foto, p.satker_id, p.jabatan_id, p.unit_kerja_id,
-			  p.golongan_id, p.eselon_id, p.status_pegawai, p.status_kerja,
-			  p.tmt_cpns, p.tmt_pns, p.tmt_jabatan, p.tmt_pangkat_terakhir, p.tmt_jabatan_terakhir,
-			  p.karpeg_no, p.karpeg_file, p.taspen_no, p.npwp,
-			  p.bpjs_kesehatan, p.bpjs_ketenagakerjaan, p.kk_no, p.kk_file, p.ktp_no, p.ktp_file,
-			  p.sikep_id, p.is_active, p.created_at, p.updated_at, p.created_by, p.updated_by, p.deleted_at, p.deleted_by
-			  FROM pegawai p
-			  WHERE p.nip = $1 AND p.is_active = true`
-
-	var pegawai models.Pegawai
-	var statusPegawai models.StatusPegawai
-	var statusKerja models.StatusKerja
-
-	err := r.db.QueryRow(ctx, query, nip).Scan(
-		&pegawai.ID, &pegawai.NIP, &pegawai.NIPLama, &pegawai.NamaLengkap, &pegawai.GelarDepan, &pegawai.GelarBelakang,
-		&pegawai.TempatLahir, &pegawai.TanggalLahir, &pegawai.JenisKelamin,
-		&pegawai.AgamaID, &pegawai.StatusKawinID, &pegawai.NIK, &pegawai.Email, &pegawai.Telepon,
-		&pegawai.Alamat, &pegawai.AlamatDomisili, &pegawai.Foto, &pegawai.SatkerID, &pegawai.JabatanID, &pegawai.UnitKerjaID,
-		&pegawai.GolonganID, &pegawai.EselonID, &statusPegawai, &statusKerja,
-		&pegawai.TMTCpns, &pegawai.TMTPns, &pegawai.TMTJabatan, &pegawai.TMTPangkatTerakhir, &pegawai.TMTJabatanTerakhir,
-		&pegawai.KarpegNo, &pegawai.KarpegFile, &pegawai.TaspenNo, &pegawai.NPWP,
-		&pegawai.BPJSSehatan, &pegawai.BPJSKetenagakerjaan, &pegawai.KKNo, &pegawai.KKFile, &pegawai.KTPNo, &pegawai.KTPFile,
-		&pegawai.SikepID, &pegawai.IsActive, &pegawai.CreatedAt, &pegawai.UpdatedAt, &pegawai.CreatedBy, &pegawai.UpdatedBy, &pegawai.DeletedAt, &pegawai.DeletedBy,
-	)
+// getPegawai mengambil pegawai di dalam transaksi. forUpdate mengunci baris
+// agar state sebelum perubahan yang dicatat audit konsisten dengan update-nya.
+func getPegawai(ctx context.Context, tx pgx.Tx, id uuid.UUID, forUpdate bool) (*models.Pegawai, error) {
+	query := `SELECT ` + pegawaiColumns + `
+				  FROM pegawai p
+				  WHERE p.id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
 
+	pegawai, err := scanPegawai(tx.QueryRow(ctx, query, id))
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("pegawai not found")
+		return nil, ErrPegawaiNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pegawai: %w", err)
 	}
 
-	pegawai.StatusPegawai = statusPegawai
-	pegawai.StatusKerja = statusKerja
+	return pegawai, nil
+}
 
-	return &pegawai, nil
+// FindLinked mencari pegawai aktif yang terhubung dengan akun user, berdasarkan
+// NIP sebagai username Keycloak atau email. Mengembalikan nil bila tidak ada.
+// Query berjalan sebagai sistem karena pegawai milik user sendiri harus
+// ditemukan walaupun berada di luar scope unit kerjanya.
+func (r *PegawaiRepository) FindLinked(ctx context.Context, username, email string) (*models.PegawaiSummary, error) {
+	if username == "" && email == "" {
+		return nil, nil
+	}
+
+	ctx = database.AsSystem(ctx)
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.PegawaiSummary, error) {
+		query := `SELECT id, nip, nama_lengkap, gelar_depan, gelar_belakang, foto,
+				  satker_id, unit_kerja_id, jabatan_id, golongan_id
+				  FROM pegawai
+				  WHERE is_active = true AND deleted_at IS NULL
+				  AND ((nip = $1 AND $1 <> '') OR (lower(email) = lower($2) AND $2 <> ''))
+				  ORDER BY (nip = $1) DESC
+				  LIMIT 1`
+
+		var p models.PegawaiSummary
+		err := tx.QueryRow(ctx, query, username, email).Scan(
+			&p.ID, &p.NIP, &p.NamaLengkap, &p.GelarDepan, &p.GelarBelakang, &p.Foto,
+			&p.SatkerID, &p.UnitKerjaID, &p.JabatanID, &p.GolonganID,
+		)
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find linked pegawai: %w", err)
+		}
+
+		return &p, nil
+	})
+}
+
+// GetByNIP mengambil detail pegawai berdasarkan NIP
+func (r *PegawaiRepository) GetByNIP(ctx context.Context, nip string) (*models.Pegawai, error) {
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Pegawai, error) {
+		query := `SELECT ` + pegawaiColumns + `
+				  FROM pegawai p
+				  WHERE p.nip = $1 AND p.is_active = true`
+
+		pegawai, err := scanPegawai(tx.QueryRow(ctx, query, nip))
+		if err == pgx.ErrNoRows {
+			return nil, ErrPegawaiNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pegawai: %w", err)
+		}
+
+		return pegawai, nil
+	})
 }
 
 // Create membuat pegawai baru
 func (r *PegawaiRepository) Create(ctx context.Context, input CreatePegawaiInput) (*models.Pegawai, error) {
-	id := uuid.New()
-
-	query := `INSERT INTO pegawai (
-		id, nip, nip_lama, nama_lengkap, gelar_depan, gelar_belakang,
-		tempat_lahir, tanggal_lahir, jenis_kelamin, agama_id, status_kawin_id,
-		nik, email, telepon, alamat, alamat_domisili, satker_id, jabatan_id, unit_kerja_id,
-		golongan_id, eselon_id, status_pegawai, status_kerja,
-		tmt_cpns, tmt_pns, tmt_jabatan, tmt_pangkat_terakhir, tmt_jabatan_terakhir,
-		karpeg_no, taspen_no, npwp, bpjs_kesehatan, bpjs_ketenagakerjaan, kk_no, ktp_no, sikep_id,
-		is_active, created_at, updated_at
-	) VALUES (
-		$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41
-	) RETURNING created_at, updated_at`
-
-	now := time.Now()
-
-	err := r.db.QueryRow(ctx, query,
-		id, input.NIP, input.NIPLama, input.NamaLengkap, input.GelarDepan, input.GelarBelakang,
-		input.TempatLahir, input.TanggalLahir, input.JenisKelamin,
-		input.AgamaID, input.StatusKawinID, input.NIK, input.Email,
-		input.Telepon, input.Alamat, input.AlamatDomisili, input.SatkerID, input.JabatanID, input.UnitKerjaID,
-		input.GolonganID, input.EselonID, input.StatusPegawai, input.StatusKerja,
-		input.TMTCpns, input.TMTPns, input.TMTJabatan, input.TMTPangkatTerakhir, input.TMTJabatanTerakhir,
-		input.KarpegNo, input.TaspenNo, input.NPWP, input.BPJSSehatan, input.BPJSKetenagakerjaan, input.KKNo, input.KTPNo, input.SikepID,
-		true, now, now,
-	).Scan(&now, &now) // dummy scan untuk createdAt, updatedAt
+	pegawai, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Pegawai, error) {
+		id := uuid.New()
+
+		query := `INSERT INTO pegawai (
+			id, nip, nip_lama, nama_lengkap, gelar_depan, gelar_belakang,
+			tempat_lahir, tanggal_lahir, jenis_kelamin, agama_id, status_kawin_id,
+			nik, email, telepon, alamat, alamat_domisili, satker_id, jabatan_id, unit_kerja_id,
+			golongan_id, eselon_id, status_pegawai, status_kerja,
+			tmt_cpns, tmt_pns, tmt_jabatan, tmt_pangkat_terakhir, tmt_jabatan_terakhir,
+			karpeg_no, taspen_no, npwp, bpjs_kesehatan, bpjs_ketenagakerjaan, kk_no, ktp_no, sikep_id,
+			is_active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41
+		) RETURNING created_at, updated_at`
+
+		now := time.Now()
+
+		err := tx.QueryRow(ctx, query,
+			id, input.NIP, input.NIPLama, input.NamaLengkap, input.GelarDepan, input.GelarBelakang,
+			input.TempatLahir, input.TanggalLahir, input.JenisKelamin,
+			input.AgamaID, input.StatusKawinID, input.NIK, input.Email,
+			input.Telepon, input.Alamat, input.AlamatDomisili, input.SatkerID, input.JabatanID, input.UnitKerjaID,
+			input.GolonganID, input.EselonID, input.StatusPegawai, input.StatusKerja,
+			input.TMTCpns, input.TMTPns, input.TMTJabatan, input.TMTPangkatTerakhir, input.TMTJabatanTerakhir,
+			input.KarpegNo, input.TaspenNo, input.NPWP, input.BPJSSehatan, input.BPJSKetenagakerjaan, input.KKNo, input.KTPNo, input.SikepID,
+			true, now, now,
+		).Scan(&now, &now) // dummy scan untuk createdAt, updatedAt
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to create pegawai: %w", err)
-	}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pegawai: %w", err)
+		}
 
-	pegawai := &models.Pegawai{
-		ID:                 id,
-		NIP:                input.NIP,
-		NIPLama:            input.NIPLama,
-		NamaLengkap:       input.NamaLengkap,
-		GelarDepan:         input.GelarDepan,
-		GelarBelakang:      input.GelarBelakang,
-		TempatLahir:        input.TempatLahir,
-		TanggalLahir:       input.TanggalLahir,
-		JenisKelamin:        input.JenisKelamin,
-		AgamaID:             input.AgamaID,
-		StatusKawinID:       input.StatusKawinID,
-		NIK:                 input.NIK,
-		Email:               input.Email,
-		Telepon:             input.Telepon,
-		Alamat:              input.Alamat,
-		AlamatDomisili:      input.AlamatDomisili,
-		SatkerID:            input.SatkerID,
-		JabatanID:           input.JabatanID,
-		UnitKerjaID:         input.UnitKerjaID,
-		GolonganID:          input.GolonganID,
-		EselonID:            input.EselonID,
-		StatusPegawai:       input.StatusPegawai,
-		StatusKerja:         input.StatusKerja,
-		TMTCpns:             input.TMTCpns,
-		TMTPns:              input.TMTPns,
-		TMTJabatan:          input.TMTJabatan,
-		TMTPangkatTerakhir: input.TMTPangkatTerakhir,
-		TMTJabatanTerakhir:  input.TMTJabatanTerakhir,
-		KarpegNo:            input.KarpegNo,
-		TaspenNo:            input.TaspenNo,
-		NPWP:                input.NPWP,
-		BPJSSehatan:         input.BPJSSehatan,
-		BPJSKetenagakerjaan: input.BPJSKetenagakerjaan,
-		KKNo:                input.KKNo,
-		KTPNo:               input.KTPNo,
-		SikepID:             input.SikepID,
-		IsActive:            true,
-		CreatedAt:           now,
-		UpdatedAt:           now,
+		pegawai := &models.Pegawai{
+			ID:                 id,
+			NIP:                input.NIP,
+			NIPLama:            input.NIPLama,
+			NamaLengkap:       input.NamaLengkap,
+			GelarDepan:         input.GelarDepan,
+			GelarBelakang:      input.GelarBelakang,
+			TempatLahir:        input.TempatLahir,
+			TanggalLahir:       input.TanggalLahir,
+			JenisKelamin:        input.JenisKelamin,
+			AgamaID:             input.AgamaID,
+			StatusKawinID:       input.StatusKawinID,
+			NIK:                 input.NIK,
+			Email:               input.Email,
+			Telepon:             input.Telepon,
+			Alamat:              input.Alamat,
+			AlamatDomisili:      input.AlamatDomisili,
+			SatkerID:            input.SatkerID,
+			JabatanID:           input.JabatanID,
+			UnitKerjaID:         input.UnitKerjaID,
+			GolonganID:          input.GolonganID,
+			EselonID:            input.EselonID,
+			StatusPegawai:       input.StatusPegawai,
+			StatusKerja:         input.StatusKerja,
+			TMTCpns:             input.TMTCpns,
+			TMTPns:              input.TMTPns,
+			TMTJabatan:          input.TMTJabatan,
+			TMTPangkatTerakhir: input.TMTPangkatTerakhir,
+			TMTJabatanTerakhir:  input.TMTJabatanTerakhir,
+			KarpegNo:            input.KarpegNo,
+			TaspenNo:            input.TaspenNo,
+			NPWP:                input.NPWP,
+			BPJSSehatan:         input.BPJSSehatan,
+			BPJSKetenagakerjaan: input.BPJSKetenagakerjaan,
+			KKNo:                input.KKNo,
+			KTPNo:               input.KTPNo,
+			SikepID:             input.SikepID,
+			IsActive:            true,
+			CreatedAt:           now,
+			UpdatedAt:           now,
+		}
+
+		return pegawai, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	audit.Record(ctx, audit.ActionCreate, "pegawai", pegawai.ID, nil, pegawai)
 	return pegawai, nil
 }
 
 // Update mengupdate pegawai
 func (r *PegawaiRepository) Update(ctx context.Context, id string, input UpdatePegawaiInput) (*models.Pegawai, error) {
+	var before *models.Pegawai
+	pegawai, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Pegawai, error) {
+		var err error
+		before, err = getPegawai(ctx, tx, uuid.MustParse(id), true)
+		if err != nil {
+			return nil, err
+		}
+		return updatePegawai(ctx, tx, before.ID, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawai.ID, before, pegawai)
+	return pegawai, nil
+}
+
+// updatePegawai menulis input ke pegawai yang sudah dikunci pemanggil
+func updatePegawai(ctx context.Context, tx pgx.Tx, id uuid.UUID, input UpdatePegawaiInput) (*models.Pegawai, error) {
 	query := `UPDATE pegawai
 			  SET nama_lengkap = $2, gelar_depan = $3, gelar_belakang = $4,
 				  email = $5, telepon = $6, alamat = $7, alamat_domisili = $8,
@@ -340,14 +438,15 @@ func (r *PegawaiRepository) Update(ctx context.Context, id string, input UpdateP
 			  RETURNING nip, nip_lama, tempat_lahir, tanggal_lahir, jenis_kelamin,
 			  agama_id, status_kawin_id, nik, foto, created_at, updated_at,
 			  karpeg_no, karpeg_file, taspen_no, npwp, bpjs_kesehatan, bpjs_ketenagakerjaan, kk_no, kk_file, ktp_no, ktp_file, sikep_id,
-			  tmt_cpns, tmt_pns, tmt_jabatan_terakhir, is_active, created_by, updated_by, deleted_at, deleted_by`
+			  tmt_cpns, tmt_pns, tmt_jabatan_terakhir, is_active, created_by, updated_by, deleted_at, deleted_by,
+			  status_pegawai, status_kerja, pendidikan_terakhir_id`
 
 	var pegawai models.Pegawai
 	var statusPegawai models.StatusPegawai
 	var statusKerja models.StatusKerja
 
-	err := r.db.QueryRow(ctx, query,
-		uuid.MustParse(id), input.NamaLengkap, input.GelarDepan, input.GelarBelakang,
+	err := tx.QueryRow(ctx, query,
+		id, input.NamaLengkap, input.GelarDepan, input.GelarBelakang,
 		input.Email, input.Telepon, input.Alamat, input.AlamatDomisili, input.SatkerID,
 		input.JabatanID, input.UnitKerjaID, input.GolonganID,
 		input.EselonID, input.StatusPegawai, input.StatusKerja, input.TMTJabatan, input.TMTPangkatTerakhir,
@@ -358,17 +457,17 @@ func (r *PegawaiRepository) Update(ctx context.Context, id string, input UpdateP
 		&pegawai.KarpegNo, &pegawai.KarpegFile, &pegawai.TaspenNo, &pegawai.NPWP,
 		&pegawai.BPJSSehatan, &pegawai.BPJSKetenagakerjaan, &pegawai.KKNo, &pegawai.KKFile, &pegawai.KTPNo, &pegawai.KTPFile, &pegawai.SikepID,
 		&pegawai.TMTCpns, &pegawai.TMTPns, &pegawai.TMTJabatanTerakhir, &pegawai.IsActive, &pegawai.CreatedBy, &pegawai.UpdatedBy, &pegawai.DeletedAt, &pegawai.DeletedBy,
-		&statusPegawai, &statusKerja,
+		&statusPegawai, &statusKerja, &pegawai.PendidikanTerakhirID,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("pegawai not found")
+		return nil, ErrPegawaiNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update pegawai: %w", err)
 	}
 
-	pegawai.ID = uuid.MustParse(id)
+	pegawai.ID = id
 	pegawai.NamaLengkap = input.NamaLengkap
 	pegawai.GelarDepan = input.GelarDepan
 	pegawai.GelarBelakang = input.GelarBelakang
@@ -391,103 +490,125 @@ func (r *PegawaiRepository) Update(ctx context.Context, id string, input UpdateP
 
 // Delete menghapus pegawai (soft delete)
 func (r *PegawaiRepository) Delete(ctx context.Context, id string) error {
-	query := `UPDATE pegawai SET is_active = false, deleted_at = NOW(), updated_at = NOW() WHERE id = $1`
+	var before, after *models.Pegawai
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		before, err = getPegawai(ctx, tx, uuid.MustParse(id), true)
+		if err != nil {
+			return err
+		}
 
-	result, err := r.db.Exec(ctx, query, uuid.MustParse(id))
+		after, err = deletePegawai(ctx, tx, before.ID)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete pegawai: %w", err)
-	}
-
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("pegawai not found")
+		return err
 	}
 
+	audit.Record(ctx, audit.ActionDelete, "pegawai", before.ID, before, after)
 	return nil
 }
 
-// GetStatistik mengambil statistik kepegawaian
-func (r *PegawaiRepository) GetStatistik(ctx context.Context) (map[string]interface{}, error) {
-	statistik := make(map[string]interface{})
+// deletePegawai menghapus (soft delete) pegawai yang sudah dikunci pemanggil
+func deletePegawai(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*models.Pegawai, error) {
+	query := `UPDATE pegawai p SET is_active = false, deleted_at = NOW(), updated_at = NOW() WHERE p.id = $1
+			  RETURNING ` + pegawaiColumns
 
-	// Total pegawai aktif
-	var totalPegawai int64
-	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM pegawai WHERE is_active = true").Scan(&totalPegawai)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count total pegawai: %w", err)
+	after, err := scanPegawai(tx.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrPegawaiNotFound
 	}
-	statistik["total_pegawai"] = totalPegawai
-
-	// Pegawai per status pegawai (PNS, CPNS, PPPK, HONORER)
-	statusQuery := `SELECT status_pegawai, COUNT(*) FROM pegawai WHERE is_active = true GROUP BY status_pegawai`
-	rows, err := r.db.Query(ctx, statusQuery)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query pegawai by status: %w", err)
+		return nil, fmt.Errorf("failed to delete pegawai: %w", err)
 	}
-	defer rows.Close()
+	return after, nil
+}
+
+// GetStatistik mengambil statistik kepegawaian
+func (r *PegawaiRepository) GetStatistik(ctx context.Context) (map[string]interface{}, error) {
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (map[string]interface{}, error) {
+		statistik := make(map[string]interface{})
 
-	statusData := make(map[string]int64)
-	for rows.Next() {
-		var status string
-		var count int64
-		err := rows.Scan(&status, &count)
+		// Total pegawai aktif
+		var totalPegawai int64
+		err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM pegawai WHERE is_active = true").Scan(&totalPegawai)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan status: %w", err)
+			return nil, fmt.Errorf("failed to count total pegawai: %w", err)
 		}
-		statusData[status] = count
-	}
-	statistik["per_status_pegawai"] = statusData
-
-	// Pegawai per status kerja
-	kerjaQuery := `SELECT status_kerja, COUNT(*) FROM pegawai WHERE is_active = true GROUP BY status_kerja`
-	rows, err = r.db.Query(ctx, kerjaQuery)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query pegawai by status kerja: %w", err)
-	}
-	defer rows.Close()
+		statistik["total_pegawai"] = totalPegawai
 
-	kerjaData := make(map[string]int64)
-	for rows.Next() {
-		var status string
-		var count int64
-		err := rows.Scan(&status, &count)
+		// Pegawai per status pegawai (PNS, CPNS, PPPK, HONORER)
+		statusQuery := `SELECT status_pegawai, COUNT(*) FROM pegawai WHERE is_active = true GROUP BY status_pegawai`
+		rows, err := tx.Query(ctx, statusQuery)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan status kerja: %w", err)
+			return nil, fmt.Errorf("failed to query pegawai by status: %w", err)
 		}
-		kerjaData[status] = count
-	}
-	statistik["per_status_kerja"] = kerjaData
-
-	// Pegawai PNS vs Non-PNS (berdasarkan status_pegawai)
-	var totalPNS, totalNonPNS int64
-	r.db.QueryRow(ctx, "SELECT COUNT(*) FROM pegawai WHERE status_pegawai IN ('PNS', 'CPNS') AND is_active = true").Scan(&totalPNS)
-	r.db.QueryRow(ctx, "SELECT COUNT(*) FROM pegawai WHERE status_pegawai IN ('PPPK', 'HONORER') AND is_active = true").Scan(&totalNonPNS)
-	statistik["pns"] = totalPNS
-	statistik["non_pns"] = totalNonPNS
-
-	// Pegawai per golongan
-	golonganQuery := `SELECT g.nama, COUNT(*) FROM pegawai p
-					  JOIN ref_golongan g ON p.golongan_id = g.id
-					  WHERE p.is_active = true AND p.golongan_id IS NOT NULL
-					  GROUP BY g.nama ORDER BY g.angka DESC`
-	rows, err = r.db.Query(ctx, golonganQuery)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query pegawai by golongan: %w", err)
-	}
-	defer rows.Close()
+		defer rows.Close()
+
+		statusData := make(map[string]int64)
+		for rows.Next() {
+			var status string
+			var count int64
+			err := rows.Scan(&status, &count)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan status: %w", err)
+			}
+			statusData[status] = count
+		}
+		statistik["per_status_pegawai"] = statusData
 
-	golonganData := make(map[string]int64)
-	for rows.Next() {
-		var nama string
-		var count int64
-		err := rows.Scan(&nama, &count)
+		// Pegawai per status kerja
+		kerjaQuery := `SELECT status_kerja, COUNT(*) FROM pegawai WHERE is_active = true GROUP BY status_kerja`
+		rows, err = tx.Query(ctx, kerjaQuery)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan golongan: %w", err)
+			return nil, fmt.Errorf("failed to query pegawai by status kerja: %w", err)
 		}
-		golonganData[nama] = count
-	}
-	statistik["per_golongan"] = golonganData
+		defer rows.Close()
+
+		kerjaData := make(map[string]int64)
+		for rows.Next() {
+			var status string
+			var count int64
+			err := rows.Scan(&status, &count)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan status kerja: %w", err)
+			}
+			kerjaData[status] = count
+		}
+		statistik["per_status_kerja"] = kerjaData
+
+		// Pegawai PNS vs Non-PNS (berdasarkan status_pegawai)
+		var totalPNS, totalNonPNS int64
+		tx.QueryRow(ctx, "SELECT COUNT(*) FROM pegawai WHERE status_pegawai IN ('PNS', 'CPNS') AND is_active = true").Scan(&totalPNS)
+		tx.QueryRow(ctx, "SELECT COUNT(*) FROM pegawai WHERE status_pegawai IN ('PPPK', 'HONORER') AND is_active = true").Scan(&totalNonPNS)
+		statistik["pns"] = totalPNS
+		statistik["non_pns"] = totalNonPNS
+
+		// Pegawai per golongan
+		golonganQuery := `SELECT g.nama, COUNT(*) FROM pegawai p
+						  JOIN ref_golongan g ON p.golongan_id = g.id
+						  WHERE p.is_active = true AND p.golongan_id IS NOT NULL
+						  GROUP BY g.nama ORDER BY g.angka DESC`
+		rows, err = tx.Query(ctx, golonganQuery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query pegawai by golongan: %w", err)
+		}
+		defer rows.Close()
+
+		golonganData := make(map[string]int64)
+		for rows.Next() {
+			var nama string
+			var count int64
+			err := rows.Scan(&nama, &count)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan golongan: %w", err)
+			}
+			golonganData[nama] = count
+		}
+		statistik["per_golongan"] = golonganData
 
-	return statistik, nil
+		return statistik, nil
+	})
 }
 
 // ==================== INPUT TYPES ====================
@@ -2,33 +2,34 @@ package repositories
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/netip"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/sikerma/backend/internal/audit"
 	"github.com/sikerma/backend/internal/models"
 )
 
-// ==================== RIWAYAT ====================
-
-// RiwayatRepository mengelola operasi database untuk riwayat
-type RiwayatRepository struct {
-	dbKepegawaian *pgxpool.Pool
-	dbMaster      *pgxpool.Pool
-}
-
-// NewRiwayatRepository membuat instance RiwayatRepository baru
-func NewRiwayatRepository(dbKepegawaian, dbMaster *pgxpool.Pool) *RiwayatRepository {
-	return &RiwayatRepository{
-		dbKepegawaian: dbKepegawaian,
-		dbMaster:      dbMaster,
-	}
-}
-
 // ==================== RBAC ====================
 
+// Error yang dikembalikan RoleRepository untuk dipetakan handler ke 404/409
+var (
+	ErrRoleNotFound        = errors.New("role not found")
+	ErrRoleExists          = errors.New("role name already exists")
+	ErrRoleInactive        = errors.New("role is not active")
+	ErrRoleNotAssigned     = errors.New("role is not assigned to user")
+	ErrSystemRoleProtected = errors.New("system role cannot be modified")
+	ErrPermissionNotFound  = errors.New("permission not found")
+	ErrRoleCycle           = errors.New("role composite would create a cycle")
+)
+
 // RoleRepository mengelola operasi database untuk Role
 type RoleRepository struct {
 	db *pgxpool.Pool
@@ -41,10 +42,10 @@ func NewRoleRepository(db *pgxpool.Pool) *RoleRepository {
 
 // List mengambil daftar roles
 func (r *RoleRepository) List(ctx context.Context) ([]models.AppRole, error) {
-	query := `SELECT id, nama, deskripsi, is_active, created_at, updated_at
+	query := `SELECT id, nama, COALESCE(deskripsi, ''), is_system, priority, keycloak_client, is_active, created_at, updated_at
 			  FROM app_roles
 			  WHERE is_active = true
-			  ORDER BY nama`
+			  ORDER BY priority DESC, nama`
 
 	rows, err := r.db.Query(ctx, query)
 	if err != nil {
@@ -56,7 +57,7 @@ func (r *RoleRepository) List(ctx context.Context) ([]models.AppRole, error) {
 	for rows.Next() {
 		var role models.AppRole
 		err := rows.Scan(
-			&role.ID, &role.Nama, &role.Deskripsi, &role.IsActive,
+			&role.ID, &role.Nama, &role.Deskripsi, &role.IsSystem, &role.Priority, &role.KeycloakClient, &role.IsActive,
 			&role.CreatedAt, &role.UpdatedAt,
 		)
 		if err != nil {
@@ -68,31 +69,489 @@ func (r *RoleRepository) List(ctx context.Context) ([]models.AppRole, error) {
 	return roles, nil
 }
 
+// GetByID mengambil role berdasarkan ID, termasuk role yang sudah dinonaktifkan
+func (r *RoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AppRole, error) {
+	query := `SELECT id, nama, COALESCE(deskripsi, ''), is_system, priority, keycloak_client, is_active, created_at, updated_at
+			  FROM app_roles WHERE id = $1`
+
+	var role models.AppRole
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&role.ID, &role.Nama, &role.Deskripsi, &role.IsSystem, &role.Priority, &role.KeycloakClient, &role.IsActive,
+		&role.CreatedAt, &role.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	return &role, nil
+}
+
 // Create membuat role baru
 func (r *RoleRepository) Create(ctx context.Context, input CreateRoleInput) (*models.AppRole, error) {
 	id := uuid.New()
 
-	query := `INSERT INTO app_roles (id, nama, deskripsi, is_active)
-			  VALUES ($1, $2, $3, true)
+	query := `INSERT INTO app_roles (id, nama, deskripsi, priority, keycloak_client, is_active)
+			  VALUES ($1, $2, $3, $4, $5, true)
 			  RETURNING created_at, updated_at`
 
-	err := r.db.QueryRow(ctx, query, id, input.Nama, input.Deskripsi).Scan(&input.CreatedAt, &input.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, id, input.Nama, input.Deskripsi, input.Priority, input.KeycloakClient).
+		Scan(&input.CreatedAt, &input.UpdatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrRoleExists
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create role: %w", err)
 	}
 
 	role := &models.AppRole{
-		ID:        id,
-		Nama:      input.Nama,
-		Deskripsi: input.Deskripsi,
-		IsActive:  true,
-		CreatedAt: input.CreatedAt,
-		UpdatedAt: input.UpdatedAt,
+		ID:             id,
+		Nama:           input.Nama,
+		Deskripsi:      input.Deskripsi,
+		Priority:       input.Priority,
+		KeycloakClient: input.KeycloakClient,
+		IsActive:       true,
+		CreatedAt:      input.CreatedAt,
+		UpdatedAt:      input.UpdatedAt,
+	}
+
+	return role, nil
+}
+
+// Update mengubah nama/deskripsi role non-sistem
+func (r *RoleRepository) Update(ctx context.Context, id uuid.UUID, input UpdateRoleInput) (*models.AppRole, error) {
+	if _, err := r.getEditable(ctx, id); err != nil {
+		return nil, err
 	}
 
+	query := `UPDATE app_roles SET
+			  nama = COALESCE($2, nama),
+			  deskripsi = COALESCE($3, deskripsi),
+			  priority = COALESCE($4, priority),
+			  keycloak_client = COALESCE($5, keycloak_client),
+			  updated_at = NOW()
+			  WHERE id = $1 AND is_system = false`
+
+	_, err := r.db.Exec(ctx, query, id, input.Nama, input.Deskripsi, input.Priority, input.KeycloakClient)
+	if isUniqueViolation(err) {
+		return nil, ErrRoleExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// Deactivate menonaktifkan role non-sistem. Assignment user tetap disimpan
+// tetapi permission role tidak lagi berlaku selama role tidak aktif.
+func (r *RoleRepository) Deactivate(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.getEditable(ctx, id); err != nil {
+		return err
+	}
+
+	query := `UPDATE app_roles SET is_active = false, updated_at = NOW()
+			  WHERE id = $1 AND is_system = false`
+
+	if _, err := r.db.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to deactivate role: %w", err)
+	}
+
+	return nil
+}
+
+// getEditable mengambil role dan menolak role sistem
+func (r *RoleRepository) getEditable(ctx context.Context, id uuid.UUID) (*models.AppRole, error) {
+	role, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if role.IsSystem {
+		return nil, ErrSystemRoleProtected
+	}
 	return role, nil
 }
 
+// ListPermissions mengambil katalog app_permissions, opsional difilter per resource
+func (r *RoleRepository) ListPermissions(ctx context.Context, resource string) ([]models.AppPermission, error) {
+	query := `SELECT id, nama, resource, action, COALESCE(deskripsi, ''), created_at, updated_at
+			  FROM app_permissions
+			  WHERE ($1 = '' OR resource = $1)
+			  ORDER BY resource, action`
+
+	return r.queryPermissions(ctx, query, resource)
+}
+
+// ListRolePermissions mengambil permission yang dimiliki role
+func (r *RoleRepository) ListRolePermissions(ctx context.Context, roleID uuid.UUID) ([]models.AppPermission, error) {
+	if _, err := r.GetByID(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT p.id, p.nama, p.resource, p.action, COALESCE(p.deskripsi, ''), p.created_at, p.updated_at
+			  FROM role_permissions rp
+			  JOIN app_permissions p ON p.id = rp.permission_id
+			  WHERE rp.role_id = $1
+			  ORDER BY p.resource, p.action`
+
+	return r.queryPermissions(ctx, query, roleID)
+}
+
+// queryPermissions menjalankan query permission dan memetakan hasilnya
+func (r *RoleRepository) queryPermissions(ctx context.Context, query string, args ...interface{}) ([]models.AppPermission, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []models.AppPermission{}
+	for rows.Next() {
+		var p models.AppPermission
+		if err := rows.Scan(&p.ID, &p.Nama, &p.Resource, &p.Action, &p.Deskripsi, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		p.Kode = p.Nama
+		permissions = append(permissions, p)
+	}
+
+	return permissions, nil
+}
+
+// AttachPermission menambahkan permission ke role non-sistem
+func (r *RoleRepository) AttachPermission(ctx context.Context, roleID, permissionID uuid.UUID) error {
+	if _, err := r.getEditable(ctx, roleID); err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM app_permissions WHERE id = $1)`, permissionID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check permission: %w", err)
+	}
+	if !exists {
+		return ErrPermissionNotFound
+	}
+
+	query := `INSERT INTO role_permissions (role_id, permission_id)
+			  VALUES ($1, $2)
+			  ON CONFLICT (role_id, permission_id) DO NOTHING`
+
+	if _, err := r.db.Exec(ctx, query, roleID, permissionID); err != nil {
+		return fmt.Errorf("failed to attach permission: %w", err)
+	}
+
+	return nil
+}
+
+// DetachPermission melepas permission dari role non-sistem
+func (r *RoleRepository) DetachPermission(ctx context.Context, roleID, permissionID uuid.UUID) error {
+	if _, err := r.getEditable(ctx, roleID); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`
+
+	result, err := r.db.Exec(ctx, query, roleID, permissionID)
+	if err != nil {
+		return fmt.Errorf("failed to detach permission: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPermissionNotFound
+	}
+
+	return nil
+}
+
+// ListIncludedRoles mengambil role yang diwarisi langsung oleh role (composite)
+func (r *RoleRepository) ListIncludedRoles(ctx context.Context, roleID uuid.UUID) ([]models.AppRole, error) {
+	if _, err := r.GetByID(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ar.id, ar.nama, COALESCE(ar.deskripsi, ''), ar.is_system, ar.priority, ar.keycloak_client,
+			  ar.is_active, ar.created_at, ar.updated_at
+			  FROM app_role_composites rc
+			  JOIN app_roles ar ON ar.id = rc.includes_role_id
+			  WHERE rc.role_id = $1
+			  ORDER BY ar.priority DESC, ar.nama`
+
+	rows, err := r.db.Query(ctx, query, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query included roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []models.AppRole{}
+	for rows.Next() {
+		var role models.AppRole
+		err := rows.Scan(
+			&role.ID, &role.Nama, &role.Deskripsi, &role.IsSystem, &role.Priority, &role.KeycloakClient, &role.IsActive,
+			&role.CreatedAt, &role.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+// IncludeRole menjadikan roleID mewarisi includedID. Ditolak bila includedID
+// sudah (langsung atau tidak langsung) mewarisi roleID.
+func (r *RoleRepository) IncludeRole(ctx context.Context, roleID, includedID uuid.UUID) error {
+	if roleID == includedID {
+		return ErrRoleCycle
+	}
+	if _, err := r.getEditable(ctx, roleID); err != nil {
+		return err
+	}
+	if _, err := r.GetByID(ctx, includedID); err != nil {
+		return err
+	}
+
+	cycleQuery := `WITH RECURSIVE descendants(role_id) AS (
+					 SELECT includes_role_id FROM app_role_composites WHERE role_id = $1
+					 UNION
+					 SELECT rc.includes_role_id FROM app_role_composites rc
+					 JOIN descendants d ON d.role_id = rc.role_id
+				   )
+				   SELECT EXISTS(SELECT 1 FROM descendants WHERE role_id = $2)`
+
+	var cycle bool
+	if err := r.db.QueryRow(ctx, cycleQuery, includedID, roleID).Scan(&cycle); err != nil {
+		return fmt.Errorf("failed to check role cycle: %w", err)
+	}
+	if cycle {
+		return ErrRoleCycle
+	}
+
+	query := `INSERT INTO app_role_composites (role_id, includes_role_id)
+			  VALUES ($1, $2)
+			  ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.Exec(ctx, query, roleID, includedID); err != nil {
+		return fmt.Errorf("failed to include role: %w", err)
+	}
+
+	return nil
+}
+
+// ExcludeRole menghapus pewarisan roleID atas includedID
+func (r *RoleRepository) ExcludeRole(ctx context.Context, roleID, includedID uuid.UUID) error {
+	if _, err := r.getEditable(ctx, roleID); err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(ctx,
+		`DELETE FROM app_role_composites WHERE role_id = $1 AND includes_role_id = $2`, roleID, includedID)
+	if err != nil {
+		return fmt.Errorf("failed to exclude role: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRoleNotFound
+	}
+
+	return nil
+}
+
+// ListRoleDefinitions mengambil semua role aktif beserta prioritas dan
+// composite langsungnya untuk resolusi hierarki di AuthMiddleware
+func (r *RoleRepository) ListRoleDefinitions(ctx context.Context) ([]models.RoleDefinition, error) {
+	query := `SELECT ar.nama, ar.keycloak_client, ar.priority,
+			  COALESCE(array_agg(child.nama ORDER BY child.nama) FILTER (WHERE child.nama IS NOT NULL), '{}')
+			  FROM app_roles ar
+			  LEFT JOIN app_role_composites rc ON rc.role_id = ar.id
+			  LEFT JOIN app_roles child ON child.id = rc.includes_role_id AND child.is_active = true
+			  WHERE ar.is_active = true
+			  GROUP BY ar.id
+			  ORDER BY ar.priority DESC, ar.nama`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query role definitions: %w", err)
+	}
+	defer rows.Close()
+
+	definitions := []models.RoleDefinition{}
+	for rows.Next() {
+		var def models.RoleDefinition
+		if err := rows.Scan(&def.Nama, &def.KeycloakClient, &def.Priority, &def.Includes); err != nil {
+			return nil, fmt.Errorf("failed to scan role definition: %w", err)
+		}
+		definitions = append(definitions, def)
+	}
+
+	return definitions, rows.Err()
+}
+
+// ListUserRoles mengambil role yang di-assign ke user Keycloak
+func (r *RoleRepository) ListUserRoles(ctx context.Context, userID string) ([]models.UserAppRole, error) {
+	query := `SELECT uar.id, uar.user_id, uar.role_id, uar.unit_kerja_id, uar.assigned_by, uar.created_at, ar.nama
+			  FROM user_app_roles uar
+			  JOIN app_roles ar ON ar.id = uar.role_id
+			  WHERE uar.user_id = $1
+			  ORDER BY ar.nama`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user roles: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []models.UserAppRole{}
+	for rows.Next() {
+		var a models.UserAppRole
+		if err := rows.Scan(&a.ID, &a.UserID, &a.RoleID, &a.UnitKerjaID, &a.AssignedBy, &a.CreatedAt, &a.RoleNama); err != nil {
+			return nil, fmt.Errorf("failed to scan user role: %w", err)
+		}
+		assignments = append(assignments, a)
+	}
+
+	return assignments, nil
+}
+
+// AssignRole memberikan role aktif ke user. Assignment ulang memperbarui scope unit kerja.
+func (r *RoleRepository) AssignRole(ctx context.Context, userID string, input AssignRoleInput, assignedBy string) (*models.UserAppRole, error) {
+	role, err := r.GetByID(ctx, input.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.IsActive {
+		return nil, ErrRoleInactive
+	}
+
+	query := `INSERT INTO user_app_roles (user_id, role_id, unit_kerja_id, assigned_by)
+			  VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (user_id, role_id) DO UPDATE SET
+			  unit_kerja_id = EXCLUDED.unit_kerja_id,
+			  assigned_by = EXCLUDED.assigned_by
+			  RETURNING id, created_at`
+
+	assignment := models.UserAppRole{
+		UserID:      userID,
+		RoleID:      role.ID,
+		UnitKerjaID: input.UnitKerjaID,
+		AssignedBy:  &assignedBy,
+		RoleNama:    role.Nama,
+	}
+	err = r.db.QueryRow(ctx, query, userID, role.ID, input.UnitKerjaID, assignedBy).Scan(&assignment.ID, &assignment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return &assignment, nil
+}
+
+// RevokeRole mencabut role dari user
+func (r *RoleRepository) RevokeRole(ctx context.Context, userID string, roleID uuid.UUID) error {
+	query := `DELETE FROM user_app_roles WHERE user_id = $1 AND role_id = $2`
+
+	result, err := r.db.Exec(ctx, query, userID, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRoleNotAssigned
+	}
+
+	return nil
+}
+
+// GetUserRoleNames mengambil nama role aktif yang di-assign ke user
+func (r *RoleRepository) GetUserRoleNames(ctx context.Context, userID string) ([]string, error) {
+	query := `SELECT ar.nama
+			  FROM user_app_roles uar
+			  JOIN app_roles ar ON ar.id = uar.role_id AND ar.is_active = true
+			  WHERE uar.user_id = $1
+			  ORDER BY ar.nama`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user role names: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan role name: %w", err)
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// GetUserPermissions mengambil kode permission efektif milik user
+// melalui user_app_roles -> role_permissions -> app_permissions
+func (r *RoleRepository) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	// Role yang di-assign ditambah semua role yang diwarisi lewat composite
+	query := `WITH RECURSIVE effective(role_id) AS (
+				SELECT ar.id
+				FROM user_app_roles uar
+				JOIN app_roles ar ON ar.id = uar.role_id AND ar.is_active = true
+				WHERE uar.user_id = $1
+				UNION
+				SELECT child.id
+				FROM effective e
+				JOIN app_role_composites rc ON rc.role_id = e.role_id
+				JOIN app_roles child ON child.id = rc.includes_role_id AND child.is_active = true
+			  )
+			  SELECT DISTINCT p.nama
+			  FROM effective e
+			  JOIN role_permissions rp ON rp.role_id = e.role_id
+			  JOIN app_permissions p ON p.id = rp.permission_id
+			  ORDER BY p.nama`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate permissions: %w", err)
+	}
+
+	return permissions, nil
+}
+
+// GetUserScope mengambil unit kerja dan satker user dari assignment role yang
+// memiliki scope unit kerja. String kosong bila user tidak memiliki scope.
+func (r *RoleRepository) GetUserScope(ctx context.Context, userID string) (string, string, error) {
+	query := `SELECT uar.unit_kerja_id::text, COALESCE(uk.satker_id::text, '')
+			  FROM user_app_roles uar
+			  JOIN app_roles ar ON ar.id = uar.role_id AND ar.is_active = true
+			  JOIN unit_kerja uk ON uk.id = uar.unit_kerja_id
+			  WHERE uar.user_id = $1
+			  ORDER BY uar.created_at
+			  LIMIT 1`
+
+	var unitKerjaID, satkerID string
+	err := r.db.QueryRow(ctx, query, userID).Scan(&unitKerjaID, &satkerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get user scope: %w", err)
+	}
+
+	return unitKerjaID, satkerID, nil
+}
+
 // ==================== AUDIT ====================
 
 // AuditRepository mengelola operasi database untuk Audit Log
@@ -105,87 +564,336 @@ func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
 	return &AuditRepository{db: db}
 }
 
-// Log menyimpan audit log baru
+// Log menyimpan audit log baru sebagai baris berikutnya pada hash chain
 func (r *AuditRepository) Log(ctx context.Context, input AuditLogInput) error {
-	id := uuid.New()
-	var userID *string
-	if input.UserID != "" {
-		userID = &input.UserID
+	return r.LogBatch(ctx, []AuditLogInput{input})
+}
+
+// LogBatch menyimpan beberapa audit log sekaligus dengan COPY sebagai baris
+// berikutnya pada hash chain. audit_chain_head dikunci selama transaksi
+// sehingga seq dan prev_hash berurutan walaupun dipanggil dari banyak
+// goroutine atau instance.
+func (r *AuditRepository) LogBatch(ctx context.Context, inputs []AuditLogInput) error {
+	if len(inputs) == 0 {
+		return nil
 	}
 
-	query := `INSERT INTO audit_logs (id, user_id, username, action, resource, resource_id,
-			  ip_address, user_agent, changes, status, error_message)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	entries := make([]audit.ChainEntry, 0, len(inputs))
+	for _, input := range inputs {
+		var userID *string
+		if input.UserID != "" {
+			userID = &input.UserID
+		}
 
-	_, err := r.db.Exec(ctx, query,
-		id, userID, input.Username, input.Action, input.Resource,
-		input.ResourceID, input.IPAddress, input.UserAgent,
-		input.Changes, input.Status, input.ErrorMessage,
-	)
+		changes, err := audit.CanonicalJSON(input.Changes)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit changes: %w", err)
+		}
+
+		username := input.Username
+		entries = append(entries, audit.ChainEntry{
+			ID:                     uuid.New(),
+			UserID:                 userID,
+			Username:               &username,
+			Action:                 input.Action,
+			Resource:               input.Resource,
+			ResourceID:             input.ResourceID,
+			IPAddress:              audit.CanonicalIP(input.IPAddress),
+			UserAgent:              input.UserAgent,
+			Changes:                changes,
+			Status:                 input.Status,
+			ErrorMessage:           input.ErrorMessage,
+			ImpersonatedUserID:     input.ImpersonatedUserID,
+			ImpersonatedUsername:   input.ImpersonatedUsername,
+			ImpersonationSessionID: input.ImpersonationSessionID,
+		})
+	}
+
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var headSeq int64
+		var headHash string
+		var headAt time.Time
+		err := tx.QueryRow(ctx, `SELECT seq, row_hash, updated_at FROM audit_chain_head FOR UPDATE`).Scan(&headSeq, &headHash, &headAt)
+		if err != nil {
+			return fmt.Errorf("failed to lock audit chain head: %w", err)
+		}
+
+		// created_at diambil setelah lock dan tidak boleh mundur dari baris
+		// sebelumnya, sehingga setiap partisi bulanan berisi rentang seq yang
+		// bersambung
+		createdAt := time.Now().UTC().Truncate(time.Microsecond)
+		if createdAt.Before(headAt) {
+			createdAt = headAt.UTC()
+		}
+
+		rows := make([][]interface{}, 0, len(entries))
+		for i := range entries {
+			e := &entries[i]
+			e.Seq = headSeq + int64(i) + 1
+			e.CreatedAt = createdAt
+			e.PrevHash = headHash
+			if e.RowHash, err = e.ComputeHash(); err != nil {
+				return err
+			}
+			headHash = e.RowHash
+
+			row, err := chainEntryRow(*e)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
 
-	return err
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"audit_logs"}, chainEntryCopyColumns, pgx.CopyFromRows(rows))
+		if err != nil {
+			return fmt.Errorf("failed to insert audit log: %w", err)
+		}
+
+		var snapshots [][]interface{}
+		for i, input := range inputs {
+			if input.Snapshot == nil || input.ResourceID == nil {
+				continue
+			}
+			e := entries[i]
+			snapshots = append(snapshots, []interface{}{
+				e.ID, e.Resource, *e.ResourceID, nullableJSON(input.Snapshot.Before), nullableJSON(input.Snapshot.After), e.CreatedAt,
+			})
+		}
+		if len(snapshots) > 0 {
+			_, err = tx.CopyFrom(ctx, pgx.Identifier{"audit_snapshots"},
+				[]string{"audit_log_id", "resource", "resource_id", "before", "after", "created_at"}, pgx.CopyFromRows(snapshots))
+			if err != nil {
+				return fmt.Errorf("failed to insert audit snapshots: %w", err)
+			}
+		}
+
+		last := entries[len(entries)-1]
+		_, err = tx.Exec(ctx, `UPDATE audit_chain_head SET seq = $1, row_hash = $2, updated_at = $3`, last.Seq, last.RowHash, createdAt)
+		if err != nil {
+			return fmt.Errorf("failed to advance audit chain head: %w", err)
+		}
+		return nil
+	})
 }
 
-// List mengambil daftar audit logs dengan filter
-func (r *AuditRepository) List(ctx context.Context, page, limit int, action, resource, userID string) ([]models.AuditLog, int64, error) {
-	offset := (page - 1) * limit
+// nullableJSON mengirim JSON kosong sebagai NULL
+func nullableJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return []byte(raw)
+}
+
+// chainEntryCopyColumns adalah urutan kolom untuk chainEntryRow
+var chainEntryCopyColumns = []string{
+	"id", "user_id", "username", "action", "resource", "resource_id",
+	"ip_address", "user_agent", "changes", "status", "error_message", "created_at",
+	"impersonated_user_id", "impersonated_username", "impersonation_session_id",
+	"seq", "prev_hash", "row_hash",
+}
+
+// chainEntryRow menyusun nilai kolom COPY untuk satu baris audit. Baris tanpa
+// hash chain (seq 0) disimpan dengan seq dan hash NULL.
+func chainEntryRow(e audit.ChainEntry) ([]interface{}, error) {
+	var seq, prevHash, rowHash interface{}
+	if e.Seq > 0 {
+		seq, prevHash, rowHash = e.Seq, e.PrevHash, e.RowHash
+	}
+	var changes, ip interface{}
+	if e.Changes != nil {
+		changes = []byte(e.Changes)
+	}
+	// COPY memakai format biner sehingga inet harus dikirim sebagai netip.Addr
+	if e.IPAddress != nil {
+		addr, err := netip.ParseAddr(*e.IPAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip_address at audit log %s: %w", e.ID, err)
+		}
+		ip = addr
+	}
+
+	return []interface{}{
+		e.ID, e.UserID, e.Username, e.Action, e.Resource, e.ResourceID,
+		ip, e.UserAgent, changes, e.Status, e.ErrorMessage, e.CreatedAt,
+		e.ImpersonatedUserID, e.ImpersonatedUsername, e.ImpersonationSessionID,
+		seq, prevHash, rowHash,
+	}, nil
+}
 
-	query := `SELECT id, user_id, username, action, resource, resource_id,
-			  ip_address, user_agent, changes, status, error_message, created_at
+// WalkChain membaca baris audit_logs yang tercakup hash chain berurutan
+// berdasarkan seq. Baris sebelum hash chain diaktifkan (seq NULL) dilewati.
+func (r *AuditRepository) WalkChain(ctx context.Context, fn func(audit.ChainEntry) error) error {
+	query := `SELECT ` + chainEntryColumns + `
 			  FROM audit_logs
-			  WHERE 1=1`
-	args := []interface{}{}
-	argCount := 1
+			  WHERE seq IS NOT NULL
+			  ORDER BY seq`
+
+	return r.queryChainEntries(ctx, query, fn)
+}
 
-	if action != "" {
-		query += fmt.Sprintf(" AND action = $%d", argCount)
-		args = append(args, action)
-		argCount++
+// chainEntryColumns adalah kolom audit_logs untuk queryChainEntries. Baris
+// tanpa hash chain menghasilkan seq 0 dan hash kosong.
+const chainEntryColumns = `COALESCE(seq, 0), id, created_at, user_id, username, action, resource, resource_id,
+			  host(ip_address), user_agent, changes, status, error_message,
+			  impersonated_user_id, impersonated_username, impersonation_session_id,
+			  COALESCE(prev_hash, ''), COALESCE(row_hash, '')`
+
+// queryChainEntries menjalankan query berkolom chainEntryColumns dan memanggil
+// fn untuk setiap baris
+func (r *AuditRepository) queryChainEntries(ctx context.Context, query string, fn func(audit.ChainEntry) error, args ...interface{}) error {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query audit chain: %w", err)
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e audit.ChainEntry
+		var changes []byte
+		err := rows.Scan(
+			&e.Seq, &e.ID, &e.CreatedAt, &e.UserID, &e.Username, &e.Action, &e.Resource, &e.ResourceID,
+			&e.IPAddress, &e.UserAgent, &changes, &e.Status, &e.ErrorMessage,
+			&e.ImpersonatedUserID, &e.ImpersonatedUsername, &e.ImpersonationSessionID,
+			&e.PrevHash, &e.RowHash,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan audit chain entry: %w", err)
+		}
+		if changes != nil {
+			if e.Changes, err = audit.CanonicalJSON(json.RawMessage(changes)); err != nil {
+				return fmt.Errorf("failed to decode audit changes at seq %d: %w", e.Seq, err)
+			}
+		}
 
-	if resource != "" {
-		query += fmt.Sprintf(" AND resource = $%d", argCount)
-		args = append(args, resource)
-		argCount++
+		if err := fn(e); err != nil {
+			return err
+		}
 	}
 
-	if userID != "" {
-		query += fmt.Sprintf(" AND user_id = $%d", argCount)
-		args = append(args, userID)
-		argCount++
+	return rows.Err()
+}
+
+// CreateAnchor mencatat ujung hash chain saat ini ke audit_chain_anchors.
+// Mengembalikan nil bila tidak ada baris baru sejak anchor terakhir.
+func (r *AuditRepository) CreateAnchor(ctx context.Context) (*audit.Anchor, error) {
+	query := `INSERT INTO audit_chain_anchors (seq, row_hash)
+			  SELECT h.seq, h.row_hash FROM audit_chain_head h
+			  WHERE h.seq > COALESCE((SELECT MAX(seq) FROM audit_chain_anchors), 0)
+			  RETURNING seq, row_hash, created_at`
+
+	var a audit.Anchor
+	err := r.db.QueryRow(ctx, query).Scan(&a.Seq, &a.RowHash, &a.AnchoredAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit anchor: %w", err)
 	}
+	return &a, nil
+}
 
-	// Get total count
-	countQuery := "SELECT COUNT(*) FROM audit_logs WHERE 1=1"
-	countArgs := []interface{}{}
-	countArgCount := 1
+// ListAnchors mengambil semua anchor hash chain yang tersimpan di database
+func (r *AuditRepository) ListAnchors(ctx context.Context) ([]audit.Anchor, error) {
+	rows, err := r.db.Query(ctx, `SELECT seq, row_hash, created_at FROM audit_chain_anchors ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit anchors: %w", err)
+	}
+	defer rows.Close()
 
-	if action != "" {
-		countQuery += fmt.Sprintf(" AND action = $%d", countArgCount)
-		countArgs = append(countArgs, action)
-		countArgCount++
+	anchors := []audit.Anchor{}
+	for rows.Next() {
+		var a audit.Anchor
+		if err := rows.Scan(&a.Seq, &a.RowHash, &a.AnchoredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit anchor: %w", err)
+		}
+		anchors = append(anchors, a)
 	}
+	return anchors, rows.Err()
+}
 
-	if resource != "" {
-		countQuery += fmt.Sprintf(" AND resource = $%d", countArgCount)
-		countArgs = append(countArgs, resource)
-		countArgCount++
+// auditLogColumns adalah kolom models.AuditLog untuk query SELECT
+const auditLogColumns = `id, user_id, username, action, resource, resource_id,
+			  host(ip_address), user_agent, changes, status, error_message, created_at,
+			  impersonated_user_id, impersonated_username, impersonation_session_id,
+			  seq, row_hash`
+
+// scanAuditLog memindai satu baris auditLogColumns
+func scanAuditLog(row pgx.Row) (*models.AuditLog, error) {
+	var log models.AuditLog
+	err := row.Scan(
+		&log.ID, &log.UserID, &log.Username, &log.Action, &log.Resource,
+		&log.ResourceID, &log.IPAddress, &log.UserAgent,
+		&log.Changes, &log.Status, &log.ErrorMessage, &log.CreatedAt,
+		&log.ImpersonatedUserID, &log.ImpersonatedUsername, &log.ImpersonationSessionID,
+		&log.Seq, &log.RowHash,
+	)
+	if err != nil {
+		return nil, err
 	}
+	return &log, nil
+}
 
-	if userID != "" {
-		countQuery += fmt.Sprintf(" AND user_id = $%d", countArgCount)
-		countArgs = append(countArgs, userID)
-		countArgCount++
+// where membangun klausa WHERE dan argumennya dari filter
+func (f AuditLogFilter) where() (string, []interface{}) {
+	clause := " WHERE 1=1"
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		clause += fmt.Sprintf(" AND "+condition, len(args))
 	}
 
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.Resource != "" {
+		add("resource = $%d", f.Resource)
+	}
+	if f.ResourceID != nil {
+		add("resource_id = $%d", *f.ResourceID)
+	}
+	if f.UserID != "" {
+		add("(user_id = $%[1]d OR impersonated_user_id = $%[1]d)", f.UserID)
+	}
+	if f.Username != "" {
+		add("(username ILIKE '%%' || $%[1]d || '%%' OR impersonated_username ILIKE '%%' || $%[1]d || '%%')", f.Username)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.IPAddress != "" {
+		add("ip_address <<= $%d::inet", f.IPAddress)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+	if len(f.ChangesContain) > 0 {
+		add("changes @> $%d::jsonb", []byte(f.ChangesContain))
+	}
+	if f.ChangedField != "" {
+		add("changes->'diff' ? $%d", f.ChangedField)
+	}
+
+	return clause, args
+}
+
+// List mengambil daftar audit logs dengan filter
+func (r *AuditRepository) List(ctx context.Context, filter AuditLogFilter, page, limit int) ([]models.AuditLog, int64, error) {
+	offset := (page - 1) * limit
+	where, args := filter.where()
+
+	// Get total count
 	var total int64
-	err := r.db.QueryRow(ctx, countQuery, countArgs...).Scan(&total)
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM audit_logs"+where, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
 	}
 
 	// Get data
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argCount, argCount+1)
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs` + where +
+		fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
 	args = append(args, limit, offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
@@ -196,29 +904,202 @@ func (r *AuditRepository) List(ctx context.Context, page, limit int, action, res
 
 	logs := []models.AuditLog{}
 	for rows.Next() {
-		var log models.AuditLog
-		err := rows.Scan(
-			&log.ID, &log.UserID, &log.Username, &log.Action, &log.Resource,
-			&log.ResourceID, &log.IPAddress, &log.UserAgent,
-			&log.Changes, &log.Status, &log.ErrorMessage, &log.CreatedAt,
-		)
+		log, err := scanAuditLog(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan audit log: %w", err)
 		}
-		logs = append(logs, log)
+		logs = append(logs, *log)
+	}
+
+	return logs, total, rows.Err()
+}
+
+// Stream membaca audit log yang cocok dengan filter secara kronologis dan
+// memanggil fn per baris tanpa menampung seluruh hasil di memori
+func (r *AuditRepository) Stream(ctx context.Context, filter AuditLogFilter, fn func(models.AuditLog) error) error {
+	where, args := filter.where()
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs` + where + ` ORDER BY created_at, seq`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		if err := fn(*log); err != nil {
+			return err
+		}
 	}
 
-	return logs, total, nil
+	return rows.Err()
+}
+
+// maxHistoryEntries membatasi panjang timeline satu record
+const maxHistoryEntries = 1000
+
+// History mengambil audit log satu record secara kronologis (terlama dulu).
+// Akses baca dan akses yang ditolak tidak termasuk timeline perubahan.
+func (r *AuditRepository) History(ctx context.Context, resource string, resourceID uuid.UUID) ([]models.AuditLog, error) {
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs
+			  WHERE resource = $1 AND resource_id = $2 AND action NOT IN ('read', 'access_denied')
+			  ORDER BY created_at, seq
+			  LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, resource, resourceID, maxHistoryEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit history: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		logs = append(logs, *log)
+	}
+
+	return logs, rows.Err()
+}
+
+// LookupReferenceLabels mengambil label data referensi db_master (golongan,
+// jabatan, satker, unit kerja, eselon, agama, status kawin, pendidikan)
+// berdasarkan ID
+func (r *AuditRepository) LookupReferenceLabels(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	labels := map[uuid.UUID]string{}
+	if len(ids) == 0 {
+		return labels, nil
+	}
+
+	query := `SELECT id, kode || ' - ' || nama FROM golongan WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM jabatan WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM satker WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM unit_kerja WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM eselon WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM ref_agama WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM ref_status_kawin WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM ref_pendidikan WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM ref_jenis_hukdis WHERE id = ANY($1)
+			  UNION ALL SELECT id, nama FROM ref_jenis_diklat WHERE id = ANY($1)`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reference labels: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var label string
+		if err := rows.Scan(&id, &label); err != nil {
+			return nil, fmt.Errorf("failed to scan reference label: %w", err)
+		}
+		labels[id] = label
+	}
+
+	return labels, rows.Err()
 }
 
 // ==================== INPUT TYPES ====================
 
 // CreateRoleInput input untuk membuat role
 type CreateRoleInput struct {
-	Nama      string    `json:"nama"`
-	Deskripsi string    `json:"deskripsi"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
-	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	Nama           string    `json:"nama"`
+	Deskripsi      string    `json:"deskripsi"`
+	Priority       int       `json:"priority"`
+	KeycloakClient *string   `json:"keycloak_client,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateRoleInput input untuk mengubah role
+type UpdateRoleInput struct {
+	Nama           *string `json:"nama,omitempty"`
+	Deskripsi      *string `json:"deskripsi,omitempty"`
+	Priority       *int    `json:"priority,omitempty"`
+	KeycloakClient *string `json:"keycloak_client,omitempty"`
+}
+
+// AssignRoleInput input untuk assign role ke user
+type AssignRoleInput struct {
+	RoleID      uuid.UUID  `json:"role_id"`
+	UnitKerjaID *uuid.UUID `json:"unit_kerja_id,omitempty"`
+}
+
+// AuditLogFilter filter pencarian audit log. Field kosong tidak memfilter.
+type AuditLogFilter struct {
+	Action     string
+	Resource   string
+	ResourceID *uuid.UUID
+	// UserID dan Username juga mencocokkan user yang diperankan saat impersonation
+	UserID   string
+	Username string
+	Status   string
+	// IPAddress berupa alamat tunggal atau CIDR, misalnya 10.1.0.0/16
+	IPAddress string
+	From      *time.Time
+	To        *time.Time
+	// ChangesContain objek JSON yang harus terkandung di changes (operator @>)
+	ChangesContain json.RawMessage
+	// ChangedField nama field yang ada di changes.diff
+	ChangedField string
+}
+
+// ParseAuditLogFilter membangun AuditLogFilter dari parameter bernama
+// (query string atau flag CLI): action, resource, resource_id, user_id,
+// username, status, ip_address, from, to, changes dan changed_field. from/to
+// menerima RFC3339 atau YYYY-MM-DD; tanggal pada to bersifat inklusif.
+// Mengembalikan nama parameter pertama yang tidak valid, atau string kosong.
+func ParseAuditLogFilter(get func(key string) string) (AuditLogFilter, string) {
+	filter := AuditLogFilter{
+		Action:       get("action"),
+		Resource:     get("resource"),
+		UserID:       get("user_id"),
+		Username:     get("username"),
+		Status:       get("status"),
+		IPAddress:    get("ip_address"),
+		ChangedField: get("changed_field"),
+	}
+
+	if v := get("resource_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return filter, "resource_id"
+		}
+		filter.ResourceID = &id
+	}
+	if v := get("from"); v != "" {
+		from, _, err := audit.ParseTime(v)
+		if err != nil {
+			return filter, "from"
+		}
+		filter.From = &from
+	}
+	if v := get("to"); v != "" {
+		to, dateOnly, err := audit.ParseTime(v)
+		if err != nil {
+			return filter, "to"
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+	if v := get("changes"); v != "" {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &object); err != nil {
+			return filter, "changes"
+		}
+		filter.ChangesContain = json.RawMessage(v)
+	}
+
+	return filter, ""
 }
 
 // AuditLogInput input untuk audit log
@@ -233,4 +1114,17 @@ type AuditLogInput struct {
 	Changes      map[string]interface{} `json:"changes,omitempty"`
 	Status       string                 `json:"status"`
 	ErrorMessage *string                `json:"error_message,omitempty"`
-}
\ No newline at end of file
+	// Terisi bila UserID bertindak atas nama user lain (impersonation)
+	ImpersonatedUserID     *string    `json:"impersonated_user_id,omitempty"`
+	ImpersonatedUsername   *string    `json:"impersonated_username,omitempty"`
+	ImpersonationSessionID *uuid.UUID `json:"impersonation_session_id,omitempty"`
+	// Snapshot state record tanpa masking untuk revert; disimpan di
+	// audit_snapshots, bukan di audit_logs
+	Snapshot *audit.Snapshot `json:"snapshot,omitempty"`
+}
+
+// isUniqueViolation mengecek apakah error berasal dari pelanggaran unique constraint
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== RIWAYAT ====================
+
+var (
+	ErrRiwayatNotFound   = errors.New("riwayat not found")
+	ErrGolonganNotFound  = errors.New("golongan not found")
+	ErrGolonganInactive  = errors.New("golongan is not active")
+	ErrJabatanNotFound   = errors.New("jabatan not found")
+	ErrUnitKerjaNotFound = errors.New("unit kerja not found")
+	ErrUnitKerjaSatker   = errors.New("unit kerja does not belong to satker")
+)
+
+// RiwayatRepository mengelola operasi database untuk riwayat
+type RiwayatRepository struct {
+	dbKepegawaian *pgxpool.Pool
+	dbMaster      *pgxpool.Pool
+}
+
+// NewRiwayatRepository membuat instance RiwayatRepository baru
+func NewRiwayatRepository(dbKepegawaian, dbMaster *pgxpool.Pool) *RiwayatRepository {
+	return &RiwayatRepository{
+		dbKepegawaian: dbKepegawaian,
+		dbMaster:      dbMaster,
+	}
+}
+
+// getPegawaiAktif mengunci pegawai yang riwayatnya diubah. Pegawai yang
+// sudah dihapus (soft delete) dianggap tidak ada.
+func getPegawaiAktif(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*models.Pegawai, error) {
+	pegawai, err := getPegawai(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if pegawai.DeletedAt != nil {
+		return nil, ErrPegawaiNotFound
+	}
+	return pegawai, nil
+}
+
+// markTerakhir menandai riwayat pegawai dengan TMT terbaru di table sebagai
+// is_terakhir dan melepas tanda dari riwayat lainnya
+func markTerakhir(ctx context.Context, tx pgx.Tx, table string, pegawaiID uuid.UUID) error {
+	query := `WITH latest AS (
+				  SELECT id FROM ` + table + ` WHERE pegawai_id = $1
+				  ORDER BY tmt DESC, tanggal_sk DESC, created_at DESC
+				  LIMIT 1
+			  )
+			  UPDATE ` + table + ` SET is_terakhir = (id IN (SELECT id FROM latest))
+			  WHERE pegawai_id = $1 AND is_terakhir IS DISTINCT FROM (id IN (SELECT id FROM latest))`
+
+	if _, err := tx.Exec(ctx, query, pegawaiID); err != nil {
+		return fmt.Errorf("failed to sync %s terakhir: %w", table, err)
+	}
+	return nil
+}
+
+// sameDate membandingkan dua tanggal tanpa komponen waktu
+func sameDate(a *time.Time, b time.Time) bool {
+	if a == nil {
+		return false
+	}
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// sameID membandingkan dua UUID opsional
+func sameID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// idList mengumpulkan UUID opsional yang terisi
+func idList(ids ...*uuid.UUID) []uuid.UUID {
+	list := []uuid.UUID{}
+	for _, id := range ids {
+		if id != nil {
+			list = append(list, *id)
+		}
+	}
+	return list
+}
@@ -0,0 +1,434 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== RIWAYAT DIKLAT ====================
+
+// TargetJPTahunan adalah kewajiban pengembangan kompetensi pegawai per tahun
+const TargetJPTahunan = 20
+
+var ErrJenisDiklatNotFound = errors.New("jenis diklat not found")
+
+// RiwayatDiklatInput input untuk membuat dan mengubah riwayat diklat
+type RiwayatDiklatInput struct {
+	JenisDiklatID     uuid.UUID  `json:"jenis_diklat_id"`
+	NamaDiklat        string     `json:"nama_diklat"`
+	Penyelenggara     *string    `json:"penyelenggara,omitempty"`
+	Tempat            *string    `json:"tempat,omitempty"`
+	TanggalMulai      *time.Time `json:"tanggal_mulai,omitempty"`
+	TanggalSelesai    *time.Time `json:"tanggal_selesai,omitempty"`
+	JamJPL            *int       `json:"jam_jpl,omitempty"`
+	NomorSertifikat   *string    `json:"nomor_sertifikat,omitempty"`
+	TanggalSertifikat *time.Time `json:"tanggal_sertifikat,omitempty"`
+}
+
+// JPPerJenis adalah akumulasi JP pegawai untuk satu jenis diklat
+type JPPerJenis struct {
+	JenisDiklat *models.RefJenisDiklat `json:"jenis_diklat"`
+	JP          int                    `json:"jp"`
+}
+
+// KepatuhanJPPegawai adalah pegawai yang JP tahunannya belum memenuhi target
+type KepatuhanJPPegawai struct {
+	PegawaiID    uuid.UUID    `json:"pegawai_id"`
+	NIP          string       `json:"nip"`
+	NamaLengkap  string       `json:"nama_lengkap"`
+	TotalJP      int          `json:"total_jp"`
+	KekuranganJP int          `json:"kekurangan_jp"`
+	PerJenis     []JPPerJenis `json:"per_jenis"`
+}
+
+// KepatuhanJPSatker adalah rekap kepatuhan JP satu satker. Satker nil
+// berisi pegawai tanpa satker.
+type KepatuhanJPSatker struct {
+	Satker         *models.Satker       `json:"satker"`
+	JumlahPegawai  int                  `json:"jumlah_pegawai"`
+	JumlahMemenuhi int                  `json:"jumlah_memenuhi"`
+	BelumMemenuhi  []KepatuhanJPPegawai `json:"belum_memenuhi"`
+}
+
+const riwayatDiklatColumns = `id, pegawai_id, jenis_diklat_id, nama_diklat, penyelenggara, tempat, tanggal_mulai,
+			  tanggal_selesai, jam_jpl, nomor_sertifikat, tanggal_sertifikat, created_at, updated_at, created_by`
+
+// scanRiwayatDiklat memindai satu baris riwayatDiklatColumns
+func scanRiwayatDiklat(row pgx.Row) (*models.Diklat, error) {
+	var d models.Diklat
+	err := row.Scan(&d.ID, &d.PegawaiID, &d.JenisDiklatID, &d.NamaDiklat, &d.Penyelenggara, &d.Tempat, &d.TanggalMulai,
+		&d.TanggalSelesai, &d.JamJPL, &d.NomorSertifikat, &d.TanggalSertifikat, &d.CreatedAt, &d.UpdatedAt, &d.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// getRiwayatDiklat mengambil riwayat diklat milik pegawai di dalam transaksi
+func getRiwayatDiklat(ctx context.Context, tx pgx.Tx, pegawaiID, id uuid.UUID, forUpdate bool) (*models.Diklat, error) {
+	query := `SELECT ` + riwayatDiklatColumns + ` FROM diklat WHERE id = $1 AND pegawai_id = $2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	d, err := scanRiwayatDiklat(tx.QueryRow(ctx, query, id, pegawaiID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrRiwayatNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get riwayat diklat: %w", err)
+	}
+	return d, nil
+}
+
+// jenisDiklat mengambil ref_jenis_diklat dari db_master; tanpa ids seluruh
+// jenis diklat dikembalikan
+func (r *RiwayatRepository) jenisDiklat(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]*models.RefJenisDiklat, error) {
+	query := `SELECT id, kode, nama, hitung_jp, COALESCE(is_active, false), created_at, updated_at
+			  FROM ref_jenis_diklat`
+	var args []interface{}
+	if ids != nil {
+		query += ` WHERE id = ANY($1)`
+		args = append(args, ids)
+	}
+
+	rows, err := r.dbMaster.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ref jenis diklat: %w", err)
+	}
+	defer rows.Close()
+
+	jenis := map[uuid.UUID]*models.RefJenisDiklat{}
+	for rows.Next() {
+		var j models.RefJenisDiklat
+		if err := rows.Scan(&j.ID, &j.Kode, &j.Nama, &j.HitungJP, &j.IsActive, &j.CreatedAt, &j.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ref jenis diklat: %w", err)
+		}
+		jenis[j.ID] = &j
+	}
+
+	return jenis, rows.Err()
+}
+
+// resolveJenisDiklat memvalidasi jenis_diklat_id input terhadap ref_jenis_diklat
+func (r *RiwayatRepository) resolveJenisDiklat(ctx context.Context, id uuid.UUID) (*models.RefJenisDiklat, error) {
+	jenis, err := r.jenisDiklat(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	j, ok := jenis[id]
+	if !ok {
+		return nil, ErrJenisDiklatNotFound
+	}
+	return j, nil
+}
+
+// ListDiklat mengambil riwayat diklat pegawai, terbaru lebih dulu
+func (r *RiwayatRepository) ListDiklat(ctx context.Context, pegawaiID uuid.UUID) ([]models.Diklat, error) {
+	list, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) ([]models.Diklat, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+
+		query := `SELECT ` + riwayatDiklatColumns + ` FROM diklat
+				  WHERE pegawai_id = $1
+				  ORDER BY COALESCE(tanggal_selesai, tanggal_mulai, tanggal_sertifikat) DESC NULLS LAST, created_at DESC`
+
+		rows, err := tx.Query(ctx, query, pegawaiID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query riwayat diklat: %w", err)
+		}
+		defer rows.Close()
+
+		list := []models.Diklat{}
+		for rows.Next() {
+			d, err := scanRiwayatDiklat(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan riwayat diklat: %w", err)
+			}
+			list = append(list, *d)
+		}
+		return list, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(list))
+	for _, d := range list {
+		ids = append(ids, d.JenisDiklatID)
+	}
+	jenis, err := r.jenisDiklat(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].JenisDiklat = jenis[list[i].JenisDiklatID]
+	}
+
+	return list, nil
+}
+
+// GetDiklat mengambil satu riwayat diklat pegawai
+func (r *RiwayatRepository) GetDiklat(ctx context.Context, pegawaiID, id uuid.UUID) (*models.Diklat, error) {
+	d, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.Diklat, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		return getRiwayatDiklat(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jenis, err := r.jenisDiklat(ctx, d.JenisDiklatID)
+	if err != nil {
+		return nil, err
+	}
+	d.JenisDiklat = jenis[d.JenisDiklatID]
+
+	return d, nil
+}
+
+// CreateDiklat menambah riwayat diklat pegawai
+func (r *RiwayatRepository) CreateDiklat(ctx context.Context, pegawaiID uuid.UUID, input RiwayatDiklatInput, userID string) (*models.Diklat, error) {
+	jenis, err := r.resolveJenisDiklat(ctx, input.JenisDiklatID)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.Diklat, error) {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return nil, err
+		}
+
+		query := `INSERT INTO diklat (pegawai_id, jenis_diklat_id, nama_diklat, penyelenggara, tempat, tanggal_mulai,
+				  tanggal_selesai, jam_jpl, nomor_sertifikat, tanggal_sertifikat, created_by)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+				  RETURNING ` + riwayatDiklatColumns
+
+		d, err := scanRiwayatDiklat(tx.QueryRow(ctx, query,
+			pegawaiID, input.JenisDiklatID, input.NamaDiklat, input.Penyelenggara, input.Tempat, input.TanggalMulai,
+			input.TanggalSelesai, input.JamJPL, input.NomorSertifikat, input.TanggalSertifikat, actorUUID(userID),
+		))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create riwayat diklat: %w", err)
+		}
+		return d, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionCreate, "diklat", d.ID, nil, d)
+	d.JenisDiklat = jenis
+	return d, nil
+}
+
+// UpdateDiklat mengubah riwayat diklat pegawai
+func (r *RiwayatRepository) UpdateDiklat(ctx context.Context, pegawaiID, id uuid.UUID, input RiwayatDiklatInput) (*models.Diklat, error) {
+	jenis, err := r.resolveJenisDiklat(ctx, input.JenisDiklatID)
+	if err != nil {
+		return nil, err
+	}
+
+	var before *models.Diklat
+	d, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.Diklat, error) {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return nil, err
+		}
+		var err error
+		before, err = getRiwayatDiklat(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return nil, err
+		}
+
+		query := `UPDATE diklat
+				  SET jenis_diklat_id = $2, nama_diklat = $3, penyelenggara = $4, tempat = $5, tanggal_mulai = $6,
+					  tanggal_selesai = $7, jam_jpl = $8, nomor_sertifikat = $9, tanggal_sertifikat = $10
+				  WHERE id = $1
+				  RETURNING ` + riwayatDiklatColumns
+
+		d, err := scanRiwayatDiklat(tx.QueryRow(ctx, query,
+			id, input.JenisDiklatID, input.NamaDiklat, input.Penyelenggara, input.Tempat, input.TanggalMulai,
+			input.TanggalSelesai, input.JamJPL, input.NomorSertifikat, input.TanggalSertifikat,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update riwayat diklat: %w", err)
+		}
+		return d, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "diklat", d.ID, before, d)
+	d.JenisDiklat = jenis
+	return d, nil
+}
+
+// DeleteDiklat menghapus riwayat diklat pegawai
+func (r *RiwayatRepository) DeleteDiklat(ctx context.Context, pegawaiID, id uuid.UUID) error {
+	var before *models.Diklat
+	err := database.WithRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) error {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return err
+		}
+		var err error
+		before, err = getRiwayatDiklat(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM diklat WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete riwayat diklat: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.ActionDelete, "diklat", id, before, nil)
+	return nil
+}
+
+// KepatuhanJP merekap JP diklat pegawai aktif per satker untuk satu tahun
+// dan mengembalikan pegawai yang belum mencapai TargetJPTahunan. Diklat
+// masuk tahun tanggal selesainya (atau tanggal mulai/sertifikat bila
+// kosong); hanya jenis diklat dengan hitung_jp yang menambah total.
+// satkerID opsional membatasi rekap ke satu satker.
+func (r *RiwayatRepository) KepatuhanJP(ctx context.Context, tahun int, satkerID *uuid.UUID) ([]KepatuhanJPSatker, error) {
+	type jpRow struct {
+		pegawaiID, jenisID uuid.UUID
+		nip, namaLengkap   string
+		satkerID           *uuid.UUID
+		jp                 int
+	}
+
+	rows, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) ([]jpRow, error) {
+		query := `SELECT p.id, p.nip, p.nama_lengkap, p.satker_id,
+				  COALESCE(d.jenis_diklat_id, '00000000-0000-0000-0000-000000000000'), COALESCE(SUM(d.jam_jpl), 0)
+				  FROM pegawai p
+				  LEFT JOIN diklat d ON d.pegawai_id = p.id AND d.jam_jpl > 0
+				  AND EXTRACT(YEAR FROM COALESCE(d.tanggal_selesai, d.tanggal_mulai, d.tanggal_sertifikat)) = $1
+				  WHERE p.is_active = true AND p.deleted_at IS NULL
+				  AND ($2::uuid IS NULL OR p.satker_id = $2)
+				  GROUP BY p.id, p.nip, p.nama_lengkap, p.satker_id, d.jenis_diklat_id`
+
+		result, err := tx.Query(ctx, query, tahun, satkerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query jp diklat: %w", err)
+		}
+		defer result.Close()
+
+		list := []jpRow{}
+		for result.Next() {
+			var row jpRow
+			if err := result.Scan(&row.pegawaiID, &row.nip, &row.namaLengkap, &row.satkerID, &row.jenisID, &row.jp); err != nil {
+				return nil, fmt.Errorf("failed to scan jp diklat: %w", err)
+			}
+			list = append(list, row)
+		}
+		return list, result.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jenis, err := r.jenisDiklat(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pegawai := map[uuid.UUID]*KepatuhanJPPegawai{}
+	pegawaiSatker := map[uuid.UUID]uuid.UUID{}
+	var order []uuid.UUID
+	for _, row := range rows {
+		p, ok := pegawai[row.pegawaiID]
+		if !ok {
+			p = &KepatuhanJPPegawai{PegawaiID: row.pegawaiID, NIP: row.nip, NamaLengkap: row.namaLengkap, PerJenis: []JPPerJenis{}}
+			pegawai[row.pegawaiID] = p
+			if row.satkerID != nil {
+				pegawaiSatker[row.pegawaiID] = *row.satkerID
+			}
+			order = append(order, row.pegawaiID)
+		}
+		if row.jenisID == uuid.Nil {
+			continue
+		}
+		// Jenis diklat yang sudah dihapus dari db_master tidak dihitung
+		j, ok := jenis[row.jenisID]
+		if !ok {
+			continue
+		}
+		p.PerJenis = append(p.PerJenis, JPPerJenis{JenisDiklat: j, JP: row.jp})
+		if j.HitungJP {
+			p.TotalJP += row.jp
+		}
+	}
+
+	satkerIDs := make([]uuid.UUID, 0, len(pegawaiSatker))
+	for _, id := range pegawaiSatker {
+		satkerIDs = append(satkerIDs, id)
+	}
+	satker, err := r.satkerByIDs(ctx, satkerIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	rekap := map[uuid.UUID]*KepatuhanJPSatker{}
+	for _, id := range order {
+		p := pegawai[id]
+		// Satker di luar scope user atau tanpa satker direkap bersama
+		key := uuid.Nil
+		if s, ok := satker[pegawaiSatker[id]]; ok {
+			key = s.ID
+		}
+		k, ok := rekap[key]
+		if !ok {
+			k = &KepatuhanJPSatker{Satker: satker[key], BelumMemenuhi: []KepatuhanJPPegawai{}}
+			rekap[key] = k
+		}
+
+		k.JumlahPegawai++
+		if p.TotalJP >= TargetJPTahunan {
+			k.JumlahMemenuhi++
+			continue
+		}
+		p.KekuranganJP = TargetJPTahunan - p.TotalJP
+		sort.Slice(p.PerJenis, func(i, j int) bool { return p.PerJenis[i].JenisDiklat.Kode < p.PerJenis[j].JenisDiklat.Kode })
+		k.BelumMemenuhi = append(k.BelumMemenuhi, *p)
+	}
+
+	result := make([]KepatuhanJPSatker, 0, len(rekap))
+	for _, k := range rekap {
+		sort.Slice(k.BelumMemenuhi, func(i, j int) bool {
+			a, b := k.BelumMemenuhi[i], k.BelumMemenuhi[j]
+			if a.TotalJP != b.TotalJP {
+				return a.TotalJP < b.TotalJP
+			}
+			return a.NamaLengkap < b.NamaLengkap
+		})
+		result = append(result, *k)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Satker, result[j].Satker
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.Kode < b.Kode
+	})
+
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+func TestKepatuhanJP(t *testing.T) {
+	suite := SetupRiwayatTest(t)
+	repo := repositories.NewRiwayatRepository(suite.Kepegawaian, suite.Master)
+	ctx := context.Background()
+
+	suite.SatkerID = suite.CreateSatker(t, "PA-JKT", "Pengadilan Agama Jakarta Pusat")
+	teknis := suite.CreateJenisDiklat(t, "DT", "Diklat Teknis", true)
+	seminar := suite.CreateJenisDiklat(t, "SEM", "Seminar", false)
+	memenuhi := suite.CreatePegawai(t, "198501012010011001")
+	kurang := suite.CreatePegawai(t, "198602022011012002")
+	tanpaDiklat := suite.CreatePegawai(t, "198703032012013003")
+
+	tgl := func(y int, m time.Month, d int) *time.Time {
+		v := date(y, m, d)
+		return &v
+	}
+	jp := func(v int) *int { return &v }
+	create := func(pegawaiID, jenisID uuid.UUID, input repositories.RiwayatDiklatInput) {
+		input.JenisDiklatID = jenisID
+		input.NamaDiklat = "Diklat"
+		_, err := repo.CreateDiklat(ctx, pegawaiID, input, "")
+		require.NoError(t, err)
+	}
+
+	create(memenuhi, teknis, repositories.RiwayatDiklatInput{TanggalMulai: tgl(2024, 3, 4), TanggalSelesai: tgl(2024, 3, 10), JamJPL: jp(12)})
+	// Tanpa tanggal pelaksanaan, tahun diambil dari tanggal sertifikat
+	create(memenuhi, teknis, repositories.RiwayatDiklatInput{TanggalSertifikat: tgl(2024, 11, 1), JamJPL: jp(8)})
+	// Diklat lintas tahun masuk tahun tanggal selesai
+	create(kurang, teknis, repositories.RiwayatDiklatInput{TanggalMulai: tgl(2023, 12, 28), TanggalSelesai: tgl(2024, 1, 3), JamJPL: jp(15)})
+	create(kurang, teknis, repositories.RiwayatDiklatInput{TanggalMulai: tgl(2023, 12, 1), TanggalSelesai: tgl(2023, 12, 30), JamJPL: jp(30)})
+	create(kurang, seminar, repositories.RiwayatDiklatInput{TanggalMulai: tgl(2024, 5, 2), JamJPL: jp(10)})
+
+	rekap, err := repo.KepatuhanJP(ctx, 2024, nil)
+	require.NoError(t, err)
+	require.Len(t, rekap, 1)
+
+	satker := rekap[0]
+	require.NotNil(t, satker.Satker)
+	assert.Equal(t, suite.SatkerID, satker.Satker.ID)
+	assert.Equal(t, 3, satker.JumlahPegawai)
+	assert.Equal(t, 1, satker.JumlahMemenuhi)
+
+	require.Len(t, satker.BelumMemenuhi, 2)
+	assert.Equal(t, tanpaDiklat, satker.BelumMemenuhi[0].PegawaiID)
+	assert.Equal(t, 0, satker.BelumMemenuhi[0].TotalJP)
+	assert.Equal(t, repositories.TargetJPTahunan, satker.BelumMemenuhi[0].KekuranganJP)
+
+	p := satker.BelumMemenuhi[1]
+	assert.Equal(t, kurang, p.PegawaiID)
+	assert.Equal(t, 15, p.TotalJP, "seminar tidak dihitung dan diklat 2023 tidak masuk")
+	assert.Equal(t, 5, p.KekuranganJP)
+	require.Len(t, p.PerJenis, 2)
+	assert.Equal(t, "DT", p.PerJenis[0].JenisDiklat.Kode)
+	assert.Equal(t, 15, p.PerJenis[0].JP)
+	assert.Equal(t, "SEM", p.PerJenis[1].JenisDiklat.Kode)
+	assert.Equal(t, 10, p.PerJenis[1].JP)
+
+	t.Run("filter satker lain kosong", func(t *testing.T) {
+		lain := uuid.New()
+		rekap, err := repo.KepatuhanJP(ctx, 2024, &lain)
+		require.NoError(t, err)
+		assert.Empty(t, rekap)
+	})
+}
@@ -0,0 +1,464 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== RIWAYAT JABATAN ====================
+
+// RiwayatJabatanInput input untuk membuat dan mengubah riwayat jabatan. Bila
+// jabatan_id diisi, nama_jabatan disalin dari master jabatan agar riwayat
+// tidak ikut berubah saat master diganti namanya.
+type RiwayatJabatanInput struct {
+	JabatanID    *uuid.UUID           `json:"jabatan_id,omitempty"`
+	UnitKerjaID  *uuid.UUID           `json:"unit_kerja_id,omitempty"`
+	SatkerID     *uuid.UUID           `json:"satker_id,omitempty"`
+	NamaJabatan  string               `json:"nama_jabatan"`
+	TMT          time.Time            `json:"tmt"`
+	NomorSK      string               `json:"nomor_sk"`
+	TanggalSK    time.Time            `json:"tanggal_sk"`
+	Pejabat      string               `json:"pejabat"`
+	FileSK       *string              `json:"file_sk,omitempty"`
+	JenisJabatan *models.JenisJabatan `json:"jenis_jabatan,omitempty"`
+}
+
+const riwayatJabatanColumns = `id, pegawai_id, jabatan_id, unit_kerja_id, satker_id, nama_jabatan, tmt, nomor_sk, tanggal_sk,
+			  pejabat, file_sk, COALESCE(is_terakhir, false), jenis_jabatan, created_at, updated_at, created_by`
+
+// scanRiwayatJabatan memindai satu baris riwayatJabatanColumns
+func scanRiwayatJabatan(row pgx.Row) (*models.RiwayatJabatan, error) {
+	var rj models.RiwayatJabatan
+	err := row.Scan(&rj.ID, &rj.PegawaiID, &rj.JabatanID, &rj.UnitKerjaID, &rj.SatkerID, &rj.NamaJabatan, &rj.TMT, &rj.NomorSK, &rj.TanggalSK,
+		&rj.Pejabat, &rj.FileSK, &rj.IsTerakhir, &rj.JenisJabatan, &rj.CreatedAt, &rj.UpdatedAt, &rj.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &rj, nil
+}
+
+// getRiwayatJabatan mengambil riwayat jabatan milik pegawai di dalam transaksi
+func getRiwayatJabatan(ctx context.Context, tx pgx.Tx, pegawaiID, id uuid.UUID, forUpdate bool) (*models.RiwayatJabatan, error) {
+	query := `SELECT ` + riwayatJabatanColumns + ` FROM riwayat_jabatan WHERE id = $1 AND pegawai_id = $2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	rj, err := scanRiwayatJabatan(tx.QueryRow(ctx, query, id, pegawaiID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrRiwayatNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get riwayat jabatan: %w", err)
+	}
+	return rj, nil
+}
+
+// referensiJabatan adalah data db_master yang dirujuk riwayat jabatan
+type referensiJabatan struct {
+	jabatan   map[uuid.UUID]*models.Jabatan
+	unitKerja map[uuid.UUID]*models.UnitKerja
+	satker    map[uuid.UUID]*models.Satker
+}
+
+// attach mengisi relasi riwayat jabatan yang ditemukan di db_master
+func (ref *referensiJabatan) attach(rj *models.RiwayatJabatan) {
+	if rj.JabatanID != nil {
+		rj.Jabatan = ref.jabatan[*rj.JabatanID]
+	}
+	if rj.UnitKerjaID != nil {
+		rj.UnitKerja = ref.unitKerja[*rj.UnitKerjaID]
+	}
+	if rj.SatkerID != nil {
+		rj.Satker = ref.satker[*rj.SatkerID]
+	}
+}
+
+// jabatanByIDs mengambil jabatan dari db_master
+func (r *RiwayatRepository) jabatanByIDs(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]*models.Jabatan, error) {
+	query := `SELECT id, kode, nama, eselon_id, COALESCE(kelas, ''), jenis, is_active, created_at, updated_at
+			  FROM jabatan WHERE id = ANY($1)`
+
+	rows, err := r.dbMaster.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jabatan: %w", err)
+	}
+	defer rows.Close()
+
+	jabatan := map[uuid.UUID]*models.Jabatan{}
+	for rows.Next() {
+		var j models.Jabatan
+		err := rows.Scan(&j.ID, &j.Kode, &j.Nama, &j.EselonID, &j.Kelas, &j.Jenis, &j.IsActive, &j.CreatedAt, &j.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan jabatan: %w", err)
+		}
+		jabatan[j.ID] = &j
+	}
+
+	return jabatan, rows.Err()
+}
+
+// unitKerjaByIDs mengambil unit kerja dari db_master dalam scope RLS user
+func (r *RiwayatRepository) unitKerjaByIDs(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]*models.UnitKerja, error) {
+	return database.QueryRLS(ctx, r.dbMaster, func(tx pgx.Tx) (map[uuid.UUID]*models.UnitKerja, error) {
+		query := `SELECT id, kode, nama, COALESCE(singkatan, ''), parent_id, satker_id, is_active, created_at, updated_at
+				  FROM unit_kerja WHERE id = ANY($1)`
+
+		rows, err := tx.Query(ctx, query, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query unit_kerja: %w", err)
+		}
+		defer rows.Close()
+
+		unitKerja := map[uuid.UUID]*models.UnitKerja{}
+		for rows.Next() {
+			var uk models.UnitKerja
+			err := rows.Scan(&uk.ID, &uk.Kode, &uk.Nama, &uk.Singkatan, &uk.ParentID, &uk.SatkerID, &uk.IsActive, &uk.CreatedAt, &uk.UpdatedAt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan unit_kerja: %w", err)
+			}
+			unitKerja[uk.ID] = &uk
+		}
+		return unitKerja, rows.Err()
+	})
+}
+
+// satkerByIDs mengambil satker dari db_master dalam scope RLS user
+func (r *RiwayatRepository) satkerByIDs(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]*models.Satker, error) {
+	return database.QueryRLS(ctx, r.dbMaster, func(tx pgx.Tx) (map[uuid.UUID]*models.Satker, error) {
+		satker := map[uuid.UUID]*models.Satker{}
+		for _, id := range ids {
+			if _, ok := satker[id]; ok {
+				continue
+			}
+			s, err := getSatker(ctx, tx, id, false)
+			if err == ErrSatkerNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			satker[id] = s
+		}
+		return satker, nil
+	})
+}
+
+// loadReferensiJabatan mengambil data db_master untuk relasi daftar riwayat jabatan
+func (r *RiwayatRepository) loadReferensiJabatan(ctx context.Context, list []models.RiwayatJabatan) (*referensiJabatan, error) {
+	var jabatanIDs, unitKerjaIDs, satkerIDs []uuid.UUID
+	for _, rj := range list {
+		jabatanIDs = append(jabatanIDs, idList(rj.JabatanID)...)
+		unitKerjaIDs = append(unitKerjaIDs, idList(rj.UnitKerjaID)...)
+		satkerIDs = append(satkerIDs, idList(rj.SatkerID)...)
+	}
+
+	var ref referensiJabatan
+	var err error
+	if ref.jabatan, err = r.jabatanByIDs(ctx, jabatanIDs...); err != nil {
+		return nil, err
+	}
+	if ref.unitKerja, err = r.unitKerjaByIDs(ctx, unitKerjaIDs...); err != nil {
+		return nil, err
+	}
+	if ref.satker, err = r.satkerByIDs(ctx, satkerIDs...); err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+// resolveJabatan memvalidasi referensi db_master pada input. Nama dan jenis
+// jabatan disalin dari master jabatan, dan satker diisi dari unit kerja bila
+// kosong.
+func (r *RiwayatRepository) resolveJabatan(ctx context.Context, input *RiwayatJabatanInput) (*referensiJabatan, error) {
+	var ref referensiJabatan
+	var err error
+
+	if ref.jabatan, err = r.jabatanByIDs(ctx, idList(input.JabatanID)...); err != nil {
+		return nil, err
+	}
+	if input.JabatanID != nil {
+		jabatan, ok := ref.jabatan[*input.JabatanID]
+		if !ok {
+			return nil, ErrJabatanNotFound
+		}
+		input.NamaJabatan = jabatan.Nama
+		if input.JenisJabatan == nil {
+			input.JenisJabatan = jabatan.Jenis
+		}
+	}
+
+	if ref.unitKerja, err = r.unitKerjaByIDs(ctx, idList(input.UnitKerjaID)...); err != nil {
+		return nil, err
+	}
+	if input.UnitKerjaID != nil {
+		unitKerja, ok := ref.unitKerja[*input.UnitKerjaID]
+		if !ok {
+			return nil, ErrUnitKerjaNotFound
+		}
+		if unitKerja.SatkerID != nil {
+			if input.SatkerID == nil {
+				input.SatkerID = unitKerja.SatkerID
+			} else if *input.SatkerID != *unitKerja.SatkerID {
+				return nil, ErrUnitKerjaSatker
+			}
+		}
+	}
+
+	if ref.satker, err = r.satkerByIDs(ctx, idList(input.SatkerID)...); err != nil {
+		return nil, err
+	}
+	if input.SatkerID != nil {
+		if _, ok := ref.satker[*input.SatkerID]; !ok {
+			return nil, ErrSatkerNotFound
+		}
+	}
+
+	return &ref, nil
+}
+
+// ListJabatan mengambil riwayat jabatan pegawai, terbaru lebih dulu
+func (r *RiwayatRepository) ListJabatan(ctx context.Context, pegawaiID uuid.UUID) ([]models.RiwayatJabatan, error) {
+	list, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) ([]models.RiwayatJabatan, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+
+		query := `SELECT ` + riwayatJabatanColumns + ` FROM riwayat_jabatan
+				  WHERE pegawai_id = $1
+				  ORDER BY tmt DESC, tanggal_sk DESC, created_at DESC`
+
+		rows, err := tx.Query(ctx, query, pegawaiID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query riwayat jabatan: %w", err)
+		}
+		defer rows.Close()
+
+		list := []models.RiwayatJabatan{}
+		for rows.Next() {
+			rj, err := scanRiwayatJabatan(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan riwayat jabatan: %w", err)
+			}
+			list = append(list, *rj)
+		}
+		return list, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := r.loadReferensiJabatan(ctx, list)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		ref.attach(&list[i])
+	}
+
+	return list, nil
+}
+
+// GetJabatan mengambil satu riwayat jabatan pegawai
+func (r *RiwayatRepository) GetJabatan(ctx context.Context, pegawaiID, id uuid.UUID) (*models.RiwayatJabatan, error) {
+	rj, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatJabatan, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		return getRiwayatJabatan(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := r.loadReferensiJabatan(ctx, []models.RiwayatJabatan{*rj})
+	if err != nil {
+		return nil, err
+	}
+	ref.attach(rj)
+
+	return rj, nil
+}
+
+// CreateJabatan menambah riwayat jabatan dan menyinkronkan jabatan terakhir pegawai
+func (r *RiwayatRepository) CreateJabatan(ctx context.Context, pegawaiID uuid.UUID, input RiwayatJabatanInput, userID string) (*models.RiwayatJabatan, error) {
+	ref, err := r.resolveJabatan(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	rj, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatJabatan, error) {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return nil, err
+		}
+
+		query := `INSERT INTO riwayat_jabatan (pegawai_id, jabatan_id, unit_kerja_id, satker_id, nama_jabatan, tmt,
+				  nomor_sk, tanggal_sk, pejabat, file_sk, jenis_jabatan, created_by)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				  RETURNING id`
+
+		var id uuid.UUID
+		err = tx.QueryRow(ctx, query,
+			pegawaiID, input.JabatanID, input.UnitKerjaID, input.SatkerID, input.NamaJabatan, input.TMT,
+			input.NomorSK, input.TanggalSK, input.Pejabat, input.FileSK, input.JenisJabatan, actorUUID(userID),
+		).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create riwayat jabatan: %w", err)
+		}
+
+		if pegawaiAfter, err = syncJabatanTerakhir(ctx, tx, pegawaiBefore); err != nil {
+			return nil, err
+		}
+		return getRiwayatJabatan(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionCreate, "riwayat_jabatan", rj.ID, nil, rj)
+	ref.attach(rj)
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return rj, nil
+}
+
+// UpdateJabatan mengubah riwayat jabatan dan menyinkronkan jabatan terakhir
+// pegawai. Nama jabatan yang sudah tersimpan dipertahankan selama jabatan_id
+// tidak berubah.
+func (r *RiwayatRepository) UpdateJabatan(ctx context.Context, pegawaiID, id uuid.UUID, input RiwayatJabatanInput) (*models.RiwayatJabatan, error) {
+	ref, err := r.resolveJabatan(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	var before *models.RiwayatJabatan
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	rj, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatJabatan, error) {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return nil, err
+		}
+		before, err = getRiwayatJabatan(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return nil, err
+		}
+		if input.JabatanID != nil && sameID(input.JabatanID, before.JabatanID) {
+			input.NamaJabatan = before.NamaJabatan
+		}
+
+		query := `UPDATE riwayat_jabatan
+				  SET jabatan_id = $2, unit_kerja_id = $3, satker_id = $4, nama_jabatan = $5, tmt = $6,
+					  nomor_sk = $7, tanggal_sk = $8, pejabat = $9, file_sk = $10, jenis_jabatan = $11
+				  WHERE id = $1`
+
+		_, err = tx.Exec(ctx, query,
+			id, input.JabatanID, input.UnitKerjaID, input.SatkerID, input.NamaJabatan, input.TMT,
+			input.NomorSK, input.TanggalSK, input.Pejabat, input.FileSK, input.JenisJabatan,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update riwayat jabatan: %w", err)
+		}
+
+		if pegawaiAfter, err = syncJabatanTerakhir(ctx, tx, pegawaiBefore); err != nil {
+			return nil, err
+		}
+		return getRiwayatJabatan(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "riwayat_jabatan", rj.ID, before, rj)
+	ref.attach(rj)
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return rj, nil
+}
+
+// DeleteJabatan menghapus riwayat jabatan. Bila yang dihapus jabatan
+// terakhir, riwayat sebelumnya menjadi jabatan terakhir pegawai.
+func (r *RiwayatRepository) DeleteJabatan(ctx context.Context, pegawaiID, id uuid.UUID) error {
+	var before *models.RiwayatJabatan
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	err := database.WithRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) error {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return err
+		}
+		before, err = getRiwayatJabatan(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM riwayat_jabatan WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete riwayat jabatan: %w", err)
+		}
+
+		pegawaiAfter, err = syncJabatanTerakhir(ctx, tx, pegawaiBefore)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.ActionDelete, "riwayat_jabatan", id, before, nil)
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return nil
+}
+
+// syncJabatanTerakhir menandai riwayat dengan TMT terbaru sebagai jabatan
+// terakhir lalu menyalin jabatan, unit kerja, satker dan TMT-nya ke pegawai.
+// Satker pegawai wajib ada, sehingga riwayat tanpa satker mempertahankan
+// satker pegawai. Mengembalikan nil bila pegawai tidak berubah.
+func syncJabatanTerakhir(ctx context.Context, tx pgx.Tx, pegawai *models.Pegawai) (*models.Pegawai, error) {
+	if err := markTerakhir(ctx, tx, "riwayat_jabatan", pegawai.ID); err != nil {
+		return nil, err
+	}
+
+	var jabatanID, unitKerjaID, satkerID *uuid.UUID
+	var tmt time.Time
+	err := tx.QueryRow(ctx, `SELECT jabatan_id, unit_kerja_id, satker_id, tmt FROM riwayat_jabatan WHERE pegawai_id = $1 AND is_terakhir`, pegawai.ID).
+		Scan(&jabatanID, &unitKerjaID, &satkerID, &tmt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get riwayat jabatan terakhir: %w", err)
+	}
+	if satkerID == nil {
+		satkerID = &pegawai.SatkerID
+	}
+
+	if sameID(pegawai.JabatanID, jabatanID) && sameID(pegawai.UnitKerjaID, unitKerjaID) &&
+		pegawai.SatkerID == *satkerID && sameDate(pegawai.TMTJabatanTerakhir, tmt) {
+		return nil, nil
+	}
+
+	query := `UPDATE pegawai p SET jabatan_id = $2, unit_kerja_id = $3, satker_id = $4, tmt_jabatan_terakhir = $5,
+			  updated_at = NOW()
+			  WHERE p.id = $1
+			  RETURNING ` + pegawaiColumns
+
+	after, err := scanPegawai(tx.QueryRow(ctx, query, pegawai.ID, jabatanID, unitKerjaID, *satkerID, tmt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync jabatan pegawai: %w", err)
+	}
+	return after, nil
+}
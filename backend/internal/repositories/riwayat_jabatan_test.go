@@ -0,0 +1,154 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+func TestRiwayatJabatanSyncPegawai(t *testing.T) {
+	suite := SetupRiwayatTest(t)
+	repo := repositories.NewRiwayatRepository(suite.Kepegawaian, suite.Master)
+	ctx := context.Background()
+
+	pnJakarta := suite.CreateSatker(t, "PN-JKT", "Pengadilan Negeri Jakarta Pusat")
+	ptJakarta := suite.CreateSatker(t, "PT-JKT", "Pengadilan Tinggi Jakarta")
+	kepaniteraan := suite.CreateUnitKerja(t, "PN-JKT-KPN", "Kepaniteraan", pnJakarta)
+	kesekretariatan := suite.CreateUnitKerja(t, "PT-JKT-SEK", "Kesekretariatan", ptJakarta)
+	panitera := suite.CreateJabatan(t, "PNT-MDA", "Panitera Muda Perdata")
+	kasubag := suite.CreateJabatan(t, "KSB-UMM", "Kepala Subbagian Umum")
+	pegawaiID := suite.CreatePegawai(t, "198903102014031001")
+
+	input := func(jabatanID, unitKerjaID uuid.UUID, tahun int) repositories.RiwayatJabatanInput {
+		return repositories.RiwayatJabatanInput{
+			JabatanID:   &jabatanID,
+			UnitKerjaID: &unitKerjaID,
+			TMT:         date(tahun, 7, 1),
+			NomorSK:     "SK/" + jabatanID.String()[:8],
+			TanggalSK:   date(tahun, 6, 1),
+			Pejabat:     "Sekretaris Mahkamah Agung",
+		}
+	}
+
+	var pertama, kedua, susulan uuid.UUID
+
+	t.Run("riwayat pertama menjadi jabatan pegawai", func(t *testing.T) {
+		rj, err := repo.CreateJabatan(ctx, pegawaiID, input(panitera, kepaniteraan, 2016), "")
+		require.NoError(t, err)
+		pertama = rj.ID
+
+		assert.True(t, rj.IsTerakhir)
+		assert.Equal(t, "Panitera Muda Perdata", rj.NamaJabatan)
+		require.NotNil(t, rj.JenisJabatan)
+		assert.Equal(t, models.JenisJabatanStruktural, *rj.JenisJabatan)
+
+		j := suite.JabatanPegawai(t, pegawaiID)
+		assert.Equal(t, panitera, *j.JabatanID)
+		assert.Equal(t, kepaniteraan, *j.UnitKerjaID)
+		assert.Equal(t, pnJakarta, j.SatkerID, "satker diisi dari unit kerja")
+		assert.Equal(t, date(2016, 7, 1), j.TMT.UTC())
+	})
+
+	t.Run("riwayat lebih baru memindahkan is_terakhir", func(t *testing.T) {
+		rj, err := repo.CreateJabatan(ctx, pegawaiID, input(kasubag, kesekretariatan, 2020), "")
+		require.NoError(t, err)
+		kedua = rj.ID
+
+		assert.Equal(t, []uuid.UUID{kedua}, suite.Terakhir(t, "riwayat_jabatan", pegawaiID))
+		j := suite.JabatanPegawai(t, pegawaiID)
+		assert.Equal(t, kasubag, *j.JabatanID)
+		assert.Equal(t, kesekretariatan, *j.UnitKerjaID)
+		assert.Equal(t, ptJakarta, j.SatkerID)
+		assert.Equal(t, date(2020, 7, 1), j.TMT.UTC())
+	})
+
+	t.Run("riwayat lama yang diinput belakangan tidak mengubah pegawai", func(t *testing.T) {
+		rj, err := repo.CreateJabatan(ctx, pegawaiID, input(panitera, kepaniteraan, 2012), "")
+		require.NoError(t, err)
+		susulan = rj.ID
+
+		assert.False(t, rj.IsTerakhir)
+		assert.Equal(t, []uuid.UUID{kedua}, suite.Terakhir(t, "riwayat_jabatan", pegawaiID))
+		assert.Equal(t, kasubag, *suite.JabatanPegawai(t, pegawaiID).JabatanID)
+	})
+
+	t.Run("nama jabatan tetap setelah master diubah", func(t *testing.T) {
+		_, err := suite.Master.Exec(ctx, `UPDATE jabatan SET nama = 'Panitera Muda Perdata Khusus' WHERE id = $1`, panitera)
+		require.NoError(t, err)
+
+		upd := input(panitera, kepaniteraan, 2012)
+		upd.Pejabat = "Ketua Pengadilan Tinggi Jakarta"
+		rj, err := repo.UpdateJabatan(ctx, pegawaiID, susulan, upd)
+		require.NoError(t, err)
+		assert.Equal(t, "Panitera Muda Perdata", rj.NamaJabatan, "jabatan sama mempertahankan snapshot nama")
+
+		rj, err = repo.GetJabatan(ctx, pegawaiID, pertama)
+		require.NoError(t, err)
+		assert.Equal(t, "Panitera Muda Perdata", rj.NamaJabatan)
+	})
+
+	t.Run("update tmt menjadi terbaru memindahkan is_terakhir", func(t *testing.T) {
+		rj, err := repo.UpdateJabatan(ctx, pegawaiID, susulan, input(panitera, kepaniteraan, 2023))
+		require.NoError(t, err)
+		assert.True(t, rj.IsTerakhir)
+
+		assert.Equal(t, []uuid.UUID{susulan}, suite.Terakhir(t, "riwayat_jabatan", pegawaiID))
+		j := suite.JabatanPegawai(t, pegawaiID)
+		assert.Equal(t, panitera, *j.JabatanID)
+		assert.Equal(t, kepaniteraan, *j.UnitKerjaID)
+		assert.Equal(t, pnJakarta, j.SatkerID)
+		assert.Equal(t, date(2023, 7, 1), j.TMT.UTC())
+	})
+
+	t.Run("delete riwayat terakhir mengembalikan jabatan sebelumnya", func(t *testing.T) {
+		require.NoError(t, repo.DeleteJabatan(ctx, pegawaiID, susulan))
+
+		assert.Equal(t, []uuid.UUID{kedua}, suite.Terakhir(t, "riwayat_jabatan", pegawaiID))
+		j := suite.JabatanPegawai(t, pegawaiID)
+		assert.Equal(t, kasubag, *j.JabatanID)
+		assert.Equal(t, kesekretariatan, *j.UnitKerjaID)
+		assert.Equal(t, ptJakarta, j.SatkerID)
+		assert.Equal(t, date(2020, 7, 1), j.TMT.UTC())
+	})
+
+	t.Run("delete riwayat bukan terakhir tidak mengubah pegawai", func(t *testing.T) {
+		require.NoError(t, repo.DeleteJabatan(ctx, pegawaiID, pertama))
+
+		assert.Equal(t, []uuid.UUID{kedua}, suite.Terakhir(t, "riwayat_jabatan", pegawaiID))
+		assert.Equal(t, kasubag, *suite.JabatanPegawai(t, pegawaiID).JabatanID)
+	})
+}
+
+func TestRiwayatJabatanValidasiSatker(t *testing.T) {
+	suite := SetupRiwayatTest(t)
+	repo := repositories.NewRiwayatRepository(suite.Kepegawaian, suite.Master)
+	ctx := context.Background()
+
+	pnJakarta := suite.CreateSatker(t, "PN-JKT", "Pengadilan Negeri Jakarta Pusat")
+	ptJakarta := suite.CreateSatker(t, "PT-JKT", "Pengadilan Tinggi Jakarta")
+	kepaniteraan := suite.CreateUnitKerja(t, "PN-JKT-KPN", "Kepaniteraan", pnJakarta)
+	panitera := suite.CreateJabatan(t, "PNT-MDA", "Panitera Muda Perdata")
+	pegawaiID := suite.CreatePegawai(t, "199001012015031002")
+
+	_, err := repo.CreateJabatan(ctx, pegawaiID, repositories.RiwayatJabatanInput{
+		JabatanID:   &panitera,
+		UnitKerjaID: &kepaniteraan,
+		SatkerID:    &ptJakarta,
+		TMT:         date(2020, 7, 1),
+		NomorSK:     "SK/1",
+		TanggalSK:   date(2020, 6, 1),
+		Pejabat:     "Sekretaris Mahkamah Agung",
+	}, "")
+	assert.ErrorIs(t, err, repositories.ErrUnitKerjaSatker)
+
+	j := suite.JabatanPegawai(t, pegawaiID)
+	assert.Nil(t, j.JabatanID)
+	assert.Equal(t, suite.SatkerID, j.SatkerID)
+	assert.Empty(t, suite.Terakhir(t, "riwayat_jabatan", pegawaiID))
+}
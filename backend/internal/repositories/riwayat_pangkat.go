@@ -0,0 +1,355 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== RIWAYAT PANGKAT ====================
+
+// RiwayatPangkatInput input untuk membuat dan mengubah riwayat pangkat.
+// Pangkat kosong diisi dari nama golongan.
+type RiwayatPangkatInput struct {
+	GolonganID     uuid.UUID                    `json:"golongan_id"`
+	Pangkat        string                       `json:"pangkat"`
+	TMT            time.Time                    `json:"tmt"`
+	NomorSK        string                       `json:"nomor_sk"`
+	TanggalSK      time.Time                    `json:"tanggal_sk"`
+	Pejabat        string                       `json:"pejabat"`
+	FileSK         *string                      `json:"file_sk,omitempty"`
+	GajiPokok      float64                      `json:"gaji_pokok"`
+	JenisKenaikan  *models.JenisKenaikanPangkat `json:"jenis_kenaikan,omitempty"`
+	MasaKerjaTahun int                          `json:"masa_kerja_tahun"`
+	MasaKerjaBulan int                          `json:"masa_kerja_bulan"`
+}
+
+const riwayatPangkatColumns = `id, pegawai_id, golongan_id, pangkat, tmt, nomor_sk, tanggal_sk, pejabat, file_sk,
+			  COALESCE(gaji_pokok, 0), COALESCE(is_terakhir, false), jenis_kenaikan,
+			  COALESCE(masa_kerja_tahun, 0), COALESCE(masa_kerja_bulan, 0), created_at, updated_at, created_by`
+
+// scanRiwayatPangkat memindai satu baris riwayatPangkatColumns
+func scanRiwayatPangkat(row pgx.Row) (*models.RiwayatPangkat, error) {
+	var rp models.RiwayatPangkat
+	err := row.Scan(&rp.ID, &rp.PegawaiID, &rp.GolonganID, &rp.Pangkat, &rp.TMT, &rp.NomorSK, &rp.TanggalSK, &rp.Pejabat, &rp.FileSK,
+		&rp.GajiPokok, &rp.IsTerakhir, &rp.JenisKenaikan,
+		&rp.MasaKerjaTahun, &rp.MasaKerjaBulan, &rp.CreatedAt, &rp.UpdatedAt, &rp.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &rp, nil
+}
+
+// getRiwayatPangkat mengambil riwayat pangkat milik pegawai di dalam transaksi
+func getRiwayatPangkat(ctx context.Context, tx pgx.Tx, pegawaiID, id uuid.UUID, forUpdate bool) (*models.RiwayatPangkat, error) {
+	query := `SELECT ` + riwayatPangkatColumns + ` FROM riwayat_pangkat WHERE id = $1 AND pegawai_id = $2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	rp, err := scanRiwayatPangkat(tx.QueryRow(ctx, query, id, pegawaiID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrRiwayatNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get riwayat pangkat: %w", err)
+	}
+	return rp, nil
+}
+
+// golonganByIDs mengambil golongan dari db_master. golongan_id di
+// db_kepegawaian tidak memiliki foreign key, sehingga ID yang tidak ada
+// tidak muncul di hasil.
+func (r *RiwayatRepository) golonganByIDs(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]*models.Golongan, error) {
+	query := `SELECT id, kode, nama, ruang, angka, min_pangkat, max_pangkat, is_active, created_at, updated_at
+			  FROM golongan WHERE id = ANY($1)`
+
+	rows, err := r.dbMaster.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query golongan: %w", err)
+	}
+	defer rows.Close()
+
+	golongan := map[uuid.UUID]*models.Golongan{}
+	for rows.Next() {
+		var g models.Golongan
+		err := rows.Scan(&g.ID, &g.Kode, &g.Nama, &g.Ruang, &g.Angka, &g.MinPangkat, &g.MaxPangkat,
+			&g.IsActive, &g.CreatedAt, &g.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan golongan: %w", err)
+		}
+		golongan[g.ID] = &g
+	}
+
+	return golongan, rows.Err()
+}
+
+// resolveGolongan memvalidasi golongan input dan mengisi pangkat kosong.
+// Golongan yang sudah tidak aktif tidak boleh dipakai.
+func (r *RiwayatRepository) resolveGolongan(ctx context.Context, input *RiwayatPangkatInput) (*models.Golongan, error) {
+	golongan, err := r.golonganByIDs(ctx, input.GolonganID)
+	if err != nil {
+		return nil, err
+	}
+	g, ok := golongan[input.GolonganID]
+	if !ok {
+		return nil, ErrGolonganNotFound
+	}
+	if !g.IsActive {
+		return nil, ErrGolonganInactive
+	}
+	if input.Pangkat == "" {
+		input.Pangkat = g.Nama
+	}
+	return g, nil
+}
+
+// ListPangkat mengambil riwayat pangkat pegawai, terbaru lebih dulu
+func (r *RiwayatRepository) ListPangkat(ctx context.Context, pegawaiID uuid.UUID) ([]models.RiwayatPangkat, error) {
+	list, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) ([]models.RiwayatPangkat, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+
+		query := `SELECT ` + riwayatPangkatColumns + ` FROM riwayat_pangkat
+				  WHERE pegawai_id = $1
+				  ORDER BY tmt DESC, tanggal_sk DESC, created_at DESC`
+
+		rows, err := tx.Query(ctx, query, pegawaiID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query riwayat pangkat: %w", err)
+		}
+		defer rows.Close()
+
+		list := []models.RiwayatPangkat{}
+		for rows.Next() {
+			rp, err := scanRiwayatPangkat(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan riwayat pangkat: %w", err)
+			}
+			list = append(list, *rp)
+		}
+		return list, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(list))
+	for _, rp := range list {
+		ids = append(ids, rp.GolonganID)
+	}
+	golongan, err := r.golonganByIDs(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Golongan = golongan[list[i].GolonganID]
+	}
+
+	return list, nil
+}
+
+// GetPangkat mengambil satu riwayat pangkat pegawai
+func (r *RiwayatRepository) GetPangkat(ctx context.Context, pegawaiID, id uuid.UUID) (*models.RiwayatPangkat, error) {
+	rp, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatPangkat, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		return getRiwayatPangkat(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	golongan, err := r.golonganByIDs(ctx, rp.GolonganID)
+	if err != nil {
+		return nil, err
+	}
+	rp.Golongan = golongan[rp.GolonganID]
+
+	return rp, nil
+}
+
+// CreatePangkat menambah riwayat pangkat dan menyinkronkan pangkat terakhir
+// pegawai. Kenaikan pangkat dengan TMT di masa hukuman disiplin ditolak.
+func (r *RiwayatRepository) CreatePangkat(ctx context.Context, pegawaiID uuid.UUID, input RiwayatPangkatInput, userID string) (*models.RiwayatPangkat, error) {
+	golongan, err := r.resolveGolongan(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	rp, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatPangkat, error) {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkHukdis(ctx, tx, pegawaiID, input.TMT); err != nil {
+			return nil, err
+		}
+
+		query := `INSERT INTO riwayat_pangkat (pegawai_id, golongan_id, pangkat, tmt, nomor_sk, tanggal_sk, pejabat,
+				  file_sk, gaji_pokok, jenis_kenaikan, masa_kerja_tahun, masa_kerja_bulan, created_by)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+				  RETURNING id`
+
+		var id uuid.UUID
+		err = tx.QueryRow(ctx, query,
+			pegawaiID, input.GolonganID, input.Pangkat, input.TMT, input.NomorSK, input.TanggalSK, input.Pejabat,
+			input.FileSK, input.GajiPokok, input.JenisKenaikan, input.MasaKerjaTahun, input.MasaKerjaBulan, actorUUID(userID),
+		).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create riwayat pangkat: %w", err)
+		}
+
+		if pegawaiAfter, err = syncPangkatTerakhir(ctx, tx, pegawaiBefore); err != nil {
+			return nil, err
+		}
+		return getRiwayatPangkat(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionCreate, "riwayat_pangkat", rp.ID, nil, rp)
+	rp.Golongan = golongan
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return rp, nil
+}
+
+// UpdatePangkat mengubah riwayat pangkat dan menyinkronkan pangkat terakhir pegawai
+func (r *RiwayatRepository) UpdatePangkat(ctx context.Context, pegawaiID, id uuid.UUID, input RiwayatPangkatInput) (*models.RiwayatPangkat, error) {
+	golongan, err := r.resolveGolongan(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	var before *models.RiwayatPangkat
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	rp, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatPangkat, error) {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return nil, err
+		}
+		before, err = getRiwayatPangkat(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return nil, err
+		}
+		// Koreksi data riwayat lama tetap boleh; hanya golongan atau TMT baru
+		// yang diperiksa terhadap hukdis
+		if before.GolonganID != input.GolonganID || !sameDate(&before.TMT, input.TMT) {
+			if err := checkHukdis(ctx, tx, pegawaiID, input.TMT); err != nil {
+				return nil, err
+			}
+		}
+
+		query := `UPDATE riwayat_pangkat
+				  SET golongan_id = $2, pangkat = $3, tmt = $4, nomor_sk = $5, tanggal_sk = $6, pejabat = $7,
+					  file_sk = $8, gaji_pokok = $9, jenis_kenaikan = $10, masa_kerja_tahun = $11, masa_kerja_bulan = $12
+				  WHERE id = $1`
+
+		_, err = tx.Exec(ctx, query,
+			id, input.GolonganID, input.Pangkat, input.TMT, input.NomorSK, input.TanggalSK, input.Pejabat,
+			input.FileSK, input.GajiPokok, input.JenisKenaikan, input.MasaKerjaTahun, input.MasaKerjaBulan,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update riwayat pangkat: %w", err)
+		}
+
+		if pegawaiAfter, err = syncPangkatTerakhir(ctx, tx, pegawaiBefore); err != nil {
+			return nil, err
+		}
+		return getRiwayatPangkat(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "riwayat_pangkat", rp.ID, before, rp)
+	rp.Golongan = golongan
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return rp, nil
+}
+
+// DeletePangkat menghapus riwayat pangkat. Bila yang dihapus pangkat
+// terakhir, riwayat sebelumnya menjadi pangkat terakhir pegawai.
+func (r *RiwayatRepository) DeletePangkat(ctx context.Context, pegawaiID, id uuid.UUID) error {
+	var before *models.RiwayatPangkat
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	err := database.WithRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) error {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return err
+		}
+		before, err = getRiwayatPangkat(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM riwayat_pangkat WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete riwayat pangkat: %w", err)
+		}
+
+		pegawaiAfter, err = syncPangkatTerakhir(ctx, tx, pegawaiBefore)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.ActionDelete, "riwayat_pangkat", id, before, nil)
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return nil
+}
+
+// syncPangkatTerakhir menandai riwayat dengan TMT terbaru sebagai pangkat
+// terakhir lalu menyalin golongan dan TMT-nya ke pegawai. Mengembalikan
+// pegawai setelah diubah, atau nil bila pegawai tidak berubah. Pegawai tanpa
+// riwayat pangkat tidak diubah.
+func syncPangkatTerakhir(ctx context.Context, tx pgx.Tx, pegawai *models.Pegawai) (*models.Pegawai, error) {
+	if err := markTerakhir(ctx, tx, "riwayat_pangkat", pegawai.ID); err != nil {
+		return nil, err
+	}
+
+	var golonganID uuid.UUID
+	var tmt time.Time
+	err := tx.QueryRow(ctx, `SELECT golongan_id, tmt FROM riwayat_pangkat WHERE pegawai_id = $1 AND is_terakhir`, pegawai.ID).
+		Scan(&golonganID, &tmt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get riwayat pangkat terakhir: %w", err)
+	}
+
+	if pegawai.GolonganID != nil && *pegawai.GolonganID == golonganID && sameDate(pegawai.TMTPangkatTerakhir, tmt) {
+		return nil, nil
+	}
+
+	query := `UPDATE pegawai p SET golongan_id = $2, tmt_pangkat_terakhir = $3, updated_at = NOW()
+			  WHERE p.id = $1
+			  RETURNING ` + pegawaiColumns
+
+	after, err := scanPegawai(tx.QueryRow(ctx, query, pegawai.ID, golonganID, tmt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync pangkat pegawai: %w", err)
+	}
+	return after, nil
+}
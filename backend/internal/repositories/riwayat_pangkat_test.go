@@ -0,0 +1,132 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+func TestRiwayatPangkatSyncPegawai(t *testing.T) {
+	suite := SetupRiwayatTest(t)
+	repo := repositories.NewRiwayatRepository(suite.Kepegawaian, suite.Master)
+	ctx := context.Background()
+
+	iiia := suite.CreateGolongan(t, "III/a", "Penata Muda", 31, true)
+	iiib := suite.CreateGolongan(t, "III/b", "Penata Muda Tingkat I", 32, true)
+	iiic := suite.CreateGolongan(t, "III/c", "Penata", 33, true)
+	pegawaiID := suite.CreatePegawai(t, "198501012010011001")
+
+	input := func(golonganID uuid.UUID, tahun int) repositories.RiwayatPangkatInput {
+		return repositories.RiwayatPangkatInput{
+			GolonganID: golonganID,
+			TMT:        date(tahun, 4, 1),
+			NomorSK:    "SK/" + golonganID.String()[:8],
+			TanggalSK:  date(tahun, 3, 1),
+			Pejabat:    "Sekretaris Mahkamah Agung",
+		}
+	}
+
+	var pertama, kedua, susulan uuid.UUID
+
+	t.Run("riwayat pertama menjadi pangkat pegawai", func(t *testing.T) {
+		rp, err := repo.CreatePangkat(ctx, pegawaiID, input(iiia, 2014), "")
+		require.NoError(t, err)
+		pertama = rp.ID
+
+		assert.True(t, rp.IsTerakhir)
+		assert.Equal(t, "Penata Muda", rp.Pangkat, "pangkat kosong diisi dari golongan")
+		golonganID, tmt := suite.PangkatPegawai(t, pegawaiID)
+		require.NotNil(t, golonganID)
+		assert.Equal(t, iiia, *golonganID)
+		assert.Equal(t, date(2014, 4, 1), tmt.UTC())
+	})
+
+	t.Run("riwayat lebih baru memindahkan is_terakhir", func(t *testing.T) {
+		rp, err := repo.CreatePangkat(ctx, pegawaiID, input(iiib, 2018), "")
+		require.NoError(t, err)
+		kedua = rp.ID
+
+		assert.Equal(t, []uuid.UUID{kedua}, suite.Terakhir(t, "riwayat_pangkat", pegawaiID))
+		golonganID, tmt := suite.PangkatPegawai(t, pegawaiID)
+		assert.Equal(t, iiib, *golonganID)
+		assert.Equal(t, date(2018, 4, 1), tmt.UTC())
+	})
+
+	t.Run("riwayat lama yang diinput belakangan tidak mengubah pegawai", func(t *testing.T) {
+		rp, err := repo.CreatePangkat(ctx, pegawaiID, input(iiia, 2010), "")
+		require.NoError(t, err)
+		susulan = rp.ID
+
+		assert.False(t, rp.IsTerakhir)
+		assert.Equal(t, []uuid.UUID{kedua}, suite.Terakhir(t, "riwayat_pangkat", pegawaiID))
+		golonganID, _ := suite.PangkatPegawai(t, pegawaiID)
+		assert.Equal(t, iiib, *golonganID)
+	})
+
+	t.Run("update tmt menjadi terbaru memindahkan is_terakhir", func(t *testing.T) {
+		_, err := repo.UpdatePangkat(ctx, pegawaiID, susulan, input(iiic, 2022))
+		require.NoError(t, err)
+
+		assert.Equal(t, []uuid.UUID{susulan}, suite.Terakhir(t, "riwayat_pangkat", pegawaiID))
+		golonganID, tmt := suite.PangkatPegawai(t, pegawaiID)
+		assert.Equal(t, iiic, *golonganID)
+		assert.Equal(t, date(2022, 4, 1), tmt.UTC())
+	})
+
+	t.Run("delete riwayat terakhir mengembalikan pangkat sebelumnya", func(t *testing.T) {
+		require.NoError(t, repo.DeletePangkat(ctx, pegawaiID, susulan))
+
+		assert.Equal(t, []uuid.UUID{kedua}, suite.Terakhir(t, "riwayat_pangkat", pegawaiID))
+		golonganID, tmt := suite.PangkatPegawai(t, pegawaiID)
+		assert.Equal(t, iiib, *golonganID)
+		assert.Equal(t, date(2018, 4, 1), tmt.UTC())
+	})
+
+	t.Run("delete riwayat bukan terakhir tidak mengubah pegawai", func(t *testing.T) {
+		require.NoError(t, repo.DeletePangkat(ctx, pegawaiID, pertama))
+
+		assert.Equal(t, []uuid.UUID{kedua}, suite.Terakhir(t, "riwayat_pangkat", pegawaiID))
+		golonganID, _ := suite.PangkatPegawai(t, pegawaiID)
+		assert.Equal(t, iiib, *golonganID)
+	})
+}
+
+func TestRiwayatPangkatValidasiGolongan(t *testing.T) {
+	suite := SetupRiwayatTest(t)
+	repo := repositories.NewRiwayatRepository(suite.Kepegawaian, suite.Master)
+	ctx := context.Background()
+
+	nonaktif := suite.CreateGolongan(t, "II/e", "Golongan Lama", 25, false)
+	pegawaiID := suite.CreatePegawai(t, "198702152012012002")
+
+	tests := []struct {
+		name       string
+		golonganID uuid.UUID
+		err        error
+	}{
+		{"golongan tidak ada di db_master", uuid.New(), repositories.ErrGolonganNotFound},
+		{"golongan tidak aktif", nonaktif, repositories.ErrGolonganInactive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repo.CreatePangkat(ctx, pegawaiID, repositories.RiwayatPangkatInput{
+				GolonganID: tt.golonganID,
+				Pangkat:    "Pengatur",
+				TMT:        date(2020, 4, 1),
+				NomorSK:    "SK/1",
+				TanggalSK:  date(2020, 3, 1),
+				Pejabat:    "Sekretaris Mahkamah Agung",
+			}, "")
+			assert.ErrorIs(t, err, tt.err)
+
+			golonganID, _ := suite.PangkatPegawai(t, pegawaiID)
+			assert.Nil(t, golonganID)
+			assert.Empty(t, suite.Terakhir(t, "riwayat_pangkat", pegawaiID))
+		})
+	}
+}
@@ -0,0 +1,520 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== RIWAYAT PENDIDIKAN ====================
+
+var ErrPendidikanNotFound = errors.New("pendidikan not found")
+
+// RiwayatPendidikanInput input untuk membuat dan mengubah riwayat pendidikan.
+// File ijazah hanya diisi melalui endpoint upload.
+type RiwayatPendidikanInput struct {
+	PendidikanID  uuid.UUID  `json:"pendidikan_id"`
+	NamaInstitusi string     `json:"nama_institusi"`
+	Jurusan       *string    `json:"jurusan,omitempty"`
+	TahunMasuk    int        `json:"tahun_masuk"`
+	TahunLulus    int        `json:"tahun_lulus"`
+	NomorIjazah   *string    `json:"nomor_ijazah,omitempty"`
+	TanggalIjazah *time.Time `json:"tanggal_ijazah,omitempty"`
+}
+
+// StatistikPendidikan jumlah pegawai aktif per pendidikan tertinggi.
+// Pendidikan nil berarti pegawai belum memiliki riwayat pendidikan.
+type StatistikPendidikan struct {
+	Pendidikan *models.RefPendidikan `json:"pendidikan"`
+	Jumlah     int64                 `json:"jumlah"`
+}
+
+const riwayatPendidikanColumns = `id, pegawai_id, pendidikan_id, nama_institusi, jurusan,
+			  COALESCE(tahun_masuk, 0), COALESCE(tahun_lulus, 0), nomor_ijazah, tanggal_ijazah, file_ijazah,
+			  created_at, updated_at, created_by`
+
+// scanRiwayatPendidikan memindai satu baris riwayatPendidikanColumns
+func scanRiwayatPendidikan(row pgx.Row) (*models.RiwayatPendidikan, error) {
+	var rp models.RiwayatPendidikan
+	err := row.Scan(&rp.ID, &rp.PegawaiID, &rp.PendidikanID, &rp.NamaInstitusi, &rp.Jurusan,
+		&rp.TahunMasuk, &rp.TahunLulus, &rp.NomorIjazah, &rp.TanggalIjazah, &rp.FileIjazah,
+		&rp.CreatedAt, &rp.UpdatedAt, &rp.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &rp, nil
+}
+
+// getRiwayatPendidikan mengambil riwayat pendidikan milik pegawai di dalam transaksi
+func getRiwayatPendidikan(ctx context.Context, tx pgx.Tx, pegawaiID, id uuid.UUID, forUpdate bool) (*models.RiwayatPendidikan, error) {
+	query := `SELECT ` + riwayatPendidikanColumns + ` FROM riwayat_pendidikan WHERE id = $1 AND pegawai_id = $2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	rp, err := scanRiwayatPendidikan(tx.QueryRow(ctx, query, id, pegawaiID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrRiwayatNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get riwayat pendidikan: %w", err)
+	}
+	return rp, nil
+}
+
+// pendidikanByIDs mengambil ref_pendidikan dari db_master
+func (r *RiwayatRepository) pendidikanByIDs(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]*models.RefPendidikan, error) {
+	query := `SELECT id, kode, nama, tingkat, urutan, COALESCE(is_active, false), created_at, updated_at
+			  FROM ref_pendidikan WHERE id = ANY($1)`
+
+	rows, err := r.dbMaster.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ref pendidikan: %w", err)
+	}
+	defer rows.Close()
+
+	pendidikan := map[uuid.UUID]*models.RefPendidikan{}
+	for rows.Next() {
+		var p models.RefPendidikan
+		err := rows.Scan(&p.ID, &p.Kode, &p.Nama, &p.Tingkat, &p.Urutan, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ref pendidikan: %w", err)
+		}
+		pendidikan[p.ID] = &p
+	}
+
+	return pendidikan, rows.Err()
+}
+
+// resolvePendidikan memvalidasi pendidikan_id input terhadap ref_pendidikan
+func (r *RiwayatRepository) resolvePendidikan(ctx context.Context, id uuid.UUID) (*models.RefPendidikan, error) {
+	pendidikan, err := r.pendidikanByIDs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := pendidikan[id]
+	if !ok {
+		return nil, ErrPendidikanNotFound
+	}
+	return p, nil
+}
+
+// ListPendidikan mengambil riwayat pendidikan pegawai, jenjang tertinggi lebih dulu
+func (r *RiwayatRepository) ListPendidikan(ctx context.Context, pegawaiID uuid.UUID) ([]models.RiwayatPendidikan, error) {
+	list, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) ([]models.RiwayatPendidikan, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+
+		query := `SELECT ` + riwayatPendidikanColumns + ` FROM riwayat_pendidikan
+				  WHERE pegawai_id = $1
+				  ORDER BY tahun_lulus DESC NULLS LAST, created_at DESC`
+
+		rows, err := tx.Query(ctx, query, pegawaiID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query riwayat pendidikan: %w", err)
+		}
+		defer rows.Close()
+
+		list := []models.RiwayatPendidikan{}
+		for rows.Next() {
+			rp, err := scanRiwayatPendidikan(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan riwayat pendidikan: %w", err)
+			}
+			list = append(list, *rp)
+		}
+		return list, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(list))
+	for _, rp := range list {
+		ids = append(ids, rp.PendidikanID)
+	}
+	pendidikan, err := r.pendidikanByIDs(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Pendidikan = pendidikan[list[i].PendidikanID]
+	}
+
+	// Urutan jenjang ada di db_master sehingga diurutkan di aplikasi
+	sort.SliceStable(list, func(i, j int) bool {
+		return urutanPendidikan(list[i].Pendidikan) > urutanPendidikan(list[j].Pendidikan)
+	})
+
+	return list, nil
+}
+
+// GetPendidikan mengambil satu riwayat pendidikan pegawai
+func (r *RiwayatRepository) GetPendidikan(ctx context.Context, pegawaiID, id uuid.UUID) (*models.RiwayatPendidikan, error) {
+	rp, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatPendidikan, error) {
+		if _, err := getPegawai(ctx, tx, pegawaiID, false); err != nil {
+			return nil, err
+		}
+		return getRiwayatPendidikan(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pendidikan, err := r.pendidikanByIDs(ctx, rp.PendidikanID)
+	if err != nil {
+		return nil, err
+	}
+	rp.Pendidikan = pendidikan[rp.PendidikanID]
+
+	return rp, nil
+}
+
+// CreatePendidikan menambah riwayat pendidikan dan menyinkronkan pendidikan
+// tertinggi pegawai
+func (r *RiwayatRepository) CreatePendidikan(ctx context.Context, pegawaiID uuid.UUID, input RiwayatPendidikanInput, userID string) (*models.RiwayatPendidikan, error) {
+	pendidikan, err := r.resolvePendidikan(ctx, input.PendidikanID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	rp, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatPendidikan, error) {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return nil, err
+		}
+
+		query := `INSERT INTO riwayat_pendidikan (pegawai_id, pendidikan_id, nama_institusi, jurusan,
+				  tahun_masuk, tahun_lulus, nomor_ijazah, tanggal_ijazah, created_by)
+				  VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), $7, $8, $9)
+				  RETURNING id`
+
+		var id uuid.UUID
+		err = tx.QueryRow(ctx, query,
+			pegawaiID, input.PendidikanID, input.NamaInstitusi, input.Jurusan,
+			input.TahunMasuk, input.TahunLulus, input.NomorIjazah, input.TanggalIjazah, actorUUID(userID),
+		).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create riwayat pendidikan: %w", err)
+		}
+
+		if pegawaiAfter, err = r.syncPendidikanTerakhir(ctx, tx, pegawaiBefore); err != nil {
+			return nil, err
+		}
+		return getRiwayatPendidikan(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionCreate, "riwayat_pendidikan", rp.ID, nil, rp)
+	rp.Pendidikan = pendidikan
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return rp, nil
+}
+
+// UpdatePendidikan mengubah riwayat pendidikan dan menyinkronkan pendidikan
+// tertinggi pegawai. File ijazah yang sudah diupload tidak berubah.
+func (r *RiwayatRepository) UpdatePendidikan(ctx context.Context, pegawaiID, id uuid.UUID, input RiwayatPendidikanInput) (*models.RiwayatPendidikan, error) {
+	pendidikan, err := r.resolvePendidikan(ctx, input.PendidikanID)
+	if err != nil {
+		return nil, err
+	}
+
+	var before *models.RiwayatPendidikan
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	rp, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatPendidikan, error) {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return nil, err
+		}
+		before, err = getRiwayatPendidikan(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return nil, err
+		}
+
+		query := `UPDATE riwayat_pendidikan
+				  SET pendidikan_id = $2, nama_institusi = $3, jurusan = $4, tahun_masuk = NULLIF($5, 0),
+					  tahun_lulus = NULLIF($6, 0), nomor_ijazah = $7, tanggal_ijazah = $8
+				  WHERE id = $1`
+
+		_, err = tx.Exec(ctx, query,
+			id, input.PendidikanID, input.NamaInstitusi, input.Jurusan,
+			input.TahunMasuk, input.TahunLulus, input.NomorIjazah, input.TanggalIjazah,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update riwayat pendidikan: %w", err)
+		}
+
+		if pegawaiAfter, err = r.syncPendidikanTerakhir(ctx, tx, pegawaiBefore); err != nil {
+			return nil, err
+		}
+		return getRiwayatPendidikan(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "riwayat_pendidikan", rp.ID, before, rp)
+	rp.Pendidikan = pendidikan
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return rp, nil
+}
+
+// DeletePendidikan menghapus riwayat pendidikan dan menyinkronkan pendidikan
+// tertinggi pegawai. Mengembalikan path file ijazah yang perlu dihapus dari
+// storage, atau nil bila tidak ada.
+func (r *RiwayatRepository) DeletePendidikan(ctx context.Context, pegawaiID, id uuid.UUID) (*string, error) {
+	var before *models.RiwayatPendidikan
+	var pegawaiBefore, pegawaiAfter *models.Pegawai
+	err := database.WithRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) error {
+		var err error
+		pegawaiBefore, err = getPegawaiAktif(ctx, tx, pegawaiID)
+		if err != nil {
+			return err
+		}
+		before, err = getRiwayatPendidikan(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM riwayat_pendidikan WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete riwayat pendidikan: %w", err)
+		}
+
+		pegawaiAfter, err = r.syncPendidikanTerakhir(ctx, tx, pegawaiBefore)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionDelete, "riwayat_pendidikan", id, before, nil)
+	if pegawaiAfter != nil {
+		audit.Record(ctx, audit.ActionUpdate, "pegawai", pegawaiID, pegawaiBefore, pegawaiAfter)
+	}
+	return before.FileIjazah, nil
+}
+
+// SetFileIjazah menyimpan path file ijazah hasil upload. Mengembalikan path
+// file lama yang perlu dihapus dari storage, atau nil bila tidak ada.
+func (r *RiwayatRepository) SetFileIjazah(ctx context.Context, pegawaiID, id uuid.UUID, path string) (*models.RiwayatPendidikan, *string, error) {
+	var before *models.RiwayatPendidikan
+	rp, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (*models.RiwayatPendidikan, error) {
+		if _, err := getPegawaiAktif(ctx, tx, pegawaiID); err != nil {
+			return nil, err
+		}
+		var err error
+		before, err = getRiwayatPendidikan(ctx, tx, pegawaiID, id, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE riwayat_pendidikan SET file_ijazah = $2 WHERE id = $1`, id, path); err != nil {
+			return nil, fmt.Errorf("failed to update file ijazah: %w", err)
+		}
+		return getRiwayatPendidikan(ctx, tx, pegawaiID, id, false)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "riwayat_pendidikan", rp.ID, before, rp)
+
+	pendidikan, err := r.pendidikanByIDs(ctx, rp.PendidikanID)
+	if err != nil {
+		return nil, nil, err
+	}
+	rp.Pendidikan = pendidikan[rp.PendidikanID]
+
+	return rp, before.FileIjazah, nil
+}
+
+// AttachPendidikanTerakhir mengisi relasi pendidikan tertinggi pegawai
+func (r *RiwayatRepository) AttachPendidikanTerakhir(ctx context.Context, pegawai *models.Pegawai) error {
+	if pegawai.PendidikanTerakhirID == nil {
+		return nil
+	}
+	pendidikan, err := r.pendidikanByIDs(ctx, *pegawai.PendidikanTerakhirID)
+	if err != nil {
+		return err
+	}
+	pegawai.PendidikanTerakhir = pendidikan[*pegawai.PendidikanTerakhirID]
+	return nil
+}
+
+// StatistikPendidikan menghitung pegawai aktif per pendidikan tertinggi,
+// dari jenjang terendah. Pegawai tanpa riwayat pendidikan di urutan terakhir.
+func (r *RiwayatRepository) StatistikPendidikan(ctx context.Context) ([]StatistikPendidikan, error) {
+	counts, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) (map[uuid.UUID]int64, error) {
+		query := `SELECT COALESCE(pendidikan_terakhir_id, '00000000-0000-0000-0000-000000000000'), COUNT(*)
+				  FROM pegawai WHERE is_active = true
+				  GROUP BY 1`
+
+		rows, err := tx.Query(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query pegawai by pendidikan: %w", err)
+		}
+		defer rows.Close()
+
+		counts := map[uuid.UUID]int64{}
+		for rows.Next() {
+			var id uuid.UUID
+			var count int64
+			if err := rows.Scan(&id, &count); err != nil {
+				return nil, fmt.Errorf("failed to scan pendidikan: %w", err)
+			}
+			counts[id] = count
+		}
+		return counts, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(counts))
+	for id := range counts {
+		if id != uuid.Nil {
+			ids = append(ids, id)
+		}
+	}
+	pendidikan, err := r.pendidikanByIDs(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	statistik := make([]StatistikPendidikan, 0, len(counts))
+	var tanpaPendidikan int64
+	for id, count := range counts {
+		// Referensi yang sudah dihapus dari db_master dihitung bersama
+		// pegawai tanpa pendidikan
+		p, ok := pendidikan[id]
+		if !ok {
+			tanpaPendidikan += count
+			continue
+		}
+		statistik = append(statistik, StatistikPendidikan{Pendidikan: p, Jumlah: count})
+	}
+	sort.Slice(statistik, func(i, j int) bool {
+		a, b := statistik[i].Pendidikan, statistik[j].Pendidikan
+		if a.Urutan != b.Urutan {
+			return a.Urutan < b.Urutan
+		}
+		return a.Kode < b.Kode
+	})
+	if tanpaPendidikan > 0 {
+		statistik = append(statistik, StatistikPendidikan{Jumlah: tanpaPendidikan})
+	}
+
+	return statistik, nil
+}
+
+// SyncPendidikanTerakhirAll menghitung ulang pendidikan tertinggi seluruh
+// pegawai, untuk mengisi data yang ada sebelum pendidikan_terakhir_id
+// dikelola aplikasi. Mengembalikan jumlah pegawai yang berubah.
+func (r *RiwayatRepository) SyncPendidikanTerakhirAll(ctx context.Context) (int, error) {
+	ctx = database.AsSystem(ctx)
+
+	ids, err := database.QueryRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) ([]uuid.UUID, error) {
+		rows, err := tx.Query(ctx, `SELECT id FROM pegawai ORDER BY id`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query pegawai: %w", err)
+		}
+		return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, id := range ids {
+		err := database.WithRLS(ctx, r.dbKepegawaian, func(tx pgx.Tx) error {
+			pegawai, err := getPegawai(ctx, tx, id, true)
+			if err != nil {
+				return err
+			}
+			after, err := r.syncPendidikanTerakhir(ctx, tx, pegawai)
+			if err != nil {
+				return err
+			}
+			if after != nil {
+				changed++
+			}
+			return nil
+		})
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// syncPendidikanTerakhir menyalin jenjang tertinggi dari riwayat pendidikan
+// ke pegawai. Mengembalikan pegawai setelah diubah, atau nil bila pegawai
+// tidak berubah.
+func (r *RiwayatRepository) syncPendidikanTerakhir(ctx context.Context, tx pgx.Tx, pegawai *models.Pegawai) (*models.Pegawai, error) {
+	rows, err := tx.Query(ctx, `SELECT DISTINCT pendidikan_id FROM riwayat_pendidikan WHERE pegawai_id = $1`, pegawai.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query riwayat pendidikan: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan riwayat pendidikan: %w", err)
+	}
+
+	var tertinggi *uuid.UUID
+	if len(ids) > 0 {
+		pendidikan, err := r.pendidikanByIDs(ctx, ids...)
+		if err != nil {
+			return nil, err
+		}
+		var top *models.RefPendidikan
+		for _, p := range pendidikan {
+			if top == nil || p.Urutan > top.Urutan || (p.Urutan == top.Urutan && p.Kode < top.Kode) {
+				top = p
+			}
+		}
+		if top != nil {
+			tertinggi = &top.ID
+		}
+	}
+
+	if sameID(pegawai.PendidikanTerakhirID, tertinggi) {
+		return nil, nil
+	}
+
+	query := `UPDATE pegawai p SET pendidikan_terakhir_id = $2, updated_at = NOW()
+			  WHERE p.id = $1
+			  RETURNING ` + pegawaiColumns
+
+	after, err := scanPegawai(tx.QueryRow(ctx, query, pegawai.ID, tertinggi))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync pendidikan pegawai: %w", err)
+	}
+	return after, nil
+}
+
+// urutanPendidikan mengembalikan urutan jenjang, 0 bila referensi tidak ada
+func urutanPendidikan(p *models.RefPendidikan) int {
+	if p == nil {
+		return 0
+	}
+	return p.Urutan
+}
@@ -0,0 +1,79 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/repositories"
+)
+
+func TestRiwayatPendidikanSyncPegawai(t *testing.T) {
+	suite := SetupRiwayatTest(t)
+	repo := repositories.NewRiwayatRepository(suite.Kepegawaian, suite.Master)
+	ctx := context.Background()
+
+	sma := suite.CreatePendidikan(t, "SMA", "SMA/Sederajat", 5)
+	s1 := suite.CreatePendidikan(t, "S1", "Sarjana", 8)
+	s2 := suite.CreatePendidikan(t, "S2", "Magister", 9)
+	pegawaiID := suite.CreatePegawai(t, "199105052016031001")
+
+	input := func(pendidikanID uuid.UUID, lulus int) repositories.RiwayatPendidikanInput {
+		return repositories.RiwayatPendidikanInput{
+			PendidikanID:  pendidikanID,
+			NamaInstitusi: "Universitas Indonesia",
+			TahunMasuk:    lulus - 4,
+			TahunLulus:    lulus,
+		}
+	}
+
+	var sarjana, magister uuid.UUID
+
+	t.Run("riwayat pertama menjadi pendidikan terakhir", func(t *testing.T) {
+		rp, err := repo.CreatePendidikan(ctx, pegawaiID, input(s1, 2013), "")
+		require.NoError(t, err)
+		sarjana = rp.ID
+
+		require.NotNil(t, suite.PendidikanPegawai(t, pegawaiID))
+		assert.Equal(t, s1, *suite.PendidikanPegawai(t, pegawaiID))
+	})
+
+	t.Run("jenjang lebih rendah tidak mengubah pegawai", func(t *testing.T) {
+		_, err := repo.CreatePendidikan(ctx, pegawaiID, input(sma, 2009), "")
+		require.NoError(t, err)
+
+		assert.Equal(t, s1, *suite.PendidikanPegawai(t, pegawaiID))
+	})
+
+	t.Run("jenjang lebih tinggi menjadi pendidikan terakhir", func(t *testing.T) {
+		rp, err := repo.CreatePendidikan(ctx, pegawaiID, input(s2, 2019), "")
+		require.NoError(t, err)
+		magister = rp.ID
+
+		assert.Equal(t, s2, *suite.PendidikanPegawai(t, pegawaiID))
+	})
+
+	t.Run("delete jenjang tertinggi mengembalikan jenjang sebelumnya", func(t *testing.T) {
+		_, err := repo.DeletePendidikan(ctx, pegawaiID, magister)
+		require.NoError(t, err)
+
+		assert.Equal(t, s1, *suite.PendidikanPegawai(t, pegawaiID))
+	})
+
+	t.Run("update jenjang menyinkronkan pegawai", func(t *testing.T) {
+		_, err := repo.UpdatePendidikan(ctx, pegawaiID, sarjana, input(s2, 2013))
+		require.NoError(t, err)
+
+		assert.Equal(t, s2, *suite.PendidikanPegawai(t, pegawaiID))
+	})
+
+	t.Run("pendidikan tidak ada di db_master ditolak", func(t *testing.T) {
+		_, err := repo.CreatePendidikan(ctx, pegawaiID, input(uuid.New(), 2021), "")
+		assert.ErrorIs(t, err, repositories.ErrPendidikanNotFound)
+
+		assert.Equal(t, s2, *suite.PendidikanPegawai(t, pegawaiID))
+	})
+}
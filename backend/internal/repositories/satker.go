@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,9 +10,14 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/sikerma/backend/internal/audit"
+	"github.com/sikerma/backend/internal/database"
 	"github.com/sikerma/backend/internal/models"
 )
 
+// ErrSatkerNotFound dikembalikan bila satker tidak ditemukan atau di luar scope RLS
+var ErrSatkerNotFound = errors.New("satker not found")
+
 // SatkerRepository mengelola operasi database untuk Satker
 type SatkerRepository struct {
 	db *pgxpool.Pool
@@ -47,33 +53,40 @@ func (r *SatkerRepository) List(ctx context.Context, page, limit int, search, is
 	// Get total count
 	countQuery := "SELECT COUNT(*) FROM satker" + query[20:] // Remove SELECT columns
 	var total int64
-	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count satker: %w", err)
-	}
+	satkers := []models.Satker{}
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, countQuery, args...).Scan(&total)
+		if err != nil {
+			return fmt.Errorf("failed to count satker: %w", err)
+		}
 
-	// Get data
-	query += fmt.Sprintf(" ORDER BY kode LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, limit, offset)
+		// Get data
+		query += fmt.Sprintf(" ORDER BY kode LIMIT $%d OFFSET $%d", argCount, argCount+1)
+		args = append(args, limit, offset)
 
-	rows, err := r.db.Query(ctx, query, args...)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to query satker: %w", err)
-	}
-	defer rows.Close()
-
-	satkers := []models.Satker{}
-	for rows.Next() {
-		var satker models.Satker
-		err := rows.Scan(
-			&satker.ID, &satker.Kode, &satker.Nama, &satker.ParentID, &satker.Level,
-			&satker.Alamat, &satker.Telepon, &satker.Email, &satker.IsActive,
-			&satker.CreatedAt, &satker.UpdatedAt, &satker.CreatedBy, &satker.UpdatedBy,
-		)
+		rows, err := tx.Query(ctx, query, args...)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan satker: %w", err)
+			return fmt.Errorf("failed to query satker: %w", err)
 		}
-		satkers = append(satkers, satker)
+		defer rows.Close()
+
+		for rows.Next() {
+			var satker models.Satker
+			err := rows.Scan(
+				&satker.ID, &satker.Kode, &satker.Nama, &satker.ParentID, &satker.Level,
+				&satker.Alamat, &satker.Telepon, &satker.Email, &satker.IsActive,
+				&satker.CreatedAt, &satker.UpdatedAt, &satker.CreatedBy, &satker.UpdatedBy,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to scan satker: %w", err)
+			}
+			satkers = append(satkers, satker)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
 	return satkers, total, nil
@@ -81,18 +94,29 @@ func (r *SatkerRepository) List(ctx context.Context, page, limit int, search, is
 
 // GetByID mengambil satker berdasarkan ID
 func (r *SatkerRepository) GetByID(ctx context.Context, id string) (*models.Satker, error) {
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Satker, error) {
+		return getSatker(ctx, tx, uuid.MustParse(id), false)
+	})
+}
+
+// getSatker mengambil satker di dalam transaksi. forUpdate mengunci baris
+// sehingga state sebelum perubahan yang dicatat audit tidak berubah di tengah jalan.
+func getSatker(ctx context.Context, tx pgx.Tx, id uuid.UUID, forUpdate bool) (*models.Satker, error) {
 	query := `SELECT id, kode, nama, parent_id, level, alamat, telepon, email, is_active, created_at, updated_at, created_by, updated_by
 			  FROM satker WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
 
 	var satker models.Satker
-	err := r.db.QueryRow(ctx, query, uuid.MustParse(id)).Scan(
+	err := tx.QueryRow(ctx, query, id).Scan(
 		&satker.ID, &satker.Kode, &satker.Nama, &satker.ParentID, &satker.Level,
 		&satker.Alamat, &satker.Telepon, &satker.Email, &satker.IsActive,
 		&satker.CreatedAt, &satker.UpdatedAt, &satker.CreatedBy, &satker.UpdatedBy,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("satker not found")
+		return nil, ErrSatkerNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get satker: %w", err)
@@ -101,63 +125,103 @@ func (r *SatkerRepository) GetByID(ctx context.Context, id string) (*models.Satk
 	return &satker, nil
 }
 
+// actorUUID mengubah user ID menjadi nilai kolom created_by/updated_by,
+// nil bila bukan UUID (misalnya client API key)
+func actorUUID(userID string) *uuid.UUID {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
 // Create membuat satker baru
 func (r *SatkerRepository) Create(ctx context.Context, input CreateSatkerInput, userID string) (*models.Satker, error) {
-	id := uuid.New()
+	satker, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Satker, error) {
+		id := uuid.New()
+		createdBy := actorUUID(userID)
 
-	query := `INSERT INTO satker (id, kode, nama, parent_id, level, alamat, telepon, email, is_active, created_by, updated_by)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
-			  RETURNING created_at, updated_at`
+		query := `INSERT INTO satker (id, kode, nama, parent_id, level, alamat, telepon, email, is_active, created_by, updated_by)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+				  RETURNING created_at, updated_at`
 
-	err := r.db.QueryRow(ctx, query,
-		id, input.Kode, input.Nama, input.ParentID, input.Level,
-		input.Alamat, input.Telepon, input.Email, true,
-	).Scan(&input.CreatedAt, &input.UpdatedAt)
+		err := tx.QueryRow(ctx, query,
+			id, input.Kode, input.Nama, input.ParentID, input.Level,
+			input.Alamat, input.Telepon, input.Email, true, createdBy,
+		).Scan(&input.CreatedAt, &input.UpdatedAt)
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to create satker: %w", err)
-	}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create satker: %w", err)
+		}
+
+		satker := &models.Satker{
+			ID:        id,
+			Kode:      input.Kode,
+			Nama:      input.Nama,
+			ParentID:  input.ParentID,
+			Level:     input.Level,
+			Alamat:    input.Alamat,
+			Telepon:   input.Telepon,
+			Email:     input.Email,
+			IsActive:  true,
+			CreatedAt: input.CreatedAt,
+			UpdatedAt: input.UpdatedAt,
+			CreatedBy: createdBy,
+			UpdatedBy: createdBy,
+		}
 
-	satker := &models.Satker{
-		ID:        id,
-		Kode:      input.Kode,
-		Nama:      input.Nama,
-		ParentID:  input.ParentID,
-		Level:     input.Level,
-		Alamat:    input.Alamat,
-		Telepon:   input.Telepon,
-		Email:     input.Email,
-		IsActive:  true,
-		CreatedAt: input.CreatedAt,
-		UpdatedAt: input.UpdatedAt,
+		return satker, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	audit.Record(ctx, audit.ActionCreate, "satker", satker.ID, nil, satker)
 	return satker, nil
 }
 
 // Update mengupdate satker
 func (r *SatkerRepository) Update(ctx context.Context, id string, input UpdateSatkerInput, userID string) (*models.Satker, error) {
+	var before *models.Satker
+	satker, err := database.QueryRLS(ctx, r.db, func(tx pgx.Tx) (*models.Satker, error) {
+		var err error
+		before, err = getSatker(ctx, tx, uuid.MustParse(id), true)
+		if err != nil {
+			return nil, err
+		}
+		return updateSatker(ctx, tx, before.ID, input, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.ActionUpdate, "satker", satker.ID, before, satker)
+	return satker, nil
+}
+
+// updateSatker menulis input ke satker yang sudah dikunci pemanggil
+func updateSatker(ctx context.Context, tx pgx.Tx, id uuid.UUID, input UpdateSatkerInput, userID string) (*models.Satker, error) {
 	query := `UPDATE satker
 			  SET kode = $2, nama = $3, parent_id = $4, level = $5,
 				  alamat = $6, telepon = $7, email = $8, is_active = $9,
-				  updated_at = NOW()
+				  updated_by = $10, updated_at = NOW()
 			  WHERE id = $1
 			  RETURNING created_at, updated_at, created_by, updated_by`
 
 	var satker models.Satker
-	err := r.db.QueryRow(ctx, query,
-		uuid.MustParse(id), input.Kode, input.Nama, input.ParentID, input.Level,
-		input.Alamat, input.Telepon, input.Email, input.IsActive,
+	err := tx.QueryRow(ctx, query,
+		id, input.Kode, input.Nama, input.ParentID, input.Level,
+		input.Alamat, input.Telepon, input.Email, input.IsActive, actorUUID(userID),
 	).Scan(&satker.CreatedAt, &satker.UpdatedAt, &satker.CreatedBy, &satker.UpdatedBy)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("satker not found")
+		return nil, ErrSatkerNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update satker: %w", err)
 	}
 
-	satker.ID = uuid.MustParse(id)
+	satker.ID = id
 	satker.Kode = input.Kode
 	satker.Nama = input.Nama
 	satker.ParentID = input.ParentID
@@ -172,41 +236,59 @@ func (r *SatkerRepository) Update(ctx context.Context, id string, input UpdateSa
 
 // Delete menghapus satker
 func (r *SatkerRepository) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM satker WHERE id = $1`
+	var before *models.Satker
+	err := database.WithRLS(ctx, r.db, func(tx pgx.Tx) error {
+		var err error
+		before, err = getSatker(ctx, tx, uuid.MustParse(id), true)
+		if err != nil {
+			return err
+		}
 
-	result, err := r.db.Exec(ctx, query, uuid.MustParse(id))
+		return deleteSatker(ctx, tx, before.ID)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete satker: %w", err)
+		return err
 	}
 
+	audit.Record(ctx, audit.ActionDelete, "satker", before.ID, before, nil)
+	return nil
+}
+
+// deleteSatker menghapus satker yang sudah dikunci pemanggil
+func deleteSatker(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	result, err := tx.Exec(ctx, `DELETE FROM satker WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete satker: %w", err)
+	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("satker not found")
+		return ErrSatkerNotFound
 	}
-
 	return nil
 }
 
 // GetDropdown mengambil data dropdown
 func (r *SatkerRepository) GetDropdown(ctx context.Context) ([]DropdownItem, error) {
-	query := `SELECT id, kode, nama FROM satker WHERE is_active = true ORDER BY kode`
-
-	rows, err := r.db.Query(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query dropdown: %w", err)
-	}
-	defer rows.Close()
+	return database.QueryRLS(ctx, r.db, func(tx pgx.Tx) ([]DropdownItem, error) {
+		query := `SELECT id, kode, nama FROM satker WHERE is_active = true ORDER BY kode`
 
-	items := []DropdownItem{}
-	for rows.Next() {
-		var item DropdownItem
-		err := rows.Scan(&item.Value, &item.Label, &item.Label)
+		rows, err := tx.Query(ctx, query)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan dropdown: %w", err)
+			return nil, fmt.Errorf("failed to query dropdown: %w", err)
+		}
+		defer rows.Close()
+
+		items := []DropdownItem{}
+		for rows.Next() {
+			var item DropdownItem
+			err := rows.Scan(&item.Value, &item.Label, &item.Label)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan dropdown: %w", err)
+			}
+			items = append(items, item)
 		}
-		items = append(items, item)
-	}
 
-	return items, nil
+		return items, nil
+	})
 }
 
 // ==================== INPUT TYPES ====================
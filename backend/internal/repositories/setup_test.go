@@ -0,0 +1,197 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/testutil"
+)
+
+// ============================================================================
+// TEST SETUP
+// ============================================================================
+
+// RiwayatTestSuite menyimpan pool db_master dan db_kepegawaian di atas skema
+// testdata/00_riwayat_schema.sql
+type RiwayatTestSuite struct {
+	Master      *pgxpool.Pool
+	Kepegawaian *pgxpool.Pool
+	SatkerID    uuid.UUID
+}
+
+// SetupRiwayatTest menjalankan container PostgreSQL dengan skema riwayat
+func SetupRiwayatTest(t *testing.T) *RiwayatTestSuite {
+	db := testutil.SetupTestDBWithScripts(t, "testdata/00_riwayat_schema.sql")
+	t.Cleanup(func() { db.Cleanup(t) })
+
+	return &RiwayatTestSuite{
+		Master:      connect(t, db.ConnStr, "db_master"),
+		Kepegawaian: connect(t, db.ConnStr, "db_kepegawaian"),
+		SatkerID:    uuid.New(),
+	}
+}
+
+// connect membuka pool ke database lain di container yang sama
+func connect(t *testing.T, connStr, database string) *pgxpool.Pool {
+	cfg, err := pgxpool.ParseConfig(connStr)
+	require.NoError(t, err)
+	cfg.ConnConfig.Database = database
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// ============================================================================
+// FIXTURES
+// ============================================================================
+
+// date membuat tanggal tanpa komponen waktu
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// CreatePegawai membuat pegawai aktif di satker suite
+func (s *RiwayatTestSuite) CreatePegawai(t *testing.T, nip string) uuid.UUID {
+	var id uuid.UUID
+	err := s.Kepegawaian.QueryRow(context.Background(),
+		`INSERT INTO pegawai (nip, nama_lengkap, satker_id) VALUES ($1, $2, $3) RETURNING id`,
+		nip, "Pegawai "+nip, s.SatkerID,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// CreateGolongan membuat golongan di db_master
+func (s *RiwayatTestSuite) CreateGolongan(t *testing.T, kode, nama string, angka int, active bool) uuid.UUID {
+	var id uuid.UUID
+	err := s.Master.QueryRow(context.Background(),
+		`INSERT INTO golongan (kode, nama, ruang, angka, is_active) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		kode, nama, kode, angka, active,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// CreateSatker membuat satker di db_master
+func (s *RiwayatTestSuite) CreateSatker(t *testing.T, kode, nama string) uuid.UUID {
+	var id uuid.UUID
+	err := s.Master.QueryRow(context.Background(),
+		`INSERT INTO satker (kode, nama) VALUES ($1, $2) RETURNING id`, kode, nama,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// CreateJabatan membuat jabatan di db_master
+func (s *RiwayatTestSuite) CreateJabatan(t *testing.T, kode, nama string) uuid.UUID {
+	var id uuid.UUID
+	err := s.Master.QueryRow(context.Background(),
+		`INSERT INTO jabatan (kode, nama, jenis) VALUES ($1, $2, 'struktural') RETURNING id`, kode, nama,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// CreateUnitKerja membuat unit kerja satker di db_master
+func (s *RiwayatTestSuite) CreateUnitKerja(t *testing.T, kode, nama string, satkerID uuid.UUID) uuid.UUID {
+	var id uuid.UUID
+	err := s.Master.QueryRow(context.Background(),
+		`INSERT INTO unit_kerja (kode, nama, satker_id) VALUES ($1, $2, $3) RETURNING id`, kode, nama, satkerID,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// CreatePendidikan membuat jenjang ref_pendidikan di db_master
+func (s *RiwayatTestSuite) CreatePendidikan(t *testing.T, kode, nama string, urutan int) uuid.UUID {
+	var id uuid.UUID
+	err := s.Master.QueryRow(context.Background(),
+		`INSERT INTO ref_pendidikan (kode, nama, urutan) VALUES ($1, $2, $3) RETURNING id`, kode, nama, urutan,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// PendidikanPegawai mengambil pendidikan_terakhir_id pegawai
+func (s *RiwayatTestSuite) PendidikanPegawai(t *testing.T, pegawaiID uuid.UUID) *uuid.UUID {
+	var id *uuid.UUID
+	err := s.Kepegawaian.QueryRow(context.Background(),
+		`SELECT pendidikan_terakhir_id FROM pegawai WHERE id = $1`, pegawaiID,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// CreateJenisHukdis membuat ref_jenis_hukdis di db_master
+func (s *RiwayatTestSuite) CreateJenisHukdis(t *testing.T, kode, nama string) uuid.UUID {
+	var id uuid.UUID
+	err := s.Master.QueryRow(context.Background(),
+		`INSERT INTO ref_jenis_hukdis (kode, nama) VALUES ($1, $2) RETURNING id`, kode, nama,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// CreateJenisDiklat membuat ref_jenis_diklat di db_master
+func (s *RiwayatTestSuite) CreateJenisDiklat(t *testing.T, kode, nama string, hitungJP bool) uuid.UUID {
+	var id uuid.UUID
+	err := s.Master.QueryRow(context.Background(),
+		`INSERT INTO ref_jenis_diklat (kode, nama, hitung_jp) VALUES ($1, $2, $3) RETURNING id`, kode, nama, hitungJP,
+	).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+// PangkatPegawai mengambil golongan dan TMT pangkat terakhir pegawai
+func (s *RiwayatTestSuite) PangkatPegawai(t *testing.T, pegawaiID uuid.UUID) (*uuid.UUID, *time.Time) {
+	var golonganID *uuid.UUID
+	var tmt *time.Time
+	err := s.Kepegawaian.QueryRow(context.Background(),
+		`SELECT golongan_id, tmt_pangkat_terakhir FROM pegawai WHERE id = $1`, pegawaiID,
+	).Scan(&golonganID, &tmt)
+	require.NoError(t, err)
+	return golonganID, tmt
+}
+
+// JabatanPegawai adalah kolom jabatan terakhir pegawai
+type JabatanPegawai struct {
+	JabatanID   *uuid.UUID
+	UnitKerjaID *uuid.UUID
+	SatkerID    uuid.UUID
+	TMT         *time.Time
+}
+
+// JabatanPegawai mengambil jabatan, unit kerja, satker dan TMT jabatan terakhir pegawai
+func (s *RiwayatTestSuite) JabatanPegawai(t *testing.T, pegawaiID uuid.UUID) JabatanPegawai {
+	var j JabatanPegawai
+	err := s.Kepegawaian.QueryRow(context.Background(),
+		`SELECT jabatan_id, unit_kerja_id, satker_id, tmt_jabatan_terakhir FROM pegawai WHERE id = $1`, pegawaiID,
+	).Scan(&j.JabatanID, &j.UnitKerjaID, &j.SatkerID, &j.TMT)
+	require.NoError(t, err)
+	return j
+}
+
+// Terakhir mengambil ID riwayat yang ditandai is_terakhir di table
+func (s *RiwayatTestSuite) Terakhir(t *testing.T, table string, pegawaiID uuid.UUID) []uuid.UUID {
+	rows, err := s.Kepegawaian.Query(context.Background(),
+		`SELECT id FROM `+table+` WHERE pegawai_id = $1 AND is_terakhir`, pegawaiID)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	return ids
+}
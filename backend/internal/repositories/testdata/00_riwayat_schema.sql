@@ -0,0 +1,247 @@
+-- Skema minimal untuk menguji sinkronisasi riwayat ke pegawai
+CREATE DATABASE db_master;
+CREATE DATABASE db_kepegawaian;
+
+\c db_master;
+
+CREATE TABLE golongan (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(10) UNIQUE NOT NULL,
+    nama VARCHAR(100) NOT NULL,
+    ruang VARCHAR(10) NOT NULL,
+    angka INTEGER NOT NULL,
+    min_pangkat INTEGER DEFAULT 1,
+    max_pangkat INTEGER DEFAULT 27,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE satker (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(50) UNIQUE NOT NULL,
+    nama VARCHAR(255) NOT NULL,
+    parent_id UUID,
+    level INTEGER NOT NULL DEFAULT 1,
+    alamat TEXT NOT NULL DEFAULT '',
+    telepon VARCHAR(20) NOT NULL DEFAULT '',
+    email VARCHAR(100) NOT NULL DEFAULT '',
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID,
+    updated_by UUID
+);
+
+CREATE TABLE jabatan (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(50) UNIQUE NOT NULL,
+    nama VARCHAR(255) NOT NULL,
+    eselon_id UUID,
+    kelas VARCHAR(50),
+    jenis VARCHAR(30),
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE unit_kerja (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(50) UNIQUE NOT NULL,
+    nama VARCHAR(255) NOT NULL,
+    singkatan VARCHAR(50),
+    parent_id UUID,
+    satker_id UUID REFERENCES satker(id),
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE ref_pendidikan (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(10) UNIQUE NOT NULL,
+    nama VARCHAR(100) NOT NULL,
+    tingkat VARCHAR(50) NOT NULL DEFAULT '',
+    urutan INTEGER NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE ref_jenis_hukdis (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(20) UNIQUE NOT NULL,
+    nama VARCHAR(255) NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE ref_jenis_diklat (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(20) UNIQUE NOT NULL,
+    nama VARCHAR(255) NOT NULL,
+    hitung_jp BOOLEAN NOT NULL DEFAULT true,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+\c db_kepegawaian;
+
+CREATE TABLE pegawai (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    nip VARCHAR(18) UNIQUE NOT NULL,
+    nip_lama VARCHAR(9),
+    nama_lengkap VARCHAR(255) NOT NULL,
+    gelar_depan VARCHAR(50),
+    gelar_belakang VARCHAR(50),
+    tempat_lahir VARCHAR(100) NOT NULL DEFAULT '',
+    tanggal_lahir DATE NOT NULL DEFAULT '1985-01-01',
+    jenis_kelamin VARCHAR(10) NOT NULL DEFAULT 'L',
+    agama_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    status_kawin_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    nik VARCHAR(16),
+    email VARCHAR(100),
+    telepon VARCHAR(20),
+    alamat TEXT,
+    alamat_domisili TEXT,
+    foto VARCHAR(255),
+    satker_id UUID NOT NULL,
+    jabatan_id UUID,
+    unit_kerja_id UUID,
+    golongan_id UUID,
+    eselon_id UUID,
+    pendidikan_terakhir_id UUID,
+    status_pegawai VARCHAR(20) NOT NULL DEFAULT 'PNS',
+    status_kerja VARCHAR(20) NOT NULL DEFAULT 'aktif',
+    tmt_cpns DATE,
+    tmt_pns DATE,
+    tmt_jabatan DATE,
+    tmt_pangkat_terakhir DATE,
+    tmt_jabatan_terakhir DATE,
+    karpeg_no VARCHAR(50),
+    karpeg_file VARCHAR(500),
+    taspen_no VARCHAR(50),
+    npwp VARCHAR(20),
+    bpjs_kesehatan VARCHAR(30),
+    bpjs_ketenagakerjaan VARCHAR(30),
+    kk_no VARCHAR(30),
+    kk_file VARCHAR(500),
+    ktp_no VARCHAR(30),
+    ktp_file VARCHAR(500),
+    sikep_id VARCHAR(50),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_by UUID,
+    updated_by UUID,
+    deleted_at TIMESTAMPTZ,
+    deleted_by UUID
+);
+
+CREATE TABLE riwayat_pangkat (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pegawai_id UUID NOT NULL REFERENCES pegawai(id) ON DELETE CASCADE,
+    golongan_id UUID NOT NULL,
+    pangkat VARCHAR(100) NOT NULL,
+    tmt DATE NOT NULL,
+    nomor_sk VARCHAR(100) NOT NULL,
+    tanggal_sk DATE NOT NULL,
+    pejabat VARCHAR(255) NOT NULL,
+    file_sk VARCHAR(255),
+    gaji_pokok DECIMAL(15, 2) DEFAULT 0,
+    is_terakhir BOOLEAN DEFAULT false,
+    jenis_kenaikan VARCHAR(50),
+    masa_kerja_tahun INT DEFAULT 0,
+    masa_kerja_bulan INT DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID
+);
+
+CREATE TABLE riwayat_jabatan (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pegawai_id UUID NOT NULL REFERENCES pegawai(id) ON DELETE CASCADE,
+    jabatan_id UUID,
+    unit_kerja_id UUID,
+    satker_id UUID,
+    nama_jabatan VARCHAR(255) NOT NULL,
+    tmt DATE NOT NULL,
+    nomor_sk VARCHAR(100) NOT NULL,
+    tanggal_sk DATE NOT NULL,
+    pejabat VARCHAR(255) NOT NULL,
+    file_sk VARCHAR(255),
+    is_terakhir BOOLEAN DEFAULT false,
+    jenis_jabatan VARCHAR(20),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID
+);
+
+CREATE TABLE riwayat_pendidikan (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pegawai_id UUID NOT NULL REFERENCES pegawai(id) ON DELETE CASCADE,
+    pendidikan_id UUID NOT NULL,
+    nama_institusi VARCHAR(255) NOT NULL,
+    jurusan VARCHAR(255),
+    tahun_masuk INTEGER,
+    tahun_lulus INTEGER,
+    nomor_ijazah VARCHAR(100),
+    tanggal_ijazah DATE,
+    file_ijazah VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID
+);
+
+CREATE TABLE keluarga (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pegawai_id UUID NOT NULL REFERENCES pegawai(id) ON DELETE CASCADE,
+    status_keluarga VARCHAR(20) NOT NULL,
+    nama VARCHAR(255) NOT NULL,
+    tempat_lahir VARCHAR(100),
+    tanggal_lahir DATE,
+    jenis_kelamin VARCHAR(10),
+    nik VARCHAR(16),
+    pendidikan VARCHAR(100),
+    pekerjaan VARCHAR(100),
+    is_tanggungan BOOLEAN DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID
+);
+
+CREATE TABLE hukdis (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pegawai_id UUID NOT NULL REFERENCES pegawai(id) ON DELETE CASCADE,
+    jenis_hukdis_id UUID NOT NULL,
+    nomor_sk VARCHAR(100) NOT NULL,
+    tanggal_sk DATE NOT NULL,
+    tanggal_mulai DATE NOT NULL,
+    tanggal_selesai DATE,
+    alasan TEXT,
+    pejabat VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID
+);
+
+CREATE INDEX idx_hukdis_pegawai_periode ON hukdis(pegawai_id, tanggal_mulai, tanggal_selesai);
+
+CREATE TABLE diklat (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pegawai_id UUID NOT NULL REFERENCES pegawai(id) ON DELETE CASCADE,
+    jenis_diklat_id UUID NOT NULL,
+    nama_diklat VARCHAR(255) NOT NULL,
+    penyelenggara VARCHAR(255),
+    tempat VARCHAR(255),
+    tanggal_mulai DATE,
+    tanggal_selesai DATE,
+    jam_jpl INTEGER,
+    nomor_sertifikat VARCHAR(100),
+    tanggal_sertifikat DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by UUID
+);
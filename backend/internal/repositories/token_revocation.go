@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+// ==================== TOKEN REVOCATIONS ====================
+
+// TokenRevocationRepository menyimpan daftar pencabutan token di db_master
+// agar berlaku di semua instance backend
+type TokenRevocationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTokenRevocationRepository membuat instance TokenRevocationRepository baru
+func NewTokenRevocationRepository(db *pgxpool.Pool) *TokenRevocationRepository {
+	return &TokenRevocationRepository{db: db}
+}
+
+// Revoke mencatat pencabutan. Pencabutan ulang untuk kind/value yang sama
+// memperpanjang expires_at dan issued_before, tidak pernah memperpendek.
+func (r *TokenRevocationRepository) Revoke(ctx context.Context, rev models.TokenRevocation) error {
+	query := `INSERT INTO token_revocations (kind, value, user_id, issued_before, reason, revoked_by, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  ON CONFLICT (kind, value) DO UPDATE SET
+			      issued_before = GREATEST(token_revocations.issued_before, EXCLUDED.issued_before),
+			      expires_at = GREATEST(token_revocations.expires_at, EXCLUDED.expires_at),
+			      reason = COALESCE(EXCLUDED.reason, token_revocations.reason),
+			      revoked_by = COALESCE(EXCLUDED.revoked_by, token_revocations.revoked_by)`
+
+	_, err := r.db.Exec(ctx, query,
+		rev.Kind, rev.Value, rev.UserID, rev.IssuedBefore, rev.Reason, rev.RevokedBy, rev.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// ListActiveRevocations mengambil pencabutan yang token-nya belum kadaluarsa
+func (r *TokenRevocationRepository) ListActiveRevocations(ctx context.Context) ([]models.TokenRevocation, error) {
+	query := `SELECT id, kind, value, user_id, issued_before, reason, revoked_by, expires_at, created_at
+			  FROM token_revocations
+			  WHERE expires_at > NOW()`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query token revocations: %w", err)
+	}
+	defer rows.Close()
+
+	revocations := []models.TokenRevocation{}
+	for rows.Next() {
+		var rev models.TokenRevocation
+		if err := rows.Scan(&rev.ID, &rev.Kind, &rev.Value, &rev.UserID, &rev.IssuedBefore, &rev.Reason,
+			&rev.RevokedBy, &rev.ExpiresAt, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan token revocation: %w", err)
+		}
+		revocations = append(revocations, rev)
+	}
+
+	return revocations, rows.Err()
+}
+
+// DeleteExpired menghapus pencabutan yang token-nya sudah kadaluarsa
+func (r *TokenRevocationRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM token_revocations WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired token revocations: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,190 @@
+package rls_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/database"
+	"github.com/sikerma/backend/internal/testutil"
+)
+
+// ============================================================================
+// TEST SETUP
+// ============================================================================
+
+// setupRLSContextDB menjalankan migration 10 di atas skema minimal lalu
+// mengembalikan pool db_kepegawaian yang terhubung sebagai role aplikasi
+func setupRLSContextDB(t *testing.T) *pgxpool.Pool {
+	db := testutil.SetupTestDBWithScripts(t,
+		"testdata/00_rls_context_schema.sql",
+		"../../../docker/postgres/migrations/10_rls_request_context.sql",
+	)
+	t.Cleanup(func() { db.Cleanup(t) })
+
+	cfg, err := pgxpool.ParseConfig(db.ConnStr)
+	require.NoError(t, err)
+	cfg.ConnConfig.Database = "db_kepegawaian"
+	cfg.ConnConfig.User = "sikerma_app"
+	cfg.ConnConfig.Password = "sikerma_app"
+	// Satu koneksi memastikan request berikutnya memakai sesi yang sama
+	cfg.MaxConns = 1
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// countPegawai menghitung pegawai yang terlihat dengan claims tertentu
+func countPegawai(t *testing.T, pool *pgxpool.Pool, claims *database.RLSClaims) int {
+	ctx := context.Background()
+	if claims != nil {
+		ctx = database.WithRLSClaims(ctx, *claims)
+	}
+
+	count, err := database.QueryRLS(ctx, pool, func(tx pgx.Tx) (int, error) {
+		var count int
+		err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM pegawai").Scan(&count)
+		return count, err
+	})
+	require.NoError(t, err)
+	return count
+}
+
+// ============================================================================
+// TEST: WithRLS Request Context
+// ============================================================================
+
+func TestWithRLSAppliesClaims(t *testing.T) {
+	pool := setupRLSContextDB(t)
+
+	userID := uuid.New().String()
+	unitID := uuid.New().String()
+	satkerID := uuid.New().String()
+	ctx := database.WithRLSClaims(context.Background(), database.RLSClaims{
+		UserID:      userID,
+		UnitKerjaID: unitID,
+		SatkerID:    satkerID,
+		Role:        "staff",
+	})
+
+	t.Run("claims visible inside transaction", func(t *testing.T) {
+		err := database.WithRLS(ctx, pool, func(tx pgx.Tx) error {
+			var gotUser, gotUnit, gotSatker, gotRole string
+			err := tx.QueryRow(ctx, `SELECT current_user_id()::text, current_unit_kerja_id()::text,
+				current_satker_id()::text, current_user_role()`).
+				Scan(&gotUser, &gotUnit, &gotSatker, &gotRole)
+			require.NoError(t, err)
+
+			assert.Equal(t, userID, gotUser)
+			assert.Equal(t, unitID, gotUnit)
+			assert.Equal(t, satkerID, gotSatker)
+			assert.Equal(t, "staff", gotRole)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("claims do not leak after transaction", func(t *testing.T) {
+		var gotUser *string
+		var gotRole string
+		err := pool.QueryRow(context.Background(),
+			"SELECT current_user_id()::text, current_user_role()").Scan(&gotUser, &gotRole)
+		require.NoError(t, err)
+
+		assert.Nil(t, gotUser)
+		assert.Equal(t, "anonymous", gotRole)
+	})
+
+	t.Run("error in fn rolls back", func(t *testing.T) {
+		err := database.WithRLS(ctx, pool, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, "SELECT 1/0")
+			return err
+		})
+		require.Error(t, err)
+
+		var gotUser *string
+		err = pool.QueryRow(context.Background(), "SELECT current_user_id()::text").Scan(&gotUser)
+		require.NoError(t, err)
+		assert.Nil(t, gotUser)
+	})
+}
+
+// ============================================================================
+// TEST: Isolasi Data per Unit Kerja
+// ============================================================================
+
+func TestWithRLSIsolatesPegawai(t *testing.T) {
+	pool := setupRLSContextDB(t)
+
+	satkerA := uuid.New().String()
+	satkerB := uuid.New().String()
+	unitA1 := uuid.New().String()
+	unitA2 := uuid.New().String()
+	unitB1 := uuid.New().String()
+
+	admin := &database.RLSClaims{UserID: uuid.New().String(), Role: "admin"}
+	adminCtx := database.WithRLSClaims(context.Background(), *admin)
+
+	// Seed melalui role aplikasi dengan claim admin
+	err := database.WithRLS(adminCtx, pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(adminCtx, `INSERT INTO pegawai (nip, nama_lengkap, satker_id, unit_kerja_id) VALUES
+			('199001012020011001', 'Pegawai A1 Satu', $1, $2),
+			('199001012020011002', 'Pegawai A1 Dua', $1, $2),
+			('199001012020011003', 'Pegawai A2', $1, $3),
+			('199001012020011004', 'Pegawai B1', $4, $5)`,
+			satkerA, unitA1, unitA2, satkerB, unitB1)
+		return err
+	})
+	require.NoError(t, err)
+
+	t.Run("admin sees all pegawai", func(t *testing.T) {
+		assert.Equal(t, 4, countPegawai(t, pool, admin))
+	})
+
+	t.Run("unit user sees only own unit", func(t *testing.T) {
+		claims := &database.RLSClaims{UserID: uuid.New().String(), UnitKerjaID: unitA1, Role: "staff"}
+		assert.Equal(t, 2, countPegawai(t, pool, claims))
+	})
+
+	t.Run("satker scope sees all units in satker", func(t *testing.T) {
+		claims := &database.RLSClaims{UserID: uuid.New().String(), UnitKerjaID: unitA2, SatkerID: satkerA, Role: "supervisor"}
+		assert.Equal(t, 3, countPegawai(t, pool, claims))
+	})
+
+	t.Run("no claims sees nothing", func(t *testing.T) {
+		assert.Equal(t, 0, countPegawai(t, pool, nil))
+	})
+
+	t.Run("user cannot insert into other unit", func(t *testing.T) {
+		ctx := database.WithRLSClaims(context.Background(), database.RLSClaims{
+			UserID: uuid.New().String(), UnitKerjaID: unitA1, SatkerID: satkerA, Role: "staff",
+		})
+		err := database.WithRLS(ctx, pool, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `INSERT INTO pegawai (nip, nama_lengkap, satker_id, unit_kerja_id)
+				VALUES ('199001012020011005', 'Pegawai Titipan', $1, $2)`, satkerB, unitB1)
+			return err
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "row-level security")
+	})
+
+	t.Run("user cannot update other unit", func(t *testing.T) {
+		ctx := database.WithRLSClaims(context.Background(), database.RLSClaims{
+			UserID: uuid.New().String(), UnitKerjaID: unitA1, Role: "staff",
+		})
+		affected, err := database.QueryRLS(ctx, pool, func(tx pgx.Tx) (int64, error) {
+			result, err := tx.Exec(ctx, "UPDATE pegawai SET nama_lengkap = 'Diubah' WHERE unit_kerja_id = $1", unitB1)
+			return result.RowsAffected(), err
+		})
+		require.NoError(t, err)
+		assert.Zero(t, affected)
+	})
+}
@@ -0,0 +1,34 @@
+-- Skema minimal untuk menguji migration 10_rls_request_context.sql
+CREATE DATABASE db_master;
+CREATE DATABASE db_kepegawaian;
+
+-- Role aplikasi non-superuser: RLS tidak berlaku untuk superuser
+CREATE ROLE sikerma_app LOGIN PASSWORD 'sikerma_app';
+
+\c db_master;
+
+CREATE TABLE satker (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(50) NOT NULL,
+    nama VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE unit_kerja (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kode VARCHAR(50) NOT NULL,
+    nama VARCHAR(255) NOT NULL
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON satker, unit_kerja TO sikerma_app;
+
+\c db_kepegawaian;
+
+CREATE TABLE pegawai (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    nip VARCHAR(18) NOT NULL,
+    nama_lengkap VARCHAR(255) NOT NULL,
+    satker_id UUID NOT NULL,
+    unit_kerja_id UUID
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON pegawai TO sikerma_app;
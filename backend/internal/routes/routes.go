@@ -15,28 +15,33 @@ func Setup(app *fiber.App, h *handlers.Handlers) {
 	api.Get("/health", h.HealthCheck)
 
 	// Auth routes (public)
+	// Login dan refresh memakai limiter terpisah agar refresh rutin tidak menghabiskan kuota login
+	rateLimitConfig := middleware.DefaultRateLimitConfig()
 	auth := api.Group("/auth")
-	auth.Post("/login", h.Login)
+	auth.Post("/login", middleware.LoginRateLimiter(rateLimitConfig), h.Login)
+	auth.Post("/refresh", middleware.LoginRateLimiter(rateLimitConfig), h.RefreshToken)
 	auth.Post("/logout", h.Logout)
 
 	// Authenticated routes
-	authenticated := api.Group("", h.AuthMiddleware.Authenticate())
+	// Impersonation dipasang sebelum ApplyScope agar scope RLS mengikuti user target
+	authenticated := api.Group("", h.AuthMiddleware.Authenticate(), h.Impersonation.Apply(), h.RLSMiddleware.ApplyScope())
 
 	// User profile
 	authenticated.Get("/auth/me", h.GetCurrentUser)
 
 	// ==================== MASTER DATA ====================
 	masterData := authenticated.Group("/master-data")
-	masterData.Use(middleware.RequirePermission("master_data.read"))
+	masterData.Use(h.RBACMiddleware.RequirePermission("master_data.read"))
 
 	// Satker
 	satker := masterData.Group("/satker")
 	satker.Get("", h.ListSatker)
 	satker.Get("/dropdown", h.GetDropdownSatker)
 	satker.Get("/:id", h.GetSatker)
-	satker.Post("", middleware.RequirePermission("master_data.create"), h.CreateSatker)
-	satker.Put("/:id", middleware.RequirePermission("master_data.update"), h.UpdateSatker)
-	satker.Delete("/:id", middleware.RequirePermission("master_data.delete"), h.DeleteSatker)
+	satker.Get("/:id/history", h.RBACMiddleware.RequirePermission("audit.read"), h.GetSatkerHistory)
+	satker.Post("", h.RBACMiddleware.RequirePermission("master_data.create"), h.CreateSatker)
+	satker.Put("/:id", h.RBACMiddleware.RequirePermission("master_data.update"), h.UpdateSatker)
+	satker.Delete("/:id", h.RBACMiddleware.RequirePermission("master_data.delete"), h.DeleteSatker)
 
 	// Jabatan
 	jabatan := masterData.Group("/jabatan")
@@ -60,29 +65,122 @@ func Setup(app *fiber.App, h *handlers.Handlers) {
 
 	// ==================== KEGAWAAN ====================
 	kepegawaian := authenticated.Group("/kepegawaian")
-	kepegawaian.Use(middleware.RequirePermission("kepegawaian.read"))
+	kepegawaian.Use(h.RBACMiddleware.RequirePermission("kepegawaian.read"))
 
 	// Pegawai
 	pegawai := kepegawaian.Group("/pegawai")
-	pegawai.Get("", h.ListPegawai)
-	pegawai.Get("/:id", h.GetPegawai)
-	pegawai.Post("", middleware.RequirePermission("kepegawaian.create"), h.CreatePegawai)
-	pegawai.Put("/:id", middleware.RequirePermission("kepegawaian.update"), h.UpdatePegawai)
-	pegawai.Delete("/:id", middleware.RequirePermission("kepegawaian.delete"), h.DeletePegawai)
+	pegawai.Get("", h.ReadAudit.Audit("pegawai"), h.ListPegawai)
+	pegawai.Get("/:id", h.ReadAudit.Audit("pegawai"), h.GetPegawai)
+	pegawai.Get("/:id/history", h.RBACMiddleware.RequirePermission("audit.read"), h.GetPegawaiHistory)
+	pegawai.Get("/:id/access-log", h.RBACMiddleware.RequirePermission("audit.read"), h.GetPegawaiAccessLog)
+	pegawai.Post("", h.RBACMiddleware.RequirePermission("kepegawaian.create"), h.CreatePegawai)
+	pegawai.Put("/:id", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.UpdatePegawai)
+	pegawai.Delete("/:id", h.RBACMiddleware.RequirePermission("kepegawaian.delete"), h.DeletePegawai)
+
+	// Riwayat pangkat; perubahan ikut menyinkronkan golongan pegawai
+	pegawai.Get("/:id/riwayat-pangkat", h.ListRiwayatPangkat)
+	pegawai.Get("/:id/riwayat-pangkat/:riwayatId", h.GetRiwayatPangkat)
+	pegawai.Post("/:id/riwayat-pangkat", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.CreateRiwayatPangkat)
+	pegawai.Put("/:id/riwayat-pangkat/:riwayatId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.UpdateRiwayatPangkat)
+	pegawai.Delete("/:id/riwayat-pangkat/:riwayatId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.DeleteRiwayatPangkat)
+
+	// Riwayat jabatan; perubahan ikut menyinkronkan jabatan, unit kerja dan satker pegawai
+	pegawai.Get("/:id/riwayat-jabatan", h.ListRiwayatJabatan)
+	pegawai.Get("/:id/riwayat-jabatan/:riwayatId", h.GetRiwayatJabatan)
+	pegawai.Post("/:id/riwayat-jabatan", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.CreateRiwayatJabatan)
+	pegawai.Put("/:id/riwayat-jabatan/:riwayatId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.UpdateRiwayatJabatan)
+	pegawai.Delete("/:id/riwayat-jabatan/:riwayatId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.DeleteRiwayatJabatan)
+
+	// Riwayat pendidikan; jenjang tertinggi menjadi pendidikan terakhir pegawai
+	pegawai.Get("/:id/riwayat-pendidikan", h.ListRiwayatPendidikan)
+	pegawai.Get("/:id/riwayat-pendidikan/:riwayatId", h.GetRiwayatPendidikan)
+	pegawai.Post("/:id/riwayat-pendidikan", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.CreateRiwayatPendidikan)
+	pegawai.Put("/:id/riwayat-pendidikan/:riwayatId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.UpdateRiwayatPendidikan)
+	pegawai.Delete("/:id/riwayat-pendidikan/:riwayatId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.DeleteRiwayatPendidikan)
+	pegawai.Get("/:id/riwayat-pendidikan/:riwayatId/ijazah", h.DownloadIjazah)
+	pegawai.Put("/:id/riwayat-pendidikan/:riwayatId/ijazah", h.RBACMiddleware.RequirePermission("kepegawaian.update"), middleware.UploadRateLimiter(rateLimitConfig), h.UploadIjazah)
+
+	// Riwayat diklat; JP per tahun direkap untuk kepatuhan pengembangan kompetensi
+	pegawai.Get("/:id/riwayat-diklat", h.ListRiwayatDiklat)
+	pegawai.Get("/:id/riwayat-diklat/:riwayatId", h.GetRiwayatDiklat)
+	pegawai.Post("/:id/riwayat-diklat", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.CreateRiwayatDiklat)
+	pegawai.Put("/:id/riwayat-diklat/:riwayatId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.UpdateRiwayatDiklat)
+	pegawai.Delete("/:id/riwayat-diklat/:riwayatId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.DeleteRiwayatDiklat)
+	kepegawaian.Get("/diklat/kepatuhan-jp", h.GetKepatuhanJP)
+
+	// Keluarga; is_tanggungan dihitung dari aturan tunjangan keluarga
+	pegawai.Get("/:id/keluarga", h.ListKeluarga)
+	pegawai.Get("/:id/keluarga/:keluargaId", h.GetKeluarga)
+	pegawai.Post("/:id/keluarga", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.CreateKeluarga)
+	pegawai.Put("/:id/keluarga/:keluargaId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.UpdateKeluarga)
+	pegawai.Delete("/:id/keluarga/:keluargaId", h.RBACMiddleware.RequirePermission("kepegawaian.update"), h.DeleteKeluarga)
+	kepegawaian.Get("/keluarga/perubahan-tanggungan", h.GetPerubahanTanggungan)
+
+	// Hukuman disiplin; tidak tercakup kepegawaian.read karena sangat sensitif
+	pegawai.Get("/:id/hukdis", h.RBACMiddleware.RequirePermission("hukdis.read"), h.ReadAudit.Audit("hukdis"), h.ListHukdis)
+	pegawai.Get("/:id/hukdis/aktif", h.RBACMiddleware.RequirePermission("hukdis.read"), h.ReadAudit.Audit("hukdis"), h.GetHukdisAktif)
+	pegawai.Get("/:id/hukdis/:hukdisId", h.RBACMiddleware.RequirePermission("hukdis.read"), h.ReadAudit.Audit("hukdis"), h.GetHukdis)
+	pegawai.Post("/:id/hukdis", h.RBACMiddleware.RequirePermission("hukdis.update"), h.CreateHukdis)
+	pegawai.Put("/:id/hukdis/:hukdisId", h.RBACMiddleware.RequirePermission("hukdis.update"), h.UpdateHukdis)
+	pegawai.Delete("/:id/hukdis/:hukdisId", h.RBACMiddleware.RequirePermission("hukdis.update"), h.DeleteHukdis)
 
 	// Statistik
 	kepegawaian.Get("/statistik", h.GetStatistikKepegawaian)
+	kepegawaian.Get("/statistik/pendidikan", h.GetStatistikPendidikan)
 
 	// ==================== RBAC ====================
 	rbac := authenticated.Group("/rbac")
-	rbac.Use(middleware.RequirePermission("rbac.read"))
+	rbac.Use(h.RBACMiddleware.RequirePermission("rbac.read"))
 
 	roles := rbac.Group("/roles")
 	roles.Get("", h.ListRoles)
-	roles.Post("", middleware.RequirePermission("rbac.create"), h.CreateRole)
+	roles.Get("/:id", h.GetRole)
+	roles.Post("", h.RBACMiddleware.RequirePermission("rbac.create"), h.CreateRole)
+	roles.Put("/:id", h.RBACMiddleware.RequirePermission("rbac.update"), h.UpdateRole)
+	roles.Delete("/:id", h.RBACMiddleware.RequirePermission("rbac.delete"), h.DeactivateRole)
+	roles.Get("/:id/permissions", h.ListRolePermissions)
+	roles.Post("/:id/permissions", h.RBACMiddleware.RequirePermission("rbac.update"), h.AttachRolePermission)
+	roles.Delete("/:id/permissions/:permissionId", h.RBACMiddleware.RequirePermission("rbac.update"), h.DetachRolePermission)
+	roles.Get("/:id/includes", h.ListIncludedRoles)
+	roles.Post("/:id/includes", h.RBACMiddleware.RequirePermission("rbac.update"), h.IncludeRole)
+	roles.Delete("/:id/includes/:includedId", h.RBACMiddleware.RequirePermission("rbac.update"), h.ExcludeRole)
+
+	rbac.Get("/permissions", h.ListPermissions)
+
+	userRoles := rbac.Group("/users/:userId/roles")
+	userRoles.Get("", h.ListUserRoles)
+	userRoles.Post("", h.RBACMiddleware.RequirePermission("rbac.create"), h.AssignUserRole)
+	userRoles.Delete("/:roleId", h.RBACMiddleware.RequirePermission("rbac.delete"), h.RevokeUserRole)
+
+	rbac.Post("/users/:userId/logout", h.RBACMiddleware.RequirePermission("session.revoke"), h.ForceLogoutUser)
+
+	apiKeys := rbac.Group("/api-keys")
+	apiKeys.Get("", h.ListAPIKeys)
+	apiKeys.Get("/:id", h.GetAPIKey)
+	apiKeys.Post("", h.RBACMiddleware.RequirePermission("rbac.create"), h.CreateAPIKey)
+	apiKeys.Post("/:id/rotate", h.RBACMiddleware.RequirePermission("rbac.update"), h.RotateAPIKey)
+	apiKeys.Delete("/:id", h.RBACMiddleware.RequirePermission("rbac.delete"), h.RevokeAPIKey)
+
+	// ==================== IMPERSONATION ====================
+	impersonation := authenticated.Group("/impersonation")
+	impersonation.Get("", h.RBACMiddleware.RequirePermission(middleware.ImpersonatePermission), h.ListImpersonations)
+	impersonation.Post("", h.RBACMiddleware.RequirePermission(middleware.ImpersonatePermission), h.StartImpersonation)
+	impersonation.Delete("/:id", h.EndImpersonation)
 
 	// ==================== AUDIT LOGS ====================
 	audit := authenticated.Group("/audit-logs")
-	audit.Use(middleware.RequirePermission("audit.read"))
+	audit.Use(h.RBACMiddleware.RequirePermission("audit.read"))
 	audit.Get("", h.ListAuditLogs)
+	audit.Get("/export", h.RBACMiddleware.RequirePermission("audit.export"), h.ExportAuditLogs)
+	audit.Get("/verify", h.RBACMiddleware.RequirePermission("audit.verify"), h.VerifyAuditChain)
+	audit.Get("/:id/revert", h.RBACMiddleware.RequirePermission("audit.revert"), h.PreviewAuditRevert)
+	audit.Post("/:id/revert", h.RBACMiddleware.RequirePermission("audit.revert"), h.ApplyAuditRevert)
+
+	// ==================== AUDIT ALERTS ====================
+	alerts := authenticated.Group("/audit-alerts")
+	alerts.Use(h.RBACMiddleware.RequirePermission("audit.alerts"))
+	alerts.Get("", h.ListAuditAlerts)
+	alerts.Get("/:id", h.GetAuditAlert)
+	alerts.Post("/:id/acknowledge", h.RBACMiddleware.RequirePermission("audit.acknowledge"), h.AcknowledgeAuditAlert)
+	alerts.Post("/:id/resolve", h.RBACMiddleware.RequirePermission("audit.acknowledge"), h.ResolveAuditAlert)
 }
\ No newline at end of file
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrFileTooLarge = errors.New("file too large")
+	ErrFileType     = errors.New("file type not allowed")
+	ErrInvalidPath  = errors.New("invalid file path")
+)
+
+// DocumentTypes jenis file dokumen kepegawaian (SK, ijazah) yang diterima,
+// dipetakan dari content type hasil deteksi ke ekstensi file
+var DocumentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// Store menyimpan file upload di bawah satu direktori root. Path yang
+// disimpan di database relatif terhadap root.
+type Store struct {
+	root string
+}
+
+// New membuat Store dengan root direktori penyimpanan
+func New(root string) *Store {
+	return &Store{root: root}
+}
+
+// Save menyimpan isi r ke dir dengan nama file UUID. Jenis file ditentukan
+// dari magic bytes, bukan nama atau header dari client, dan harus ada di
+// allowed. Mengembalikan path relatif file yang disimpan.
+func (s *Store) Save(dir string, r io.Reader, maxSize int64, allowed map[string]string) (string, error) {
+	if !filepath.IsLocal(dir) {
+		return "", ErrInvalidPath
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	head = head[:n]
+
+	ext, ok := allowed[http.DetectContentType(head)]
+	if !ok {
+		return "", ErrFileType
+	}
+
+	target := filepath.Join(s.root, dir)
+	if err := os.MkdirAll(target, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(target, ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// Baca satu byte melebihi batas untuk mendeteksi file yang terlalu besar
+	written, err := io.Copy(tmp, io.LimitReader(io.MultiReader(bytes.NewReader(head), r), maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	if written > maxSize {
+		return "", ErrFileTooLarge
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	name := filepath.Join(dir, uuid.New().String()+ext)
+	if err := os.Rename(tmp.Name(), filepath.Join(s.root, name)); err != nil {
+		return "", fmt.Errorf("failed to store file: %w", err)
+	}
+	return filepath.ToSlash(name), nil
+}
+
+// Open membuka file yang disimpan Save
+func (s *Store) Open(path string) (*os.File, error) {
+	full, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(full)
+}
+
+// Remove menghapus file yang disimpan Save. File yang sudah tidak ada
+// tidak dianggap error.
+func (s *Store) Remove(path string) error {
+	full, err := s.resolve(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
+}
+
+// resolve menolak path yang keluar dari root
+func (s *Store) resolve(path string) (string, error) {
+	local := filepath.FromSlash(path)
+	if !filepath.IsLocal(local) {
+		return "", ErrInvalidPath
+	}
+	return filepath.Join(s.root, local), nil
+}
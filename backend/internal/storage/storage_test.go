@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pdf = []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\n%%EOF\n")
+
+func TestSaveOpenRemove(t *testing.T) {
+	root := t.TempDir()
+	store := New(root)
+
+	path, err := store.Save("ijazah/abc", bytes.NewReader(pdf), 1024, DocumentTypes)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(path, "ijazah/abc/"))
+	assert.Equal(t, ".pdf", filepath.Ext(path))
+
+	f, err := store.Open(path)
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, pdf, content)
+
+	require.NoError(t, store.Remove(path))
+	_, err = os.Stat(filepath.Join(root, path))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NoError(t, store.Remove(path))
+}
+
+func TestSaveRejectsFile(t *testing.T) {
+	root := t.TempDir()
+	store := New(root)
+
+	_, err := store.Save("ijazah", strings.NewReader("#!/bin/sh\necho pdf\n"), 1024, DocumentTypes)
+	assert.ErrorIs(t, err, ErrFileType)
+
+	_, err = store.Save("ijazah", bytes.NewReader(pdf), int64(len(pdf)-1), DocumentTypes)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	_, err = store.Save("../luar", bytes.NewReader(pdf), 1024, DocumentTypes)
+	assert.ErrorIs(t, err, ErrInvalidPath)
+
+	// File yang ditolak tidak meninggalkan sisa di storage
+	entries, err := os.ReadDir(filepath.Join(root, "ijazah"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOpenRejectsPathOutsideRoot(t *testing.T) {
+	store := New(t.TempDir())
+
+	_, err := store.Open("../../etc/passwd")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+	_, err = store.Open("/etc/passwd")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+	assert.ErrorIs(t, store.Remove("../x"), ErrInvalidPath)
+}
@@ -0,0 +1,156 @@
+// Package tanggungan menentukan anggota keluarga pegawai yang ditanggung
+// tunjangan keluarga: paling banyak satu suami/istri dan dua anak. Anak
+// ditanggung sampai usia 21 tahun, atau 25 tahun bila masih sekolah/kuliah.
+package tanggungan
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+const (
+	MaxPasangan          = 1
+	MaxAnak              = 2
+	BatasUsiaAnak        = 21
+	BatasUsiaAnakSekolah = 25
+)
+
+// tidakSekolah adalah nilai pendidikan anak yang berarti tidak sedang
+// menempuh pendidikan
+var tidakSekolah = map[string]bool{
+	"-":             true,
+	"tidak sekolah": true,
+	"putus sekolah": true,
+	"tamat":         true,
+	"lulus":         true,
+	"bekerja":       true,
+}
+
+// MasihSekolah menganggap anak masih sekolah/kuliah bila pendidikan diisi
+// dan bukan keterangan tidak sekolah
+func MasihSekolah(pendidikan *string) bool {
+	if pendidikan == nil {
+		return false
+	}
+	value := strings.ToLower(strings.TrimSpace(*pendidikan))
+	return value != "" && !tidakSekolah[value]
+}
+
+// BatasUsia mengembalikan tanggal anak tidak lagi memenuhi syarat usia,
+// atau nil bila tanggal lahir tidak diketahui
+func BatasUsia(anak models.Keluarga) *time.Time {
+	if anak.TanggalLahir == nil {
+		return nil
+	}
+	usia := BatasUsiaAnak
+	if MasihSekolah(anak.Pendidikan) {
+		usia = BatasUsiaAnakSekolah
+	}
+	batas := tanggal(*anak.TanggalLahir).AddDate(usia, 0, 0)
+	return &batas
+}
+
+// Evaluate menentukan status tanggungan setiap anggota keluarga satu pegawai
+// pada tanggal at. Suami/istri yang lebih dulu dicatat dan anak tertua yang
+// memenuhi syarat usia didahulukan.
+func Evaluate(keluarga []models.Keluarga, at time.Time) map[uuid.UUID]bool {
+	at = tanggal(at)
+	result := make(map[uuid.UUID]bool, len(keluarga))
+
+	var pasangan, anak []models.Keluarga
+	for _, k := range keluarga {
+		result[k.ID] = false
+		switch k.Hubungan {
+		case models.StatusKeluargaSuami, models.StatusKeluargaIstri:
+			pasangan = append(pasangan, k)
+		case models.StatusKeluargaAnak:
+			if batas := BatasUsia(k); batas != nil && at.Before(*batas) {
+				anak = append(anak, k)
+			}
+		}
+	}
+
+	sort.SliceStable(pasangan, func(i, j int) bool {
+		return pasangan[i].CreatedAt.Before(pasangan[j].CreatedAt)
+	})
+	sort.SliceStable(anak, func(i, j int) bool {
+		if !anak[i].TanggalLahir.Equal(*anak[j].TanggalLahir) {
+			return anak[i].TanggalLahir.Before(*anak[j].TanggalLahir)
+		}
+		return anak[i].CreatedAt.Before(anak[j].CreatedAt)
+	})
+
+	for i := 0; i < len(pasangan) && i < MaxPasangan; i++ {
+		result[pasangan[i].ID] = true
+	}
+	for i := 0; i < len(anak) && i < MaxAnak; i++ {
+		result[anak[i].ID] = true
+	}
+	return result
+}
+
+// Change adalah perubahan status tanggungan satu anggota keluarga
+type Change struct {
+	KeluargaID   uuid.UUID `json:"keluarga_id"`
+	Tanggal      time.Time `json:"tanggal"`
+	IsTanggungan bool      `json:"is_tanggungan"`
+	Alasan       string    `json:"alasan"`
+}
+
+// Changes mencari perubahan status tanggungan keluarga satu pegawai setelah
+// from sampai dengan to. Status hanya berubah ketika anak mencapai batas
+// usia; anak berikutnya dapat menggantikan anak tersebut pada tanggal yang
+// sama.
+func Changes(keluarga []models.Keluarga, from, to time.Time) []Change {
+	from, to = tanggal(from), tanggal(to)
+
+	alasan := map[time.Time]map[uuid.UUID]string{}
+	for _, k := range keluarga {
+		if k.Hubungan != models.StatusKeluargaAnak {
+			continue
+		}
+		batas := BatasUsia(k)
+		if batas == nil || !batas.After(from) || batas.After(to) {
+			continue
+		}
+		if alasan[*batas] == nil {
+			alasan[*batas] = map[uuid.UUID]string{}
+		}
+		alasan[*batas][k.ID] = "Mencapai batas usia tanggungan anak"
+	}
+
+	dates := make([]time.Time, 0, len(alasan))
+	for d := range alasan {
+		dates = append(dates, d)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	changes := []Change{}
+	current := Evaluate(keluarga, from)
+	for _, d := range dates {
+		next := Evaluate(keluarga, d)
+		for _, k := range keluarga {
+			if next[k.ID] == current[k.ID] {
+				continue
+			}
+			reason, ok := alasan[d][k.ID]
+			if !ok {
+				reason = "Menggantikan anak yang tidak lagi ditanggung"
+			}
+			changes = append(changes, Change{KeluargaID: k.ID, Tanggal: d, IsTanggungan: next[k.ID], Alasan: reason})
+		}
+		current = next
+	}
+	return changes
+}
+
+// tanggal membuang komponen waktu
+func tanggal(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,94 @@
+package tanggungan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sikerma/backend/internal/models"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func stringPtr(s string) *string { return &s }
+
+func anggota(hubungan models.StatusKeluarga, lahir *time.Time, pendidikan *string, created time.Time) models.Keluarga {
+	return models.Keluarga{
+		ID:           uuid.New(),
+		Hubungan:     hubungan,
+		TanggalLahir: lahir,
+		Pendidikan:   pendidikan,
+		CreatedAt:    created,
+	}
+}
+
+func lahir(y int, m time.Month, d int) *time.Time {
+	t := date(y, m, d)
+	return &t
+}
+
+func TestEvaluate(t *testing.T) {
+	created := date(2020, 1, 1)
+	istri := anggota(models.StatusKeluargaIstri, lahir(1990, 5, 1), nil, created)
+	istriKedua := anggota(models.StatusKeluargaIstri, lahir(1992, 5, 1), nil, created.AddDate(1, 0, 0))
+	ayah := anggota(models.StatusKeluargaAyah, lahir(1960, 1, 1), nil, created)
+	anakDewasa := anggota(models.StatusKeluargaAnak, lahir(2003, 3, 10), stringPtr("Tamat"), created)
+	anakKuliah := anggota(models.StatusKeluargaAnak, lahir(2004, 6, 1), stringPtr("S1"), created)
+	anakKedua := anggota(models.StatusKeluargaAnak, lahir(2010, 2, 1), stringPtr("SMP"), created)
+	anakKetiga := anggota(models.StatusKeluargaAnak, lahir(2015, 8, 1), stringPtr("SD"), created)
+	tanpaTanggal := anggota(models.StatusKeluargaAnak, nil, nil, created)
+
+	result := Evaluate([]models.Keluarga{anakKetiga, istriKedua, anakKedua, ayah, anakDewasa, istri, anakKuliah, tanpaTanggal}, date(2026, 10, 18))
+
+	assert.True(t, result[istri.ID])
+	assert.False(t, result[istriKedua.ID], "hanya satu suami/istri")
+	assert.False(t, result[ayah.ID], "orang tua tidak ditanggung")
+	assert.False(t, result[anakDewasa.ID], "sudah 23 tahun dan tidak sekolah")
+	assert.True(t, result[anakKuliah.ID], "22 tahun dan masih kuliah")
+	assert.True(t, result[anakKedua.ID])
+	assert.False(t, result[anakKetiga.ID], "hanya dua anak")
+	assert.False(t, result[tanpaTanggal.ID])
+}
+
+func TestBatasUsia(t *testing.T) {
+	anak := anggota(models.StatusKeluargaAnak, lahir(2005, 11, 2), nil, time.Time{})
+	assert.Equal(t, date(2026, 11, 2), *BatasUsia(anak))
+
+	anak.Pendidikan = stringPtr("D3")
+	assert.Equal(t, date(2030, 11, 2), *BatasUsia(anak))
+
+	anak.Pendidikan = stringPtr(" lulus ")
+	assert.Equal(t, date(2026, 11, 2), *BatasUsia(anak))
+
+	anak.TanggalLahir = nil
+	assert.Nil(t, BatasUsia(anak))
+}
+
+func TestChanges(t *testing.T) {
+	created := date(2020, 1, 1)
+	sulung := anggota(models.StatusKeluargaAnak, lahir(2005, 11, 2), stringPtr("Bekerja"), created)
+	tengah := anggota(models.StatusKeluargaAnak, lahir(2008, 1, 5), stringPtr("SMA"), created)
+	bungsu := anggota(models.StatusKeluargaAnak, lahir(2012, 4, 9), stringPtr("SMP"), created)
+	keluarga := []models.Keluarga{sulung, tengah, bungsu}
+
+	changes := Changes(keluarga, date(2026, 10, 18), date(2026, 11, 18))
+	require.Len(t, changes, 2)
+
+	byID := map[uuid.UUID]Change{}
+	for _, c := range changes {
+		byID[c.KeluargaID] = c
+	}
+	assert.Equal(t, date(2026, 11, 2), byID[sulung.ID].Tanggal)
+	assert.False(t, byID[sulung.ID].IsTanggungan)
+	assert.Equal(t, "Mencapai batas usia tanggungan anak", byID[sulung.ID].Alasan)
+	assert.Equal(t, date(2026, 11, 2), byID[bungsu.ID].Tanggal)
+	assert.True(t, byID[bungsu.ID].IsTanggungan)
+	assert.Equal(t, "Menggantikan anak yang tidak lagi ditanggung", byID[bungsu.ID].Alasan)
+
+	assert.Empty(t, Changes(keluarga, date(2026, 11, 3), date(2026, 12, 3)))
+}
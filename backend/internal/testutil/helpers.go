@@ -24,12 +24,18 @@ type TestDB struct {
 // SetupTestDB creates a test database container for integration tests
 // Usage: defer testdb.Cleanup(t)
 func SetupTestDB(t *testing.T, migrationsPath string) *TestDB {
+	return SetupTestDBWithScripts(t, migrationsPath)
+}
+
+// SetupTestDBWithScripts seperti SetupTestDB namun menerima beberapa init script
+// (dijalankan berurutan sesuai nama file)
+func SetupTestDBWithScripts(t *testing.T, scripts ...string) *TestDB {
 	ctx := context.Background()
 
 	// Create PostgreSQL container with migrations
 	container, err := postgres.Run(ctx,
 		"postgres:18-alpine",
-		postgres.WithInitScripts(migrationsPath),
+		postgres.WithInitScripts(scripts...),
 		testcontainers.WithWaitStrategy(
 			wait.ForLog("database system is ready to accept connections").
 				WithOccurrence(2).
@@ -25,6 +25,8 @@ var sensitiveFields = map[string]bool{
 	"tempat_lahir":   true,
 	"tanggal_lahir":  true,
 	"ibu_kandung":    true,
+	"access_token":   true,
+	"refresh_token":  true,
 }
 
 // MaskValue melakukan masking pada nilai field
@@ -109,6 +111,9 @@ func MaskSensitiveData(data map[string]interface{}) map[string]interface{} {
 			switch v := value.(type) {
 			case string:
 				switch key {
+				case "password", "access_token", "refresh_token":
+					// Kredensial tidak boleh bocor sebagian pun
+					masked[key] = "****"
 				case "nik", "no_ktp":
 					masked[key] = MaskNIK(v)
 				case "nip":
@@ -158,6 +163,9 @@ func MaskPII(data map[string]interface{}, fieldsToMask []string) map[string]inte
 			switch v := value.(type) {
 			case string:
 				switch lowerKey {
+				case "password", "access_token", "refresh_token":
+					// Kredensial tidak boleh bocor sebagian pun
+					masked[key] = "****"
 				case "nik", "no_ktp":
 					masked[key] = MaskNIK(v)
 				case "nip":
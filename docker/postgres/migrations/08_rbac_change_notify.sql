@@ -0,0 +1,73 @@
+-- ============================================================================
+-- MIGRATION: RBAC Change Notification
+-- Version: 08
+-- Date: 2026-10-18
+-- Description: Mengirim NOTIFY 'rbac_changed' setiap kali assignment role atau
+--              permission berubah agar cache permission di backend dibuang.
+--              Payload berisi user_id yang terdampak, atau string kosong jika
+--              perubahan berdampak ke banyak user (role/permission).
+-- ============================================================================
+
+\c db_master;
+
+-- ============================================================================
+-- 1. TRIGGER FUNCTIONS
+-- ============================================================================
+
+-- Perubahan assignment user: hanya user terkait yang dibuang dari cache
+CREATE OR REPLACE FUNCTION notify_user_app_roles_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM pg_notify('rbac_changed', OLD.user_id);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM pg_notify('rbac_changed', NEW.user_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Perubahan role/permission: seluruh cache dibuang
+CREATE OR REPLACE FUNCTION notify_rbac_definition_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('rbac_changed', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- ============================================================================
+-- 2. TRIGGERS
+-- ============================================================================
+
+DROP TRIGGER IF EXISTS notify_user_app_roles_change ON user_app_roles;
+CREATE TRIGGER notify_user_app_roles_change
+    AFTER INSERT OR UPDATE OR DELETE ON user_app_roles
+    FOR EACH ROW EXECUTE FUNCTION notify_user_app_roles_change();
+
+DROP TRIGGER IF EXISTS notify_role_permissions_change ON role_permissions;
+CREATE TRIGGER notify_role_permissions_change
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON role_permissions
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_rbac_definition_change();
+
+DROP TRIGGER IF EXISTS notify_app_roles_change ON app_roles;
+CREATE TRIGGER notify_app_roles_change
+    AFTER UPDATE OR DELETE ON app_roles
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_rbac_definition_change();
+
+DROP TRIGGER IF EXISTS notify_app_permissions_change ON app_permissions;
+CREATE TRIGGER notify_app_permissions_change
+    AFTER UPDATE OR DELETE ON app_permissions
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_rbac_definition_change();
+
+-- ============================================================================
+-- 3. ADMIN PERMISSION SET
+-- ============================================================================
+
+-- Backend tidak lagi mem-bypass role 'admin'; pastikan role tersebut memegang
+-- seluruh permission yang ada.
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM app_roles r, app_permissions p
+WHERE r.nama = 'admin'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
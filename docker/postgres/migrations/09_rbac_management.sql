@@ -0,0 +1,27 @@
+-- ============================================================================
+-- MIGRATION: RBAC Management
+-- Version: 09
+-- Date: 2026-10-18
+-- Description: Menandai role bawaan sebagai role sistem yang tidak bisa diubah
+--              lewat API, dan menambahkan scope unit kerja pada assignment role
+-- ============================================================================
+
+\c db_master;
+
+-- ============================================================================
+-- 1. ROLE SISTEM
+-- ============================================================================
+
+ALTER TABLE app_roles ADD COLUMN IF NOT EXISTS is_system BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE app_roles SET is_system = true
+WHERE nama IN ('admin', 'supervisor', 'officer', 'staff', 'user');
+
+-- ============================================================================
+-- 2. SCOPE UNIT KERJA PADA ASSIGNMENT
+-- ============================================================================
+
+ALTER TABLE user_app_roles ADD COLUMN IF NOT EXISTS unit_kerja_id UUID REFERENCES unit_kerja(id) ON DELETE SET NULL;
+ALTER TABLE user_app_roles ADD COLUMN IF NOT EXISTS assigned_by VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_user_app_roles_unit_kerja ON user_app_roles(unit_kerja_id);
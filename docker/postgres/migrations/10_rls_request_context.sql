@@ -0,0 +1,165 @@
+-- ============================================================================
+-- MIGRATION: RLS Request Context
+-- Version: 10
+-- Date: 2026-10-18
+-- Description: Menyelaraskan RLS (03) dengan database terpisah. Backend
+--              mengisi request.jwt.claim.* lewat set_config(..., true) di
+--              setiap transaksi repository (database.WithRLS).
+--
+-- CATATAN: superuser dan pemilik tabel tanpa FORCE melewati RLS. Backend harus
+--          terhubung sebagai role aplikasi non-superuser agar policy berlaku.
+-- ============================================================================
+
+\c db_master;
+
+-- ============================================================================
+-- 1. SATKER PADA UNIT KERJA
+-- ============================================================================
+
+-- Policy unit_kerja dan pencarian scope user membutuhkan satker pemilik unit
+ALTER TABLE unit_kerja ADD COLUMN IF NOT EXISTS satker_id UUID REFERENCES satker(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_unit_kerja_satker_rls ON unit_kerja(satker_id);
+
+-- ============================================================================
+-- 2. HELPER FUNCTIONS
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION current_user_id()
+RETURNS UUID LANGUAGE SQL STABLE AS $$
+    SELECT NULLIF(current_setting('request.jwt.claim.user_id', true), '')::UUID;
+$$;
+
+CREATE OR REPLACE FUNCTION current_unit_kerja_id()
+RETURNS UUID LANGUAGE SQL STABLE AS $$
+    SELECT NULLIF(current_setting('request.jwt.claim.unit_kerja_id', true), '')::UUID;
+$$;
+
+CREATE OR REPLACE FUNCTION current_satker_id()
+RETURNS UUID LANGUAGE SQL STABLE AS $$
+    SELECT NULLIF(current_setting('request.jwt.claim.satker_id', true), '')::UUID;
+$$;
+
+CREATE OR REPLACE FUNCTION current_user_role()
+RETURNS TEXT LANGUAGE SQL STABLE AS $$
+    SELECT COALESCE(NULLIF(current_setting('request.jwt.claim.role', true), ''), 'anonymous');
+$$;
+
+CREATE OR REPLACE FUNCTION is_admin()
+RETURNS BOOLEAN LANGUAGE SQL STABLE AS $$
+    SELECT current_user_role() IN ('admin', 'superadmin');
+$$;
+
+-- ============================================================================
+-- 3. POLICIES MASTER
+-- ============================================================================
+
+ALTER TABLE unit_kerja ENABLE ROW LEVEL SECURITY;
+ALTER TABLE satker ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS unit_kerja_select_policy ON unit_kerja;
+CREATE POLICY unit_kerja_select_policy ON unit_kerja
+    FOR SELECT
+    USING (
+        is_admin() OR
+        satker_id = current_satker_id() OR
+        id = current_unit_kerja_id()
+    );
+
+DROP POLICY IF EXISTS unit_kerja_write_policy ON unit_kerja;
+CREATE POLICY unit_kerja_write_policy ON unit_kerja
+    FOR ALL
+    USING (is_admin())
+    WITH CHECK (is_admin());
+
+DROP POLICY IF EXISTS satker_select_policy ON satker;
+CREATE POLICY satker_select_policy ON satker
+    FOR SELECT
+    USING (
+        is_admin() OR
+        id = current_satker_id()
+    );
+
+DROP POLICY IF EXISTS satker_write_policy ON satker;
+CREATE POLICY satker_write_policy ON satker
+    FOR ALL
+    USING (is_admin())
+    WITH CHECK (is_admin());
+
+\c db_kepegawaian;
+
+-- ============================================================================
+-- 4. HELPER FUNCTIONS
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION current_user_id()
+RETURNS UUID LANGUAGE SQL STABLE AS $$
+    SELECT NULLIF(current_setting('request.jwt.claim.user_id', true), '')::UUID;
+$$;
+
+CREATE OR REPLACE FUNCTION current_unit_kerja_id()
+RETURNS UUID LANGUAGE SQL STABLE AS $$
+    SELECT NULLIF(current_setting('request.jwt.claim.unit_kerja_id', true), '')::UUID;
+$$;
+
+CREATE OR REPLACE FUNCTION current_satker_id()
+RETURNS UUID LANGUAGE SQL STABLE AS $$
+    SELECT NULLIF(current_setting('request.jwt.claim.satker_id', true), '')::UUID;
+$$;
+
+CREATE OR REPLACE FUNCTION current_user_role()
+RETURNS TEXT LANGUAGE SQL STABLE AS $$
+    SELECT COALESCE(NULLIF(current_setting('request.jwt.claim.role', true), ''), 'anonymous');
+$$;
+
+CREATE OR REPLACE FUNCTION is_admin()
+RETURNS BOOLEAN LANGUAGE SQL STABLE AS $$
+    SELECT current_user_role() IN ('admin', 'superadmin');
+$$;
+
+-- ============================================================================
+-- 5. POLICIES PEGAWAI
+-- ============================================================================
+
+-- unit_kerja ada di db_master, sehingga policy memakai kolom pegawai langsung:
+-- user melihat pegawai di unit kerjanya atau di satker yang sama.
+ALTER TABLE pegawai ENABLE ROW LEVEL SECURITY;
+ALTER TABLE pegawai FORCE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS pegawai_select_policy ON pegawai;
+CREATE POLICY pegawai_select_policy ON pegawai
+    FOR SELECT
+    USING (
+        is_admin() OR
+        unit_kerja_id = current_unit_kerja_id() OR
+        satker_id = current_satker_id()
+    );
+
+DROP POLICY IF EXISTS pegawai_insert_policy ON pegawai;
+CREATE POLICY pegawai_insert_policy ON pegawai
+    FOR INSERT
+    WITH CHECK (
+        is_admin() OR
+        unit_kerja_id = current_unit_kerja_id()
+    );
+
+DROP POLICY IF EXISTS pegawai_update_policy ON pegawai;
+CREATE POLICY pegawai_update_policy ON pegawai
+    FOR UPDATE
+    USING (
+        is_admin() OR
+        unit_kerja_id = current_unit_kerja_id()
+    )
+    WITH CHECK (
+        is_admin() OR
+        unit_kerja_id = current_unit_kerja_id()
+    );
+
+DROP POLICY IF EXISTS pegawai_delete_policy ON pegawai;
+CREATE POLICY pegawai_delete_policy ON pegawai
+    FOR DELETE
+    USING (
+        is_admin() OR
+        unit_kerja_id = current_unit_kerja_id()
+    );
+
+CREATE INDEX IF NOT EXISTS idx_pegawai_unit_kerja_rls ON pegawai(unit_kerja_id);
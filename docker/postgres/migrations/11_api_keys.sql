@@ -0,0 +1,33 @@
+-- ============================================================================
+-- MIGRATION: API Keys
+-- Version: 11
+-- Date: 2026-10-18
+-- Description: Kredensial jangka panjang untuk client mesin (script
+--              pelaporan, backend portal). Key dikirim lewat header X-API-Key.
+-- ============================================================================
+
+\c db_master;
+
+-- ============================================================================
+-- 1. TABEL API KEYS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    nama VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) UNIQUE NOT NULL,           -- bagian publik key untuk lookup
+    secret_hash VARCHAR(64) NOT NULL,             -- SHA-256 hex dari key utuh
+    owner_user_id VARCHAR(255) NOT NULL,          -- Keycloak user ID yang diwakili key
+    scopes TEXT[] NOT NULL DEFAULT '{}',          -- nama app_permissions yang boleh dipakai
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip VARCHAR(45),
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by VARCHAR(255),
+    rotated_from UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_owner ON api_keys(owner_user_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_active ON api_keys(prefix) WHERE revoked_at IS NULL;
@@ -0,0 +1,62 @@
+-- ============================================================================
+-- MIGRATION: Role Hierarchy
+-- Version: 12
+-- Date: 2026-10-18
+-- Description: Prioritas role dan composite role disimpan di app_roles sehingga
+--              role baru (mis. operator_satker) tidak membutuhkan perubahan
+--              kode. Role bisa berasal dari realm atau client role Keycloak.
+-- ============================================================================
+
+\c db_master;
+
+-- ============================================================================
+-- 1. PRIORITAS & SUMBER ROLE
+-- ============================================================================
+
+-- Role dengan prioritas tertinggi menjadi role utama user (userRole)
+ALTER TABLE app_roles ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
+
+-- NULL = realm role; selain itu client ID Keycloak pemilik client role
+ALTER TABLE app_roles ADD COLUMN IF NOT EXISTS keycloak_client VARCHAR(100);
+
+UPDATE app_roles SET priority = CASE nama
+    WHEN 'admin' THEN 100
+    WHEN 'supervisor' THEN 80
+    WHEN 'officer' THEN 60
+    WHEN 'staff' THEN 40
+    WHEN 'user' THEN 20
+END
+WHERE nama IN ('admin', 'supervisor', 'officer', 'staff', 'user');
+
+-- ============================================================================
+-- 2. COMPOSITE ROLE
+-- ============================================================================
+
+-- role_id mewarisi semua permission (dan composite) milik includes_role_id
+CREATE TABLE IF NOT EXISTS app_role_composites (
+    role_id UUID NOT NULL REFERENCES app_roles(id) ON DELETE CASCADE,
+    includes_role_id UUID NOT NULL REFERENCES app_roles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (role_id, includes_role_id),
+    CHECK (role_id <> includes_role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_role_composites_includes ON app_role_composites(includes_role_id);
+
+-- Hierarki bawaan: admin > supervisor > officer > staff > user
+INSERT INTO app_role_composites (role_id, includes_role_id)
+SELECT parent.id, child.id
+FROM (VALUES
+    ('admin', 'supervisor'),
+    ('supervisor', 'officer'),
+    ('officer', 'staff'),
+    ('staff', 'user')
+) AS h(parent_nama, child_nama)
+JOIN app_roles parent ON parent.nama = h.parent_nama
+JOIN app_roles child ON child.nama = h.child_nama
+ON CONFLICT DO NOTHING;
+
+DROP TRIGGER IF EXISTS notify_app_role_composites_change ON app_role_composites;
+CREATE TRIGGER notify_app_role_composites_change
+    AFTER INSERT OR UPDATE OR DELETE ON app_role_composites
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_rbac_definition_change();
@@ -0,0 +1,63 @@
+-- ============================================================================
+-- MIGRATION: Token Revocations
+-- Version: 13
+-- Date: 2026-10-18
+-- Description: Daftar pencabutan access token Keycloak yang dibagi antar
+--              instance backend. Token ditolak bila jti, sid, atau user
+--              (untuk token yang terbit sebelum force logout) tercatat di sini.
+--              Baris boleh dihapus setelah expires_at karena token yang
+--              dicabut sudah kadaluarsa dengan sendirinya.
+-- ============================================================================
+
+\c db_master;
+
+-- ============================================================================
+-- 1. TABEL TOKEN REVOCATIONS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS token_revocations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('jti', 'sid', 'sub')),
+    value VARCHAR(255) NOT NULL,                  -- jti, sid, atau Keycloak user ID
+    user_id VARCHAR(255),                         -- pemilik token, untuk penelusuran
+    issued_before TIMESTAMP WITH TIME ZONE,       -- kind 'sub': token dengan iat sebelum ini ditolak
+    reason TEXT,
+    revoked_by VARCHAR(255),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL, -- exp terakhir token yang terdampak
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (kind, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_revocations_expires ON token_revocations(expires_at);
+CREATE INDEX IF NOT EXISTS idx_token_revocations_user ON token_revocations(user_id);
+
+-- ============================================================================
+-- 2. NOTIFIKASI ANTAR INSTANCE
+-- ============================================================================
+
+-- Setiap instance memuat ulang daftar pencabutan saat menerima 'token_revoked'
+CREATE OR REPLACE FUNCTION notify_token_revocations_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('token_revoked', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notify_token_revocations_change ON token_revocations;
+CREATE TRIGGER notify_token_revocations_change
+    AFTER INSERT OR UPDATE ON token_revocations
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_token_revocations_change();
+
+-- ============================================================================
+-- 3. PERMISSION
+-- ============================================================================
+
+INSERT INTO app_permissions (nama, resource, action, deskripsi) VALUES
+('session.revoke', 'session', 'revoke', 'Akses force logout session user')
+ON CONFLICT (nama) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM app_roles r, app_permissions p
+WHERE r.nama = 'admin' AND p.nama = 'session.revoke'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,59 @@
+-- ============================================================================
+-- MIGRATION: Impersonation
+-- Version: 14
+-- Date: 2026-10-18
+-- Description: Session "act as" berbatas waktu untuk helpdesk. Selama session
+--              aktif, request dievaluasi dengan role dan scope unit kerja user
+--              target, sedangkan audit_logs tetap mencatat aktor sebenarnya.
+-- ============================================================================
+
+\c db_master;
+
+-- ============================================================================
+-- 1. TABEL IMPERSONATION SESSIONS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS impersonation_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_user_id VARCHAR(255) NOT NULL,          -- admin/helpdesk yang melakukan impersonation
+    actor_username VARCHAR(100),
+    target_user_id VARCHAR(255) NOT NULL,         -- Keycloak user ID yang diperankan
+    target_username VARCHAR(100),
+    reason TEXT NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    ended_by VARCHAR(255),
+    CHECK (actor_user_id <> target_user_id),
+    CHECK (expires_at > started_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_actor ON impersonation_sessions(actor_user_id);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_target ON impersonation_sessions(target_user_id);
+CREATE INDEX IF NOT EXISTS idx_impersonation_sessions_active ON impersonation_sessions(expires_at) WHERE ended_at IS NULL;
+
+-- ============================================================================
+-- 2. ATRIBUSI AUDIT
+-- ============================================================================
+
+-- user_id/username tetap aktor sebenarnya; kolom berikut terisi bila request
+-- dilakukan atas nama user lain
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS impersonated_user_id VARCHAR(255);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS impersonated_username VARCHAR(100);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS impersonation_session_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_impersonated ON audit_logs(impersonated_user_id)
+    WHERE impersonated_user_id IS NOT NULL;
+
+-- ============================================================================
+-- 3. PERMISSION
+-- ============================================================================
+
+INSERT INTO app_permissions (nama, resource, action, deskripsi) VALUES
+('impersonation.start', 'impersonation', 'start', 'Akses bertindak sebagai user lain (helpdesk)')
+ON CONFLICT (nama) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM app_roles r, app_permissions p
+WHERE r.nama = 'admin' AND p.nama = 'impersonation.start'
+ON CONFLICT DO NOTHING;
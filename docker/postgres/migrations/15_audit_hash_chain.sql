@@ -0,0 +1,77 @@
+-- ============================================================================
+-- MIGRATION: Audit Hash Chain
+-- Version: 15
+-- Date: 2026-10-18
+-- Description: Setiap baris audit_logs menyimpan hash atas isinya dan hash
+--              baris sebelumnya sehingga perubahan atau penghapusan langsung di
+--              database terdeteksi oleh verifier. Anchor berkala diekspor ke
+--              file sebagai bukti saat pemeriksaan.
+-- ============================================================================
+
+\c db_master;
+
+-- ============================================================================
+-- 1. KOLOM HASH CHAIN
+-- ============================================================================
+
+-- Baris lama (sebelum migrasi) tidak memiliki seq dan tidak diverifikasi
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS seq BIGINT;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash CHAR(64);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS row_hash CHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_seq ON audit_logs(seq) WHERE seq IS NOT NULL;
+
+-- ============================================================================
+-- 2. UJUNG CHAIN
+-- ============================================================================
+
+-- Satu baris yang dikunci (SELECT ... FOR UPDATE) oleh setiap penulis audit
+-- sehingga seq bertambah tanpa celah dan prev_hash selalu menyambung
+CREATE TABLE IF NOT EXISTS audit_chain_head (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    seq BIGINT NOT NULL DEFAULT 0,
+    row_hash CHAR(64) NOT NULL DEFAULT repeat('0', 64),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO audit_chain_head DEFAULT VALUES ON CONFLICT DO NOTHING;
+
+-- ============================================================================
+-- 3. ANCHOR
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS audit_chain_anchors (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    seq BIGINT NOT NULL UNIQUE,
+    row_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- ============================================================================
+-- 4. IMMUTABILITY
+-- ============================================================================
+
+-- Baris audit tidak pernah diubah oleh aplikasi
+CREATE OR REPLACE FUNCTION audit_logs_reject_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_immutable ON audit_logs;
+CREATE TRIGGER audit_logs_immutable BEFORE UPDATE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_reject_update();
+
+-- ============================================================================
+-- 5. PERMISSION
+-- ============================================================================
+
+INSERT INTO app_permissions (nama, resource, action, deskripsi) VALUES
+('audit.verify', 'audit', 'verify', 'Verifikasi integritas hash chain audit log')
+ON CONFLICT (nama) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM app_roles r, app_permissions p
+WHERE r.nama = 'admin' AND p.nama = 'audit.verify'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,23 @@
+-- ============================================================================
+-- MIGRATION: Audit Search
+-- Version: 16
+-- Date: 2026-10-18
+-- Description: Index untuk filter audit log (resource_id, status, IP,
+--              username, isi changes) dan timeline riwayat per record.
+-- ============================================================================
+
+\c db_master;
+
+-- Timeline satu record: WHERE resource = ? AND resource_id = ? ORDER BY created_at
+CREATE INDEX IF NOT EXISTS idx_audit_logs_record ON audit_logs(resource, resource_id, created_at)
+    WHERE resource_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_status ON audit_logs(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_ip ON audit_logs USING gist (ip_address inet_ops);
+
+-- Pencarian username dengan ILIKE '%...%'
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_username_trgm ON audit_logs USING gin (username gin_trgm_ops);
+
+-- Filter changes @> '{...}' dan changes->'diff' ? 'field'
+CREATE INDEX IF NOT EXISTS idx_audit_logs_changes ON audit_logs USING gin (changes);
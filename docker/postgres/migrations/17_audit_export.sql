@@ -0,0 +1,21 @@
+-- ============================================================================
+-- MIGRATION: Audit Export
+-- Version: 17
+-- Date: 2026-10-18
+-- Description: Permission untuk ekspor audit log (CSV/JSONL) dan untuk
+--              ekspor tanpa masking PII.
+-- ============================================================================
+
+\c db_master;
+
+INSERT INTO app_permissions (nama, resource, action, deskripsi) VALUES
+('audit.export', 'audit', 'export', 'Ekspor audit log ke CSV/JSONL (data PII di-mask)'),
+('audit.unmask', 'audit', 'unmask', 'Ekspor audit log tanpa masking PII')
+ON CONFLICT (nama) DO NOTHING;
+
+-- audit.unmask sengaja tidak diberikan ke role mana pun; assign eksplisit
+-- ke role pemeriksa bila dibutuhkan
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM app_roles r, app_permissions p
+WHERE r.nama = 'admin' AND p.nama = 'audit.export'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,166 @@
+-- ============================================================================
+-- MIGRATION: Audit Log Partitioning
+-- Version: 18
+-- Date: 2026-10-18
+-- Description: audit_logs dipecah menjadi partisi bulanan (RANGE created_at,
+--              batas bulan Asia/Jakarta). Partisi bulan berikutnya dibuat lebih
+--              dulu oleh backend; partisi yang melewati masa retensi diekspor
+--              ke file JSONL terkompresi beserta checksum lalu di-drop, dan
+--              dapat dimuat kembali ke audit_logs_restored untuk investigasi.
+-- ============================================================================
+
+\c db_master;
+
+BEGIN;
+
+-- ============================================================================
+-- 1. TABEL PARTISI
+-- ============================================================================
+
+ALTER TABLE audit_logs RENAME TO audit_logs_legacy;
+DROP TRIGGER IF EXISTS audit_logs_immutable ON audit_logs_legacy;
+
+-- Primary key tabel partisi wajib memuat kolom partisi. Keunikan seq dijaga
+-- oleh audit_chain_head, sehingga index seq tidak lagi UNIQUE.
+CREATE TABLE audit_logs (
+    id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(255),
+    username VARCHAR(100),
+    action VARCHAR(50) NOT NULL,
+    resource VARCHAR(100) NOT NULL,
+    resource_id UUID,
+    ip_address INET,
+    user_agent TEXT,
+    changes JSONB,
+    status VARCHAR(20) NOT NULL DEFAULT 'success',
+    error_message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    impersonated_user_id VARCHAR(255),
+    impersonated_username VARCHAR(100),
+    impersonation_session_id UUID,
+    seq BIGINT,
+    prev_hash CHAR(64),
+    row_hash CHAR(64),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+-- Index pada tabel induk otomatis dibuat di setiap partisi
+CREATE INDEX idx_audit_logs_user ON audit_logs(user_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX idx_audit_logs_resource ON audit_logs(resource);
+CREATE INDEX idx_audit_logs_created ON audit_logs(created_at DESC);
+CREATE INDEX idx_audit_logs_resource_composite ON audit_logs(resource, resource_id);
+CREATE INDEX idx_audit_logs_impersonated ON audit_logs(impersonated_user_id)
+    WHERE impersonated_user_id IS NOT NULL;
+CREATE INDEX idx_audit_logs_seq ON audit_logs(seq) WHERE seq IS NOT NULL;
+CREATE INDEX idx_audit_logs_record ON audit_logs(resource, resource_id, created_at)
+    WHERE resource_id IS NOT NULL;
+CREATE INDEX idx_audit_logs_status ON audit_logs(status, created_at DESC);
+CREATE INDEX idx_audit_logs_ip ON audit_logs USING gist (ip_address inet_ops);
+CREATE INDEX idx_audit_logs_username_trgm ON audit_logs USING gin (username gin_trgm_ops);
+CREATE INDEX idx_audit_logs_changes ON audit_logs USING gin (changes);
+
+CREATE TRIGGER audit_logs_immutable BEFORE UPDATE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_reject_update();
+
+-- ============================================================================
+-- 2. PEMBUATAN PARTISI
+-- ============================================================================
+
+-- Membuat partisi audit_logs_yYYYYmMM untuk bulan yang memuat p_month bila
+-- belum ada. Dipanggil backend setiap maintenance untuk beberapa bulan ke depan.
+CREATE OR REPLACE FUNCTION audit_logs_ensure_partition(p_month DATE)
+RETURNS TEXT AS $$
+DECLARE
+    v_from DATE := date_trunc('month', p_month)::DATE;
+    v_name TEXT := 'audit_logs_' || to_char(v_from, '"y"YYYY"m"MM');
+BEGIN
+    IF to_regclass(v_name) IS NULL THEN
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF audit_logs FOR VALUES FROM (%L) TO (%L)',
+            v_name,
+            v_from::TIMESTAMP AT TIME ZONE 'Asia/Jakarta',
+            (v_from + INTERVAL '1 month')::TIMESTAMP AT TIME ZONE 'Asia/Jakarta'
+        );
+    END IF;
+    RETURN v_name;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT audit_logs_ensure_partition(m::DATE)
+FROM generate_series(
+    date_trunc('month', COALESCE((SELECT MIN(created_at) FROM audit_logs_legacy), NOW()) AT TIME ZONE 'Asia/Jakarta'),
+    date_trunc('month', NOW() AT TIME ZONE 'Asia/Jakarta') + INTERVAL '3 months',
+    INTERVAL '1 month'
+) AS m;
+
+-- ============================================================================
+-- 3. PINDAH DATA
+-- ============================================================================
+
+INSERT INTO audit_logs (id, user_id, username, action, resource, resource_id,
+    ip_address, user_agent, changes, status, error_message, created_at,
+    impersonated_user_id, impersonated_username, impersonation_session_id,
+    seq, prev_hash, row_hash)
+SELECT id, user_id, username, action, resource, resource_id,
+    ip_address, user_agent, changes, status, error_message, COALESCE(created_at, NOW()),
+    impersonated_user_id, impersonated_username, impersonation_session_id,
+    seq, prev_hash, row_hash
+FROM audit_logs_legacy;
+
+DROP TABLE audit_logs_legacy;
+
+-- created_at baris berikutnya tidak boleh lebih awal dari baris terakhir
+UPDATE audit_chain_head h
+SET updated_at = GREATEST(h.updated_at, COALESCE((SELECT MAX(created_at) FROM audit_logs WHERE seq IS NOT NULL), h.updated_at));
+
+-- ============================================================================
+-- 4. ARSIP
+-- ============================================================================
+
+-- Partisi yang sudah diekspor ke file. Baris terakhir menjadi titik awal
+-- verifikasi hash chain untuk data yang masih ada di database.
+CREATE TABLE IF NOT EXISTS audit_archives (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    partition_name VARCHAR(63) NOT NULL UNIQUE,
+    range_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    range_to TIMESTAMP WITH TIME ZONE NOT NULL,
+    file TEXT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    row_count BIGINT NOT NULL,
+    first_seq BIGINT,
+    last_seq BIGINT,
+    last_row_hash CHAR(64),
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Baris dari file arsip yang dimuat ulang untuk investigasi. Terpisah dari
+-- audit_logs agar tidak mengganggu hash chain dan retensi.
+CREATE TABLE IF NOT EXISTS audit_logs_restored (
+    id UUID NOT NULL,
+    user_id VARCHAR(255),
+    username VARCHAR(100),
+    action VARCHAR(50) NOT NULL,
+    resource VARCHAR(100) NOT NULL,
+    resource_id UUID,
+    ip_address INET,
+    user_agent TEXT,
+    changes JSONB,
+    status VARCHAR(20) NOT NULL,
+    error_message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    impersonated_user_id VARCHAR(255),
+    impersonated_username VARCHAR(100),
+    impersonation_session_id UUID,
+    seq BIGINT,
+    prev_hash CHAR(64),
+    row_hash CHAR(64),
+    archive_file TEXT NOT NULL,
+    restored_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, created_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_restored_record ON audit_logs_restored(resource, resource_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_restored_file ON audit_logs_restored(archive_file);
+
+COMMIT;
@@ -0,0 +1,49 @@
+-- ============================================================================
+-- MIGRATION: Audit Alerts
+-- Version: 19
+-- Date: 2026-10-18
+-- Description: Alert aktivitas mencurigakan hasil rule deteksi atas audit
+--              stream (hapus massal, baca PII massal, akses ditolak
+--              berulang, aktivitas di luar jam kerja, IP baru) beserta
+--              alur acknowledge dan resolve.
+-- ============================================================================
+
+\c db_master;
+
+CREATE TABLE IF NOT EXISTS audit_alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rule VARCHAR(100) NOT NULL,
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('low', 'medium', 'high')),
+    user_id VARCHAR(255),
+    username VARCHAR(100),
+    ip_address INET,
+    summary TEXT NOT NULL,
+    details JSONB,
+    event_count INTEGER NOT NULL DEFAULT 1,
+    first_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'acknowledged', 'resolved')),
+    acknowledged_by VARCHAR(255),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    resolved_by VARCHAR(255),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_alerts_status ON audit_alerts(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_alerts_user ON audit_alerts(user_id, created_at DESC);
+
+-- Riwayat IP per user untuk rule new_ip
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_ip ON audit_logs(user_id, created_at DESC)
+    WHERE ip_address IS NOT NULL;
+
+INSERT INTO app_permissions (nama, resource, action, deskripsi) VALUES
+('audit.alerts', 'audit', 'alerts', 'Melihat alert aktivitas mencurigakan'),
+('audit.acknowledge', 'audit', 'acknowledge', 'Acknowledge dan resolve alert aktivitas mencurigakan')
+ON CONFLICT (nama) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM app_roles r, app_permissions p
+WHERE r.nama = 'admin' AND p.nama IN ('audit.alerts', 'audit.acknowledge')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,32 @@
+-- ============================================================================
+-- MIGRATION: Audit Snapshots
+-- Version: 20
+-- Date: 2026-10-18
+-- Description: State record lengkap (tanpa masking) sebelum dan sesudah
+--              setiap mutasi yang dicatat audit, dipakai untuk membatalkan
+--              (revert) perubahan pegawai dan satker. Disimpan terpisah dari
+--              audit_logs karena berisi PII; hanya dibaca oleh endpoint revert
+--              dan dihapus bersama partisi audit_logs yang diarsipkan.
+-- ============================================================================
+
+\c db_master;
+
+CREATE TABLE IF NOT EXISTS audit_snapshots (
+    audit_log_id UUID PRIMARY KEY,
+    resource VARCHAR(100) NOT NULL,
+    resource_id UUID NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_snapshots_created ON audit_snapshots(created_at);
+
+INSERT INTO app_permissions (nama, resource, action, deskripsi) VALUES
+('audit.revert', 'audit', 'revert', 'Membatalkan perubahan data dari audit log')
+ON CONFLICT (nama) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM app_roles r, app_permissions p
+WHERE r.nama = 'admin' AND p.nama = 'audit.revert'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,38 @@
+-- ============================================================================
+-- MIGRATION: Riwayat Pendidikan
+-- Version: 21
+-- Date: 2026-10-18
+-- Description: Urutan jenjang pendidikan di ref_pendidikan dan pendidikan
+--              tertinggi pegawai yang diturunkan dari riwayat pendidikan.
+--              pendidikan_terakhir_id dikelola aplikasi setiap riwayat
+--              pendidikan berubah; jalankan `pendidikan-sync` sekali setelah
+--              migration untuk mengisi data yang sudah ada.
+-- ============================================================================
+
+\c db_master;
+
+ALTER TABLE ref_pendidikan ADD COLUMN IF NOT EXISTS urutan INT NOT NULL DEFAULT 0;
+
+UPDATE ref_pendidikan SET urutan = CASE tingkat
+    WHEN 'SD' THEN 1
+    WHEN 'SMP' THEN 2
+    WHEN 'SMA' THEN 3
+    WHEN 'D1' THEN 4
+    WHEN 'D2' THEN 5
+    WHEN 'D3' THEN 6
+    WHEN 'D4' THEN 7
+    WHEN 'S1' THEN 7 -- D4 setara S1
+    WHEN 'S2' THEN 8
+    WHEN 'S3' THEN 9
+    ELSE 0
+END
+WHERE urutan = 0;
+
+COMMENT ON COLUMN ref_pendidikan.urutan IS 'Urutan jenjang; nilai lebih besar berarti pendidikan lebih tinggi';
+
+\c db_kepegawaian;
+
+-- NOTE: pendidikan_terakhir_id references db_master.ref_pendidikan - integrity at app level
+ALTER TABLE pegawai ADD COLUMN IF NOT EXISTS pendidikan_terakhir_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_pegawai_pendidikan_terakhir ON pegawai(pendidikan_terakhir_id);
@@ -0,0 +1,28 @@
+-- ============================================================================
+-- MIGRATION: Hukuman Disiplin
+-- Version: 22
+-- Date: 2026-10-18
+-- Description: Kolom created_by hukdis dan permission khusus untuk membaca
+--              dan mengelola hukdis. Data hukdis tidak tercakup
+--              kepegawaian.read; hanya admin yang diberi akses, role lain
+--              ditambahkan lewat endpoint RBAC.
+-- ============================================================================
+
+\c db_kepegawaian;
+
+ALTER TABLE hukdis ADD COLUMN IF NOT EXISTS created_by UUID;
+
+-- Pencarian hukdis aktif per pegawai pada suatu tanggal
+CREATE INDEX IF NOT EXISTS idx_hukdis_pegawai_periode ON hukdis(pegawai_id, tanggal_mulai, tanggal_selesai);
+
+\c db_master;
+
+INSERT INTO app_permissions (nama, resource, action, deskripsi) VALUES
+('hukdis.read', 'hukdis', 'read', 'Melihat hukuman disiplin pegawai'),
+('hukdis.update', 'hukdis', 'update', 'Mencatat, mengubah dan menghapus hukuman disiplin pegawai')
+ON CONFLICT (nama) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM app_roles r, app_permissions p
+WHERE r.nama = 'admin' AND p.nama IN ('hukdis.read', 'hukdis.update')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+-- ============================================================================
+-- MIGRATION: Riwayat Diklat
+-- Version: 23
+-- Date: 2026-10-18
+-- Description: Kategori diklat yang dihitung untuk kewajiban 20 JP
+--              pengembangan kompetensi per tahun, dan kolom created_by
+--              diklat. Semua jenis diklat yang ada dihitung; matikan
+--              hitung_jp untuk kategori yang tidak diakui.
+-- ============================================================================
+
+\c db_master;
+
+ALTER TABLE ref_jenis_diklat ADD COLUMN IF NOT EXISTS hitung_jp BOOLEAN NOT NULL DEFAULT true;
+
+COMMENT ON COLUMN ref_jenis_diklat.hitung_jp IS 'JP diklat jenis ini dihitung untuk kewajiban pengembangan kompetensi tahunan';
+
+\c db_kepegawaian;
+
+ALTER TABLE diklat ADD COLUMN IF NOT EXISTS created_by UUID;